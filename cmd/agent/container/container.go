@@ -16,8 +16,11 @@ func NewContainerCmd(flags *flags.GlobalFlags) *cobra.Command {
 	containerCmd.AddCommand(NewDaemonCmd())
 	containerCmd.AddCommand(NewVSCodeAsyncCmd())
 	containerCmd.AddCommand(NewOpenVSCodeAsyncCmd())
+	containerCmd.AddCommand(NewLifecycleHooksCmd())
 	containerCmd.AddCommand(NewCredentialsServerCmd(flags))
 	containerCmd.AddCommand(NewSetupLoftPlatformAccessCmd(flags))
 	containerCmd.AddCommand(NewSSHServerCmd(flags))
+	containerCmd.AddCommand(NewStatusCmd())
+	containerCmd.AddCommand(NewSessionsCmd())
 	return containerCmd
 }
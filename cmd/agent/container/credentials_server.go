@@ -13,6 +13,8 @@ import (
 	"github.com/skevetter/devpod/pkg/agent/tunnel"
 	"github.com/skevetter/devpod/pkg/agent/tunnelserver"
 	"github.com/skevetter/devpod/pkg/credentials"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/setup"
 	"github.com/skevetter/devpod/pkg/dockercredentials"
 	"github.com/skevetter/devpod/pkg/gitcredentials"
 	"github.com/skevetter/devpod/pkg/gitsshsigning"
@@ -34,6 +36,7 @@ type CredentialsServerCmd struct {
 	ConfigureDockerHelper bool
 
 	ForwardPorts      bool
+	Sync              bool
 	GitUserSigningKey string
 }
 
@@ -58,6 +61,7 @@ func NewCredentialsServerCmd(flags *flags.GlobalFlags) *cobra.Command {
 	credentialsServerCmd.Flags().BoolVar(&cmd.ConfigureGitHelper, "configure-git-helper", false, "If true will configure git helper")
 	credentialsServerCmd.Flags().BoolVar(&cmd.ConfigureDockerHelper, "configure-docker-helper", false, "If true will configure docker helper")
 	credentialsServerCmd.Flags().BoolVar(&cmd.ForwardPorts, "forward-ports", false, "If true will automatically try to forward open ports within the container")
+	credentialsServerCmd.Flags().BoolVar(&cmd.Sync, "sync", false, "If true will continuously sync the workspace folder with the local folder")
 	credentialsServerCmd.Flags().StringVar(&cmd.GitUserSigningKey, "git-user-signing-key", "", "")
 	credentialsServerCmd.Flags().StringVar(&cmd.User, "user", "", "The user to use")
 	_ = credentialsServerCmd.MarkFlagRequired("user")
@@ -93,12 +97,28 @@ func (cmd *CredentialsServerCmd) Run(ctx context.Context, port int) error {
 		}()
 	}
 
+	// refresh secrets from the local secret stores
+	err = setup.FetchSecrets(ctx, tunnelClient, cmd.User)
+	if err != nil {
+		log.Errorf("Error retrieving secrets: %v", err)
+	}
+
 	addr := net.JoinHostPort("localhost", strconv.Itoa(port))
 	if ok, err := portpkg.IsAvailable(addr); !ok || err != nil {
 		log.Debugf("Port %d not available, exiting", port)
 		return nil
 	}
 
+	// sync the workspace folder, only a single credentials server does this
+	if cmd.Sync {
+		go func() {
+			err := syncWorkspace(ctx, cmd.User, tunnelClient, log)
+			if err != nil {
+				log.Errorf("Error syncing workspace folder: %v", err)
+			}
+		}()
+	}
+
 	// configure docker credential helper
 	if cmd.ConfigureDockerHelper {
 		err = dockercredentials.ConfigureCredentialsContainer(cmd.User, port, log)
@@ -191,7 +211,18 @@ func configureGitUserLocally(ctx context.Context, userName string, client tunnel
 }
 
 func forwardPorts(ctx context.Context, client tunnel.TunnelClient, log log.Logger) error {
-	return netstat.NewWatcher(&forwarder{ctx: ctx, client: client}, log).Run(ctx)
+	options := []netstat.Option{}
+	result, err := setup.ReadResult()
+	if err != nil {
+		log.Debugf("Error reading devcontainer result, forwarding ports without port attributes: %v", err)
+	} else if result.MergedConfig != nil {
+		options = append(options,
+			netstat.WithPortsAttributes(result.MergedConfig.PortsAttributes, result.MergedConfig.OtherPortsAttributes),
+			netstat.WithUnixSockets(config.GetForwardSockets(result.MergedConfig)),
+		)
+	}
+
+	return netstat.NewWatcher(&forwarder{ctx: ctx, client: client}, log, options...).Run(ctx)
 }
 
 type forwarder struct {
@@ -200,12 +231,12 @@ type forwarder struct {
 	client tunnel.TunnelClient
 }
 
-func (f *forwarder) Forward(port string) error {
-	_, err := f.client.ForwardPort(f.ctx, &tunnel.ForwardPortRequest{Port: port})
+func (f *forwarder) Forward(port netstat.Port) error {
+	_, err := f.client.ForwardPort(f.ctx, &tunnel.ForwardPortRequest{Port: port.Port, Protocol: port.Protocol})
 	return err
 }
 
-func (f *forwarder) StopForward(port string) error {
-	_, err := f.client.StopForwardPort(f.ctx, &tunnel.StopForwardPortRequest{Port: port})
+func (f *forwarder) StopForward(port netstat.Port) error {
+	_, err := f.client.StopForwardPort(f.ctx, &tunnel.StopForwardPortRequest{Port: port.Port, Protocol: port.Protocol})
 	return err
 }
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -16,9 +17,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/command"
 	agentd "github.com/skevetter/devpod/pkg/daemon/agent"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/setup"
 	"github.com/skevetter/devpod/pkg/platform/client"
+	devssh "github.com/skevetter/devpod/pkg/ssh"
 	"github.com/skevetter/devpod/pkg/ts"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
@@ -29,6 +33,19 @@ const (
 	DaemonConfigPath = "/var/run/secrets/devpod/daemon_config"
 )
 
+const (
+	shutdownActionNone          = "none"
+	shutdownActionStopContainer = "stopContainer"
+	shutdownActionStopCompose   = "stopCompose"
+
+	// shutdownActionGracePeriod is the time to wait after the last session was closed, so that
+	// reloading the IDE window doesn't stop the container
+	shutdownActionGracePeriod = time.Minute
+
+	// stopContainerTimeout is the time to wait for the init process of the container to exit
+	stopContainerTimeout = 30 * time.Second
+)
+
 type DaemonCmd struct {
 	Config *agentd.DaemonConfig
 	Log    log.Logger
@@ -99,6 +116,15 @@ func (cmd *DaemonCmd) Run(c *cobra.Command, args []string) error {
 		go runTimeoutMonitor(ctx, timeoutDuration, errChan, &wg)
 	}
 
+	// Start shutdown action monitor.
+	shutdownChan := make(chan struct{})
+	var shutdownOnce sync.Once
+	tasksStarted = true
+	wg.Add(1)
+	go runShutdownActionMonitor(ctx, cmd.Log, func() {
+		shutdownOnce.Do(func() { close(shutdownChan) })
+	}, &wg)
+
 	// Start ssh server.
 	if cmd.shouldRunSsh() {
 		tasksStarted = true
@@ -116,8 +142,13 @@ func (cmd *DaemonCmd) Run(c *cobra.Command, args []string) error {
 	// Listen for OS termination signals.
 	go handleSignals(ctx, errChan)
 
-	// Wait until an error (or termination signal) occurs.
-	err := <-errChan
+	// Wait until an error (or termination signal) occurs or the shutdown action stops the container.
+	var err error
+	select {
+	case err = <-errChan:
+	case <-shutdownChan:
+		cmd.Log.Infof("Last session closed, stopping container because of shutdownAction %s", shutdownActionStopContainer)
+	}
 	cancel()
 	wg.Wait()
 
@@ -213,6 +244,93 @@ func runTimeoutMonitor(ctx context.Context, duration time.Duration, errChan chan
 	}
 }
 
+// runShutdownActionMonitor counts the open ssh sessions and stops the container once the last session was
+// closed and the shutdownAction is stopContainer. The stopCompose action stops the other services of the
+// compose project as well, which only DevPod on the host can do.
+func runShutdownActionMonitor(ctx context.Context, log log.Logger, shutdown func(), wg *sync.WaitGroup) {
+	defer wg.Done()
+	counter := devssh.NewConnectionCounter(ctx, shutdownActionGracePeriod, func() {
+		shutdownAction := readShutdownAction()
+		switch shutdownAction {
+		case shutdownActionStopContainer:
+			stopContainer(log, shutdown)
+		case "", shutdownActionNone, shutdownActionStopCompose:
+		default:
+			log.Warnf("Unsupported shutdownAction '%s', expected one of none, stopContainer or stopCompose", shutdownAction)
+		}
+	}, "container", log)
+
+	connections := 0
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sessions, err := agent.CountContainerSessions()
+			if err != nil {
+				log.Debugf("Error counting sessions: %v", err)
+				continue
+			}
+
+			for ; connections < sessions; connections++ {
+				counter.Add()
+			}
+			for ; connections > sessions; connections-- {
+				counter.Dec()
+			}
+		}
+	}
+}
+
+// stopContainer stops the container by exiting if the daemon is the container entrypoint, otherwise the
+// init process of the container is asked to terminate
+func stopContainer(log log.Logger, shutdown func()) {
+	if os.Getpid() == 1 {
+		shutdown()
+		return
+	}
+
+	err := terminateProcess(1, stopContainerTimeout)
+	if err != nil {
+		// an init process without a handler for SIGTERM ignores it, e.g. sleep infinity
+		log.Warnf("Error stopping container, use an init process that handles SIGTERM, e.g. with \"init\": true in the devcontainer.json: %v", err)
+	}
+}
+
+// terminateProcess sends SIGTERM to the process and waits until it exited
+func terminateProcess(pid int, timeout time.Duration) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	err = process.Signal(syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(100 * time.Millisecond) {
+		running, err := command.IsRunning(strconv.Itoa(pid))
+		if err == nil && !running {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("process %d is still running %s after SIGTERM", pid, timeout)
+}
+
+// readShutdownAction returns the shutdownAction of the devcontainer.json. The setup result is
+// read on demand, because the daemon is started before the container is set up.
+func readShutdownAction() string {
+	result, err := setup.ReadResult()
+	if err != nil || result.MergedConfig == nil {
+		return ""
+	}
+
+	return result.MergedConfig.ShutdownAction
+}
+
 // runNetworkServer starts the network server.
 func runNetworkServer(ctx context.Context, cmd *DaemonCmd, errChan chan<- error, wg *sync.WaitGroup) {
 	defer wg.Done()
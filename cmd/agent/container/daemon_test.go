@@ -0,0 +1,51 @@
+package container
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTerminateProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("processes are terminated by signals")
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		wantErr bool
+	}{
+		{name: "terminated", command: "sleep 60"},
+		{name: "handles SIGTERM", command: "trap 'exit 0' TERM; while true; do sleep 0.1; done"},
+		{name: "ignores SIGTERM", command: "trap '' TERM; while true; do sleep 0.1; done", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			process := exec.Command("sh", "-c", tt.command)
+			err := process.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// reap the process, so it doesn't stay around as zombie
+			exited := make(chan struct{})
+			go func() {
+				_ = process.Wait()
+				close(exited)
+			}()
+			defer func() {
+				_ = process.Process.Kill()
+				<-exited
+			}()
+
+			// give the shell time to install its traps
+			time.Sleep(200 * time.Millisecond)
+			err = terminateProcess(process.Process.Pid, 2*time.Second)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+		})
+	}
+}
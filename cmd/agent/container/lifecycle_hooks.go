@@ -0,0 +1,95 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/compress"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/setup"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// LifecycleHooksCmd holds the cmd flags
+type LifecycleHooksCmd struct {
+	*flags.GlobalFlags
+
+	SetupInfo string
+}
+
+// NewLifecycleHooksCmd creates a new command
+func NewLifecycleHooksCmd() *cobra.Command {
+	cmd := &LifecycleHooksCmd{}
+	lifecycleHooksCmd := &cobra.Command{
+		Use:   "lifecycle-hooks",
+		Short: "Runs the lifecycle hooks after waitFor in the background",
+		Args:  cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, _ []string) error {
+			return cmd.Run(cobraCmd.Context())
+		},
+	}
+	lifecycleHooksCmd.Flags().StringVar(&cmd.SetupInfo, "setup-info", "", "The container setup info")
+	_ = lifecycleHooksCmd.MarkFlagRequired("setup-info")
+	return lifecycleHooksCmd
+}
+
+// Run runs the command logic
+func (cmd *LifecycleHooksCmd) Run(ctx context.Context) error {
+	err := os.MkdirAll(filepath.Dir(setup.LifecycleHooksLogFile), 0777)
+	if err != nil {
+		return err
+	}
+	logger := log.NewFileLogger(setup.LifecycleHooksLogFile, logrus.InfoLevel)
+
+	decompressed, err := compress.Decompress(cmd.SetupInfo)
+	if err != nil {
+		return err
+	}
+
+	setupInfo := &config.Result{}
+	err = json.Unmarshal([]byte(decompressed), setupInfo)
+	if err != nil {
+		return err
+	}
+
+	// fill container env
+	err = fillContainerEnv(setupInfo)
+	if err != nil {
+		return err
+	}
+
+	err = setup.RunBackgroundLifecycleHooks(ctx, setupInfo, logger)
+	if err != nil {
+		logger.Errorf("Error running lifecycle hooks: %v", err)
+		return fmt.Errorf("lifecycle hooks %w", err)
+	}
+
+	logger.Donef("Successfully ran lifecycle hooks")
+	return nil
+}
+
+func fillContainerEnv(setupInfo *config.Result) error {
+	// set remote-env
+	if setupInfo.MergedConfig.RemoteEnv == nil {
+		setupInfo.MergedConfig.RemoteEnv = make(map[string]string)
+	}
+
+	if _, ok := setupInfo.MergedConfig.RemoteEnv["PATH"]; !ok {
+		setupInfo.MergedConfig.RemoteEnv["PATH"] = "${containerEnv:PATH}"
+	}
+
+	// merge config
+	newMergedConfig := &config.MergedDevContainerConfig{}
+	err := config.SubstituteContainerEnv(config.ListToObject(os.Environ()), setupInfo.MergedConfig, newMergedConfig)
+	if err != nil {
+		return fmt.Errorf("substitute container env %w", err)
+	}
+	setupInfo.MergedConfig = newMergedConfig
+	return nil
+}
@@ -0,0 +1,69 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/skevetter/devpod/pkg/asciicast"
+	sshserver "github.com/skevetter/devpod/pkg/ssh/server"
+	"github.com/spf13/cobra"
+)
+
+// SessionsCmd holds the cmd flags
+type SessionsCmd struct {
+	Name string
+}
+
+// NewSessionsCmd creates a new command
+func NewSessionsCmd() *cobra.Command {
+	cmd := &SessionsCmd{}
+	sessionsCmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Lists the recorded ssh sessions in the container or prints a recording",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return cmd.Run()
+		},
+	}
+	sessionsCmd.Flags().StringVar(&cmd.Name, "name", "", "The recording to print, if empty the recordings are listed as json")
+	return sessionsCmd
+}
+
+// Run prints the recordings as json or the content of a single recording
+func (cmd *SessionsCmd) Run() error {
+	if cmd.Name == "" {
+		recordings, err := asciicast.List(sshserver.RecordingsDir)
+		if err != nil {
+			return fmt.Errorf("list recordings %w", err)
+		}
+
+		out, err := json.Marshal(recordings)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(out))
+		return nil
+	}
+
+	err := asciicast.ValidateName(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Join(sshserver.RecordingsDir, cmd.Name+asciicast.Extension))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session %s not found", cmd.Name)
+		}
+
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = io.Copy(os.Stdout, f)
+	return err
+}
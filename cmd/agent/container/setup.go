@@ -45,7 +45,9 @@ import (
 	"github.com/skevetter/devpod/pkg/ide/vscode"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
 	"github.com/skevetter/devpod/pkg/single"
+	sshserver "github.com/skevetter/devpod/pkg/ssh/server"
 	"github.com/skevetter/devpod/pkg/ts"
+	"github.com/skevetter/devpod/pkg/upload"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
 )
@@ -194,6 +196,20 @@ func (cmd *SetupContainerCmd) Run(ctx context.Context) error {
 		}
 	}
 
+	// apply the ssh access policy before the workspace is accessible
+	err = writeAccessPolicy(setupInfo, &workspaceInfo.Agent.SSH)
+	if err != nil {
+		return fmt.Errorf("write ssh access policy %w", err)
+	}
+	err = sshserver.PrepareAuditFiles(config.GetRemoteUser(setupInfo))
+	if err != nil {
+		logger.Warnf("Error preparing ssh audit log: %v", err)
+	}
+	err = sshserver.PrepareRecordingsDir()
+	if err != nil {
+		logger.Warnf("Error preparing session recordings folder: %v", err)
+	}
+
 	// setup container
 	err = setup.SetupContainer(ctx, setupInfo, workspaceInfo.CLIOptions.WorkspaceEnv, cmd.ChownWorkspace, &workspaceInfo.CLIOptions.Platform, tunnelClient, logger)
 	if err != nil {
@@ -205,6 +221,26 @@ func (cmd *SetupContainerCmd) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	err = setup.WriteIDEInstalled(workspaceInfo.IDE.Name)
+	if err != nil {
+		logger.Debugf("Error recording installed ide: %v", err)
+	}
+
+	// run remaining lifecycle hooks in the background
+	if setup.HasBackgroundLifecycleHooks(setupInfo) {
+		err = single.Single(fmt.Sprintf("devpod.lifecyclehooks-%s.pid", setup.BackgroundLifecycleHooksHash(setupInfo)[:16]), func() (*exec.Cmd, error) {
+			logger.Infof("Run remaining lifecycle hooks in the background, see devpod logs --lifecycle-hooks")
+			binaryPath, err := os.Executable()
+			if err != nil {
+				return nil, err
+			}
+
+			return exec.Command(binaryPath, "agent", "container", "lifecycle-hooks", "--setup-info", cmd.SetupInfo), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
 
 	// start container daemon if necessary
 	if !workspaceInfo.CLIOptions.Platform.Enabled && !workspaceInfo.CLIOptions.DisableDaemon && workspaceInfo.ContainerTimeout != "" {
@@ -235,26 +271,6 @@ func (cmd *SetupContainerCmd) Run(ctx context.Context) error {
 	return nil
 }
 
-func fillContainerEnv(setupInfo *config.Result) error {
-	// set remote-env
-	if setupInfo.MergedConfig.RemoteEnv == nil {
-		setupInfo.MergedConfig.RemoteEnv = make(map[string]string)
-	}
-
-	if _, ok := setupInfo.MergedConfig.RemoteEnv["PATH"]; !ok {
-		setupInfo.MergedConfig.RemoteEnv["PATH"] = "${containerEnv:PATH}"
-	}
-
-	// merge config
-	newMergedConfig := &config.MergedDevContainerConfig{}
-	err := config.SubstituteContainerEnv(config.ListToObject(os.Environ()), setupInfo.MergedConfig, newMergedConfig)
-	if err != nil {
-		return fmt.Errorf("substitute container env %w", err)
-	}
-	setupInfo.MergedConfig = newMergedConfig
-	return nil
-}
-
 func dockerlessBuild(
 	ctx context.Context,
 	setupInfo *config.Result,
@@ -400,6 +416,40 @@ func parseIgnorePaths(ignorePaths string) []string {
 	return retPaths
 }
 
+// writeAccessPolicy merges the ssh access policy of the provider and the devcontainer.json. Deny rules of
+// both are applied, the allow list of the provider takes precedence over the one of the devcontainer.json.
+// Sessions are recorded if either of them enables it.
+func writeAccessPolicy(setupInfo *config.Result, providerPolicy *provider2.ProviderSSHConfig) error {
+	devContainerPolicy := config.GetSSHConfiguration(setupInfo.MergedConfig)
+	recordSessions, _ := providerPolicy.RecordSessions.Bool()
+	policy := &sshserver.AccessPolicy{
+		AllowForwards:  splitList(providerPolicy.AllowForwards),
+		DenyForwards:   append(splitList(providerPolicy.DenyForwards), devContainerPolicy.DenyForwards...),
+		AllowSockets:   splitList(providerPolicy.AllowSockets),
+		DenySockets:    append(splitList(providerPolicy.DenySockets), devContainerPolicy.DenySockets...),
+		RecordSessions: recordSessions || devContainerPolicy.RecordSessions,
+	}
+	if len(policy.AllowForwards) == 0 {
+		policy.AllowForwards = devContainerPolicy.AllowForwards
+	}
+	if len(policy.AllowSockets) == 0 {
+		policy.AllowSockets = devContainerPolicy.AllowSockets
+	}
+
+	return sshserver.WriteAccessPolicy(sshserver.AccessPolicyFile, policy)
+}
+
+func splitList(list string) []string {
+	retList := []string{}
+	for s := range strings.SplitSeq(list, ",") {
+		if strings.TrimSpace(s) != "" {
+			retList = append(retList, strings.TrimSpace(s))
+		}
+	}
+
+	return retList
+}
+
 func configureDockerCredentials(
 	ctx context.Context,
 	cancel context.CancelFunc,
@@ -659,6 +709,18 @@ func streamMount(ctx context.Context, workspaceInfo *provider2.ContainerWorkspac
 
 	// stream mount
 	logger.Infof("Copy %s into DevContainer %s", m.Source, m.Target)
+	err := upload.Receive(ctx, func(ctx context.Context) (upload.Stream, error) {
+		return tunnelClient.UploadWorkspace(ctx)
+	}, upload.Options{Mount: m.String(), Target: m.Target}, logger)
+	if !errors.Is(err, upload.ErrUnsupported) {
+		if err != nil {
+			return fmt.Errorf("stream mount %s %w", m.String(), err)
+		}
+
+		return nil
+	}
+
+	// older clients only support streaming the whole folder
 	stream, err := tunnelClient.StreamMount(ctx, &tunnel.StreamMountRequest{Mount: m.String()})
 	if err != nil {
 		return fmt.Errorf("init stream mount %s %w", m.String(), err)
@@ -49,6 +49,7 @@ func (cmd *SSHServerCmd) Run(_ *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	defer func() { _ = server.Close() }()
 
 	// check if ssh is already running at that port
 	available, err := port.IsAvailable(cmd.Address)
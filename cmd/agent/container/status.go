@@ -0,0 +1,58 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/devcontainer/setup"
+	"github.com/skevetter/devpod/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// StatusCmd holds the cmd flags
+type StatusCmd struct{}
+
+// NewStatusCmd creates a new command
+func NewStatusCmd() *cobra.Command {
+	cmd := &StatusCmd{}
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Prints the status of the workspace from inside the container",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return cmd.Run()
+		},
+	}
+	return statusCmd
+}
+
+// Run prints the last activity, the installed ide and the lifecycle hooks state as json
+func (cmd *StatusCmd) Run() error {
+	status := &client.WorkspaceStatus{}
+
+	stat, err := os.Stat(agent.ContainerActivityFile)
+	if err == nil {
+		status.LastActivity = &types.Time{Time: stat.ModTime()}
+	}
+
+	ide := setup.ReadIDEInstalled()
+	if ide != "" {
+		status.IDE = &client.IDEStatus{Name: ide, Installed: true}
+	}
+
+	status.LifecycleHooks, err = setup.ReadLifecycleHooksStatus()
+	if err != nil {
+		return fmt.Errorf("read lifecycle hooks status %w", err)
+	}
+
+	out, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(out))
+	return nil
+}
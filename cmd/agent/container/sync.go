@@ -0,0 +1,138 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/skevetter/devpod/pkg/agent/tunnel"
+	"github.com/skevetter/devpod/pkg/agent/tunnelserver"
+	"github.com/skevetter/devpod/pkg/devcontainer/setup"
+	"github.com/skevetter/devpod/pkg/filesync"
+	"github.com/skevetter/log"
+)
+
+func syncWorkspace(ctx context.Context, userName string, client tunnel.TunnelClient, log log.Logger) error {
+	result, err := setup.ReadResult()
+	if err != nil {
+		return fmt.Errorf("read devcontainer result %w", err)
+	} else if result.SubstitutionContext == nil || result.SubstitutionContext.ContainerWorkspaceFolder == "" {
+		return fmt.Errorf("workspace folder not found")
+	}
+
+	log.Debugf("Start syncing %s", result.SubstitutionContext.ContainerWorkspaceFolder)
+	return filesync.NewEngine(result.SubstitutionContext.ContainerWorkspaceFolder, userName, &syncRemote{client: client, log: log}, log).Run(ctx)
+}
+
+// syncRemote accesses the local folder through the tunnel
+type syncRemote struct {
+	client tunnel.TunnelClient
+	log    log.Logger
+}
+
+func (s *syncRemote) Watch(ctx context.Context, generation string) (*filesync.WatchResponse, error) {
+	response, err := s.client.SyncWatch(ctx, &tunnel.Message{Message: generation})
+	if err != nil {
+		return nil, err
+	}
+
+	watchResponse := &filesync.WatchResponse{}
+	err = json.Unmarshal([]byte(response.Message), watchResponse)
+	if err != nil {
+		return nil, fmt.Errorf("decode watch response %w", err)
+	}
+
+	return watchResponse, nil
+}
+
+func (s *syncRemote) Index(ctx context.Context, request *filesync.IndexRequest) (filesync.Index, error) {
+	out, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := s.client.SyncIndex(ctx, &tunnel.Message{Message: string(out)})
+	if err != nil {
+		return nil, err
+	}
+
+	index := filesync.Index{}
+	err = json.NewDecoder(tunnelserver.NewStreamReader(stream, s.log)).Decode(&index)
+	if err != nil {
+		return nil, fmt.Errorf("decode index %w", err)
+	}
+
+	return index, nil
+}
+
+func (s *syncRemote) Download(ctx context.Context, paths []string) (io.Reader, error) {
+	out, err := json.Marshal(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := s.client.SyncDownload(ctx, &tunnel.Message{Message: string(out)})
+	if err != nil {
+		return nil, err
+	}
+
+	return tunnelserver.NewStreamReader(stream, s.log), nil
+}
+
+func (s *syncRemote) Upload(ctx context.Context, reader io.Reader) error {
+	stream, err := s.client.SyncUpload(ctx)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 10*1024)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			sendErr := stream.Send(&tunnel.Chunk{Content: buf[:n]})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			_ = stream.CloseSend()
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+func (s *syncRemote) Delete(ctx context.Context, paths []string) ([]string, error) {
+	out, err := json.Marshal(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.SyncDelete(ctx, &tunnel.Message{Message: string(out)})
+	if err != nil {
+		return nil, err
+	}
+
+	failed := []string{}
+	err = json.Unmarshal([]byte(response.Message), &failed)
+	if err != nil {
+		return nil, fmt.Errorf("decode delete response %w", err)
+	}
+
+	return failed, nil
+}
+
+func (s *syncRemote) ReportStatus(ctx context.Context, status *filesync.Status) error {
+	out, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.SyncStatus(ctx, &tunnel.Message{Message: string(out)})
+	return err
+}
@@ -68,6 +68,11 @@ func (cmd *DeleteCmd) Run(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("remove container %w", err)
 		}
+
+		err = deleteSnapshotImages(ctx, workspaceInfo, log.Default)
+		if err != nil {
+			return fmt.Errorf("delete snapshots %w", err)
+		}
 	}
 
 	// delete workspace folder
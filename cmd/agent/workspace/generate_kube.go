@@ -0,0 +1,68 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/devcontainer"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/devpod/pkg/driver/drivercreate"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// GenerateKubeCmd holds the cmd flags
+type GenerateKubeCmd struct {
+	*flags.GlobalFlags
+
+	ID string
+}
+
+// NewGenerateKubeCmd creates a new command
+func NewGenerateKubeCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &GenerateKubeCmd{
+		GlobalFlags: flags,
+	}
+	generateKubeCmd := &cobra.Command{
+		Use:   "generate-kube",
+		Short: "Prints the workspace container as kubernetes yaml",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return cmd.Run(context.Background())
+		},
+	}
+	generateKubeCmd.Flags().StringVar(&cmd.ID, "id", "", "The workspace id")
+	_ = generateKubeCmd.MarkFlagRequired("id")
+	return generateKubeCmd
+}
+
+func (cmd *GenerateKubeCmd) Run(ctx context.Context) error {
+	// get workspace info
+	shouldExit, workspaceInfo, err := agent.ReadAgentWorkspaceInfo(cmd.AgentDir, cmd.Context, cmd.ID, log.Default.ErrorStreamOnly())
+	if err != nil {
+		return err
+	} else if shouldExit {
+		return nil
+	}
+	logger := log.Default.ErrorStreamOnly()
+
+	d, err := drivercreate.NewDriver(workspaceInfo, logger)
+	if err != nil {
+		return fmt.Errorf("create driver %w", err)
+	}
+	kubeDriver, ok := d.(driver.KubeExportDriver)
+	if !ok {
+		return fmt.Errorf("generating kubernetes yaml is only supported by the podman driver")
+	}
+
+	out, err := kubeDriver.GenerateKube(ctx, devcontainer.GetRunnerIDFromWorkspace(workspaceInfo.Workspace))
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(out)
+	return err
+}
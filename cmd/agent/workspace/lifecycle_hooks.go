@@ -0,0 +1,71 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/devcontainer"
+	"github.com/skevetter/devpod/pkg/devcontainer/setup"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// LifecycleHooksCmd holds the cmd flags
+type LifecycleHooksCmd struct {
+	*flags.GlobalFlags
+
+	ID     string
+	Output string
+}
+
+// NewLifecycleHooksCmd creates a new command
+func NewLifecycleHooksCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &LifecycleHooksCmd{
+		GlobalFlags: flags,
+	}
+	c := &cobra.Command{
+		Use:   "lifecycle-hooks",
+		Short: "Returns the state or the logs of the lifecycle hooks in the workspace container",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return cmd.Run(context.Background())
+		},
+	}
+	c.Flags().StringVar(&cmd.ID, "id", "", "The workspace id")
+	_ = c.MarkFlagRequired("id")
+	c.Flags().StringVar(&cmd.Output, "output", "logs", "What to print, either logs or json")
+	return c
+}
+
+func (cmd *LifecycleHooksCmd) Run(ctx context.Context) error {
+	var file string
+	switch cmd.Output {
+	case "logs":
+		file = setup.LifecycleHooksLogFile
+	case "json":
+		file = setup.LifecycleHooksStatusFile
+	default:
+		return fmt.Errorf("unexpected output format, choose either logs or json. Got %s", cmd.Output)
+	}
+
+	// get workspace info
+	shouldExit, workspaceInfo, err := agent.ReadAgentWorkspaceInfo(cmd.AgentDir, cmd.Context, cmd.ID, log.Default.ErrorStreamOnly())
+	if err != nil {
+		return err
+	} else if shouldExit {
+		return nil
+	}
+	logger := log.Default.ErrorStreamOnly()
+
+	// create new runner
+	runner, err := devcontainer.NewRunner(agent.ContainerDevPodHelperLocation, agent.DefaultAgentDownloadURL(), workspaceInfo, logger)
+	if err != nil {
+		return fmt.Errorf("create runner %w", err)
+	}
+
+	// the files don't exist if no lifecycle hooks ran yet
+	return runner.Command(ctx, "root", fmt.Sprintf("cat '%s' 2>/dev/null || true", file), nil, os.Stdout, os.Stderr)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/skevetter/devpod/cmd/flags"
 	"github.com/skevetter/devpod/pkg/agent"
 	"github.com/skevetter/devpod/pkg/devcontainer"
+	sshserver "github.com/skevetter/devpod/pkg/ssh/server"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
 )
@@ -16,7 +17,8 @@ import (
 type LogsCmd struct {
 	*flags.GlobalFlags
 
-	ID string
+	ID    string
+	Audit bool
 }
 
 // NewLogsCmd creates a new command
@@ -34,6 +36,7 @@ func NewLogsCmd(flags *flags.GlobalFlags) *cobra.Command {
 	}
 	c.Flags().StringVar(&cmd.ID, "id", "", "The workspace id")
 	_ = c.MarkFlagRequired("id")
+	c.Flags().BoolVar(&cmd.Audit, "audit", false, "If enabled prints the ssh audit log of the workspace container")
 
 	return c
 }
@@ -54,6 +57,11 @@ func (cmd *LogsCmd) Run(ctx context.Context) error {
 		return fmt.Errorf("create runner %w", err)
 	}
 
+	// the audit logs don't exist if nobody connected yet
+	if cmd.Audit {
+		return runner.Command(ctx, "root", fmt.Sprintf("cat '%s' %s 2>/dev/null || true", sshserver.AuditLogFile, sshserver.AuditLogFileOf("*")), nil, os.Stdout, os.Stderr)
+	}
+
 	// write devcontainer logs to stdout
 	return runner.Logs(ctx, os.Stdout)
 }
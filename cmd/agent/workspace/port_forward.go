@@ -0,0 +1,134 @@
+package workspace
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/devcontainer"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/devpod/pkg/driver/drivercreate"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// PortForwardCmd holds the cmd flags
+type PortForwardCmd struct {
+	*flags.GlobalFlags
+
+	ID string
+}
+
+// NewPortForwardCmd creates a new command
+func NewPortForwardCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &PortForwardCmd{
+		GlobalFlags: flags,
+	}
+	portForwardCmd := &cobra.Command{
+		Use:   "port-forward",
+		Short: "Forwards connections to ports of the workspace container through the driver",
+		Long: `Listens on a unix socket and forwards its connections to ports of the workspace container through the
+driver. The path of the socket is printed once it is ready, every connection starts with the port followed by a
+newline. The command stops once stdin is closed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cobraCmd *cobra.Command, _ []string) error {
+			return cmd.Run(cobraCmd.Context())
+		},
+	}
+	portForwardCmd.Flags().StringVar(&cmd.ID, "id", "", "The workspace id")
+	_ = portForwardCmd.MarkFlagRequired("id")
+	return portForwardCmd
+}
+
+func (cmd *PortForwardCmd) Run(ctx context.Context) error {
+	// stdout is used to print the socket, so only errors may be logged
+	logger := log.Default.ErrorStreamOnly()
+
+	// get workspace info
+	shouldExit, workspaceInfo, err := agent.ReadAgentWorkspaceInfo(cmd.AgentDir, cmd.Context, cmd.ID, logger)
+	if err != nil {
+		return err
+	} else if shouldExit {
+		return nil
+	}
+
+	d, err := drivercreate.NewDriver(workspaceInfo, logger)
+	if err != nil {
+		return fmt.Errorf("create driver %w", err)
+	}
+	portForwardDriver, ok := d.(driver.PortForwardDriver)
+	if !ok {
+		return fmt.Errorf("forwarding ports through the driver is only supported by the kubernetes driver")
+	}
+
+	socketDir, err := os.MkdirTemp("", "devpod-port-forward-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(socketDir) }()
+
+	socketPath := filepath.Join(socketDir, "port-forward.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = listener.Close() }()
+
+	// stop once the client closes stdin
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		_, _ = io.Copy(io.Discard, os.Stdin)
+		cancel()
+		_ = listener.Close()
+	}()
+
+	fmt.Println(socketPath)
+	workspaceID := devcontainer.GetRunnerIDFromWorkspace(workspaceInfo.Workspace)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("accept connection %w", err)
+		}
+
+		go func() {
+			defer func() { _ = conn.Close() }()
+
+			err := forwardConnection(ctx, portForwardDriver, workspaceID, conn)
+			if err != nil {
+				logger.Errorf("Error forwarding connection: %v", err)
+			}
+		}()
+	}
+}
+
+// forwardConnection reads the port from the first line of the connection and connects the rest of it to the
+// port of the workspace container
+func forwardConnection(ctx context.Context, portForwardDriver driver.PortForwardDriver, workspaceID string, conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read port %w", err)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return fmt.Errorf("parse port %w", err)
+	}
+
+	return portForwardDriver.PortForward(ctx, workspaceID, port, struct {
+		io.Reader
+		io.Writer
+	}{reader, conn})
+}
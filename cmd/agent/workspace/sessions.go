@@ -0,0 +1,70 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/asciicast"
+	"github.com/skevetter/devpod/pkg/devcontainer"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// SessionsCmd holds the cmd flags
+type SessionsCmd struct {
+	*flags.GlobalFlags
+
+	ID   string
+	Name string
+}
+
+// NewSessionsCmd creates a new command
+func NewSessionsCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &SessionsCmd{
+		GlobalFlags: flags,
+	}
+	c := &cobra.Command{
+		Use:   "sessions",
+		Short: "Lists the recorded ssh sessions of the workspace container or prints a recording",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return cmd.Run(context.Background())
+		},
+	}
+	c.Flags().StringVar(&cmd.ID, "id", "", "The workspace id")
+	_ = c.MarkFlagRequired("id")
+	c.Flags().StringVar(&cmd.Name, "name", "", "The recording to print, if empty the recordings are listed as json")
+	return c
+}
+
+func (cmd *SessionsCmd) Run(ctx context.Context) error {
+	// get workspace info
+	shouldExit, workspaceInfo, err := agent.ReadAgentWorkspaceInfo(cmd.AgentDir, cmd.Context, cmd.ID, log.Default.ErrorStreamOnly())
+	if err != nil {
+		return err
+	} else if shouldExit {
+		return nil
+	}
+	logger := log.Default.ErrorStreamOnly()
+
+	// create new runner
+	runner, err := devcontainer.NewRunner(agent.ContainerDevPodHelperLocation, agent.DefaultAgentDownloadURL(), workspaceInfo, logger)
+	if err != nil {
+		return fmt.Errorf("create runner %w", err)
+	}
+
+	command := fmt.Sprintf("'%s' agent container sessions", agent.ContainerDevPodHelperLocation)
+	if cmd.Name != "" {
+		err = asciicast.ValidateName(cmd.Name)
+		if err != nil {
+			return err
+		}
+
+		command += fmt.Sprintf(" --name '%s'", cmd.Name)
+	}
+
+	return runner.Command(ctx, "root", command, nil, os.Stdout, os.Stderr)
+}
@@ -0,0 +1,299 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/devcontainer"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/devpod/pkg/driver/drivercreate"
+	"github.com/skevetter/devpod/pkg/extract"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/types"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	snapshotsFolder        = "snapshots"
+	snapshotVolumesFolder  = "volumes"
+	snapshotContentArchive = "content.tar.gz"
+)
+
+// SnapshotCmd holds the cmd flags
+type SnapshotCmd struct {
+	*flags.GlobalFlags
+
+	ID   string
+	Name string
+}
+
+// NewSnapshotCmd creates a new command
+func NewSnapshotCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &SnapshotCmd{
+		GlobalFlags: flags,
+	}
+	snapshotCmd := &cobra.Command{
+		Use:       "snapshot [create|restore|delete]",
+		Short:     "Creates, restores or deletes a snapshot of the workspace container",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"create", "restore", "delete"},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(context.Background(), args[0])
+		},
+	}
+	snapshotCmd.Flags().StringVar(&cmd.ID, "id", "", "The workspace id")
+	_ = snapshotCmd.MarkFlagRequired("id")
+	snapshotCmd.Flags().StringVar(&cmd.Name, "name", "", "The snapshot name")
+	_ = snapshotCmd.MarkFlagRequired("name")
+	return snapshotCmd
+}
+
+func (cmd *SnapshotCmd) Run(ctx context.Context, action string) error {
+	err := provider2.ValidateSnapshotName(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	// get workspace info
+	shouldExit, workspaceInfo, err := agent.ReadAgentWorkspaceInfo(cmd.AgentDir, cmd.Context, cmd.ID, log.Default.ErrorStreamOnly())
+	if err != nil {
+		return err
+	} else if shouldExit {
+		return nil
+	}
+	logger := log.Default.ErrorStreamOnly()
+
+	d, err := drivercreate.NewDriver(workspaceInfo, logger)
+	if err != nil {
+		return fmt.Errorf("create driver %w", err)
+	}
+	snapshotDriver, ok := d.(driver.SnapshotDriver)
+	if !ok {
+		return fmt.Errorf("snapshots are only supported by the docker and podman drivers")
+	}
+
+	runnerID := devcontainer.GetRunnerIDFromWorkspace(workspaceInfo.Workspace)
+	snapshotDir := filepath.Join(workspaceInfo.Origin, snapshotsFolder, cmd.Name)
+	image := provider2.SnapshotImageName(workspaceInfo.Workspace.UID, cmd.Name)
+	switch action {
+	case "create":
+		return createSnapshot(ctx, snapshotDriver, workspaceInfo, runnerID, cmd.Name, image, snapshotDir, logger)
+	case "restore":
+		err = restoreSnapshot(ctx, snapshotDriver, workspaceInfo, runnerID, image, snapshotDir, logger)
+		if err != nil {
+			return err
+		}
+
+		return recreateFromSnapshot(ctx, workspaceInfo, image, logger)
+	case "delete":
+		err = snapshotDriver.DeleteSnapshot(ctx, image)
+		if err != nil {
+			return fmt.Errorf("delete snapshot image %w", err)
+		}
+
+		err = os.RemoveAll(snapshotDir)
+		if err != nil {
+			return fmt.Errorf("remove snapshot folder %w", err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unexpected action %s, choose either create, restore or delete", action)
+	}
+}
+
+func createSnapshot(
+	ctx context.Context,
+	snapshotDriver driver.SnapshotDriver,
+	workspaceInfo *provider2.AgentWorkspaceInfo,
+	runnerID, name, image, snapshotDir string,
+	log log.Logger,
+) (err error) {
+	_, err = os.Stat(snapshotDir)
+	if err == nil {
+		return fmt.Errorf("snapshot %s already exists", name)
+	}
+	err = os.MkdirAll(snapshotDir, 0755)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(snapshotDir)
+		}
+	}()
+
+	volumes, err := snapshotDriver.CreateSnapshot(ctx, runnerID, image, filepath.Join(snapshotDir, snapshotVolumesFolder))
+	if err != nil {
+		return fmt.Errorf("create snapshot %w", err)
+	}
+
+	snapshot := &provider2.Snapshot{
+		Name:              name,
+		Image:             image,
+		Volumes:           volumes,
+		CreationTimestamp: types.Now(),
+	}
+	if hasManagedContentFolder(workspaceInfo) {
+		log.Debugf("archiving content folder %s", workspaceInfo.ContentFolder)
+		err = archiveContentFolder(workspaceInfo.ContentFolder, filepath.Join(snapshotDir, snapshotContentArchive))
+		if err != nil {
+			return fmt.Errorf("archive content folder %w", err)
+		}
+
+		snapshot.Content = true
+	}
+
+	out, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(out))
+	return nil
+}
+
+func restoreSnapshot(
+	ctx context.Context,
+	snapshotDriver driver.SnapshotDriver,
+	workspaceInfo *provider2.AgentWorkspaceInfo,
+	runnerID, image, snapshotDir string,
+	log log.Logger,
+) error {
+	volumesDir := filepath.Join(snapshotDir, snapshotVolumesFolder)
+	entries, err := os.ReadDir(volumesDir)
+	if err != nil {
+		return fmt.Errorf("read snapshot %w", err)
+	}
+
+	volumes := []string{}
+	for _, entry := range entries {
+		if volume, ok := strings.CutSuffix(entry.Name(), ".tar"); ok {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	err = snapshotDriver.RestoreSnapshot(ctx, runnerID, image, volumesDir, volumes)
+	if err != nil {
+		return fmt.Errorf("restore snapshot %w", err)
+	}
+
+	contentArchive := filepath.Join(snapshotDir, snapshotContentArchive)
+	_, err = os.Stat(contentArchive)
+	if err == nil && hasManagedContentFolder(workspaceInfo) {
+		log.Debugf("restoring content folder %s", workspaceInfo.ContentFolder)
+		err = restoreContentFolder(contentArchive, workspaceInfo.ContentFolder)
+		if err != nil {
+			return fmt.Errorf("restore content folder %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recreateFromSnapshot creates the devcontainer from the snapshot image and prints the result
+func recreateFromSnapshot(ctx context.Context, workspaceInfo *provider2.AgentWorkspaceInfo, image string, log log.Logger) error {
+	runner, err := CreateRunner(workspaceInfo, log)
+	if err != nil {
+		return err
+	}
+
+	options := workspaceInfo.CLIOptions
+	options.Recreate = true
+	result, err := runner.Up(ctx, devcontainer.UpOptions{
+		CLIOptions:    options,
+		RegistryCache: workspaceInfo.RegistryCache,
+		SnapshotImage: image,
+	}, workspaceInfo.InjectTimeout)
+	if err != nil {
+		return fmt.Errorf("recreate devcontainer %w", err)
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(out))
+	return nil
+}
+
+// deleteSnapshotImages removes the images of all snapshots of the workspace
+func deleteSnapshotImages(ctx context.Context, workspaceInfo *provider2.AgentWorkspaceInfo, log log.Logger) error {
+	entries, err := os.ReadDir(filepath.Join(workspaceInfo.Origin, snapshotsFolder))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	d, err := drivercreate.NewDriver(workspaceInfo, log)
+	if err != nil {
+		return fmt.Errorf("create driver %w", err)
+	}
+	snapshotDriver, ok := d.(driver.SnapshotDriver)
+	if !ok {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		image := provider2.SnapshotImageName(workspaceInfo.Workspace.UID, entry.Name())
+		log.Debugf("removing snapshot image %s", image)
+		err = snapshotDriver.DeleteSnapshot(ctx, image)
+		if err != nil {
+			return fmt.Errorf("delete snapshot image %s %w", image, err)
+		}
+	}
+
+	return nil
+}
+
+// hasManagedContentFolder returns true if the content folder is owned by DevPod. For local folder
+// workspaces the content folder is the folder of the user and is neither archived nor overwritten.
+func hasManagedContentFolder(workspaceInfo *provider2.AgentWorkspaceInfo) bool {
+	return workspaceInfo.ContentFolder != "" && workspaceInfo.ContentFolder != workspaceInfo.Workspace.Source.LocalFolder
+}
+
+func archiveContentFolder(contentFolder, archive string) error {
+	file, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	return extract.WriteTar(file, contentFolder, true)
+}
+
+func restoreContentFolder(archive, contentFolder string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	entries, err := os.ReadDir(contentFolder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(contentFolder, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return extract.Extract(file, contentFolder)
+}
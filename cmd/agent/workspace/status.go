@@ -1,13 +1,20 @@
 package workspace
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/skevetter/devpod/cmd/flags"
 	"github.com/skevetter/devpod/pkg/agent"
 	"github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/devcontainer"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/version"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +24,7 @@ type StatusCmd struct {
 	*flags.GlobalFlags
 
 	WorkspaceInfo string
+	Output        string
 }
 
 // NewStatusCmd creates a new command
@@ -34,6 +42,7 @@ func NewStatusCmd(flags *flags.GlobalFlags) *cobra.Command {
 	}
 	statusCmd.Flags().StringVar(&cmd.WorkspaceInfo, "workspace-info", "", "The workspace info")
 	_ = statusCmd.MarkFlagRequired("workspace-info")
+	statusCmd.Flags().StringVar(&cmd.Output, "output", "plain", "The output format to use. Can be json or plain")
 	return statusCmd
 }
 
@@ -56,20 +65,83 @@ func (cmd *StatusCmd) Run(ctx context.Context, log log.Logger) error {
 	containerDetails, err := runner.Find(ctx)
 	if err != nil {
 		return err
-	} else if containerDetails == nil {
-		fmt.Print(client.StatusNotFound)
-		return nil
 	}
 
-	// is running?
-	if strings.ToLower(containerDetails.State.Status) == "running" {
-		fmt.Print(client.StatusRunning)
-		return nil
-	} else if strings.ToLower(containerDetails.State.Status) == "exited" {
-		fmt.Print(client.StatusStopped)
+	status := &client.WorkspaceStatus{
+		State: containerState(containerDetails),
+		Agent: &client.AgentStatus{Version: version.GetVersion()},
+	}
+	switch cmd.Output {
+	case "plain":
+		fmt.Print(status.State)
 		return nil
+	case "json":
+	default:
+		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+	}
+
+	if containerDetails != nil {
+		status.Container = containerStatus(workspaceInfo, containerDetails)
+	}
+	if status.State == client.StatusRunning {
+		err = containerInfo(ctx, runner, status)
+		if err != nil {
+			log.Debugf("Error retrieving status from container: %v", err)
+		}
+	}
+
+	out, err := json.Marshal(status)
+	if err != nil {
+		return err
 	}
 
-	fmt.Print(client.StatusBusy)
+	fmt.Print(string(out))
 	return nil
 }
+
+func containerState(containerDetails *config.ContainerDetails) client.Status {
+	if containerDetails == nil {
+		return client.StatusNotFound
+	}
+
+	switch strings.ToLower(containerDetails.State.Status) {
+	case "running":
+		return client.StatusRunning
+	case "exited":
+		return client.StatusStopped
+	default:
+		return client.StatusBusy
+	}
+}
+
+func containerStatus(workspaceInfo *provider2.AgentWorkspaceInfo, containerDetails *config.ContainerDetails) *client.ContainerStatus {
+	status := &client.ContainerStatus{
+		ID:        containerDetails.ID,
+		Image:     containerDetails.Config.LegacyImage,
+		Driver:    workspaceInfo.Agent.Driver,
+		Status:    containerDetails.State.Status,
+		StartedAt: containerDetails.State.StartedAt,
+	}
+	if status.Driver == "" {
+		status.Driver = provider2.DockerDriver
+	}
+
+	startedAt, err := time.Parse(time.RFC3339Nano, containerDetails.State.StartedAt)
+	if err == nil && strings.ToLower(containerDetails.State.Status) == "running" {
+		status.Uptime = time.Since(startedAt).Round(time.Second).String()
+	}
+
+	return status
+}
+
+// containerInfo adds the status that is only known inside the container, like the last activity
+func containerInfo(ctx context.Context, runner devcontainer.Runner, status *client.WorkspaceStatus) error {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := runner.Command(ctx, "root", fmt.Sprintf("'%s' agent container status", agent.ContainerDevPodHelperLocation), nil, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("%s %w", stderr.String(), err)
+	}
+
+	return json.Unmarshal(stdout.Bytes(), status)
+}
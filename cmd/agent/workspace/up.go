@@ -27,6 +27,7 @@ import (
 	"github.com/skevetter/devpod/pkg/dockerinstall"
 	"github.com/skevetter/devpod/pkg/extract"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/upload"
 	"github.com/skevetter/devpod/pkg/util"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
@@ -141,7 +142,7 @@ func (cmd *UpCmd) cleanupCredentials(credentialsDir string) {
 }
 
 func (cmd *UpCmd) up(ctx context.Context, workspaceInfo *provider2.AgentWorkspaceInfo, tunnelClient tunnel.TunnelClient, logger log.Logger) error {
-	result, err := cmd.devPodUp(ctx, workspaceInfo, logger)
+	result, err := cmd.devPodUp(ctx, workspaceInfo, tunnelClient, logger)
 	if err != nil {
 		return err
 	}
@@ -163,7 +164,7 @@ func (cmd *UpCmd) sendResult(ctx context.Context, result *config2.Result, tunnel
 	return nil
 }
 
-func (cmd *UpCmd) devPodUp(ctx context.Context, workspaceInfo *provider2.AgentWorkspaceInfo, log log.Logger) (*config2.Result, error) {
+func (cmd *UpCmd) devPodUp(ctx context.Context, workspaceInfo *provider2.AgentWorkspaceInfo, tunnelClient tunnel.TunnelClient, log log.Logger) (*config2.Result, error) {
 	runner, err := CreateRunner(workspaceInfo, log)
 	if err != nil {
 		return nil, err
@@ -172,6 +173,7 @@ func (cmd *UpCmd) devPodUp(ctx context.Context, workspaceInfo *provider2.AgentWo
 	return runner.Up(ctx, devcontainer.UpOptions{
 		CLIOptions:    workspaceInfo.CLIOptions,
 		RegistryCache: workspaceInfo.RegistryCache,
+		SecretsClient: tunnelClient,
 	}, workspaceInfo.InjectTimeout)
 }
 
@@ -620,6 +622,18 @@ func installDaemon(workspaceInfo *provider2.AgentWorkspaceInfo, log log.Logger)
 
 func downloadLocalFolder(ctx context.Context, workspaceDir string, client tunnel.TunnelClient, log log.Logger) error {
 	log.Infof("Upload folder to server")
+	err := upload.Receive(ctx, func(ctx context.Context) (upload.Stream, error) {
+		return client.UploadWorkspace(ctx)
+	}, upload.Options{
+		Target:   workspaceDir,
+		CacheDir: filepath.Join(filepath.Dir(workspaceDir), "upload"),
+	}, log)
+	if !errors.Is(err, upload.ErrUnsupported) {
+		return err
+	}
+
+	// older clients only support streaming the whole folder
+	log.Debugf("incremental upload is not supported, streaming the whole folder")
 	stream, err := client.StreamWorkspace(ctx, &tunnel.Empty{})
 	if err != nil {
 		return fmt.Errorf("read workspace %w", err)
@@ -22,5 +22,10 @@ func NewWorkspaceCmd(flags *flags.GlobalFlags) *cobra.Command {
 	workspaceCmd.AddCommand(NewInstallDotfilesCmd(flags))
 	workspaceCmd.AddCommand(NewSetupGPGCmd(flags))
 	workspaceCmd.AddCommand(NewLogsCmd(flags))
+	workspaceCmd.AddCommand(NewLifecycleHooksCmd(flags))
+	workspaceCmd.AddCommand(NewSnapshotCmd(flags))
+	workspaceCmd.AddCommand(NewSessionsCmd(flags))
+	workspaceCmd.AddCommand(NewGenerateKubeCmd(flags))
+	workspaceCmd.AddCommand(NewPortForwardCmd(flags))
 	return workspaceCmd
 }
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/spf13/cobra"
+)
+
+// NewCacheCmd returns a new command
+func NewCacheCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "DevPod content cache commands",
+		Long: `Manages the cache of features, images, agent binaries and IDE servers that is used by
+downloads and, with --offline, instead of them.`,
+	}
+
+	cacheCmd.AddCommand(NewWarmCmd(flags))
+	cacheCmd.AddCommand(NewExportCmd(flags))
+	cacheCmd.AddCommand(NewImportCmd(flags))
+	return cacheCmd
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"os"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/cache"
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// ExportCmd holds the export cmd flags
+type ExportCmd struct {
+	*flags.GlobalFlags
+}
+
+// NewExportCmd creates a new command
+func NewExportCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ExportCmd{
+		GlobalFlags: flags,
+	}
+	exportCmd := &cobra.Command{
+		Use:   "export file",
+		Short: "Export the cache as tar.gz",
+		Long: `Writes the cache together with the cached images into a tar.gz that can be imported on another
+machine with devpod cache import.
+
+Example:
+  devpod cache export devpod-cache.tgz`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.Context(), args[0])
+		},
+	}
+	return exportCmd
+}
+
+// Run runs the command logic
+func (cmd *ExportCmd) Run(ctx context.Context, target string) error {
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	err = cache.Export(ctx, &docker.DockerHelper{DockerCommand: "docker", Log: log.Default}, file, log.Default)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(target)
+		return err
+	}
+
+	log.Default.Donef("Exported %s to %s", cache.Dir(), target)
+	return nil
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"os"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/cache"
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// ImportCmd holds the import cmd flags
+type ImportCmd struct {
+	*flags.GlobalFlags
+}
+
+// NewImportCmd creates a new command
+func NewImportCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ImportCmd{
+		GlobalFlags: flags,
+	}
+	importCmd := &cobra.Command{
+		Use:   "import file",
+		Short: "Import a cache exported with devpod cache export",
+		Long: `Extracts an exported cache into the cache folder and loads its images into docker.
+
+Example:
+  devpod cache import devpod-cache.tgz`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.Context(), args[0])
+		},
+	}
+	return importCmd
+}
+
+// Run runs the command logic
+func (cmd *ImportCmd) Run(ctx context.Context, source string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	err = cache.Import(ctx, &docker.DockerHelper{DockerCommand: "docker", Log: log.Default}, file, log.Default)
+	if err != nil {
+		return err
+	}
+
+	log.Default.Donef("Imported %s to %s", source, cache.Dir())
+	return nil
+}
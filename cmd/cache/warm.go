@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/cache"
+	"github.com/skevetter/devpod/pkg/config"
+	devcontainerconfig "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/feature"
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/devpod/pkg/dockerfile"
+	"github.com/skevetter/devpod/pkg/download"
+	"github.com/skevetter/devpod/pkg/file"
+	"github.com/skevetter/devpod/pkg/git"
+	"github.com/skevetter/devpod/pkg/ide/openvscode"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// featuresBuildFrontend is the dockerfile frontend the image of features is built with
+const featuresBuildFrontend = "docker.io/docker/dockerfile:1.4"
+
+// WarmCmd holds the warm cmd flags
+type WarmCmd struct {
+	*flags.GlobalFlags
+
+	DevContainerPath string
+	Architectures    []string
+	SkipImages       bool
+	SkipIDE          bool
+}
+
+// NewWarmCmd creates a new command
+func NewWarmCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &WarmCmd{
+		GlobalFlags: flags,
+	}
+	warmCmd := &cobra.Command{
+		Use:   "warm [flags] workspace-source",
+		Short: "Pre-fetch the content a workspace needs into the cache",
+		Long: `Downloads the features and pulls the base images of the devcontainer.json of a local folder or git
+repository, as well as the agent binary and the openvscode server for each architecture, so that the
+workspace can be started with --offline.
+
+Example:
+  devpod cache warm ./my-project
+  devpod cache warm github.com/my-org/my-repo --arch amd64`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.Context(), args[0])
+		},
+	}
+
+	warmCmd.Flags().StringVar(&cmd.DevContainerPath, "devcontainer-path", "", "The path to the devcontainer.json relative to the source")
+	warmCmd.Flags().StringSliceVar(&cmd.Architectures, "arch", []string{"amd64", "arm64"}, "The architectures to cache the agent and IDE servers for")
+	warmCmd.Flags().BoolVar(&cmd.SkipImages, "skip-images", false, "If true, doesn't pull the images")
+	warmCmd.Flags().BoolVar(&cmd.SkipIDE, "skip-ide", false, "If true, doesn't download the IDE servers")
+	return warmCmd
+}
+
+// Run runs the command logic
+func (cmd *WarmCmd) Run(ctx context.Context, source string) error {
+	if cache.Offline() {
+		return fmt.Errorf("cannot warm the cache in offline mode")
+	}
+
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	}
+
+	folder, cleanup, err := cmd.sourceFolder(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	devContainerConfig, err := devcontainerconfig.ParseDevContainerJSON(folder, cmd.DevContainerPath)
+	if err != nil {
+		return fmt.Errorf("parse devcontainer.json %w", err)
+	} else if devContainerConfig == nil {
+		return fmt.Errorf("couldn't find a devcontainer.json in %s", source)
+	}
+
+	// fetching the features stores them in the cache
+	lockFile, err := feature.LockFeatures(devContainerConfig, false, log.Default)
+	if err != nil {
+		return err
+	}
+	for id, lockedFeature := range lockFile.Features {
+		log.Default.Infof("Cached feature %s (%s)", id, lockedFeature.Resolved)
+	}
+
+	if !cmd.SkipImages {
+		images, err := baseImages(devContainerConfig)
+		if err != nil {
+			return err
+		}
+		if len(lockFile.Features) > 0 {
+			images = append(images, featuresBuildFrontend)
+		}
+
+		err = pullImages(ctx, images)
+		if err != nil {
+			return err
+		}
+	}
+
+	agentURL := agent.DefaultAgentDownloadURL()
+	if contextAgentURL := devPodConfig.ContextOption(config.ContextOptionAgentURL); contextAgentURL != "" {
+		agentURL = strings.TrimSuffix(contextAgentURL, "/")
+	}
+	for _, arch := range cmd.Architectures {
+		err = agent.CacheBinary(ctx, agentURL, arch)
+		if err != nil {
+			return fmt.Errorf("cache agent binary for %s %w", arch, err)
+		}
+		log.Default.Infof("Cached agent binary for %s", arch)
+
+		if !cmd.SkipIDE {
+			url := openvscode.ReleaseURL(devPodConfig.IDEOptions(string(config.IDEOpenVSCode)), arch)
+			err = downloadToCache(cache.IDEFolder, url)
+			if err != nil {
+				return fmt.Errorf("cache openvscode server for %s %w", arch, err)
+			}
+			log.Default.Infof("Cached openvscode server for %s", arch)
+		}
+	}
+
+	log.Default.Donef("Warmed cache %s", cache.Dir())
+	return nil
+}
+
+// sourceFolder returns the local folder of the source, git repositories are cloned into a temporary folder
+func (cmd *WarmCmd) sourceFolder(ctx context.Context, source string) (string, func(), error) {
+	isLocalDir, _ := file.IsLocalDir(source)
+	if isLocalDir {
+		return source, func() {}, nil
+	}
+
+	gitRepository, gitPRReference, gitBranch, gitCommit, gitSubDir := git.NormalizeRepository(source)
+	if !strings.HasSuffix(source, ".git") && !git.PingRepository(gitRepository, git.GetDefaultExtraEnv(false)) {
+		return "", nil, fmt.Errorf("%s is neither a local folder nor a git repository", source)
+	}
+
+	tempDir, err := os.MkdirTemp("", "devpod-cache-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(tempDir) }
+
+	gitInfo := git.NewGitInfo(gitRepository, gitBranch, gitCommit, gitPRReference, gitSubDir)
+	err = git.CloneRepository(ctx, gitInfo, tempDir, "", false, log.Default)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("clone %s %w", gitRepository, err)
+	}
+
+	return filepath.Join(tempDir, gitSubDir), cleanup, nil
+}
+
+// baseImages returns the image or the base image of the Dockerfile of the devcontainer.json
+func baseImages(devContainerConfig *devcontainerconfig.DevContainerConfig) ([]string, error) {
+	if devContainerConfig.Image != "" {
+		return []string{devContainerConfig.Image}, nil
+	} else if devContainerConfig.GetDockerfile() == "" {
+		log.Default.Warnf("Skip images, only the images of image and Dockerfile based devcontainer.json are cached")
+		return nil, nil
+	}
+
+	dockerfilePath := filepath.Join(filepath.Dir(devContainerConfig.Origin), devContainerConfig.GetDockerfile())
+	content, err := os.ReadFile(dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedDockerfile, err := dockerfile.Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse dockerfile %w", err)
+	}
+
+	baseImage := parsedDockerfile.FindBaseImage(devContainerConfig.GetArgs(), devContainerConfig.GetTarget())
+	if baseImage == "" {
+		return nil, fmt.Errorf("find base image of %s", dockerfilePath)
+	}
+
+	return []string{baseImage}, nil
+}
+
+func pullImages(ctx context.Context, images []string) error {
+	dockerHelper := &docker.DockerHelper{DockerCommand: "docker", Log: log.Default}
+	writer := log.Default.Writer(logrus.DebugLevel, false)
+	defer func() { _ = writer.Close() }()
+
+	for _, image := range images {
+		log.Default.Infof("Pull image %s", image)
+		err := dockerHelper.Pull(ctx, image, nil, writer, writer)
+		if err != nil {
+			return fmt.Errorf("pull image %s %w", image, err)
+		}
+	}
+
+	return cache.AddImages(images...)
+}
+
+// downloadToCache downloads the url into the cache folder if it isn't cached yet
+func downloadToCache(folder, url string) error {
+	target := cache.Path(folder, url)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	body, err := download.File(url, log.Default)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	temp := target + ".tmp"
+	out, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, body)
+	_ = out.Close()
+	if err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+
+	return os.Rename(temp, target)
+}
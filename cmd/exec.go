@@ -0,0 +1,414 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/mattn/go-isatty"
+	"github.com/skevetter/devpod/cmd/completion"
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/agent"
+	client2 "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/client/clientimplementation"
+	"github.com/skevetter/devpod/pkg/config"
+	devssh "github.com/skevetter/devpod/pkg/ssh"
+	"github.com/skevetter/devpod/pkg/tunnel"
+	workspace2 "github.com/skevetter/devpod/pkg/workspace"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// ExecCmd holds the exec cmd flags
+type ExecCmd struct {
+	*flags.GlobalFlags
+
+	Env     []string
+	User    string
+	WorkDir string
+	TTY     string
+	JSON    bool
+}
+
+// ExecResult is printed if --json is used
+type ExecResult struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Duration string `json:"duration"`
+}
+
+// NewExecCmd creates a new exec command
+func NewExecCmd(f *flags.GlobalFlags) *cobra.Command {
+	cmd := &ExecCmd{
+		GlobalFlags: f,
+	}
+	execCmd := &cobra.Command{
+		Use:   "exec [flags] [workspace-folder|workspace-name] -- command [args...]",
+		Short: "Executes a command in a workspace",
+		Long: `Executes a command in a workspace and exits with the exit code of the command.
+The arguments after -- are passed as is to the command without being interpreted by a shell.
+
+Example:
+devpod exec my-workspace -- make test
+devpod exec my-workspace --workdir /workspaces/my-workspace/sub --env CI=true -- go test ./...`,
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			dashIdx := cobraCmd.ArgsLenAtDash()
+			if dashIdx == -1 || dashIdx == len(args) {
+				return fmt.Errorf("please specify the command to execute after --, e.g. devpod exec my-workspace -- ls")
+			}
+			workspaceArgs, commandArgs := args[:dashIdx], args[dashIdx:]
+
+			devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+			if err != nil {
+				return err
+			}
+
+			ctx := cobraCmd.Context()
+			client, err := workspace2.Get(ctx, devPodConfig, workspaceArgs, true, cmd.Owner, false, log.Default.ErrorStreamOnly())
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(ctx, devPodConfig, client, commandArgs, log.Default.ErrorStreamOnly())
+		},
+		ValidArgsFunction: func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
+		},
+	}
+
+	execCmd.Flags().StringArrayVarP(&cmd.Env, "env", "e", []string{}, "Env variables to set for the command in the form KEY=VALUE")
+	execCmd.Flags().StringVar(&cmd.User, "user", "", "The user of the workspace to use")
+	execCmd.Flags().StringVarP(&cmd.WorkDir, "workdir", "w", "", "The working directory in the container")
+	execCmd.Flags().StringVarP(&cmd.TTY, "tty", "t", "auto", "Allocate a pseudo terminal, either true, false or auto to allocate one if stdin and stdout are terminals")
+	execCmd.Flags().Lookup("tty").NoOptDefVal = "true"
+	execCmd.Flags().BoolVar(&cmd.JSON, "json", false, "If enabled prints the exit code, stdout and stderr of the command as json")
+	return execCmd
+}
+
+// Run runs the command logic
+func (cmd *ExecCmd) Run(
+	ctx context.Context,
+	devPodConfig *config.Config,
+	client client2.BaseWorkspaceClient,
+	args []string,
+	log log.Logger,
+) error {
+	tty, err := cmd.useTTY(isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd()))
+	if err != nil {
+		return err
+	}
+
+	envVars, err := cmd.envVars()
+	if err != nil {
+		return err
+	}
+
+	// get user
+	if cmd.User == "" {
+		cmd.User, err = devssh.GetUser(client.WorkspaceConfig().ID, client.WorkspaceConfig().SSHConfigPath, client.WorkspaceConfig().SSHConfigIncludePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// always quote the arguments, so they are passed as is to the command
+	command := shellescape.QuoteCommand(args)
+
+	// run the command and collect the output if needed
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	stdoutBuffer, stderrBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	if cmd.JSON {
+		stdout, stderr = stdoutBuffer, stderrBuffer
+	}
+
+	start := time.Now()
+	err = cmd.exec(ctx, devPodConfig, client, command, envVars, tty, stdout, stderr, log)
+	return cmd.exitResult(err, stdoutBuffer.String(), stderrBuffer.String(), time.Since(start), os.Stdout)
+}
+
+// exitResult unwraps the exit error of the command, so devpod exits with the exit code of the command,
+// and prints the result of the command to out if --json is used
+func (cmd *ExecCmd) exitResult(err error, stdout, stderr string, duration time.Duration, out io.Writer) error {
+	exitCode := 0
+	if err != nil {
+		var exitErr *ssh.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		err = exitErr
+		exitCode = exitErr.ExitStatus()
+	}
+	if !cmd.JSON {
+		return err
+	}
+
+	result, jsonErr := json.Marshal(&ExecResult{
+		ExitCode: exitCode,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Duration: duration.Round(time.Millisecond).String(),
+	})
+	if jsonErr != nil {
+		return jsonErr
+	}
+	_, _ = fmt.Fprintln(out, string(result))
+
+	return err
+}
+
+func (cmd *ExecCmd) exec(
+	ctx context.Context,
+	devPodConfig *config.Config,
+	client client2.BaseWorkspaceClient,
+	command string,
+	envVars map[string]string,
+	tty bool,
+	stdout, stderr io.Writer,
+	log log.Logger,
+) error {
+	switch c := client.(type) {
+	case client2.WorkspaceClient:
+		// lock the workspace as long as we init the connection
+		err := c.Lock(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.Unlock()
+
+		// start the workspace
+		err = clientimplementation.StartWait(ctx, c, false, log)
+		if err != nil {
+			return err
+		}
+
+		return tunnel.NewContainerTunnel(c, log).
+			Run(ctx, func(ctx context.Context, containerClient *ssh.Client) error {
+				// we have a connection to the container, make sure others can connect as well
+				c.Unlock()
+
+				return cmd.execInContainer(ctx, containerClient, client.Workspace(), command, envVars, tty, stdout, stderr)
+			}, devPodConfig, nil)
+	case client2.ProxyClient:
+		return tunnel.NewTunnel(
+			ctx,
+			func(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+				return c.Ssh(ctx, client2.SshOptions{
+					User:   cmd.User,
+					Stdin:  stdin,
+					Stdout: stdout,
+				})
+			},
+			func(ctx context.Context, containerClient *ssh.Client) error {
+				return cmd.execInContainer(ctx, containerClient, client.Workspace(), command, envVars, tty, stdout, stderr)
+			},
+		)
+	case client2.DaemonClient:
+		err := c.CheckWorkspaceReachable(ctx)
+		if err != nil {
+			return err
+		}
+
+		toolSSHClient, sshClient, err := c.SSHClients(ctx, cmd.User)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = toolSSHClient.Close() }()
+		defer func() { _ = sshClient.Close() }()
+
+		return runExecSession(ctx, sshClient, workdirCommand(command, cmd.WorkDir), envVars, tty, stdout, stderr)
+	}
+
+	return fmt.Errorf("unsupported workspace client %T", client)
+}
+
+// execInContainer starts a ssh server in the container and runs the command through it
+func (cmd *ExecCmd) execInContainer(
+	ctx context.Context,
+	containerClient *ssh.Client,
+	workspace string,
+	command string,
+	envVars map[string]string,
+	tty bool,
+	stdout, stderr io.Writer,
+) error {
+	sshServerCommand := cmd.sshServerCommand(workspace)
+
+	// create readers
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stdoutReader.Close() }()
+	defer func() { _ = stdoutWriter.Close() }()
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stdinWriter.Close() }()
+	defer func() { _ = stdinReader.Close() }()
+
+	// start ssh server in the container
+	go func() {
+		_ = devssh.Run(ctx, containerClient, sshServerCommand, stdinReader, stdoutWriter, io.Discard, nil)
+	}()
+
+	sshClient, err := devssh.StdioClientWithUser(stdoutReader, stdinWriter, cmd.User, false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sshClient.Close() }()
+
+	return runExecSession(ctx, sshClient, command, envVars, tty, stdout, stderr)
+}
+
+// sshServerCommand returns the command to start the ssh server of the user in the container
+func (cmd *ExecCmd) sshServerCommand(workspace string) string {
+	workdir := filepath.Join("/workspaces", workspace)
+	if cmd.WorkDir != "" {
+		workdir = cmd.WorkDir
+	}
+
+	sshServerCommand := fmt.Sprintf("'%s' helper ssh-server --track-activity --stdio --workdir '%s'", agent.ContainerDevPodHelperLocation, workdir)
+	if cmd.Debug {
+		sshServerCommand += " --debug"
+	}
+	if cmd.User != "" && cmd.User != "root" {
+		sshServerCommand = fmt.Sprintf("su -c \"%s\" '%s'", sshServerCommand, cmd.User)
+	}
+
+	return sshServerCommand
+}
+
+// workdirCommand changes into the working directory before running the command, used if the ssh server doesn't
+// start in the working directory
+func workdirCommand(command string, workdir string) string {
+	if workdir == "" {
+		return command
+	}
+
+	return fmt.Sprintf("cd %s && %s", shellescape.Quote(workdir), command)
+}
+
+// runExecSession runs the command in a new session and returns the *ssh.ExitError of the command as is,
+// so the exit code is propagated.
+func runExecSession(
+	ctx context.Context,
+	sshClient *ssh.Client,
+	command string,
+	envVars map[string]string,
+	tty bool,
+	stdout, stderr io.Writer,
+) error {
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+
+	for k, v := range envVars {
+		err = session.Setenv(k, v)
+		if err != nil {
+			return fmt.Errorf("set env %s %w", k, err)
+		}
+	}
+
+	if tty {
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = term.Restore(int(os.Stdin.Fd()), state)
+		}()
+
+		windowChange := devssh.WatchWindowSize(ctx)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-windowChange:
+				}
+				width, height, err := term.GetSize(int(os.Stdout.Fd()))
+				if err != nil {
+					continue
+				}
+				_ = session.WindowChange(height, width)
+			}
+		}()
+
+		t := "xterm-256color"
+		if termEnv, ok := os.LookupEnv("TERM"); ok {
+			t = termEnv
+		}
+		width, height := 80, 40
+		if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			width, height = w, h
+		}
+		if err = session.RequestPty(t, height, width, ssh.TerminalModes{}); err != nil {
+			return fmt.Errorf("request pty %w", err)
+		}
+	}
+
+	// only forward stdin if there is something to read, otherwise the command would wait for input
+	if tty || !isatty.IsTerminal(os.Stdin.Fd()) {
+		session.Stdin = os.Stdin
+	}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	exit := make(chan struct{})
+	defer close(exit)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGINT)
+			_ = session.Close()
+		case <-exit:
+		}
+	}()
+
+	return session.Run(command)
+}
+
+// useTTY returns whether a pseudo terminal should be allocated, terminal is true if stdin and stdout are terminals
+func (cmd *ExecCmd) useTTY(terminal bool) (bool, error) {
+	if cmd.TTY == "auto" {
+		return !cmd.JSON && terminal, nil
+	}
+
+	tty, err := strconv.ParseBool(cmd.TTY)
+	if err != nil {
+		return false, fmt.Errorf("parse --tty, expected true, false or auto %w", err)
+	} else if tty && cmd.JSON {
+		return false, fmt.Errorf("--tty and --json cannot be used together")
+	}
+
+	return tty, nil
+}
+
+func (cmd *ExecCmd) envVars() (map[string]string, error) {
+	envVars := map[string]string{}
+	for _, envVar := range cmd.Env {
+		key, value, ok := strings.Cut(envVar, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid env var %s, expected KEY=VALUE", envVar)
+		}
+
+		envVars[key] = value
+	}
+
+	return envVars, nil
+}
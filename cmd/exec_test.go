@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/ssh/server"
+	"github.com/skevetter/log"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestExecUseTTY(t *testing.T) {
+	tests := []struct {
+		tty      string
+		json     bool
+		terminal bool
+
+		want    bool
+		wantErr bool
+	}{
+		{tty: "auto", terminal: true, want: true},
+		{tty: "auto", terminal: false, want: false},
+		{tty: "auto", json: true, terminal: true, want: false},
+		{tty: "true", terminal: false, want: true},
+		{tty: "false", terminal: true, want: false},
+		{tty: "false", json: true, terminal: true, want: false},
+		{tty: "true", json: true, wantErr: true},
+		{tty: "maybe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("tty=%s json=%t terminal=%t", tt.tty, tt.json, tt.terminal), func(t *testing.T) {
+			cmd := &ExecCmd{TTY: tt.tty, JSON: tt.json}
+			got, err := cmd.useTTY(tt.terminal)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Fatalf("expected tty %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecEnvVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", want: map[string]string{}},
+		{name: "values", env: []string{"CI=true", "EMPTY=", "URL=a=b"}, want: map[string]string{"CI": "true", "EMPTY": "", "URL": "a=b"}},
+		{name: "no value", env: []string{"CI"}, wantErr: true},
+		{name: "no key", env: []string{"=true"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &ExecCmd{Env: tt.env}
+			got, err := cmd.envVars()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			} else if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecSSHServerCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  *ExecCmd
+		want string
+	}{
+		{
+			name: "default workdir",
+			cmd:  &ExecCmd{GlobalFlags: &flags.GlobalFlags{}},
+			want: "'/usr/local/bin/devpod' helper ssh-server --track-activity --stdio --workdir '/workspaces/my-workspace'",
+		},
+		{
+			name: "workdir",
+			cmd:  &ExecCmd{GlobalFlags: &flags.GlobalFlags{}, WorkDir: "/workspaces/my-workspace/sub"},
+			want: "'/usr/local/bin/devpod' helper ssh-server --track-activity --stdio --workdir '/workspaces/my-workspace/sub'",
+		},
+		{
+			name: "root",
+			cmd:  &ExecCmd{GlobalFlags: &flags.GlobalFlags{Debug: true}, User: "root"},
+			want: "'/usr/local/bin/devpod' helper ssh-server --track-activity --stdio --workdir '/workspaces/my-workspace' --debug",
+		},
+		{
+			name: "user",
+			cmd:  &ExecCmd{GlobalFlags: &flags.GlobalFlags{}, User: "vscode"},
+			want: "su -c \"'/usr/local/bin/devpod' helper ssh-server --track-activity --stdio --workdir '/workspaces/my-workspace'\" 'vscode'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.sshServerCommand("my-workspace"); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunExecSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands need a posix shell")
+	}
+
+	workdir := filepath.Join(t.TempDir(), "my project")
+	err := os.Mkdir(workdir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workdir, err = filepath.EvalSymlinks(workdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		workdir string
+
+		wantStdout string
+		wantStderr string
+		wantExit   int
+	}{
+		{
+			name:       "arguments are passed as is",
+			args:       []string{"printf", "%s|", "a b", "it's", "$HOME", "a;b", "*", ""},
+			wantStdout: "a b|it's|$HOME|a;b|*||",
+		},
+		{
+			name:       "env",
+			args:       []string{"sh", "-c", "echo \"$GREETING\""},
+			env:        map[string]string{"GREETING": "hello world=1"},
+			wantStdout: "hello world=1\n",
+		},
+		{
+			name:       "workdir",
+			args:       []string{"pwd"},
+			workdir:    workdir,
+			wantStdout: workdir + "\n",
+		},
+		{
+			name:       "exit code",
+			args:       []string{"sh", "-c", "echo out; echo err >&2; exit 3"},
+			wantStdout: "out\n",
+			wantStderr: "err\n",
+			wantExit:   3,
+		},
+	}
+
+	sshClient := startExecServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			command := workdirCommand(shellescape.QuoteCommand(tt.args), tt.workdir)
+			err := runExecSession(context.Background(), sshClient, command, tt.env, false, stdout, stderr)
+
+			exitCode := 0
+			var exitErr *ssh.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitStatus()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if exitCode != tt.wantExit {
+				t.Fatalf("expected exit code %d, got %d", tt.wantExit, exitCode)
+			} else if stdout.String() != tt.wantStdout {
+				t.Fatalf("expected stdout %q, got %q", tt.wantStdout, stdout.String())
+			} else if stderr.String() != tt.wantStderr {
+				t.Fatalf("expected stderr %q, got %q", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestExecExitResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands need a posix shell")
+	}
+
+	sshClient := startExecServer(t)
+	exitErr := runExecSession(context.Background(), sshClient, "exit 3", nil, false, &bytes.Buffer{}, &bytes.Buffer{})
+	if exitErr == nil {
+		t.Fatal("expected an exit error")
+	}
+	otherErr := errors.New("workspace not found")
+
+	tests := []struct {
+		name string
+		json bool
+		err  error
+
+		wantExit   int
+		wantErr    error
+		wantResult bool
+	}{
+		{name: "success"},
+		{name: "exit code", err: exitErr, wantExit: 3, wantErr: exitErr},
+		{name: "wrapped exit code", err: fmt.Errorf("run command %w", exitErr), wantExit: 3, wantErr: exitErr},
+		{name: "other error", err: otherErr, wantErr: otherErr},
+		{name: "json success", json: true, wantResult: true},
+		{name: "json exit code", json: true, err: fmt.Errorf("run command %w", exitErr), wantExit: 3, wantErr: exitErr, wantResult: true},
+		{name: "json other error", json: true, err: otherErr, wantErr: otherErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &ExecCmd{JSON: tt.json}
+			out := &bytes.Buffer{}
+			err := cmd.exitResult(tt.err, "out", "err", 1500*time.Microsecond, out)
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if !tt.wantResult {
+				if out.Len() > 0 {
+					t.Fatalf("unexpected result %s", out.String())
+				}
+				return
+			}
+
+			result := &ExecResult{}
+			err = json.Unmarshal(out.Bytes(), result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ExecResult{ExitCode: tt.wantExit, Stdout: "out", Stderr: "err", Duration: "2ms"}
+			if *result != want {
+				t.Fatalf("expected result %+v, got %+v", want, *result)
+			}
+		})
+	}
+}
+
+// startExecServer starts a ssh server that runs the commands of the current user and returns a client connected to it
+func startExecServer(t *testing.T) *ssh.Client {
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	sshServer, err := server.NewServer(listener.Addr().String(), nil, nil, "", "", log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = sshServer.Serve(listener) }()
+
+	sshClient, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            currentUser.Username,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sshClient.Close() })
+
+	return sshClient
+}
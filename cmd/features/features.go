@@ -0,0 +1,21 @@
+package features
+
+import (
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/spf13/cobra"
+)
+
+// NewFeaturesCmd returns a new command
+func NewFeaturesCmd(flags *flags.GlobalFlags) *cobra.Command {
+	featuresCmd := &cobra.Command{
+		Use:   "features",
+		Short: "DevPod devcontainer feature commands",
+	}
+
+	featuresCmd.AddCommand(NewLockCmd(flags))
+	featuresCmd.AddCommand(NewUpgradeCmd(flags))
+	featuresCmd.AddCommand(NewTestCmd(flags))
+	featuresCmd.AddCommand(NewPackageCmd(flags))
+	featuresCmd.AddCommand(NewPublishCmd(flags))
+	return featuresCmd
+}
@@ -0,0 +1,75 @@
+package features
+
+import (
+	"fmt"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/feature"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// LockCmd holds the lock cmd flags
+type LockCmd struct {
+	*flags.GlobalFlags
+
+	DevContainerPath string
+
+	// Upgrade ignores an existing lock file and resolves every feature again
+	Upgrade bool
+}
+
+// NewLockCmd creates a new command
+func NewLockCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &LockCmd{
+		GlobalFlags: flags,
+	}
+	lockCmd := &cobra.Command{
+		Use:   "lock [folder]",
+		Short: "Write a devcontainer-lock.json for the features of a devcontainer.json",
+		Long: `Resolves the features of a devcontainer.json and writes the exact versions, references and
+checksums into a devcontainer-lock.json next to it. Features that are already locked are kept.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(folderFromArgs(args))
+		},
+	}
+
+	lockCmd.Flags().StringVar(&cmd.DevContainerPath, "devcontainer-path", "", "The path to the devcontainer.json relative to the folder")
+	return lockCmd
+}
+
+// Run runs the command logic
+func (cmd *LockCmd) Run(folder string) error {
+	devContainerConfig, err := config.ParseDevContainerJSON(folder, cmd.DevContainerPath)
+	if err != nil {
+		return fmt.Errorf("parse devcontainer.json %w", err)
+	} else if devContainerConfig == nil {
+		return fmt.Errorf("couldn't find a devcontainer.json in %s", folder)
+	}
+
+	lockFile, err := feature.LockFeatures(devContainerConfig, cmd.Upgrade, log.Default)
+	if err != nil {
+		return err
+	}
+
+	err = config.WriteLockFile(devContainerConfig.Origin, lockFile)
+	if err != nil {
+		return fmt.Errorf("write lock file %w", err)
+	}
+
+	for id, lockedFeature := range lockFile.Features {
+		log.Default.Infof("Locked %s to %s (%s)", id, lockedFeature.Version, lockedFeature.Resolved)
+	}
+	log.Default.Donef("Wrote %s", config.GetLockFilePath(devContainerConfig.Origin))
+	return nil
+}
+
+func folderFromArgs(args []string) string {
+	if len(args) == 0 {
+		return "."
+	}
+
+	return args[0]
+}
@@ -0,0 +1,52 @@
+package features
+
+import (
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/devcontainer/feature"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// PackageCmd holds the package cmd flags
+type PackageCmd struct {
+	*flags.GlobalFlags
+
+	OutputFolder string
+}
+
+// NewPackageCmd creates a new command
+func NewPackageCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &PackageCmd{
+		GlobalFlags: flags,
+	}
+	packageCmd := &cobra.Command{
+		Use:   "package [folder]",
+		Short: "Package features as tarballs",
+		Long: `Writes a devcontainer-feature-<id>.tgz for every feature together with the ` + feature.DEVCONTAINER_COLLECTION_FILE_NAME + `
+into the output folder. The folder is a single feature, a collection or its src folder.
+
+Example:
+  devpod features package ./features --output-folder ./output`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(folderFromArgs(args))
+		},
+	}
+
+	packageCmd.Flags().StringVar(&cmd.OutputFolder, "output-folder", "output", "The folder to write the packaged features to")
+	return packageCmd
+}
+
+// Run runs the command logic
+func (cmd *PackageCmd) Run(folder string) error {
+	packaged, err := feature.PackageFeatures(folder, cmd.OutputFolder, log.Default)
+	if err != nil {
+		return err
+	}
+
+	for _, packagedFeature := range packaged {
+		log.Default.Infof("Packaged %s (%s) to %s", packagedFeature.Config.ID, packagedFeature.Config.Version, packagedFeature.Tarball)
+	}
+	log.Default.Donef("Packaged %d features to %s", len(packaged), cmd.OutputFolder)
+	return nil
+}
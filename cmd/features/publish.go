@@ -0,0 +1,46 @@
+package features
+
+import (
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/devcontainer/feature"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// PublishCmd holds the publish cmd flags
+type PublishCmd struct {
+	*flags.GlobalFlags
+
+	Namespace string
+}
+
+// NewPublishCmd creates a new command
+func NewPublishCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &PublishCmd{
+		GlobalFlags: flags,
+	}
+	publishCmd := &cobra.Command{
+		Use:   "publish [folder]",
+		Short: "Publish features to an OCI registry",
+		Long: `Packages the features and pushes each of them as OCI artifact to <namespace>/<id>, tagged with its
+version and, unless a greater version is already published, its major and minor version and latest.
+Versions that are already published are skipped. The collection metadata is pushed to <namespace>:latest.
+Registry credentials are read from the docker config.
+
+Example:
+  devpod features publish ./features --namespace ghcr.io/my-org/features`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(folderFromArgs(args))
+		},
+	}
+
+	publishCmd.Flags().StringVar(&cmd.Namespace, "namespace", "", "The registry and namespace to publish to, e.g. ghcr.io/my-org/features")
+	_ = publishCmd.MarkFlagRequired("namespace")
+	return publishCmd
+}
+
+// Run runs the command logic
+func (cmd *PublishCmd) Run(folder string) error {
+	return feature.PublishFeatures(folder, cmd.Namespace, log.Default)
+}
@@ -0,0 +1,208 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/copy"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/feature"
+	"github.com/skevetter/devpod/pkg/devcontainer/metadata"
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/devpod/pkg/driver"
+	dockerdriver "github.com/skevetter/devpod/pkg/driver/docker"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/hash"
+	"github.com/spf13/cobra"
+)
+
+// containerTestFolder is where the test folder of a feature is mounted into the test container
+const containerTestFolder = "/tmp/devpod-feature-test"
+
+// TestCmd holds the test cmd flags
+type TestCmd struct {
+	*flags.GlobalFlags
+
+	Features          []string
+	BaseImages        []string
+	SkipAutogenerated bool
+	SkipScenarios     bool
+	KeepImages        bool
+}
+
+// NewTestCmd creates a new command
+func NewTestCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &TestCmd{
+		GlobalFlags: flags,
+	}
+	testCmd := &cobra.Command{
+		Use:   "test [folder]",
+		Short: "Build and test the features of a collection",
+		Long: `Installs the features in src/<id> of the folder into test images and runs the scripts in test/<id>
+inside of them. Every feature is installed with its default options into each base image and verified
+with test.sh. Each scenario of test/<id>/scenarios.json is a devcontainer config that is verified with
+the script named after the scenario. Test scripts can source ` + feature.FeatureTestLibFileName + `
+to use the check and reportResults helpers.
+
+Example:
+  devpod features test
+  devpod features test ./features --features color --base-image ubuntu:24.04`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.Context(), folderFromArgs(args))
+		},
+	}
+
+	testCmd.Flags().StringSliceVar(&cmd.Features, "features", []string{}, "The features to test, defaults to all features of the collection")
+	testCmd.Flags().StringSliceVar(&cmd.BaseImages, "base-image", []string{"mcr.microsoft.com/devcontainers/base:ubuntu"}, "The images the autogenerated tests install the features into")
+	testCmd.Flags().BoolVar(&cmd.SkipAutogenerated, "skip-autogenerated", false, "If true, skips the autogenerated tests")
+	testCmd.Flags().BoolVar(&cmd.SkipScenarios, "skip-scenarios", false, "If true, skips the scenarios")
+	testCmd.Flags().BoolVar(&cmd.KeepImages, "keep-images", false, "If true, keeps the built test images")
+	return testCmd
+}
+
+// Run runs the command logic
+func (cmd *TestCmd) Run(ctx context.Context, folder string) error {
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return err
+	}
+
+	tests, err := feature.FindFeatureTests(folder, feature.FeatureTestOptions{
+		Features:          cmd.Features,
+		BaseImages:        cmd.BaseImages,
+		SkipAutogenerated: cmd.SkipAutogenerated,
+		SkipScenarios:     cmd.SkipScenarios,
+	})
+	if err != nil {
+		return err
+	} else if len(tests) == 0 {
+		return fmt.Errorf("couldn't find any tests in %s", folder)
+	}
+
+	dockerDriver, err := dockerdriver.NewDockerDriver(&provider2.AgentWorkspaceInfo{Workspace: &provider2.Workspace{}}, log.Default)
+	if err != nil {
+		return err
+	}
+	dockerHelper := &docker.DockerHelper{DockerCommand: "docker", Log: log.Default}
+
+	failed := []string{}
+	for _, test := range tests {
+		testName := test.Feature + " (" + test.Name + ")"
+		log.Default.Infof("Test %s", testName)
+		err := cmd.runTest(ctx, dockerDriver, dockerHelper, filepath.Join(folder, "src"), test)
+		if err != nil {
+			log.Default.Errorf("Test %s failed: %v", testName, err)
+			failed = append(failed, testName)
+			continue
+		}
+
+		log.Default.Donef("Test %s passed", testName)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d tests failed: %s", len(failed), len(tests), strings.Join(failed, ", "))
+	}
+
+	log.Default.Donef("All %d tests passed", len(tests))
+	return nil
+}
+
+// runTest builds an image with the features of the test through the feature build path of devpod up and
+// runs the test script in a container of it
+func (cmd *TestCmd) runTest(ctx context.Context, dockerDriver driver.DockerDriver, dockerHelper *docker.DockerHelper, srcFolder string, test *feature.FeatureTest) error {
+	workspaceFolder, err := os.MkdirTemp("", "devpod-feature-test-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workspaceFolder) }()
+
+	// local features are referenced relative to the config, so the workspace holds all features of the collection
+	err = copy.Directory(srcFolder, workspaceFolder)
+	if err != nil {
+		return fmt.Errorf("copy features %w", err)
+	}
+	testFolder := filepath.Join(workspaceFolder, ".devpod-feature-test")
+	err = copy.Directory(test.TestFolder, testFolder)
+	if err != nil {
+		return fmt.Errorf("copy tests %w", err)
+	}
+	err = os.WriteFile(filepath.Join(testFolder, feature.FeatureTestLibFileName), []byte(feature.FeatureTestLib), 0755)
+	if err != nil {
+		return err
+	}
+
+	rawConfig := config.CloneDevContainerConfig(test.Config)
+	rawConfig.Origin = filepath.Join(workspaceFolder, ".devcontainer.json")
+	substitutionContext := &config.SubstitutionContext{
+		DevContainerID:           hash.String(workspaceFolder)[:10],
+		LocalWorkspaceFolder:     workspaceFolder,
+		ContainerWorkspaceFolder: "/workspaces/" + filepath.Base(workspaceFolder),
+		Env:                      config.ListToObject(os.Environ()),
+	}
+	parsedConfig := &config.DevContainerConfig{}
+	err = config.Substitute(substitutionContext, rawConfig, parsedConfig)
+	if err != nil {
+		return err
+	}
+	parsedConfig.Origin = rawConfig.Origin
+	substitutedConfig := &config.SubstitutedConfig{Config: parsedConfig, Raw: rawConfig}
+
+	imageDetails, err := dockerDriver.InspectImage(ctx, parsedConfig.Image)
+	if err != nil {
+		return fmt.Errorf("inspect image %s %w", parsedConfig.Image, err)
+	}
+	imageUser := "root"
+	if imageDetails.Config.User != "" {
+		imageUser = imageDetails.Config.User
+	}
+	imageMetadata, err := metadata.GetImageMetadata(imageDetails, substitutionContext, log.Default)
+	if err != nil {
+		return fmt.Errorf("get image metadata %w", err)
+	}
+
+	extendedBuildInfo, err := feature.GetExtendedBuildInfo(substitutionContext, &config.ImageBuildInfo{
+		ImageDetails: imageDetails,
+		User:         imageUser,
+		Metadata:     imageMetadata,
+	}, parsedConfig.Image, substitutedConfig, log.Default, true)
+	if err != nil {
+		return fmt.Errorf("get extended build info %w", err)
+	}
+
+	buildInfo, err := dockerDriver.BuildDevContainer(ctx, "test-"+hash.String(test.Feature + "/" + test.Name)[:10], substitutedConfig, extendedBuildInfo, "", "", workspaceFolder, provider2.BuildOptions{
+		CLIOptions: provider2.CLIOptions{ForceBuild: true},
+	})
+	if err != nil {
+		return fmt.Errorf("build image %w", err)
+	}
+	if !cmd.KeepImages {
+		defer func() {
+			err := dockerHelper.RemoveImage(context.Background(), buildInfo.ImageName)
+			if err != nil {
+				log.Default.Debugf("Error removing test image %s: %v", buildInfo.ImageName, err)
+			}
+		}()
+	}
+
+	args := []string{"run", "--rm", "--entrypoint", "/bin/bash", "-v", testFolder + ":" + containerTestFolder, "-w", containerTestFolder}
+	if parsedConfig.RemoteUser != "" {
+		args = append(args, "-u", parsedConfig.RemoteUser)
+	}
+	args = append(args, buildInfo.ImageName, "./"+test.Script)
+
+	writer := log.Default.Writer(logrus.InfoLevel, false)
+	defer func() { _ = writer.Close() }()
+	err = dockerHelper.Run(ctx, args, nil, writer, writer)
+	if err != nil {
+		return fmt.Errorf("run %s %w", test.Script, err)
+	}
+
+	return nil
+}
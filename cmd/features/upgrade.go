@@ -0,0 +1,27 @@
+package features
+
+import (
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/spf13/cobra"
+)
+
+// NewUpgradeCmd creates a new command
+func NewUpgradeCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &LockCmd{
+		GlobalFlags: flags,
+		Upgrade:     true,
+	}
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade [folder]",
+		Short: "Upgrade the features in a devcontainer-lock.json to their latest versions",
+		Long: `Ignores an existing devcontainer-lock.json, resolves every feature of the devcontainer.json
+to the latest version matching its tag and rewrites the lock file.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(folderFromArgs(args))
+		},
+	}
+
+	upgradeCmd.Flags().StringVar(&cmd.DevContainerPath, "devcontainer-path", "", "The path to the devcontainer.json relative to the folder")
+	return upgradeCmd
+}
@@ -16,6 +16,7 @@ type GlobalFlags struct {
 	LogOutput string
 	Debug     bool
 	Silent    bool
+	Offline   bool
 }
 
 // SetGlobalFlags applies the global flags
@@ -28,6 +29,7 @@ func SetGlobalFlags(flags *flag.FlagSet) *GlobalFlags {
 	flags.StringVar(&globalFlags.Provider, "provider", "", "The provider to use. Needs to be configured for the selected context")
 	flags.BoolVar(&globalFlags.Debug, "debug", false, "Prints the stack trace if an error occurs")
 	flags.BoolVar(&globalFlags.Silent, "silent", false, "Run in silent mode and prevents any devpod log output except panics & fatals")
+	flags.BoolVar(&globalFlags.Offline, "offline", false, "If true, downloads only use the cache and fail if the content isn't cached")
 
 	flags.Var(&globalFlags.Owner, "owner", "Show pro workspaces for owner")
 	_ = flags.MarkHidden("owner")
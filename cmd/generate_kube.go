@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/completion"
+	"github.com/skevetter/devpod/cmd/flags"
+	clientpkg "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/config"
+	"github.com/skevetter/devpod/pkg/workspace"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// GenerateKubeCmd holds the generate-kube cmd flags
+type GenerateKubeCmd struct {
+	*flags.GlobalFlags
+
+	File string
+}
+
+// NewGenerateKubeCmd creates a new generate-kube command
+func NewGenerateKubeCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &GenerateKubeCmd{
+		GlobalFlags: flags,
+	}
+	generateKubeCmd := &cobra.Command{
+		Use:   "generate-kube [flags] [workspace-path|workspace-name]",
+		Short: "Exports the workspace container of the podman driver as kubernetes yaml",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
+		},
+	}
+	generateKubeCmd.Flags().StringVar(&cmd.File, "file", "", "The file to write the yaml to, if empty the yaml is written to stdout")
+	return generateKubeCmd
+}
+
+// Run runs the command logic
+func (cmd *GenerateKubeCmd) Run(ctx context.Context, args []string) error {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	}
+
+	baseClient, err := workspace.Get(ctx, devPodConfig, args, false, cmd.Owner, false, log.Default)
+	if err != nil {
+		return err
+	}
+
+	client, ok := baseClient.(clientpkg.WorkspaceClient)
+	if !ok {
+		return fmt.Errorf("this command is not supported for proxy providers")
+	}
+
+	agentCommand := fmt.Sprintf("'%s' agent workspace generate-kube --context '%s' --id '%s'", client.AgentPath(), client.Context(), client.Workspace())
+	if log.Default.GetLevel() == logrus.DebugLevel {
+		agentCommand += " --debug"
+	}
+
+	if cmd.File == "" {
+		return runAgentCommand(ctx, devPodConfig, client, agentCommand, os.Stdout, log.Default)
+	}
+
+	f, err := os.Create(cmd.File)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	err = runAgentCommand(ctx, devPodConfig, client, agentCommand, f, log.Default)
+	if err != nil {
+		return fmt.Errorf("generate kubernetes yaml %w", err)
+	}
+
+	log.Default.Donef("Wrote kubernetes yaml of workspace %s to %s", client.Workspace(), cmd.File)
+	return nil
+}
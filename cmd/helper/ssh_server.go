@@ -95,10 +95,19 @@ func (cmd *SSHServerCmd) Run(_ *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	defer func() { _ = server.Close() }()
 
 	// should we listen on stdout & stdin?
 	if cmd.Stdio {
 		if cmd.TrackActivity {
+			// register the session so the container daemon can run the shutdown action once all sessions are closed
+			unregister, err := agent.RegisterContainerSession()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error registering session: %v\n", err)
+			} else {
+				defer unregister()
+			}
+
 			go func() {
 				_, err = os.Stat(agent.ContainerActivityFile)
 				if err != nil {
@@ -0,0 +1,49 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skevetter/devpod/pkg/agent"
+	helperssh "github.com/skevetter/devpod/pkg/ssh/server"
+	"github.com/spf13/cobra"
+)
+
+// SSHSessionHostCmd holds the ssh session host cmd flags
+type SSHSessionHostCmd struct {
+	Socket string
+}
+
+// NewSSHSessionHostCmd creates a new command that hosts a persistent ssh session
+func NewSSHSessionHostCmd() *cobra.Command {
+	cmd := &SSHSessionHostCmd{}
+	sessionHostCmd := &cobra.Command{
+		Use:    "ssh-session-host [flags] -- command",
+		Short:  "Runs a command in a persistent terminal session the ssh server can attach to",
+		Hidden: true,
+		Args:   cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run(args)
+		},
+	}
+
+	sessionHostCmd.Flags().StringVar(&cmd.Socket, "socket", "", "The unix socket to listen on")
+	_ = sessionHostCmd.MarkFlagRequired("socket")
+	return sessionHostCmd
+}
+
+// Run runs the command logic
+func (cmd *SSHSessionHostCmd) Run(args []string) error {
+	// register the session so the container isn't shut down while the command is running
+	unregister, err := agent.RegisterContainerSession()
+	if err == nil {
+		defer unregister()
+	}
+
+	err = helperssh.RunPersistentSessionHost(cmd.Socket, args, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("run session host %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package helper
+
+import (
+	"os"
+	"time"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	devssh "github.com/skevetter/devpod/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// UDPRelayCmd holds the cmd flags
+type UDPRelayCmd struct {
+	*flags.GlobalFlags
+
+	Address     string
+	IdleTimeout time.Duration
+}
+
+// NewUDPRelayCmd creates a new command
+func NewUDPRelayCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &UDPRelayCmd{
+		GlobalFlags: flags,
+	}
+	udpRelayCmd := &cobra.Command{
+		Use:   "udp-relay",
+		Short: "Relays length prefixed datagrams between stdio and an udp address",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return devssh.UDPRelay(os.Stdin, os.Stdout, cmd.Address, cmd.IdleTimeout)
+		},
+	}
+	udpRelayCmd.Flags().StringVar(&cmd.Address, "address", "", "The udp address to relay to")
+	_ = udpRelayCmd.MarkFlagRequired("address")
+	udpRelayCmd.Flags().DurationVar(&cmd.IdleTimeout, "idle-timeout", 2*time.Minute, "The duration after which the relay exits if no datagrams were exchanged")
+	return udpRelayCmd
+}
@@ -16,11 +16,15 @@ import (
 	"github.com/skevetter/devpod/pkg/workspace"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 // LogsCmd holds the configuration
 type LogsCmd struct {
 	*flags.GlobalFlags
+
+	LifecycleHooks bool
+	Audit          bool
 }
 
 // NewLogsCmd creates a new destroy command
@@ -38,7 +42,8 @@ func NewLogsCmd(flags *flags.GlobalFlags) *cobra.Command {
 			return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
 		},
 	}
-
+	startCmd.Flags().BoolVar(&cmd.LifecycleHooks, "lifecycle-hooks", false, "If enabled prints the logs of the lifecycle hooks that ran in the background")
+	startCmd.Flags().BoolVar(&cmd.Audit, "audit", false, "If enabled prints the audit log of the ssh sessions in the workspace container")
 	return startCmd
 }
 
@@ -60,17 +65,55 @@ func (cmd *LogsCmd) Run(ctx context.Context, args []string) error {
 	}
 	log := log.Default
 
+	// create agent command
+	agentCommand := fmt.Sprintf("'%s' agent workspace logs --context '%s' --id '%s'", client.AgentPath(), client.Context(), client.Workspace())
+	if cmd.LifecycleHooks {
+		agentCommand = fmt.Sprintf("'%s' agent workspace lifecycle-hooks --context '%s' --id '%s'", client.AgentPath(), client.Context(), client.Workspace())
+	} else if cmd.Audit {
+		agentCommand += " --audit"
+	}
+	if log.GetLevel() == logrus.DebugLevel {
+		agentCommand += " --debug"
+	}
+
+	return runAgentCommand(ctx, devPodConfig, client, agentCommand, os.Stdout, log)
+}
+
+// runAgentCommand injects the agent into the workspace machine and runs the given agent command there
+func runAgentCommand(ctx context.Context, devPodConfig *config.Config, client clientpkg.WorkspaceClient, agentCommand string, stdout io.Writer, log log.Logger) error {
+	sshClient, err := newAgentSSHClient(ctx, devPodConfig, client, log)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sshClient.Close() }()
+
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+
+	session.Stdout = stdout
+	session.Stderr = os.Stderr
+	err = session.Run(agentCommand)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newAgentSSHClient starts the ssh server of the agent on the machine of the workspace and connects to it
+func newAgentSSHClient(ctx context.Context, devPodConfig *config.Config, client clientpkg.WorkspaceClient, log log.Logger) (*gossh.Client, error) {
 	// create readers
 	stdoutReader, stdoutWriter, err := os.Pipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	stdinReader, stdinWriter, err := os.Pipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() { _ = stdoutWriter.Close() }()
-	defer func() { _ = stdinWriter.Close() }()
 	// ssh tunnel command
 	sshServerCmd := fmt.Sprintf("'%s' helper ssh-server --stdio", client.AgentPath())
 	if log.GetLevel() == logrus.DebugLevel {
@@ -83,10 +126,13 @@ func (cmd *LogsCmd) Run(ctx context.Context, args []string) error {
 	// start ssh server in background
 	errChan := make(chan error, 1)
 	go func() {
+		// the client stops once the ssh server exits
+		defer func() { _ = stdoutWriter.Close() }()
+
 		stderr := log.ErrorStreamOnly().Writer(logrus.DebugLevel, false)
 		defer func() { _ = stderr.Close() }()
 
-		errChan <- agent.InjectAgent(&agent.InjectOptions{
+		err := agent.InjectAgent(&agent.InjectOptions{
 			Ctx: ctx,
 			Exec: func(ctx context.Context, command string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 				return client.Command(ctx, clientpkg.CommandOptions{
@@ -106,34 +152,32 @@ func (cmd *LogsCmd) Run(ctx context.Context, args []string) error {
 			Log:             log.ErrorStreamOnly(),
 			Timeout:         timeout,
 		})
+		if err != nil && ctx.Err() == nil {
+			errChan <- fmt.Errorf("run agent ssh server %w", err)
+		}
+		close(errChan)
 	}()
 
-	// create agent command
-	agentCommand := fmt.Sprintf("'%s' agent workspace logs --context '%s' --id '%s'", client.AgentPath(), client.Context(), client.Workspace())
-	if log.GetLevel() == logrus.DebugLevel {
-		agentCommand += " --debug"
-	}
-
 	// create new ssh client
 	// start ssh client as root / default user
 	sshClient, err := ssh.StdioClientWithUser(stdoutReader, stdinWriter, "" /* default */, false)
 	if err != nil {
-		return err
-	}
-	defer func() { _ = sshClient.Close() }()
-
-	session, err := sshClient.NewSession()
-	if err != nil {
-		return err
-	}
-	defer func() { _ = session.Close() }()
-
-	session.Stdout = os.Stdout
-	session.Stderr = os.Stderr
-	err = session.Run(agentCommand)
-	if err != nil {
-		return err
+		_ = stdinWriter.Close()
+		// the agent error is sent before the ssh server output is closed
+		select {
+		case agentErr := <-errChan:
+			if agentErr != nil {
+				return nil, agentErr
+			}
+		default:
+		}
+		return nil, err
 	}
+	go func() {
+		if err := <-errChan; err != nil {
+			log.Errorf("Agent ssh server exited: %v", err)
+		}
+	}()
 
-	return nil
+	return sshClient, nil
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
 
 	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
@@ -72,6 +74,7 @@ func (cmd *SSHCmd) Run(ctx context.Context, args []string) error {
 		"",
 		cmd.Command,
 		cmd.AgentForwarding,
+		nil,
 		func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 			command := fmt.Sprintf("'%s' helper ssh-server --stdio", machineClient.AgentPath())
 			if cmd.Debug {
@@ -102,7 +105,7 @@ func (cmd *SSHCmd) Run(ctx context.Context, args []string) error {
 
 type ExecFunc func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 
-func StartSSHSession(ctx context.Context, user, command string, agentForwarding bool, exec ExecFunc, stderr io.Writer) error {
+func StartSSHSession(ctx context.Context, user, command string, agentForwarding bool, env map[string]string, exec ExecFunc, stderr io.Writer) error {
 	// create readers
 	stdoutReader, stdoutWriter, err := os.Pipe()
 	if err != nil {
@@ -129,10 +132,11 @@ func StartSSHSession(ctx context.Context, user, command string, agentForwarding
 	}
 	defer func() { _ = sshClient.Close() }()
 
-	return RunSSHSession(ctx, sshClient, agentForwarding, command, stderr)
+	return RunSSHSession(ctx, sshClient, agentForwarding, command, env, stderr)
 }
 
-func RunSSHSession(ctx context.Context, sshClient *ssh.Client, agentForwarding bool, command string, stderr io.Writer) error {
+// RunSSHSession runs the command or a shell in a new session, env is set in the session before it starts
+func RunSSHSession(ctx context.Context, sshClient *ssh.Client, agentForwarding bool, command string, env map[string]string, stderr io.Writer) error {
 	// create a new session
 	session, err := sshClient.NewSession()
 	if err != nil {
@@ -198,6 +202,12 @@ func RunSSHSession(ctx context.Context, sshClient *ssh.Client, agentForwarding b
 		}
 	}
 
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		if err := session.Setenv(key, env[key]); err != nil {
+			return fmt.Errorf("set env %s %w", key, err)
+		}
+	}
+
 	session.Stdin = stdin
 	session.Stdout = stdout
 	session.Stderr = stderr
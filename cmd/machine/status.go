@@ -50,10 +50,11 @@ func (cmd *StatusCmd) Run(ctx context.Context, args []string) error {
 	}
 
 	// get status
-	machineStatus, err := machineClient.Status(ctx, client.StatusOptions{})
+	status, err := machineClient.Status(ctx, client.StatusOptions{})
 	if err != nil {
 		return err
 	}
+	machineStatus := status.State
 
 	switch cmd.Output {
 	case "plain":
@@ -68,17 +69,7 @@ func (cmd *StatusCmd) Run(ctx context.Context, args []string) error {
 			log.Default.Infof("Machine '%s' is '%s'", machineClient.Machine(), machineStatus)
 		}
 	case "json":
-		out, err := json.Marshal(struct {
-			ID       string `json:"id,omitempty"`
-			Context  string `json:"context,omitempty"`
-			Provider string `json:"provider,omitempty"`
-			State    string `json:"state,omitempty"`
-		}{
-			ID:       machineClient.Machine(),
-			Context:  machineClient.Context(),
-			Provider: machineClient.Provider(),
-			State:    string(machineStatus),
-		})
+		out, err := json.Marshal(status)
 		if err != nil {
 			return err
 		}
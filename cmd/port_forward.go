@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/completion"
+	"github.com/skevetter/devpod/cmd/flags"
+	clientpkg "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/config"
+	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/tunnel"
+	"github.com/skevetter/devpod/pkg/workspace"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// PortForwardCmd holds the port-forward cmd flags
+type PortForwardCmd struct {
+	*flags.GlobalFlags
+}
+
+// NewPortForwardCmd creates a new port-forward command
+func NewPortForwardCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &PortForwardCmd{
+		GlobalFlags: flags,
+	}
+	portForwardCmd := &cobra.Command{
+		Use:   "port-forward [flags] workspace-path|workspace-name [[local-address:]local-port:]remote-port...",
+		Short: "Forwards local ports to the workspace container through the driver",
+		Long: `Forwards local ports to the workspace container through the driver of the provider, which doesn't need
+the agent to run in the container. If no ports are given, the forwardPorts and appPort of the devcontainer.json are forwarded.
+
+Example:
+  devpod port-forward my-workspace 8080:80`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(cobraCmd.Context(), args[:1], args[1:])
+		},
+		ValidArgsFunction: func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
+		},
+	}
+	return portForwardCmd
+}
+
+// Run runs the command logic
+func (cmd *PortForwardCmd) Run(ctx context.Context, args []string, ports []string) error {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return err
+	}
+
+	baseClient, err := workspace.Get(ctx, devPodConfig, args, false, cmd.Owner, false, log.Default)
+	if err != nil {
+		return err
+	}
+
+	client, ok := baseClient.(clientpkg.WorkspaceClient)
+	if !ok {
+		return fmt.Errorf("this command is not supported for proxy providers")
+	}
+
+	var result *config2.Result
+	if len(ports) == 0 {
+		result, err = provider.LoadWorkspaceResult(client.Context(), client.Workspace())
+		if err != nil {
+			return fmt.Errorf("load workspace result %w", err)
+		} else if result == nil {
+			return fmt.Errorf("no ports given and the ports of workspace %s are unknown, please run devpod up first", client.Workspace())
+		}
+	}
+
+	return forwardPortsWithDriver(ctx, devPodConfig, client, ports, result, log.Default)
+}
+
+// driverPortForwardSupported returns true if the driver of the workspace can forward ports without the agent
+// running in the container
+func driverPortForwardSupported(client clientpkg.WorkspaceClient) bool {
+	_, agentInfo, err := client.AgentInfo(provider.CLIOptions{})
+	return err == nil && agentInfo.Agent.Driver == provider.KubernetesDriver
+}
+
+// forwardPortsWithDriver forwards ports through the driver. A single agent forwards all connections, so the
+// driver and its connection to the workspace container are reused.
+func forwardPortsWithDriver(
+	ctx context.Context,
+	devPodConfig *config.Config,
+	client clientpkg.WorkspaceClient,
+	ports []string,
+	result *config2.Result,
+	log log.Logger,
+) error {
+	sshClient, err := newAgentSSHClient(ctx, devPodConfig, client, log)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sshClient.Close() }()
+
+	socketPath, stop, err := startDriverPortForward(sshClient, client, log)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	dial := func(ctx context.Context, port int, stream io.ReadWriter) error {
+		conn, err := sshClient.Dial("unix", socketPath)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = conn.Close() }()
+
+		_, err = fmt.Fprintf(conn, "%d\n", port)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			_, _ = io.Copy(conn, stream)
+			if closeWriter, ok := conn.(interface{ CloseWrite() error }); ok {
+				_ = closeWriter.CloseWrite()
+			}
+		}()
+
+		_, err = io.Copy(stream, conn)
+		return err
+	}
+
+	return tunnel.ForwardPortsWithDriver(ctx, dial, ports, result, log)
+}
+
+// startDriverPortForward starts the agent that forwards the connections of its socket through the driver and
+// returns the path of the socket together with a function that stops the agent
+func startDriverPortForward(sshClient *gossh.Client, client clientpkg.WorkspaceClient, log log.Logger) (string, func(), error) {
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the agent stops once stdin is closed
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = session.Close()
+		return "", nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		return "", nil, err
+	}
+	stderr := &agentStderr{log: log}
+	session.Stderr = stderr
+
+	agentCommand := fmt.Sprintf("'%s' agent workspace port-forward --context '%s' --id '%s'", client.AgentPath(), client.Context(), client.Workspace())
+	if log.GetLevel() == logrus.DebugLevel {
+		agentCommand += " --debug"
+	}
+	err = session.Start(agentCommand)
+	if err != nil {
+		_ = session.Close()
+		return "", nil, err
+	}
+
+	socketPath, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		err = session.Wait()
+		_ = session.Close()
+		return "", nil, fmt.Errorf("start port forwarding through the driver: %s %w", stderr.String(), err)
+	}
+	stderr.ready()
+
+	return strings.TrimSpace(socketPath), func() {
+		_ = stdin.Close()
+		_ = session.Close()
+	}, nil
+}
+
+// agentStderr collects the output of the agent until it is ready, afterwards every write is logged as an error
+type agentStderr struct {
+	m       sync.Mutex
+	buf     bytes.Buffer
+	started bool
+	log     log.Logger
+}
+
+func (a *agentStderr) Write(p []byte) (int, error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if a.started {
+		a.log.Error(strings.TrimSpace(string(p)))
+		return len(p), nil
+	}
+
+	return a.buf.Write(p)
+}
+
+func (a *agentStderr) ready() {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.started = true
+}
+
+func (a *agentStderr) String() string {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return strings.TrimSpace(a.buf.String())
+}
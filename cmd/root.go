@@ -8,15 +8,19 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/cmd/agent"
+	"github.com/skevetter/devpod/cmd/cache"
 	"github.com/skevetter/devpod/cmd/completion"
 	"github.com/skevetter/devpod/cmd/context"
+	"github.com/skevetter/devpod/cmd/features"
 	"github.com/skevetter/devpod/cmd/flags"
 	"github.com/skevetter/devpod/cmd/helper"
 	"github.com/skevetter/devpod/cmd/ide"
 	"github.com/skevetter/devpod/cmd/machine"
 	"github.com/skevetter/devpod/cmd/pro"
 	"github.com/skevetter/devpod/cmd/provider"
+	"github.com/skevetter/devpod/cmd/templates"
 	"github.com/skevetter/devpod/cmd/use"
+	cache2 "github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/client/clientimplementation"
 	"github.com/skevetter/devpod/pkg/config"
 	"github.com/skevetter/devpod/pkg/telemetry"
@@ -56,6 +60,9 @@ func NewRootCmd() *cobra.Command {
 			if globalFlags.DevPodHome != "" {
 				_ = os.Setenv(config.DEVPOD_HOME, globalFlags.DevPodHome)
 			}
+			if globalFlags.Offline {
+				_ = os.Setenv(cache2.DEVPOD_OFFLINE, "true")
+			}
 
 			devPodConfig, err := config.LoadConfig(globalFlags.Context, globalFlags.Provider)
 			if err == nil {
@@ -124,12 +131,21 @@ func BuildRoot() *cobra.Command {
 	rootCmd.AddCommand(ide.NewIDECmd(globalFlags))
 	rootCmd.AddCommand(machine.NewMachineCmd(globalFlags))
 	rootCmd.AddCommand(context.NewContextCmd(globalFlags))
+	rootCmd.AddCommand(features.NewFeaturesCmd(globalFlags))
+	rootCmd.AddCommand(templates.NewTemplatesCmd(globalFlags))
+	rootCmd.AddCommand(cache.NewCacheCmd(globalFlags))
 	rootCmd.AddCommand(pro.NewProCmd(globalFlags, log2.Default))
 	rootCmd.AddCommand(NewUpCmd(globalFlags))
 	rootCmd.AddCommand(NewDeleteCmd(globalFlags))
 	rootCmd.AddCommand(NewSSHCmd(globalFlags))
+	rootCmd.AddCommand(NewExecCmd(globalFlags))
 	rootCmd.AddCommand(NewVersionCmd())
 	rootCmd.AddCommand(NewStopCmd(globalFlags))
+	rootCmd.AddCommand(NewSnapshotCmd(globalFlags))
+	rootCmd.AddCommand(NewSyncCmd(globalFlags))
+	rootCmd.AddCommand(NewSessionCmd(globalFlags))
+	rootCmd.AddCommand(NewGenerateKubeCmd(globalFlags))
+	rootCmd.AddCommand(NewPortForwardCmd(globalFlags))
 	rootCmd.AddCommand(NewListCmd(globalFlags))
 	rootCmd.AddCommand(NewStatusCmd(globalFlags))
 	rootCmd.AddCommand(NewBuildCmd(globalFlags))
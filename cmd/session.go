@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/completion"
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/asciicast"
+	clientpkg "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/config"
+	"github.com/skevetter/devpod/pkg/workspace"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/table"
+	"github.com/spf13/cobra"
+)
+
+// SessionCmd holds the session cmd flags
+type SessionCmd struct {
+	*flags.GlobalFlags
+
+	Name   string
+	Output string
+
+	Speed         float64
+	IdleTimeLimit time.Duration
+
+	File string
+}
+
+// NewSessionCmd creates a new session command
+func NewSessionCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &SessionCmd{
+		GlobalFlags: flags,
+	}
+	sessionCmd := &cobra.Command{
+		Use:   "session",
+		Short: "List, replay and export recorded ssh sessions of a workspace",
+	}
+	validArgsFunction := func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list [flags] [workspace-path|workspace-name]",
+		Short: "Lists the recorded sessions of a workspace",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.List(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	listCmd.Flags().StringVar(&cmd.Output, "output", "plain", "The output format to use. Can be json or plain")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay [flags] [workspace-path|workspace-name]",
+		Short: "Plays a recorded session back in the terminal",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Replay(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	replayCmd.Flags().StringVar(&cmd.Name, "name", "", "The name of the session to replay")
+	_ = replayCmd.MarkFlagRequired("name")
+	replayCmd.Flags().Float64Var(&cmd.Speed, "speed", 1, "The playback speed")
+	replayCmd.Flags().DurationVar(&cmd.IdleTimeLimit, "idle-time-limit", 2*time.Second, "Shortens pauses between outputs to this duration, 0 keeps the original pauses")
+
+	exportCmd := &cobra.Command{
+		Use:   "export [flags] [workspace-path|workspace-name]",
+		Short: "Exports a recorded session as asciicast v2 file",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Export(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	exportCmd.Flags().StringVar(&cmd.Name, "name", "", "The name of the session to export")
+	_ = exportCmd.MarkFlagRequired("name")
+	exportCmd.Flags().StringVar(&cmd.File, "file", "", "The file to write the recording to, if empty the recording is written to stdout")
+
+	sessionCmd.AddCommand(listCmd, replayCmd, exportCmd)
+	return sessionCmd
+}
+
+// List prints the recorded sessions of the workspace
+func (cmd *SessionCmd) List(ctx context.Context, args []string) error {
+	devPodConfig, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	stdout := &bytes.Buffer{}
+	err = runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client), stdout, log.Default)
+	if err != nil {
+		return fmt.Errorf("list sessions %w", err)
+	}
+
+	recordings := []*asciicast.Recording{}
+	if stdout.Len() > 0 {
+		err = json.Unmarshal(stdout.Bytes(), &recordings)
+		if err != nil {
+			return fmt.Errorf("parse sessions %w", err)
+		}
+	}
+
+	switch cmd.Output {
+	case "json":
+		out, err := json.Marshal(recordings)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+	case "plain":
+		tableEntries := [][]string{}
+		for _, recording := range recordings {
+			tableEntries = append(tableEntries, []string{
+				recording.Name,
+				recording.Title,
+				recording.Command,
+				time.Since(recording.Start).Round(1 * time.Second).String(),
+				recording.Duration,
+			})
+		}
+
+		table.PrintTable(log.Default, []string{
+			"Name",
+			"User",
+			"Command",
+			"Age",
+			"Duration",
+		}, tableEntries)
+	default:
+		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+	}
+
+	return nil
+}
+
+// Replay streams the recording from the workspace and plays it back in the terminal
+func (cmd *SessionCmd) Replay(ctx context.Context, args []string) error {
+	err := asciicast.ValidateName(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	devPodConfig, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client), writer, log.Default))
+	}()
+	defer func() { _ = reader.Close() }()
+
+	err = asciicast.Play(ctx, reader, os.Stdout, asciicast.PlayOptions{
+		Speed:         cmd.Speed,
+		IdleTimeLimit: cmd.IdleTimeLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("replay session %s %w", cmd.Name, err)
+	}
+
+	fmt.Print("\r\n")
+	return nil
+}
+
+// Export writes the recording to a file or stdout
+func (cmd *SessionCmd) Export(ctx context.Context, args []string) error {
+	err := asciicast.ValidateName(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	devPodConfig, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if cmd.File == "" {
+		return runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client), os.Stdout, log.Default)
+	}
+
+	f, err := os.Create(cmd.File)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	err = runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client), f, log.Default)
+	if err != nil {
+		return fmt.Errorf("export session %s %w", cmd.Name, err)
+	}
+
+	log.Default.Donef("Exported session %s to %s", cmd.Name, cmd.File)
+	return nil
+}
+
+func (cmd *SessionCmd) workspaceClient(ctx context.Context, args []string) (*config.Config, clientpkg.WorkspaceClient, error) {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	baseClient, err := workspace.Get(ctx, devPodConfig, args, false, cmd.Owner, false, log.Default)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, ok := baseClient.(clientpkg.WorkspaceClient)
+	if !ok {
+		return nil, nil, fmt.Errorf("session recordings are not supported for proxy providers")
+	}
+
+	return devPodConfig, client, nil
+}
+
+func (cmd *SessionCmd) agentCommand(client clientpkg.WorkspaceClient) string {
+	agentCommand := fmt.Sprintf("'%s' agent workspace sessions --context '%s' --id '%s'", client.AgentPath(), client.Context(), client.Workspace())
+	if cmd.Name != "" {
+		agentCommand += fmt.Sprintf(" --name '%s'", cmd.Name)
+	}
+	if log.Default.GetLevel() == logrus.DebugLevel {
+		agentCommand += " --debug"
+	}
+
+	return agentCommand
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/agent"
+	client2 "github.com/skevetter/devpod/pkg/client"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+)
+
+const (
+	shutdownActionStopCompose = "stopCompose"
+
+	// shutdownActionGracePeriod is the time to wait after the last session was closed, so that
+	// reconnecting clients don't stop the workspace
+	shutdownActionGracePeriod = time.Minute
+)
+
+// registerShutdownActionSession marks the current process as an open session of a workspace with the
+// shutdownAction stopCompose. The container can't stop the other services of its compose project, so once
+// the last session on this host was closed, a background process stops the workspace after a grace period.
+func registerShutdownActionSession(client client2.BaseWorkspaceClient, log log.Logger) func() {
+	result, err := provider2.LoadWorkspaceResult(client.Context(), client.Workspace())
+	if err != nil || result == nil || result.MergedConfig == nil || result.MergedConfig.ShutdownAction != shutdownActionStopCompose {
+		return func() {}
+	}
+
+	sessionsDir, err := shutdownActionSessionsDir(client)
+	if err != nil {
+		log.Debugf("Error registering session: %v", err)
+		return func() {}
+	}
+
+	return shutdownActionSession(sessionsDir, func() error { return startIdleStop(client) }, log)
+}
+
+// shutdownActionSession marks the current process as an open session in the sessions dir and calls stop once
+// the last session was closed
+func shutdownActionSession(sessionsDir string, stop func() error, log log.Logger) func() {
+	unregister, err := agent.RegisterSession(sessionsDir)
+	if err != nil {
+		log.Debugf("Error registering session: %v", err)
+		return func() {}
+	}
+
+	return func() {
+		unregister()
+
+		sessions, err := agent.CountSessions(sessionsDir)
+		if err != nil || sessions > 0 {
+			return
+		}
+
+		err = stop()
+		if err != nil {
+			log.Errorf("Error stopping workspace because of shutdownAction %s: %v", shutdownActionStopCompose, err)
+		}
+	}
+}
+
+// startIdleStop starts a detached devpod stop that only stops the workspace if no new session was opened
+// within the grace period
+func startIdleStop(client client2.BaseWorkspaceClient) error {
+	binary, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	stopCmd := exec.CommandContext(
+		context.Background(),
+		binary,
+		"stop", client.Workspace(),
+		"--context", client.Context(),
+		"--if-idle", shutdownActionGracePeriod.String(),
+	)
+	err = stopCmd.Start()
+	if err != nil {
+		return err
+	}
+
+	return stopCmd.Process.Release()
+}
+
+// countShutdownActionSessions returns the number of open sessions on this host
+func countShutdownActionSessions(client client2.BaseWorkspaceClient) (int, error) {
+	sessionsDir, err := shutdownActionSessionsDir(client)
+	if err != nil {
+		return 0, err
+	}
+
+	return agent.CountSessions(sessionsDir)
+}
+
+func shutdownActionSessionsDir(client client2.BaseWorkspaceClient) (string, error) {
+	workspaceDir, err := provider2.GetWorkspaceDir(client.Context(), client.Workspace())
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(workspaceDir, "sessions"), nil
+}
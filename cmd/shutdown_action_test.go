@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/agent"
+	client2 "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/config"
+	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+)
+
+type fakeWorkspaceClient struct {
+	client2.BaseWorkspaceClient
+	workspace *provider2.Workspace
+}
+
+func (c *fakeWorkspaceClient) Context() string   { return c.workspace.Context }
+func (c *fakeWorkspaceClient) Workspace() string { return c.workspace.ID }
+
+func TestShutdownActionSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("processes are checked by signals")
+	}
+
+	tests := []struct {
+		name          string
+		otherSessions int
+		stopErr       error
+		wantStopped   bool
+	}{
+		{name: "last session", wantStopped: true},
+		{name: "last session stop fails", stopErr: errors.New("stop failed"), wantStopped: true},
+		{name: "other sessions", otherSessions: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionsDir := filepath.Join(t.TempDir(), "sessions")
+			for range tt.otherSessions {
+				startSession(t, sessionsDir)
+			}
+
+			stopped := false
+			unregister := shutdownActionSession(sessionsDir, func() error {
+				stopped = true
+				return tt.stopErr
+			}, log.Discard)
+
+			sessions, err := agent.CountSessions(sessionsDir)
+			if err != nil {
+				t.Fatal(err)
+			} else if sessions != tt.otherSessions+1 {
+				t.Fatalf("expected %d sessions, got %d", tt.otherSessions+1, sessions)
+			}
+
+			unregister()
+			if stopped != tt.wantStopped {
+				t.Fatalf("expected stopped %t, got %t", tt.wantStopped, stopped)
+			}
+		})
+	}
+}
+
+func TestRegisterShutdownActionSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("processes are checked by signals")
+	}
+
+	tests := []struct {
+		name           string
+		shutdownAction string
+		wantSession    bool
+	}{
+		{name: "no result"},
+		{name: "stop container", shutdownAction: "stopContainer"},
+		{name: "stop compose", shutdownAction: shutdownActionStopCompose, wantSession: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.DEVPOD_HOME, t.TempDir())
+			workspace := &provider2.Workspace{ID: "my-workspace", Context: "default"}
+			if tt.shutdownAction != "" {
+				mergedConfig := &config2.MergedDevContainerConfig{}
+				mergedConfig.ShutdownAction = tt.shutdownAction
+				err := provider2.SaveWorkspaceResult(workspace, &config2.Result{MergedConfig: mergedConfig})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// another session keeps the workspace running, so the test doesn't start devpod stop
+			client := &fakeWorkspaceClient{workspace: workspace}
+			sessionsDir, err := shutdownActionSessionsDir(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			startSession(t, sessionsDir)
+
+			unregister := registerShutdownActionSession(client, log.Discard)
+			defer unregister()
+
+			sessions, err := countShutdownActionSessions(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if registered := sessions == 2; registered != tt.wantSession {
+				t.Fatalf("expected registered session %t, got %d sessions", tt.wantSession, sessions)
+			}
+		})
+	}
+}
+
+// startSession registers a session of a running process in the sessions dir
+func startSession(t *testing.T, sessionsDir string) {
+	process := exec.Command("sleep", "60")
+	err := process.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = process.Process.Kill()
+		_ = process.Wait()
+	})
+
+	err = os.MkdirAll(sessionsDir, 0o777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(sessionsDir, strconv.Itoa(process.Process.Pid)), nil, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/cmd/completion"
+	"github.com/skevetter/devpod/cmd/flags"
+	clientpkg "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/client/clientimplementation"
+	"github.com/skevetter/devpod/pkg/config"
+	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/workspace"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/table"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotCmd holds the snapshot cmd flags
+type SnapshotCmd struct {
+	*flags.GlobalFlags
+
+	Name   string
+	Output string
+}
+
+// NewSnapshotCmd creates a new snapshot command
+func NewSnapshotCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &SnapshotCmd{
+		GlobalFlags: flags,
+	}
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Create and restore snapshots of a workspace",
+	}
+	validArgsFunction := func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create [flags] [workspace-path|workspace-name]",
+		Short: "Commits the workspace container and archives its volumes and content",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Create(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	createCmd.Flags().StringVar(&cmd.Name, "name", "", "The name of the snapshot, defaults to the current time")
+
+	listCmd := &cobra.Command{
+		Use:   "list [flags] [workspace-path|workspace-name]",
+		Short: "Lists the snapshots of a workspace",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.List(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	listCmd.Flags().StringVar(&cmd.Output, "output", "plain", "The output format to use. Can be json or plain")
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [flags] [workspace-path|workspace-name]",
+		Short: "Recreates the workspace from a snapshot",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Restore(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	restoreCmd.Flags().StringVar(&cmd.Name, "name", "", "The name of the snapshot to restore")
+	_ = restoreCmd.MarkFlagRequired("name")
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete [flags] [workspace-path|workspace-name]",
+		Short: "Deletes a snapshot of a workspace",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Delete(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	deleteCmd.Flags().StringVar(&cmd.Name, "name", "", "The name of the snapshot to delete")
+	_ = deleteCmd.MarkFlagRequired("name")
+
+	snapshotCmd.AddCommand(createCmd, listCmd, restoreCmd, deleteCmd)
+	return snapshotCmd
+}
+
+// Create creates a new snapshot of the workspace
+func (cmd *SnapshotCmd) Create(ctx context.Context, args []string) error {
+	if cmd.Name == "" {
+		cmd.Name = time.Now().Format("20060102-150405")
+	}
+	err := provider.ValidateSnapshotName(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	devPodConfig, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := provider.LoadWorkspaceSnapshots(client.Context(), client.Workspace())
+	if err != nil {
+		return err
+	} else if findSnapshot(snapshots, cmd.Name) != nil {
+		return fmt.Errorf("snapshot %s already exists", cmd.Name)
+	}
+
+	err = client.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Unlock()
+
+	err = clientimplementation.StartWait(ctx, client, false, log.Default)
+	if err != nil {
+		return err
+	}
+
+	log.Default.Infof("creating snapshot %s", cmd.Name)
+	stdout := &bytes.Buffer{}
+	err = runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client, "create"), stdout, log.Default)
+	if err != nil {
+		return fmt.Errorf("create snapshot %w", err)
+	}
+
+	snapshot := &provider.Snapshot{}
+	err = json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), snapshot)
+	if err != nil {
+		return fmt.Errorf("parse snapshot %w", err)
+	}
+
+	err = provider.SaveWorkspaceSnapshots(client.Context(), client.Workspace(), append(snapshots, snapshot))
+	if err != nil {
+		return fmt.Errorf("save snapshot %w", err)
+	}
+
+	log.Default.WithFields(logrus.Fields{
+		"workspace": client.Workspace(),
+		"snapshot":  snapshot.Name,
+		"volumes":   snapshot.Volumes,
+	}).Done("created snapshot")
+	return nil
+}
+
+// List prints the snapshots of the workspace
+func (cmd *SnapshotCmd) List(ctx context.Context, args []string) error {
+	_, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := provider.LoadWorkspaceSnapshots(client.Context(), client.Workspace())
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].CreationTimestamp.Unix() > snapshots[j].CreationTimestamp.Unix()
+	})
+
+	switch cmd.Output {
+	case "json":
+		out, err := json.Marshal(snapshots)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+	case "plain":
+		tableEntries := [][]string{}
+		for _, snapshot := range snapshots {
+			tableEntries = append(tableEntries, []string{
+				snapshot.Name,
+				snapshot.Image,
+				fmt.Sprintf("%d", len(snapshot.Volumes)),
+				fmt.Sprintf("%t", snapshot.Content),
+				time.Since(snapshot.CreationTimestamp.Time).Round(1 * time.Second).String(),
+			})
+		}
+
+		table.PrintTable(log.Default, []string{
+			"Name",
+			"Image",
+			"Volumes",
+			"Content",
+			"Age",
+		}, tableEntries)
+	default:
+		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+	}
+
+	return nil
+}
+
+// Restore restores the volumes and content of the snapshot and recreates the workspace container from the snapshot image
+func (cmd *SnapshotCmd) Restore(ctx context.Context, args []string) error {
+	devPodConfig, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := provider.LoadWorkspaceSnapshots(client.Context(), client.Workspace())
+	if err != nil {
+		return err
+	}
+	snapshot := findSnapshot(snapshots, cmd.Name)
+	if snapshot == nil {
+		return fmt.Errorf("snapshot %s not found", cmd.Name)
+	}
+
+	err = cmd.restoreSnapshot(ctx, devPodConfig, client)
+	if err != nil {
+		return err
+	}
+
+	log.Default.WithFields(logrus.Fields{
+		"workspace": client.Workspace(),
+		"snapshot":  snapshot.Name,
+	}).Done("restored snapshot")
+	return nil
+}
+
+func (cmd *SnapshotCmd) restoreSnapshot(ctx context.Context, devPodConfig *config.Config, client clientpkg.WorkspaceClient) error {
+	err := client.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Unlock()
+
+	err = clientimplementation.StartWait(ctx, client, false, log.Default)
+	if err != nil {
+		return err
+	}
+
+	// the agent recreates the container from the snapshot image and prints the result
+	log.Default.Infof("restoring snapshot %s", cmd.Name)
+	stdout := &bytes.Buffer{}
+	err = runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client, "restore"), stdout, log.Default)
+	if err != nil {
+		return fmt.Errorf("restore snapshot %w", err)
+	}
+
+	result := &config2.Result{}
+	err = json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), result)
+	if err != nil {
+		return fmt.Errorf("parse result %w", err)
+	}
+
+	err = provider.SaveWorkspaceResult(client.WorkspaceConfig(), result)
+	if err != nil {
+		return fmt.Errorf("save workspace result %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the snapshot image and archives
+func (cmd *SnapshotCmd) Delete(ctx context.Context, args []string) error {
+	devPodConfig, client, err := cmd.workspaceClient(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := provider.LoadWorkspaceSnapshots(client.Context(), client.Workspace())
+	if err != nil {
+		return err
+	} else if findSnapshot(snapshots, cmd.Name) == nil {
+		return fmt.Errorf("snapshot %s not found", cmd.Name)
+	}
+
+	err = client.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Unlock()
+
+	err = clientimplementation.StartWait(ctx, client, false, log.Default)
+	if err != nil {
+		return err
+	}
+
+	writer := log.Default.Writer(logrus.InfoLevel, false)
+	defer func() { _ = writer.Close() }()
+
+	err = runAgentCommand(ctx, devPodConfig, client, cmd.agentCommand(client, "delete"), writer, log.Default)
+	if err != nil {
+		return fmt.Errorf("delete snapshot %w", err)
+	}
+
+	snapshots = slices.DeleteFunc(snapshots, func(snapshot *provider.Snapshot) bool {
+		return snapshot.Name == cmd.Name
+	})
+	err = provider.SaveWorkspaceSnapshots(client.Context(), client.Workspace(), snapshots)
+	if err != nil {
+		return fmt.Errorf("save snapshots %w", err)
+	}
+
+	log.Default.WithFields(logrus.Fields{
+		"workspace": client.Workspace(),
+		"snapshot":  cmd.Name,
+	}).Done("deleted snapshot")
+	return nil
+}
+
+func (cmd *SnapshotCmd) workspaceClient(ctx context.Context, args []string) (*config.Config, clientpkg.WorkspaceClient, error) {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	baseClient, err := workspace.Get(ctx, devPodConfig, args, false, cmd.Owner, false, log.Default)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, ok := baseClient.(clientpkg.WorkspaceClient)
+	if !ok {
+		return nil, nil, fmt.Errorf("snapshots are not supported for proxy providers")
+	}
+
+	return devPodConfig, client, nil
+}
+
+func (cmd *SnapshotCmd) agentCommand(client clientpkg.WorkspaceClient, action string) string {
+	agentCommand := fmt.Sprintf("'%s' agent workspace snapshot %s --context '%s' --id '%s' --name '%s'", client.AgentPath(), action, client.Context(), client.Workspace(), cmd.Name)
+	if log.Default.GetLevel() == logrus.DebugLevel {
+		agentCommand += " --debug"
+	}
+
+	return agentCommand
+}
+
+func findSnapshot(snapshots []*provider.Snapshot, name string) *provider.Snapshot {
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return snapshot
+		}
+	}
+
+	return nil
+}
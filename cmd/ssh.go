@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -24,6 +25,7 @@ import (
 	"github.com/skevetter/devpod/pkg/port"
 	"github.com/skevetter/devpod/pkg/provider"
 	devssh "github.com/skevetter/devpod/pkg/ssh"
+	sshserver "github.com/skevetter/devpod/pkg/ssh/server"
 	"github.com/skevetter/devpod/pkg/tunnel"
 	workspace2 "github.com/skevetter/devpod/pkg/workspace"
 	"github.com/skevetter/log"
@@ -60,6 +62,11 @@ type SSHCmd struct {
 	Command string
 	User    string
 	WorkDir string
+
+	// persistent terminal sessions
+	Session      string
+	ListSessions bool
+	KillSession  string
 }
 
 // NewSSHCmd creates a new ssh command
@@ -106,6 +113,9 @@ func NewSSHCmd(f *flags.GlobalFlags) *cobra.Command {
 	sshCmd.Flags().BoolVar(&cmd.Stdio, "stdio", false, "If true will tunnel connection through stdout and stdin")
 	sshCmd.Flags().BoolVar(&cmd.StartServices, "start-services", true, "If false will not start any port-forwarding or git / docker credentials helper")
 	sshCmd.Flags().DurationVar(&cmd.SSHKeepAliveInterval, "ssh-keepalive-interval", 55*time.Second, "How often should keepalive request be made (55s)")
+	sshCmd.Flags().StringVar(&cmd.Session, "session", "", "Attaches to the persistent terminal session with the given name or creates it. The session keeps running if the connection is lost, press Ctrl-\\ to detach")
+	sshCmd.Flags().BoolVar(&cmd.ListSessions, "list-sessions", false, "Lists the persistent terminal sessions in the workspace")
+	sshCmd.Flags().StringVar(&cmd.KillSession, "kill-session", "", "Terminates the persistent terminal session with the given name")
 
 	return sshCmd
 }
@@ -116,6 +126,10 @@ func (cmd *SSHCmd) Run(
 	devPodConfig *config.Config,
 	client client2.BaseWorkspaceClient,
 	log log.Logger) error {
+	if cmd.Stdio && (cmd.Session != "" || cmd.ListSessions || cmd.KillSession != "") {
+		return fmt.Errorf("persistent sessions can't be used with --stdio")
+	}
+
 	// add ssh keys to agent
 	if devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true" && devPodConfig.ContextOption(config.ContextOptionSSHAddPrivateKeys) == "true" {
 		log.Debug("adding ssh keys to agent, disable via 'devpod context set-options -o SSH_ADD_PRIVATE_KEYS=false'")
@@ -220,12 +234,18 @@ func (cmd *SSHCmd) jumpContainerTailscale(
 		return client.DirectTunnel(ctx, os.Stdin, os.Stdout)
 	}
 
+	sessionEnv, err := cmd.sessionEnv()
+	if err != nil {
+		return err
+	}
+
 	// Connect to the inner server and handle user session
 	return machine.RunSSHSession(
 		ctx,
 		sshClient,
 		cmd.AgentForwarding,
 		cmd.Command,
+		sessionEnv,
 		os.Stderr,
 	)
 }
@@ -252,6 +272,33 @@ func (cmd *SSHCmd) startProxyTunnel(
 	)
 }
 
+// sessionEnv returns the environment that selects the persistent session on the ssh server of the container
+func (cmd *SSHCmd) sessionEnv() (map[string]string, error) {
+	switch {
+	case cmd.ListSessions:
+		return map[string]string{sshserver.PersistentSessionActionEnv: sshserver.PersistentSessionActionList}, nil
+	case cmd.KillSession != "":
+		err := sshserver.ValidateSessionName(cmd.KillSession)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]string{
+			sshserver.PersistentSessionEnv:       cmd.KillSession,
+			sshserver.PersistentSessionActionEnv: sshserver.PersistentSessionActionKill,
+		}, nil
+	case cmd.Session != "":
+		err := sshserver.ValidateSessionName(cmd.Session)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]string{sshserver.PersistentSessionEnv: cmd.Session}, nil
+	}
+
+	return nil, nil
+}
+
 func (cmd *SSHCmd) retrieveEnVars() (map[string]string, error) {
 	envVars := make(map[string]string)
 	for _, envVar := range cmd.SendEnvVars {
@@ -296,14 +343,27 @@ func (cmd *SSHCmd) jumpContainer(
 	}
 
 	// tunnel to container
-	return tunnel.NewContainerTunnel(client, log).
+	connected := &atomic.Bool{}
+	err = tunnel.NewContainerTunnel(client, log).
 		Run(ctx, func(ctx context.Context, containerClient *ssh.Client) error {
 			// we have a connection to the container, make sure others can connect as well
+			connected.Store(true)
 			client.Unlock()
 
+			// run the shutdown action of the workspace once the last session was closed
+			defer registerShutdownActionSession(client, log)()
+
 			// start ssh tunnel
 			return cmd.startTunnel(ctx, devPodConfig, containerClient, client, log)
 		}, devPodConfig, envVars)
+	if err != nil && !connected.Load() && len(cmd.ForwardPorts) > 0 && !cmd.Stdio && ctx.Err() == nil && driverPortForwardSupported(client) {
+		// the agent might not be able to run in the container, try the driver instead
+		log.Warnf("Error connecting to the workspace container, forwarding ports through the driver instead: %v", err)
+		client.Unlock()
+		return forwardPortsWithDriver(ctx, devPodConfig, client, cmd.ForwardPorts, nil, log)
+	}
+
+	return err
 }
 
 func (cmd *SSHCmd) forwardTimeout(log log.Logger) (time.Duration, error) {
@@ -474,11 +534,17 @@ func (cmd *SSHCmd) startTunnel(ctx context.Context, devPodConfig *config.Config,
 		return devssh.Run(ctx, containerClient, command, os.Stdin, os.Stdout, writer, envVars)
 	}
 
+	sessionEnv, err := cmd.sessionEnv()
+	if err != nil {
+		return err
+	}
+
 	return machine.StartSSHSession(
 		ctx,
 		cmd.User,
 		cmd.Command,
 		cmd.AgentForwarding && devPodConfig.ContextOption(config.ContextOptionSSHAgentForwarding) == "true",
+		sessionEnv,
 		func(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 			if cmd.SSHKeepAliveInterval != DisableSSHKeepAlive {
 				go startSSHKeepAlive(ctx, containerClient, cmd.SSHKeepAliveInterval, log)
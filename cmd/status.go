@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,9 @@ import (
 	client2 "github.com/skevetter/devpod/pkg/client"
 	"github.com/skevetter/devpod/pkg/client/clientimplementation"
 	"github.com/skevetter/devpod/pkg/config"
+	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/version"
 	workspace2 "github.com/skevetter/devpod/pkg/workspace"
 	"github.com/skevetter/log"
 	"github.com/spf13/cobra"
@@ -21,8 +25,11 @@ type StatusCmd struct {
 	*flags.GlobalFlags
 	client2.StatusOptions
 
-	Output  string
-	Timeout string
+	Output         string
+	Timeout        string
+	LifecycleHooks bool
+	Watch          bool
+	WatchInterval  time.Duration
 }
 
 // NewStatusCmd creates a new command
@@ -51,7 +58,7 @@ func NewStatusCmd(flags *flags.GlobalFlags) *cobra.Command {
 				return err
 			}
 
-			return cmd.Run(ctx, client, logger)
+			return cmd.Run(ctx, devPodConfig, client, logger)
 		},
 		ValidArgsFunction: func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
@@ -59,58 +66,214 @@ func NewStatusCmd(flags *flags.GlobalFlags) *cobra.Command {
 	}
 
 	statusCmd.Flags().BoolVar(&cmd.ContainerStatus, "container-status", true, "If enabled shows the workspace container status as well")
-	statusCmd.Flags().StringVar(&cmd.Output, "output", "plain", "Status shows the workspace status")
+	statusCmd.Flags().StringVarP(&cmd.Output, "output", "o", "plain", "The output format to use. Can be json or plain")
 	statusCmd.Flags().StringVar(&cmd.Timeout, "timeout", "30s", "The timeout to wait until the status can be retrieved")
+	statusCmd.Flags().BoolVar(&cmd.LifecycleHooks, "lifecycle-hooks", false, "If enabled shows the progress of the lifecycle hooks in the workspace container")
+	statusCmd.Flags().BoolVar(&cmd.Watch, "watch", false, "If enabled prints the status again whenever it changes")
+	statusCmd.Flags().DurationVar(&cmd.WatchInterval, "watch-interval", 5*time.Second, "How often to retrieve the status in watch mode")
 	return statusCmd
 }
 
 // Run runs the command logic
-func (cmd *StatusCmd) Run(ctx context.Context, client client2.BaseWorkspaceClient, log log.Logger) error {
+func (cmd *StatusCmd) Run(ctx context.Context, devPodConfig *config.Config, client client2.BaseWorkspaceClient, log log.Logger) error {
+	if cmd.Output != "plain" && cmd.Output != "json" {
+		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+	}
+
 	// parse timeout
+	var timeout time.Duration
 	if cmd.Timeout != "" {
-		duration, err := time.ParseDuration(cmd.Timeout)
+		var err error
+		timeout, err = time.ParseDuration(cmd.Timeout)
 		if err != nil {
 			return fmt.Errorf("parse --timeout %w", err)
 		}
+	}
+
+	lastPrinted := ""
+	for {
+		status, err := cmd.status(ctx, timeout, devPodConfig, client, log)
+		if err != nil {
+			if !cmd.Watch || ctx.Err() != nil {
+				return err
+			}
+
+			// keep watching, the workspace might be unreachable for a moment
+			log.Errorf("Error retrieving status: %v", err)
+		} else {
+			key, err := statusKey(status)
+			if err != nil {
+				return err
+			}
+
+			if key != lastPrinted {
+				lastPrinted = key
+				err = cmd.print(status, log)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if !cmd.Watch {
+			return nil
+		}
 
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cmd.WatchInterval):
+		}
+	}
+}
+
+func (cmd *StatusCmd) status(ctx context.Context, timeout time.Duration, devPodConfig *config.Config, client client2.BaseWorkspaceClient, log log.Logger) (*client2.WorkspaceStatus, error) {
+	if timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, duration)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
 	// get instance status
-	instanceStatus, err := client.Status(ctx, cmd.StatusOptions)
+	status, err := client.Status(ctx, cmd.StatusOptions)
 	if err != nil {
-		return err
-	}
-
-	switch cmd.Output {
-	case "plain":
-		switch instanceStatus {
-		case client2.StatusStopped:
-			log.Infof("Workspace '%s' is '%s', you can start it via 'devpod up %s'", client.Workspace(), instanceStatus, client.Workspace())
-		case client2.StatusBusy:
-			log.Infof("Workspace '%s' is '%s', which means its currently unaccessible. This is usually resolved by waiting a couple of minutes", client.Workspace(), instanceStatus)
-		case client2.StatusNotFound:
-			log.Infof("Workspace '%s' is '%s', you can create it via 'devpod up %s'", client.Workspace(), instanceStatus, client.Workspace())
-		default:
-			log.Infof("Workspace '%s' is '%s'", client.Workspace(), instanceStatus)
+		return nil, err
+	}
+
+	// get lifecycle hooks status if the container status didn't include it
+	if cmd.LifecycleHooks && status.State == client2.StatusRunning && status.LifecycleHooks == nil {
+		status.LifecycleHooks, err = cmd.lifecycleHooks(ctx, devPodConfig, client, log)
+		if err != nil {
+			return nil, fmt.Errorf("get lifecycle hooks status %w", err)
 		}
-	case "json":
-		out, err := json.Marshal(&client2.WorkspaceStatus{
-			ID:       client.Workspace(),
-			Context:  client.Context(),
-			Provider: client.Provider(),
-			State:    string(instanceStatus),
-		})
+	}
+
+	// get the configured ports
+	status.Ports, err = cmd.ports(client)
+	if err != nil {
+		return nil, fmt.Errorf("get ports status %w", err)
+	}
+
+	return status, nil
+}
+
+func (cmd *StatusCmd) print(status *client2.WorkspaceStatus, log log.Logger) error {
+	if cmd.Output == "json" {
+		out, err := json.Marshal(status)
 		if err != nil {
 			return err
 		}
 
-		fmt.Print(string(out))
+		// print one status per line in watch mode
+		if cmd.Watch {
+			fmt.Println(string(out))
+		} else {
+			fmt.Print(string(out))
+		}
+		return nil
+	}
+
+	switch status.State {
+	case client2.StatusStopped:
+		log.Infof("Workspace '%s' is '%s', you can start it via 'devpod up %s'", status.ID, status.State, status.ID)
+	case client2.StatusBusy:
+		log.Infof("Workspace '%s' is '%s', which means its currently unaccessible. This is usually resolved by waiting a couple of minutes", status.ID, status.State)
+	case client2.StatusNotFound:
+		log.Infof("Workspace '%s' is '%s', you can create it via 'devpod up %s'", status.ID, status.State, status.ID)
 	default:
-		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+		log.Infof("Workspace '%s' is '%s'", status.ID, status.State)
+	}
+	if status.Container != nil && status.Container.ID != "" {
+		log.Infof("Container '%s' of image '%s' (driver %s)", status.Container.ID, status.Container.Image, status.Container.Driver)
+		if status.Container.Uptime != "" {
+			log.Infof("Container is up for %s", status.Container.Uptime)
+		}
+	}
+	if status.LastActivity != nil {
+		log.Infof("Last activity %s ago", time.Since(status.LastActivity.Time).Round(time.Second))
+	}
+	if status.IDE != nil && status.IDE.Name != "" {
+		if status.IDE.Installed {
+			log.Infof("IDE '%s' is installed", status.IDE.Name)
+		} else {
+			log.Infof("IDE '%s' is not installed", status.IDE.Name)
+		}
+	}
+	if status.Agent != nil && status.Agent.Outdated {
+		log.Warnf("Agent version %s is older than the cli version %s, it will be updated with the next 'devpod up'", status.Agent.Version, version.GetVersion())
+	}
+	if cmd.LifecycleHooks {
+		for _, hook := range status.LifecycleHooks {
+			if hook.Error != "" {
+				log.Infof("Lifecycle hook '%s' is '%s': %s", hook.Name, hook.State, hook.Error)
+				continue
+			}
+
+			log.Infof("Lifecycle hook '%s' is '%s'", hook.Name, hook.State)
+		}
+	}
+	for _, port := range status.Ports {
+		if port.Label != "" {
+			log.Infof("Port %s '%s': %s", port.Port, port.Label, port.OnAutoForward)
+			continue
+		}
+
+		log.Infof("Port %s: %s", port.Port, port.OnAutoForward)
 	}
 
 	return nil
 }
+
+// ports returns the ports of the devcontainer.json from the last result of devpod up
+func (cmd *StatusCmd) ports(client client2.BaseWorkspaceClient) ([]config2.PortStatus, error) {
+	result, err := provider.LoadWorkspaceResult(client.Context(), client.Workspace())
+	if err != nil {
+		return nil, err
+	} else if result == nil || result.MergedConfig == nil {
+		return nil, nil
+	}
+
+	return config2.GetPortsStatus(result.MergedConfig), nil
+}
+
+func (cmd *StatusCmd) lifecycleHooks(ctx context.Context, devPodConfig *config.Config, client client2.BaseWorkspaceClient, log log.Logger) ([]config2.LifecycleHookStatus, error) {
+	workspaceClient, ok := client.(client2.WorkspaceClient)
+	if !ok {
+		log.Debugf("Skip lifecycle hooks status, because it is not supported for proxy providers")
+		return nil, nil
+	}
+
+	agentCommand := fmt.Sprintf("'%s' agent workspace lifecycle-hooks --output json --context '%s' --id '%s'", workspaceClient.AgentPath(), workspaceClient.Context(), workspaceClient.Workspace())
+	stdout := &bytes.Buffer{}
+	err := runAgentCommand(ctx, devPodConfig, workspaceClient, agentCommand, stdout, log)
+	if err != nil {
+		return nil, err
+	} else if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil, nil
+	}
+
+	lifecycleHooks := []config2.LifecycleHookStatus{}
+	err = json.Unmarshal(stdout.Bytes(), &lifecycleHooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return lifecycleHooks, nil
+}
+
+// statusKey returns a key of the status to detect changes in watch mode, the uptime changes all the time
+// so it's ignored
+func statusKey(status *client2.WorkspaceStatus) (string, error) {
+	compare := *status
+	if compare.Container != nil {
+		container := *compare.Container
+		container.Uptime = ""
+		compare.Container = &container
+	}
+	out, err := json.Marshal(&compare)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"testing"
+
+	client2 "github.com/skevetter/devpod/pkg/client"
+	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
+)
+
+func TestStatusKey(t *testing.T) {
+	status := func(state client2.Status, uptime string, hook config2.LifecycleHookState) *client2.WorkspaceStatus {
+		return &client2.WorkspaceStatus{
+			ID:             "workspace",
+			State:          state,
+			Container:      &client2.ContainerStatus{ID: "container", Uptime: uptime},
+			LifecycleHooks: []config2.LifecycleHookStatus{{Name: "postCreateCommand", State: hook}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		previous    *client2.WorkspaceStatus
+		current     *client2.WorkspaceStatus
+		wantChanged bool
+	}{
+		{
+			name:     "unchanged",
+			previous: status(client2.StatusRunning, "1m", config2.LifecycleHookRunning),
+			current:  status(client2.StatusRunning, "1m", config2.LifecycleHookRunning),
+		},
+		{
+			name:     "uptime changed",
+			previous: status(client2.StatusRunning, "1m", config2.LifecycleHookRunning),
+			current:  status(client2.StatusRunning, "2m", config2.LifecycleHookRunning),
+		},
+		{
+			name:        "state changed",
+			previous:    status(client2.StatusRunning, "1m", config2.LifecycleHookRunning),
+			current:     status(client2.StatusStopped, "", config2.LifecycleHookRunning),
+			wantChanged: true,
+		},
+		{
+			name:        "lifecycle hook changed",
+			previous:    status(client2.StatusRunning, "1m", config2.LifecycleHookRunning),
+			current:     status(client2.StatusRunning, "2m", config2.LifecycleHookDone),
+			wantChanged: true,
+		},
+		{
+			name:        "container removed",
+			previous:    status(client2.StatusRunning, "1m", config2.LifecycleHookRunning),
+			current:     &client2.WorkspaceStatus{ID: "workspace", State: client2.StatusNotFound},
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, err := statusKey(tt.previous)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current, err := statusKey(tt.current)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if changed := previous != current; changed != tt.wantChanged {
+				t.Fatalf("expected changed %t, got %t", tt.wantChanged, changed)
+			}
+		})
+	}
+
+	// the printed status keeps the uptime
+	s := status(client2.StatusRunning, "1m", config2.LifecycleHookRunning)
+	_, err := statusKey(s)
+	if err != nil {
+		t.Fatal(err)
+	} else if s.Container.Uptime != "1m" {
+		t.Fatal("status key modified the status")
+	}
+}
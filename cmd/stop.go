@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/cmd/completion"
@@ -19,6 +20,8 @@ import (
 type StopCmd struct {
 	*flags.GlobalFlags
 	client2.StopOptions
+
+	IfIdle time.Duration
 }
 
 // NewStopCmd creates a new destroy command
@@ -54,11 +57,23 @@ func NewStopCmd(flags *flags.GlobalFlags) *cobra.Command {
 		},
 	}
 
+	stopCmd.Flags().DurationVar(&cmd.IfIdle, "if-idle", 0, "Only stop the workspace if no session was opened after waiting for the given duration")
+	_ = stopCmd.Flags().MarkHidden("if-idle")
 	return stopCmd
 }
 
 // Run runs the command logic
 func (cmd *StopCmd) Run(ctx context.Context, devPodConfig *config.Config, client client2.BaseWorkspaceClient) error {
+	if cmd.IfIdle > 0 {
+		time.Sleep(cmd.IfIdle)
+		sessions, err := countShutdownActionSessions(client)
+		if err != nil {
+			return err
+		} else if sessions > 0 {
+			return nil
+		}
+	}
+
 	// lock workspace
 	if !cmd.Platform.Enabled {
 		err := client.Lock(ctx)
@@ -72,8 +87,11 @@ func (cmd *StopCmd) Run(ctx context.Context, devPodConfig *config.Config, client
 	instanceStatus, err := client.Status(ctx, client2.StatusOptions{})
 	if err != nil {
 		return err
-	} else if instanceStatus != client2.StatusRunning {
-		return fmt.Errorf("cannot stop workspace because it is '%s'", instanceStatus)
+	} else if instanceStatus.State != client2.StatusRunning {
+		if cmd.IfIdle > 0 {
+			return nil
+		}
+		return fmt.Errorf("cannot stop workspace because it is '%s'", instanceStatus.State)
 	}
 
 	// stop if single machine provider
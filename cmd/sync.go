@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/skevetter/devpod/cmd/completion"
+	"github.com/skevetter/devpod/cmd/flags"
+	clientpkg "github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/devpod/pkg/config"
+	"github.com/skevetter/devpod/pkg/filesync"
+	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/workspace"
+	"github.com/skevetter/log"
+	"github.com/spf13/cobra"
+)
+
+// SyncCmd holds the sync cmd flags
+type SyncCmd struct {
+	*flags.GlobalFlags
+
+	Output string
+}
+
+// NewSyncCmd creates a new sync command
+func NewSyncCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &SyncCmd{
+		GlobalFlags: flags,
+	}
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Inspect and control the file sync of a workspace",
+	}
+	validArgsFunction := func(rootCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.GetWorkspaceSuggestions(rootCmd, cmd.Context, cmd.Provider, args, toComplete, cmd.Owner, log.Default)
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status [flags] [workspace-path|workspace-name]",
+		Short: "Shows the file sync status of a workspace",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Status(cobraCmd.Context(), args)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+	statusCmd.Flags().StringVarP(&cmd.Output, "output", "o", "plain", "The output format to use. Can be json or plain")
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause [flags] [workspace-path|workspace-name]",
+		Short: "Pauses the file sync of a workspace",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.SetPaused(cobraCmd.Context(), args, true)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume [flags] [workspace-path|workspace-name]",
+		Short: "Resumes the file sync of a workspace",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.SetPaused(cobraCmd.Context(), args, false)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+
+	syncCmd.AddCommand(statusCmd, pauseCmd, resumeCmd)
+	return syncCmd
+}
+
+// Status prints the last reported sync status of the workspace
+func (cmd *SyncCmd) Status(ctx context.Context, args []string) error {
+	if cmd.Output != "plain" && cmd.Output != "json" {
+		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+	}
+
+	client, workspaceDir, err := cmd.workspaceDir(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	status, err := filesync.LoadStatus(workspaceDir)
+	if err != nil {
+		return fmt.Errorf("load sync status %w", err)
+	} else if status == nil {
+		status = &filesync.Status{LocalFolder: client.WorkspaceConfig().Source.LocalFolder}
+	}
+	if filesync.IsPaused(workspaceDir) {
+		status.State = filesync.StatePaused
+	}
+
+	if cmd.Output == "json" {
+		out, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(out))
+		return nil
+	}
+
+	if status.State == "" {
+		log.Default.Infof("Sync of workspace '%s' didn't report yet, it starts with 'devpod ssh' or when an IDE is connected", client.Workspace())
+		return nil
+	}
+
+	log.Default.Infof("Sync of workspace '%s' is '%s'", client.Workspace(), status.State)
+	log.Default.Infof("Syncing %s with %s (%d files)", status.LocalFolder, status.ContainerFolder, status.Files)
+	if status.LastSync != nil {
+		log.Default.Infof("Last sync %s ago", time.Since(status.LastSync.Time).Round(time.Second))
+	}
+	if status.Error != "" {
+		log.Default.Errorf("Last sync failed: %s", status.Error)
+	}
+	for _, conflict := range status.Conflicts {
+		log.Default.Warnf("Conflict: %s was changed locally and in the container", conflict)
+	}
+
+	return nil
+}
+
+// SetPaused pauses or resumes the sync of the workspace
+func (cmd *SyncCmd) SetPaused(ctx context.Context, args []string, paused bool) error {
+	client, workspaceDir, err := cmd.workspaceDir(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	err = filesync.SetPaused(workspaceDir, paused)
+	if err != nil {
+		return err
+	}
+
+	if paused {
+		log.Default.Infof("Paused sync of workspace '%s'", client.Workspace())
+	} else {
+		log.Default.Infof("Resumed sync of workspace '%s'", client.Workspace())
+	}
+
+	return nil
+}
+
+func (cmd *SyncCmd) workspaceDir(ctx context.Context, args []string) (clientpkg.BaseWorkspaceClient, string, error) {
+	devPodConfig, err := config.LoadConfig(cmd.Context, cmd.Provider)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := workspace.Get(ctx, devPodConfig, args, false, cmd.Owner, false, log.Default)
+	if err != nil {
+		return nil, "", err
+	} else if !client.WorkspaceConfig().Sync {
+		return nil, "", fmt.Errorf("sync is not enabled for workspace %s, enable it via 'devpod up %s --sync'", client.Workspace(), client.Workspace())
+	}
+
+	workspaceDir, err := provider.GetWorkspaceDir(client.Context(), client.Workspace())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, workspaceDir, nil
+}
@@ -0,0 +1,179 @@
+package templates
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/template"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/survey"
+	"github.com/skevetter/log/terminal"
+	"github.com/spf13/cobra"
+)
+
+// ApplyCmd holds the apply cmd flags
+type ApplyCmd struct {
+	*flags.GlobalFlags
+
+	Options   []string
+	OmitPaths []string
+	Force     bool
+}
+
+// NewApplyCmd creates a new command
+func NewApplyCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ApplyCmd{
+		GlobalFlags: flags,
+	}
+	applyCmd := &cobra.Command{
+		Use:   "apply template [folder]",
+		Short: "Apply a devcontainer template to a folder",
+		Long: `Downloads a devcontainer template and writes its files, such as the .devcontainer folder, into the
+folder. Templates without registry are resolved against ` + template.DefaultCollection + `.
+Options that are not given are asked interactively or use their default value.
+
+Example:
+  devpod templates apply go --option imageVariant=1.22-bookworm
+  devpod templates apply ghcr.io/devcontainers/templates/python:4 ./my-project`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			folder := "."
+			if len(args) > 1 {
+				folder = args[1]
+			}
+
+			return cmd.Run(args[0], folder)
+		},
+	}
+
+	applyCmd.Flags().StringArrayVar(&cmd.Options, "option", []string{}, "Template option in the form KEY=VALUE")
+	applyCmd.Flags().StringSliceVar(&cmd.OmitPaths, "omit-path", []string{}, "Optional paths of the template that should not be written")
+	applyCmd.Flags().BoolVar(&cmd.Force, "force", false, "If true, overwrites existing files of the folder with the files of the template")
+	return applyCmd
+}
+
+// Run runs the command logic
+func (cmd *ApplyCmd) Run(templateID, folder string) error {
+	values := map[string]string{}
+	for _, option := range cmd.Options {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return fmt.Errorf("invalid option %s, expected format KEY=VALUE", option)
+		}
+
+		values[key] = value
+	}
+
+	return ApplyTemplate(templateID, folder, values, cmd.OmitPaths, cmd.Force, terminal.IsTerminalIn, log.Default)
+}
+
+// ApplyTemplate downloads the template and writes it into the folder. Existing files are only overwritten
+// if force is true. If interactive, options without a value are asked, otherwise their default value is used.
+func ApplyTemplate(templateID, folder string, values map[string]string, omitPaths []string, force, interactive bool, log log.Logger) error {
+	log.Infof("Download template %s", template.ResolveTemplateID(templateID))
+	templateFolder, templateConfig, err := template.PullTemplate(templateID, log)
+	if err != nil {
+		return err
+	}
+
+	for _, omitPath := range omitPaths {
+		if !isOptionalPath(templateConfig, omitPath) {
+			return fmt.Errorf("%s is not an optional path of template %s, expected one of: %s", omitPath, templateConfig.ID, strings.Join(templateConfig.OptionalPaths, ", "))
+		}
+	}
+
+	var question func(name string, option config.FeatureConfigOption) (string, error)
+	if interactive {
+		question = func(name string, option config.FeatureConfigOption) (string, error) {
+			return askOption(name, option, log)
+		}
+	}
+	options, err := template.ResolveOptions(templateConfig, values, question)
+	if err != nil {
+		return err
+	}
+
+	written, err := template.Apply(templateFolder, folder, options, omitPaths, force)
+	if err != nil {
+		return err
+	}
+	for _, file := range written {
+		log.Debugf("Wrote %s", filepath.Join(folder, file))
+	}
+
+	log.Donef("Applied template %s (%s) to %s", templateConfig.ID, templateConfig.Version, folder)
+	return nil
+}
+
+// SelectTemplate asks which template of the default collection to use, it returns an empty id if the
+// user chose none
+func SelectTemplate(question string, noneOption string, log log.Logger) (string, error) {
+	templates, err := template.ListTemplates(template.DefaultCollection, log.ErrorStreamOnly())
+	if err != nil {
+		return "", err
+	}
+
+	options := []string{noneOption}
+	ids := map[string]string{}
+	for _, entry := range templates {
+		option := entry.ID
+		if entry.Name != "" {
+			option = fmt.Sprintf("%s (%s)", entry.Name, entry.ID)
+		}
+
+		options = append(options, option)
+		ids[option] = entry.ID
+	}
+
+	answer, err := log.Question(&survey.QuestionOptions{
+		Question:     question,
+		DefaultValue: noneOption,
+		Options:      options,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return ids[answer], nil
+}
+
+func askOption(name string, option config.FeatureConfigOption, log log.Logger) (string, error) {
+	question := name
+	if option.Description != "" {
+		question = fmt.Sprintf("%s (%s)", option.Description, name)
+	}
+
+	questionOptions := &survey.QuestionOptions{
+		Question:     question,
+		DefaultValue: string(option.Default),
+	}
+	switch {
+	case option.Type == "boolean":
+		questionOptions.Options = []string{"true", "false"}
+		if questionOptions.DefaultValue == "" {
+			questionOptions.DefaultValue = "false"
+		}
+	case len(option.Enum) > 0:
+		questionOptions.Options = option.Enum
+		if questionOptions.DefaultValue == "" {
+			questionOptions.DefaultValue = option.Enum[0]
+		}
+	case len(option.Proposals) > 0:
+		questionOptions.Question = fmt.Sprintf("%s, e.g. %s", question, strings.Join(option.Proposals, ", "))
+	}
+
+	return log.Question(questionOptions)
+}
+
+func isOptionalPath(templateConfig *template.TemplateConfig, path string) bool {
+	for _, optionalPath := range templateConfig.OptionalPaths {
+		if strings.TrimSuffix(optionalPath, "/") == strings.TrimSuffix(path, "/") {
+			return true
+		}
+	}
+
+	return false
+}
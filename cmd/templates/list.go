@@ -0,0 +1,86 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/pkg/devcontainer/template"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/table"
+	"github.com/spf13/cobra"
+)
+
+// ListCmd holds the list cmd flags
+type ListCmd struct {
+	*flags.GlobalFlags
+
+	Output string
+}
+
+// NewListCmd creates a new command
+func NewListCmd(flags *flags.GlobalFlags) *cobra.Command {
+	cmd := &ListCmd{
+		GlobalFlags: flags,
+	}
+	listCmd := &cobra.Command{
+		Use:   "list [collection]",
+		Short: "List the devcontainer templates of a collection",
+		Long: `Lists the templates published to an OCI collection. Without a collection,
+the templates of ` + template.DefaultCollection + ` are listed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			collection := template.DefaultCollection
+			if len(args) > 0 {
+				collection = args[0]
+			}
+
+			return cmd.Run(collection)
+		},
+	}
+
+	listCmd.Flags().StringVar(&cmd.Output, "output", "plain", "The output format to use. Can be json or plain")
+	return listCmd
+}
+
+// Run runs the command logic
+func (cmd *ListCmd) Run(collection string) error {
+	templates, err := template.ListTemplates(collection, log.Default.ErrorStreamOnly())
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].ID < templates[j].ID
+	})
+
+	switch cmd.Output {
+	case "json":
+		out, err := json.Marshal(templates)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+	case "plain":
+		tableEntries := [][]string{}
+		for _, entry := range templates {
+			tableEntries = append(tableEntries, []string{
+				entry.ID,
+				entry.Version,
+				entry.Name,
+				entry.Description,
+			})
+		}
+
+		table.PrintTable(log.Default, []string{
+			"ID",
+			"Version",
+			"Name",
+			"Description",
+		}, tableEntries)
+	default:
+		return fmt.Errorf("unexpected output format, choose either json or plain. Got %s", cmd.Output)
+	}
+
+	return nil
+}
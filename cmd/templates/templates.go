@@ -0,0 +1,18 @@
+package templates
+
+import (
+	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/spf13/cobra"
+)
+
+// NewTemplatesCmd returns a new command
+func NewTemplatesCmd(flags *flags.GlobalFlags) *cobra.Command {
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "DevPod devcontainer template commands",
+	}
+
+	templatesCmd.AddCommand(NewListCmd(flags))
+	templatesCmd.AddCommand(NewApplyCmd(flags))
+	return templatesCmd
+}
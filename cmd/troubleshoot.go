@@ -54,7 +54,7 @@ func (cmd *TroubleshootCmd) Run(ctx context.Context, args []string) {
 		Providers             map[string]provider.ProviderWithDefault
 		DevPodProInstances    []DevPodProInstance
 		Workspace             *pkgprovider.Workspace
-		WorkspaceStatus       client.Status
+		WorkspaceStatus       *client.WorkspaceStatus
 		WorkspaceTroubleshoot *managementv1.DevPodWorkspaceInstanceTroubleshoot
 		DaemonStatus          *daemon.Status
 
@@ -13,11 +13,14 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/cmd/flags"
+	"github.com/skevetter/devpod/cmd/templates"
 	"github.com/skevetter/devpod/pkg/agent"
 	"github.com/skevetter/devpod/pkg/agent/tunnelserver"
 	client2 "github.com/skevetter/devpod/pkg/client"
@@ -39,6 +42,7 @@ import (
 	"github.com/skevetter/devpod/pkg/platform"
 	"github.com/skevetter/devpod/pkg/port"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/secrets"
 	devssh "github.com/skevetter/devpod/pkg/ssh"
 	"github.com/skevetter/devpod/pkg/telemetry"
 	"github.com/skevetter/devpod/pkg/tunnel"
@@ -46,6 +50,7 @@ import (
 	"github.com/skevetter/devpod/pkg/version"
 	workspace2 "github.com/skevetter/devpod/pkg/workspace"
 	"github.com/skevetter/log"
+	"github.com/skevetter/log/terminal"
 	"github.com/skratchdot/open-golang/open"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh"
@@ -64,6 +69,7 @@ type UpCmd struct {
 	GPGAgentForwarding bool
 	OpenIDE            bool
 	Reconfigure        bool
+	ChooseTemplate     bool
 
 	SSHConfigPath string
 
@@ -107,6 +113,17 @@ func (cmd *UpCmd) execute(cobraCmd *cobra.Command, args []string) error {
 	if cmd.ExtraDevContainerPath != "" && client.Provider() != "docker" {
 		return fmt.Errorf("extra devcontainer file is only supported with local provider")
 	}
+	if cobraCmd.Flags().Changed("sync") {
+		err = cmd.configureSync(client, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cmd.offerTemplate(client, logger)
+	if err != nil {
+		return err
+	}
 
 	telemetry.CollectorCLI.SetClient(client)
 	return cmd.Run(ctx, devPodConfig, client, args, logger)
@@ -126,6 +143,56 @@ func (cmd *UpCmd) validate() error {
 	return nil
 }
 
+// configureSync enables or disables the continuous sync of the local folder for the workspace
+func (cmd *UpCmd) configureSync(client client2.BaseWorkspaceClient, log log.Logger) error {
+	workspace := client.WorkspaceConfig()
+	if cmd.Sync {
+		if workspace.Source.LocalFolder == "" {
+			return fmt.Errorf("sync is only supported for local folder workspaces")
+		} else if client.Provider() == "docker" {
+			log.Warnf("Sync is not needed with the docker provider, the local folder is mounted into the container")
+			cmd.Sync = false
+		}
+	}
+	if workspace.Sync == cmd.Sync {
+		return nil
+	}
+
+	workspace.Sync = cmd.Sync
+	err := provider2.SaveWorkspaceConfig(workspace)
+	if err != nil {
+		return fmt.Errorf("save workspace config %w", err)
+	}
+
+	return nil
+}
+
+// offerTemplate lets the user choose a devcontainer template for a local folder without a devcontainer.json,
+// instead of detecting the programming language of the folder, if enabled with --choose-template
+func (cmd *UpCmd) offerTemplate(client client2.BaseWorkspaceClient, log log.Logger) error {
+	workspace := client.WorkspaceConfig()
+	if !cmd.ChooseTemplate || !terminal.IsTerminalIn || cmd.Platform.Enabled || workspace.Source.LocalFolder == "" ||
+		cmd.FallbackImage != "" || cmd.DevContainerImage != "" || workspace.DevContainerImage != "" {
+		return nil
+	}
+
+	existing, err := config2.ParseDevContainerJSON(workspace.Source.LocalFolder, workspace.DevContainerPath)
+	if existing != nil || (err != nil && !os.IsNotExist(err)) {
+		return nil
+	}
+
+	const detectOption = "None, detect the programming language"
+	templateID, err := templates.SelectTemplate("Couldn't find a devcontainer.json, which template do you want to use?", detectOption, log)
+	if err != nil {
+		log.Warnf("Error listing devcontainer templates, detecting the programming language instead: %v", err)
+		return nil
+	} else if templateID == "" {
+		return nil
+	}
+
+	return templates.ApplyTemplate(templateID, workspace.Source.LocalFolder, nil, nil, false, true, log)
+}
+
 func (cmd *UpCmd) registerFlags(upCmd *cobra.Command) {
 	cmd.registerSSHFlags(upCmd)
 	cmd.registerDotfilesFlags(upCmd)
@@ -155,7 +222,9 @@ func (cmd *UpCmd) registerDevContainerFlags(upCmd *cobra.Command) {
 	upCmd.Flags().StringVar(&cmd.DevContainerPath, "devcontainer-path", "", "The path to the devcontainer.json relative to the project")
 	upCmd.Flags().StringVar(&cmd.DevContainerID, "devcontainer-id", "", "The ID of the devcontainer to use when multiple exist (e.g., folder name in .devcontainer/FOLDER/devcontainer.json)")
 	upCmd.Flags().StringVar(&cmd.ExtraDevContainerPath, "extra-devcontainer-path", "", "The path to an additional devcontainer.json file to override original devcontainer.json")
+	upCmd.Flags().BoolVar(&cmd.ChooseTemplate, "choose-template", false, "If true and no devcontainer.json is found in a local folder, asks which devcontainer template to use")
 	upCmd.Flags().StringVar(&cmd.FallbackImage, "fallback-image", "", "The fallback image to use if no devcontainer configuration has been detected")
+	upCmd.Flags().BoolVar(&cmd.StrictHostRequirements, "strict-host-requirements", false, "If true will fail instead of warn if the target machine does not satisfy the devcontainer hostRequirements")
 }
 
 func (cmd *UpCmd) registerIDEFlags(upCmd *cobra.Command) {
@@ -189,6 +258,7 @@ func (cmd *UpCmd) registerWorkspaceFlags(upCmd *cobra.Command) {
 	upCmd.Flags().StringSliceVar(&cmd.WorkspaceEnvFile, "workspace-env-file", []string{}, "The path to files containing a list of extra env variables to put into the workspace. E.g. MY_ENV_VAR=MY_VALUE")
 	upCmd.Flags().StringArrayVar(&cmd.InitEnv, "init-env", []string{}, "Extra env variables to inject during the initialization of the workspace. E.g. MY_ENV_VAR=MY_VALUE")
 	upCmd.Flags().BoolVar(&cmd.DisableDaemon, "disable-daemon", false, "If enabled, will not install a daemon into the target machine to track activity")
+	upCmd.Flags().BoolVar(&cmd.Sync, "sync", false, "If true will continuously sync the local folder with the workspace in both directions")
 }
 
 func (cmd *UpCmd) registerTestingFlags(upCmd *cobra.Command) {
@@ -258,6 +328,7 @@ func (cmd *UpCmd) executeDevPodUp(ctx context.Context, devPodConfig *config.Conf
 	if result == nil {
 		return nil, fmt.Errorf("did not receive a result back from agent")
 	}
+	logLifecycleHookTimings(result.LifecycleHooks, log)
 	if cmd.Platform.Enabled {
 		return nil, nil
 	}
@@ -275,6 +346,24 @@ func (cmd *UpCmd) executeDevPodUp(ctx context.Context, devPodConfig *config.Conf
 	return &workspaceContext{result: result, user: user, workdir: workdir}, nil
 }
 
+// logLifecycleHookTimings shows how long the lifecycle hooks and their named commands took
+func logLifecycleHookTimings(hooks []config2.LifecycleHookStatus, log log.Logger) {
+	for _, hook := range hooks {
+		if hook.FinishedAt == nil {
+			continue
+		}
+
+		log.Infof("%s took %s", hook.Name, hook.Duration().Round(time.Millisecond))
+		for _, command := range hook.Commands {
+			if command.Name == "" || command.FinishedAt == nil {
+				continue
+			}
+
+			log.Infof("  %s took %s", command.Name, command.Duration().Round(time.Millisecond))
+		}
+	}
+}
+
 // configureWorkspace sets up SSH, Git, and dotfiles
 func (cmd *UpCmd) configureWorkspace(devPodConfig *config.Config, client client2.BaseWorkspaceClient, wctx *workspaceContext, log log.Logger) error {
 	if cmd.ConfigureSSH {
@@ -606,6 +695,13 @@ func (cmd *UpCmd) devPodUpMachine(
 		return nil, err
 	}
 
+	// secrets are resolved on request of the container and never stored
+	secretsResolver, err := secrets.NewResolver(devPodConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create secrets resolver %w", err)
+	}
+	secretsOption := tunnelserver.WithSecretsResolver(secretsResolver, secrets.WorkspaceReferences(client.WorkspaceConfig(), cmd.WorkspaceEnv, secretsResolver, log))
+
 	// create container etc.
 	log.Info("creating devcontainer")
 	defer log.Debug("done creating devcontainer")
@@ -617,7 +713,7 @@ func (cmd *UpCmd) devPodUpMachine(
 			CLIOptions:      cmd.CLIOptions,
 			AgentCommand:    "up",
 			Log:             log,
-			TunnelOptions:   []tunnelserver.Option{tunnelserver.WithPlatformOptions(&cmd.Platform)},
+			TunnelOptions:   []tunnelserver.Option{tunnelserver.WithPlatformOptions(&cmd.Platform), secretsOption},
 		})
 	}
 
@@ -678,6 +774,7 @@ func (cmd *UpCmd) devPodUpMachine(
 				client.AgentInjectDockerCredentials(cmd.CLIOptions),
 				client.WorkspaceConfig(),
 				log,
+				secretsOption,
 			)
 		},
 	)
@@ -1015,6 +1112,7 @@ func startBrowserTunnel(
 		return nil
 	}
 
+	connected := &atomic.Bool{}
 	err := tunnel.NewTunnel(
 		ctx,
 		func(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
@@ -1035,6 +1133,8 @@ func startBrowserTunnel(
 			return cmd.Run()
 		},
 		func(ctx context.Context, containerClient *ssh.Client) error {
+			connected.Store(true)
+
 			// print port to console
 			streamLogger, ok := logger.(*log.StreamLogger)
 			if ok {
@@ -1071,7 +1171,20 @@ func startBrowserTunnel(
 			return nil
 		},
 	)
-	if err != nil {
+	if err != nil && !connected.Load() && forwardPorts && ctx.Err() == nil {
+		// the agent might not be able to run in the container, forward the ports through the driver instead
+		workspaceClient, ok := client.(client2.WorkspaceClient)
+		if !ok || !driverPortForwardSupported(workspaceClient) {
+			return err
+		}
+		result, resultErr := provider2.LoadWorkspaceResult(workspaceClient.Context(), workspaceClient.Workspace())
+		if resultErr != nil || result == nil {
+			return err
+		}
+
+		logger.Warnf("Error connecting to the workspace container, forwarding ports through the driver instead: %v", err)
+		return forwardPortsWithDriver(ctx, devPodConfig, workspaceClient, extraPorts, result, logger)
+	} else if err != nil {
 		return err
 	}
 
@@ -83,6 +83,10 @@ module.exports = {
           type: "doc",
           id: "developing-in-workspaces/prebuild-a-workspace",
         },
+        {
+          type: "doc",
+          id: "developing-in-workspaces/offline-mode",
+        },
         {
           type: "doc",
           id: "developing-in-workspaces/dotfiles-in-a-workspace",
@@ -95,6 +99,10 @@ module.exports = {
           type: "doc",
           id: "developing-in-workspaces/inactivity-timeout",
         },
+        {
+          type: "doc",
+          id: "developing-in-workspaces/sync-a-workspace",
+        },
         {
           type: "doc",
           id: "developing-in-workspaces/stop-a-workspace",
@@ -58,7 +58,7 @@ var _ = DevPodDescribe("devpod machine provider test suite", func() {
 			// check status
 			status, err := f.DevPodStatus(ctx, tempDir)
 			framework.ExpectNoError(err)
-			framework.ExpectEqual(strings.ToUpper(status.State), "RUNNING", "workspace status did not match")
+			framework.ExpectEqual(strings.ToUpper(string(status.State)), "RUNNING", "workspace status did not match")
 
 			// stop container
 			err = f.DevPodStop(ctx, tempDir)
@@ -67,7 +67,7 @@ var _ = DevPodDescribe("devpod machine provider test suite", func() {
 			// check status
 			status, err = f.DevPodStatus(ctx, tempDir)
 			framework.ExpectNoError(err)
-			framework.ExpectEqual(strings.ToUpper(status.State), "STOPPED", "workspace status did not match")
+			framework.ExpectEqual(strings.ToUpper(string(status.State)), "STOPPED", "workspace status did not match")
 
 			// wait for devpod workspace to come online (deadline: 30s)
 			err = f.DevPodUp(ctx, tempDir)
@@ -123,7 +123,7 @@ var _ = DevPodDescribe("devpod machine provider test suite", func() {
 			// check status
 			status, err := f.DevPodStatus(ctx, tempDir, "--container-status=false")
 			framework.ExpectNoError(err)
-			framework.ExpectEqual(strings.ToUpper(status.State), "RUNNING", "workspace status did not match")
+			framework.ExpectEqual(strings.ToUpper(string(status.State)), "RUNNING", "workspace status did not match")
 
 			// stop container
 			err = f.DevPodStop(ctx, tempDir)
@@ -132,7 +132,7 @@ var _ = DevPodDescribe("devpod machine provider test suite", func() {
 			// check status
 			status, err = f.DevPodStatus(ctx, tempDir, "--container-status=false")
 			framework.ExpectNoError(err)
-			framework.ExpectEqual(strings.ToUpper(status.State), "STOPPED", "workspace status did not match")
+			framework.ExpectEqual(strings.ToUpper(string(status.State)), "STOPPED", "workspace status did not match")
 
 			// wait for devpod workspace to come online (deadline: 30s)
 			err = f.DevPodUp(ctx, tempDir, "--daemon-interval=3s")
@@ -141,7 +141,7 @@ var _ = DevPodDescribe("devpod machine provider test suite", func() {
 			// check status
 			status, err = f.DevPodStatus(ctx, tempDir, "--container-status=false")
 			framework.ExpectNoError(err)
-			framework.ExpectEqual(strings.ToUpper(status.State), "RUNNING", "workspace status did not match")
+			framework.ExpectEqual(strings.ToUpper(string(status.State)), "RUNNING", "workspace status did not match")
 
 			// wait until workspace is stopped again
 			now := time.Now()
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/command"
 	"github.com/skevetter/devpod/pkg/compress"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
@@ -197,6 +198,9 @@ func decodeWorkspaceInfoAndWrite(
 		}).Error("failed to decode workspace info")
 		return false, nil, err
 	}
+	if workspaceInfo.CLIOptions.Offline {
+		_ = os.Setenv(cache.DEVPOD_OFFLINE, "true")
+	}
 
 	log.WithFields(logrus.Fields{
 		"workspaceId": workspaceInfo.Workspace.ID,
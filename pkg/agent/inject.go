@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/cache"
 	devpodhttp "github.com/skevetter/devpod/pkg/http"
 	"github.com/skevetter/devpod/pkg/inject"
 	"github.com/skevetter/devpod/pkg/shell"
@@ -488,14 +489,13 @@ type BinaryManager struct {
 }
 
 func NewBinaryManager(logger log.Logger, downloadURL string) *BinaryManager {
-	cachePath := filepath.Join(os.TempDir(), "devpod-cache")
-	cache := &BinaryCache{BaseDir: cachePath}
+	binaryCache := newBinaryCache()
 
 	return &BinaryManager{
 		sources: []BinarySource{
 			&InjectSource{},
-			&FileCacheSource{Cache: cache},
-			&HTTPDownloadSource{BaseURL: downloadURL, Cache: cache},
+			&FileCacheSource{Cache: binaryCache},
+			&HTTPDownloadSource{BaseURL: downloadURL, Cache: binaryCache},
 		},
 		logger: logger,
 	}
@@ -513,10 +513,31 @@ func (m *BinaryManager) AcquireBinary(ctx context.Context, arch string) (io.Read
 	return nil, "", ErrBinaryNotFound
 }
 
+// CacheBinary downloads the agent binary for the architecture into the cache if it isn't cached yet
+func CacheBinary(ctx context.Context, downloadURL, arch string) error {
+	binaryCache := newBinaryCache()
+	if _, err := os.Stat(binaryCache.pathFor(arch)); err == nil {
+		return nil
+	}
+
+	binary, err := (&HTTPDownloadSource{BaseURL: downloadURL}).GetBinary(ctx, arch)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = binary.Close() }()
+
+	return binaryCache.Set(arch, binary)
+}
+
 type BinaryCache struct {
 	BaseDir string
 }
 
+// newBinaryCache returns the cache of the agent binaries of this version
+func newBinaryCache() *BinaryCache {
+	return &BinaryCache{BaseDir: filepath.Join(cache.Folder(cache.AgentFolder), version.GetVersion())}
+}
+
 func (c *BinaryCache) Get(arch string) (io.ReadCloser, error) {
 	return os.Open(c.pathFor(arch))
 }
@@ -603,6 +624,10 @@ type HTTPDownloadSource struct {
 
 func (s *HTTPDownloadSource) GetBinary(ctx context.Context, arch string) (io.ReadCloser, error) {
 	binaryName := "devpod-linux-" + arch
+	if cache.Offline() {
+		return nil, cache.OfflineError("agent binary " + binaryName)
+	}
+
 	downloadURL, err := url.JoinPath(s.BaseURL, binaryName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct download URL: %w", err)
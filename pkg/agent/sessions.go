@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/skevetter/devpod/pkg/command"
+)
+
+// ContainerSessionsDir holds a file per open ssh session into the container
+const ContainerSessionsDir = "/tmp/devpod.sessions"
+
+// RegisterContainerSession marks the current process as an open session until the returned function is called
+func RegisterContainerSession() (func(), error) {
+	err := os.MkdirAll(ContainerSessionsDir, 0o777)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Chmod(ContainerSessionsDir, 0o777)
+
+	return RegisterSession(ContainerSessionsDir)
+}
+
+// CountContainerSessions returns the number of open sessions. Sessions of processes that
+// exited without cleaning up are removed.
+func CountContainerSessions() (int, error) {
+	return CountSessions(ContainerSessionsDir)
+}
+
+// RegisterSession marks the current process as an open session in the given directory until the
+// returned function is called
+func RegisterSession(dir string) (func(), error) {
+	err := os.MkdirAll(dir, 0o777)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionFile := filepath.Join(dir, strconv.Itoa(os.Getpid()))
+	err = os.WriteFile(sessionFile, nil, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = os.Remove(sessionFile)
+	}, nil
+}
+
+// CountSessions returns the number of open sessions in the given directory. Sessions of processes
+// that exited without cleaning up are removed.
+func CountSessions(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	sessions := 0
+	for _, entry := range entries {
+		_, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		running, err := command.IsRunning(entry.Name())
+		if err != nil || !running {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+
+		sessions++
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionsTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestSessionsSuite(t *testing.T) {
+	suite.Run(t, new(SessionsTestSuite))
+}
+
+func (s *SessionsTestSuite) SetupTest() {
+	s.dir = filepath.Join(s.T().TempDir(), "sessions")
+}
+
+func (s *SessionsTestSuite) TestNoSessions() {
+	sessions, err := CountSessions(s.dir)
+	s.Require().NoError(err)
+	s.Equal(0, sessions)
+}
+
+func (s *SessionsTestSuite) TestRegisterSession() {
+	unregister, err := RegisterSession(s.dir)
+	s.Require().NoError(err)
+	s.FileExists(filepath.Join(s.dir, strconv.Itoa(os.Getpid())))
+
+	sessions, err := CountSessions(s.dir)
+	s.Require().NoError(err)
+	s.Equal(1, sessions)
+
+	unregister()
+	sessions, err = CountSessions(s.dir)
+	s.Require().NoError(err)
+	s.Equal(0, sessions)
+}
+
+func (s *SessionsTestSuite) TestStaleSession() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("processes are checked by signals")
+	}
+
+	// the session of a process that exited without unregistering
+	process := exec.Command("true")
+	s.Require().NoError(process.Run())
+	stale := filepath.Join(s.dir, strconv.Itoa(process.Process.Pid))
+	s.Require().NoError(os.MkdirAll(s.dir, 0o777))
+	s.Require().NoError(os.WriteFile(stale, nil, 0o666))
+	other := filepath.Join(s.dir, "not-a-session")
+	s.Require().NoError(os.WriteFile(other, nil, 0o666))
+
+	unregister, err := RegisterSession(s.dir)
+	s.Require().NoError(err)
+	defer unregister()
+
+	sessions, err := CountSessions(s.dir)
+	s.Require().NoError(err)
+	s.Equal(1, sessions)
+	s.NoFileExists(stale)
+	s.FileExists(other)
+}
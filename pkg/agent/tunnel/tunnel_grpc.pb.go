@@ -32,11 +32,19 @@ const (
 	Tunnel_LoftConfig_FullMethodName        = "/tunnel.Tunnel/LoftConfig"
 	Tunnel_GPGPublicKeys_FullMethodName     = "/tunnel.Tunnel/GPGPublicKeys"
 	Tunnel_KubeConfig_FullMethodName        = "/tunnel.Tunnel/KubeConfig"
+	Tunnel_Secrets_FullMethodName           = "/tunnel.Tunnel/Secrets"
 	Tunnel_ForwardPort_FullMethodName       = "/tunnel.Tunnel/ForwardPort"
 	Tunnel_StopForwardPort_FullMethodName   = "/tunnel.Tunnel/StopForwardPort"
 	Tunnel_StreamGitClone_FullMethodName    = "/tunnel.Tunnel/StreamGitClone"
 	Tunnel_StreamWorkspace_FullMethodName   = "/tunnel.Tunnel/StreamWorkspace"
 	Tunnel_StreamMount_FullMethodName       = "/tunnel.Tunnel/StreamMount"
+	Tunnel_UploadWorkspace_FullMethodName   = "/tunnel.Tunnel/UploadWorkspace"
+	Tunnel_SyncWatch_FullMethodName         = "/tunnel.Tunnel/SyncWatch"
+	Tunnel_SyncIndex_FullMethodName         = "/tunnel.Tunnel/SyncIndex"
+	Tunnel_SyncDownload_FullMethodName      = "/tunnel.Tunnel/SyncDownload"
+	Tunnel_SyncUpload_FullMethodName        = "/tunnel.Tunnel/SyncUpload"
+	Tunnel_SyncDelete_FullMethodName        = "/tunnel.Tunnel/SyncDelete"
+	Tunnel_SyncStatus_FullMethodName        = "/tunnel.Tunnel/SyncStatus"
 )
 
 // TunnelClient is the client API for Tunnel service.
@@ -53,11 +61,19 @@ type TunnelClient interface {
 	LoftConfig(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
 	GPGPublicKeys(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
 	KubeConfig(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
+	Secrets(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
 	ForwardPort(ctx context.Context, in *ForwardPortRequest, opts ...grpc.CallOption) (*ForwardPortResponse, error)
 	StopForwardPort(ctx context.Context, in *StopForwardPortRequest, opts ...grpc.CallOption) (*StopForwardPortResponse, error)
 	StreamGitClone(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
 	StreamWorkspace(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
 	StreamMount(ctx context.Context, in *StreamMountRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
+	UploadWorkspace(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Chunk, Chunk], error)
+	SyncWatch(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
+	SyncIndex(ctx context.Context, in *Message, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
+	SyncDownload(ctx context.Context, in *Message, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
+	SyncUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Chunk, Empty], error)
+	SyncDelete(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error)
+	SyncStatus(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type tunnelClient struct {
@@ -168,6 +184,16 @@ func (c *tunnelClient) KubeConfig(ctx context.Context, in *Message, opts ...grpc
 	return out, nil
 }
 
+func (c *tunnelClient) Secrets(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Message)
+	err := c.cc.Invoke(ctx, Tunnel_Secrets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *tunnelClient) ForwardPort(ctx context.Context, in *ForwardPortRequest, opts ...grpc.CallOption) (*ForwardPortResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ForwardPortResponse)
@@ -245,6 +271,100 @@ func (c *tunnelClient) StreamMount(ctx context.Context, in *StreamMountRequest,
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Tunnel_StreamMountClient = grpc.ServerStreamingClient[Chunk]
 
+func (c *tunnelClient) UploadWorkspace(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Chunk, Chunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tunnel_ServiceDesc.Streams[3], Tunnel_UploadWorkspace_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Chunk, Chunk]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_UploadWorkspaceClient = grpc.BidiStreamingClient[Chunk, Chunk]
+
+func (c *tunnelClient) SyncWatch(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Message)
+	err := c.cc.Invoke(ctx, Tunnel_SyncWatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tunnelClient) SyncIndex(ctx context.Context, in *Message, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tunnel_ServiceDesc.Streams[4], Tunnel_SyncIndex_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Message, Chunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_SyncIndexClient = grpc.ServerStreamingClient[Chunk]
+
+func (c *tunnelClient) SyncDownload(ctx context.Context, in *Message, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tunnel_ServiceDesc.Streams[5], Tunnel_SyncDownload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Message, Chunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_SyncDownloadClient = grpc.ServerStreamingClient[Chunk]
+
+func (c *tunnelClient) SyncUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Chunk, Empty], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tunnel_ServiceDesc.Streams[6], Tunnel_SyncUpload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Chunk, Empty]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_SyncUploadClient = grpc.ClientStreamingClient[Chunk, Empty]
+
+func (c *tunnelClient) SyncDelete(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Message, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Message)
+	err := c.cc.Invoke(ctx, Tunnel_SyncDelete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tunnelClient) SyncStatus(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Tunnel_SyncStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TunnelServer is the server API for Tunnel service.
 // All implementations must embed UnimplementedTunnelServer
 // for forward compatibility.
@@ -259,11 +379,19 @@ type TunnelServer interface {
 	LoftConfig(context.Context, *Message) (*Message, error)
 	GPGPublicKeys(context.Context, *Message) (*Message, error)
 	KubeConfig(context.Context, *Message) (*Message, error)
+	Secrets(context.Context, *Message) (*Message, error)
 	ForwardPort(context.Context, *ForwardPortRequest) (*ForwardPortResponse, error)
 	StopForwardPort(context.Context, *StopForwardPortRequest) (*StopForwardPortResponse, error)
 	StreamGitClone(*Empty, grpc.ServerStreamingServer[Chunk]) error
 	StreamWorkspace(*Empty, grpc.ServerStreamingServer[Chunk]) error
 	StreamMount(*StreamMountRequest, grpc.ServerStreamingServer[Chunk]) error
+	UploadWorkspace(grpc.BidiStreamingServer[Chunk, Chunk]) error
+	SyncWatch(context.Context, *Message) (*Message, error)
+	SyncIndex(*Message, grpc.ServerStreamingServer[Chunk]) error
+	SyncDownload(*Message, grpc.ServerStreamingServer[Chunk]) error
+	SyncUpload(grpc.ClientStreamingServer[Chunk, Empty]) error
+	SyncDelete(context.Context, *Message) (*Message, error)
+	SyncStatus(context.Context, *Message) (*Empty, error)
 	mustEmbedUnimplementedTunnelServer()
 }
 
@@ -304,6 +432,9 @@ func (UnimplementedTunnelServer) GPGPublicKeys(context.Context, *Message) (*Mess
 func (UnimplementedTunnelServer) KubeConfig(context.Context, *Message) (*Message, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method KubeConfig not implemented")
 }
+func (UnimplementedTunnelServer) Secrets(context.Context, *Message) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Secrets not implemented")
+}
 func (UnimplementedTunnelServer) ForwardPort(context.Context, *ForwardPortRequest) (*ForwardPortResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ForwardPort not implemented")
 }
@@ -319,6 +450,27 @@ func (UnimplementedTunnelServer) StreamWorkspace(*Empty, grpc.ServerStreamingSer
 func (UnimplementedTunnelServer) StreamMount(*StreamMountRequest, grpc.ServerStreamingServer[Chunk]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamMount not implemented")
 }
+func (UnimplementedTunnelServer) UploadWorkspace(grpc.BidiStreamingServer[Chunk, Chunk]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadWorkspace not implemented")
+}
+func (UnimplementedTunnelServer) SyncWatch(context.Context, *Message) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncWatch not implemented")
+}
+func (UnimplementedTunnelServer) SyncIndex(*Message, grpc.ServerStreamingServer[Chunk]) error {
+	return status.Errorf(codes.Unimplemented, "method SyncIndex not implemented")
+}
+func (UnimplementedTunnelServer) SyncDownload(*Message, grpc.ServerStreamingServer[Chunk]) error {
+	return status.Errorf(codes.Unimplemented, "method SyncDownload not implemented")
+}
+func (UnimplementedTunnelServer) SyncUpload(grpc.ClientStreamingServer[Chunk, Empty]) error {
+	return status.Errorf(codes.Unimplemented, "method SyncUpload not implemented")
+}
+func (UnimplementedTunnelServer) SyncDelete(context.Context, *Message) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncDelete not implemented")
+}
+func (UnimplementedTunnelServer) SyncStatus(context.Context, *Message) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncStatus not implemented")
+}
 func (UnimplementedTunnelServer) mustEmbedUnimplementedTunnelServer() {}
 func (UnimplementedTunnelServer) testEmbeddedByValue()                {}
 
@@ -520,6 +672,24 @@ func _Tunnel_KubeConfig_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Tunnel_Secrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TunnelServer).Secrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tunnel_Secrets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TunnelServer).Secrets(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Tunnel_ForwardPort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ForwardPortRequest)
 	if err := dec(in); err != nil {
@@ -589,6 +759,96 @@ func _Tunnel_StreamMount_Handler(srv interface{}, stream grpc.ServerStream) erro
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Tunnel_StreamMountServer = grpc.ServerStreamingServer[Chunk]
 
+func _Tunnel_UploadWorkspace_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TunnelServer).UploadWorkspace(&grpc.GenericServerStream[Chunk, Chunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_UploadWorkspaceServer = grpc.BidiStreamingServer[Chunk, Chunk]
+
+func _Tunnel_SyncWatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TunnelServer).SyncWatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tunnel_SyncWatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TunnelServer).SyncWatch(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tunnel_SyncIndex_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Message)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TunnelServer).SyncIndex(m, &grpc.GenericServerStream[Message, Chunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_SyncIndexServer = grpc.ServerStreamingServer[Chunk]
+
+func _Tunnel_SyncDownload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Message)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TunnelServer).SyncDownload(m, &grpc.GenericServerStream[Message, Chunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_SyncDownloadServer = grpc.ServerStreamingServer[Chunk]
+
+func _Tunnel_SyncUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TunnelServer).SyncUpload(&grpc.GenericServerStream[Chunk, Empty]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tunnel_SyncUploadServer = grpc.ClientStreamingServer[Chunk, Empty]
+
+func _Tunnel_SyncDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TunnelServer).SyncDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tunnel_SyncDelete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TunnelServer).SyncDelete(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tunnel_SyncStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TunnelServer).SyncStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tunnel_SyncStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TunnelServer).SyncStatus(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Tunnel_ServiceDesc is the grpc.ServiceDesc for Tunnel service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -636,6 +896,10 @@ var Tunnel_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "KubeConfig",
 			Handler:    _Tunnel_KubeConfig_Handler,
 		},
+		{
+			MethodName: "Secrets",
+			Handler:    _Tunnel_Secrets_Handler,
+		},
 		{
 			MethodName: "ForwardPort",
 			Handler:    _Tunnel_ForwardPort_Handler,
@@ -644,6 +908,18 @@ var Tunnel_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StopForwardPort",
 			Handler:    _Tunnel_StopForwardPort_Handler,
 		},
+		{
+			MethodName: "SyncWatch",
+			Handler:    _Tunnel_SyncWatch_Handler,
+		},
+		{
+			MethodName: "SyncDelete",
+			Handler:    _Tunnel_SyncDelete_Handler,
+		},
+		{
+			MethodName: "SyncStatus",
+			Handler:    _Tunnel_SyncStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -661,6 +937,27 @@ var Tunnel_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Tunnel_StreamMount_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "UploadWorkspace",
+			Handler:       _Tunnel_UploadWorkspace_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SyncIndex",
+			Handler:       _Tunnel_SyncIndex_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SyncDownload",
+			Handler:       _Tunnel_SyncDownload_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SyncUpload",
+			Handler:       _Tunnel_SyncUpload_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "tunnel.proto",
 }
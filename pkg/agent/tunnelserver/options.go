@@ -2,9 +2,12 @@ package tunnelserver
 
 import (
 	"github.com/loft-sh/api/v4/pkg/devpod"
+	"github.com/skevetter/devpod/pkg/agent/tunnel"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/filesync"
 	"github.com/skevetter/devpod/pkg/netstat"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/secrets"
 )
 
 type Option func(*tunnelServer) *tunnelServer
@@ -57,3 +60,29 @@ func WithPlatformOptions(options *devpod.PlatformOptions) Option {
 		return s
 	}
 }
+
+// WithSync allows the container to sync the local folder of the workspace
+func WithSync(local *filesync.Local) Option {
+	return func(s *tunnelServer) *tunnelServer {
+		s.sync = local
+		return s
+	}
+}
+
+// WithSecretsResolver allows the container to retrieve the given secret references resolved with the local
+// secret stores. References requested by the container itself are ignored.
+func WithSecretsResolver(resolver *secrets.Resolver, references map[string]string) Option {
+	return func(s *tunnelServer) *tunnelServer {
+		s.secretsResolver = resolver
+		s.secretReferences = references
+		return s
+	}
+}
+
+// WithSecretsClient forwards secret requests of the container to the tunnel of the client
+func WithSecretsClient(client tunnel.TunnelClient) Option {
+	return func(s *tunnelServer) *tunnelServer {
+		s.secretsClient = client
+		return s
+	}
+}
@@ -9,7 +9,17 @@ import (
 	"github.com/skevetter/log"
 )
 
-func NewStreamReader(stream tunnel.Tunnel_StreamWorkspaceClient, log log.Logger) io.Reader {
+// ChunkReceiver is a stream of chunks, e.g. the client of a server stream or the server of a client stream
+type ChunkReceiver interface {
+	Recv() (*tunnel.Chunk, error)
+}
+
+// ChunkSender is a stream chunks can be sent to
+type ChunkSender interface {
+	Send(*tunnel.Chunk) error
+}
+
+func NewStreamReader(stream ChunkReceiver, log log.Logger) io.Reader {
 	reader, writer := io.Pipe()
 
 	go func() {
@@ -36,12 +46,12 @@ func NewStreamReader(stream tunnel.Tunnel_StreamWorkspaceClient, log log.Logger)
 	return reader
 }
 
-func NewStreamWriter(stream tunnel.Tunnel_StreamWorkspaceServer, log log.Logger) io.Writer {
+func NewStreamWriter(stream ChunkSender, log log.Logger) io.Writer {
 	return &streamWriter{stream: stream, log: log, lastMessage: time.Now()}
 }
 
 type streamWriter struct {
-	stream tunnel.Tunnel_StreamWorkspaceServer
+	stream ChunkSender
 
 	lastMessage  time.Time
 	bytesWritten int64
@@ -62,3 +72,17 @@ func (s *streamWriter) Write(p []byte) (int, error) {
 
 	return len(p), nil
 }
+
+// chunkWriter sends everything written as chunks without reporting the progress
+type chunkWriter struct {
+	stream ChunkSender
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	err := c.stream.Send(&tunnel.Chunk{Content: p})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
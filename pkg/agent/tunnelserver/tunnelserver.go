@@ -18,6 +18,7 @@ import (
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/dockercredentials"
 	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/devpod/pkg/filesync"
 	"github.com/skevetter/devpod/pkg/gitcredentials"
 	"github.com/skevetter/devpod/pkg/gitsshsigning"
 	"github.com/skevetter/devpod/pkg/gpg"
@@ -25,7 +26,9 @@ import (
 	"github.com/skevetter/devpod/pkg/netstat"
 	"github.com/skevetter/devpod/pkg/platform"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/secrets"
 	"github.com/skevetter/devpod/pkg/stdio"
+	"github.com/skevetter/devpod/pkg/upload"
 	"github.com/skevetter/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -94,6 +97,11 @@ type tunnelServer struct {
 	log                    log.Logger
 
 	platformOptions *devpod.PlatformOptions
+
+	secretsResolver  *secrets.Resolver
+	secretReferences map[string]string
+	secretsClient    tunnel.TunnelClient
+	sync             *filesync.Local
 }
 
 func (t *tunnelServer) RunWithResult(ctx context.Context, reader io.Reader, writer io.WriteCloser) (*config.Result, error) {
@@ -124,7 +132,7 @@ func (t *tunnelServer) ForwardPort(ctx context.Context, portRequest *tunnel.Forw
 		return nil, fmt.Errorf("cannot forward ports")
 	}
 
-	err := t.forwarder.Forward(portRequest.Port)
+	err := t.forwarder.Forward(forwardedPort(portRequest.Protocol, portRequest.Port))
 	if err != nil {
 		return nil, fmt.Errorf("error forwarding port %s %w", portRequest.Port, err)
 	}
@@ -136,7 +144,7 @@ func (t *tunnelServer) StopForwardPort(ctx context.Context, portRequest *tunnel.
 		return nil, fmt.Errorf("cannot forward ports")
 	}
 
-	err := t.forwarder.StopForward(portRequest.Port)
+	err := t.forwarder.StopForward(forwardedPort(portRequest.Protocol, portRequest.Port))
 	if err != nil {
 		return nil, fmt.Errorf("error stop forwarding port %s %w", portRequest.Port, err)
 	}
@@ -144,6 +152,15 @@ func (t *tunnelServer) StopForwardPort(ctx context.Context, portRequest *tunnel.
 	return &tunnel.StopForwardPortResponse{}, nil
 }
 
+// forwardedPort converts a port request into a port, requests without protocol are tcp ports
+func forwardedPort(protocol, port string) netstat.Port {
+	if protocol == "" {
+		protocol = netstat.ProtocolTCP
+	}
+
+	return netstat.Port{Protocol: protocol, Port: port}
+}
+
 func (t *tunnelServer) DockerCredentials(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
 	if !t.allowDockerCredentials {
 		return nil, fmt.Errorf("docker credentials forbidden")
@@ -327,6 +344,27 @@ func (t *tunnelServer) KubeConfig(ctx context.Context, message *tunnel.Message)
 	return &tunnel.Message{Message: string(kubeConfig)}, nil
 }
 
+func (t *tunnelServer) Secrets(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
+	if t.secretsClient != nil {
+		return t.secretsClient.Secrets(ctx, message)
+	} else if t.secretsResolver == nil {
+		return nil, fmt.Errorf("secrets forbidden")
+	}
+
+	// only resolve the references of the client, the container could otherwise request any secret
+	values, err := t.secretsResolver.ResolveAll(ctx, t.secretReferences)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tunnel.Message{Message: string(out)}, nil
+}
+
 func (t *tunnelServer) GPGPublicKeys(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
 	rawPubKeys, err := gpg.GetHostPubKey()
 	if err != nil {
@@ -398,7 +436,28 @@ func (t *tunnelServer) StreamWorkspace(message *tunnel.Empty, stream tunnel.Tunn
 	}
 
 	// make sure buffer is flushed
-	return buf.Flush()
+	err = buf.Flush()
+	if err != nil {
+		return err
+	}
+
+	t.recordUpload()
+	return nil
+}
+
+// recordUpload remembers the uploaded content as starting point of the sync
+func (t *tunnelServer) recordUpload() {
+	if !t.workspace.Sync {
+		return
+	}
+
+	workspaceDir, err := provider2.GetWorkspaceDir(t.workspace.Context, t.workspace.ID)
+	if err == nil {
+		err = filesync.RecordUpload(t.workspace.Source.LocalFolder, workspaceDir)
+	}
+	if err != nil {
+		t.log.Warnf("Error recording uploaded folder for sync: %v", err)
+	}
 }
 
 func (t *tunnelServer) StreamMount(message *tunnel.StreamMountRequest, stream tunnel.Tunnel_StreamMountServer) error {
@@ -440,3 +499,183 @@ func (t *tunnelServer) StreamMount(message *tunnel.StreamMountRequest, stream tu
 	// make sure buffer is flushed
 	return buf.Flush()
 }
+
+func (t *tunnelServer) UploadWorkspace(stream tunnel.Tunnel_UploadWorkspaceServer) error {
+	if t.platformOptions != nil && t.platformOptions.Enabled && !t.allowPlatformOptions {
+		return fmt.Errorf("uploading workspace from local computer to platform workspace is not supported. Please specify a git repository to clone instead")
+	}
+
+	request, err := upload.ReadRequest(NewStreamReader(stream, t.log))
+	if err != nil {
+		return err
+	}
+
+	// find the folder to upload
+	root := ""
+	cacheFile := ""
+	if request.Mount == "" {
+		if t.workspace == nil {
+			return fmt.Errorf("workspace is nil")
+		}
+
+		workspaceDir, err := provider2.GetWorkspaceDir(t.workspace.Context, t.workspace.ID)
+		if err != nil {
+			return err
+		}
+
+		root = t.workspace.Source.LocalFolder
+		cacheFile = filepath.Join(workspaceDir, upload.ManifestCacheFile)
+	} else {
+		for _, m := range t.mounts {
+			if m.String() == request.Mount {
+				root = m.Source
+				break
+			}
+		}
+		if root == "" {
+			return fmt.Errorf("mount %s is not allowed to download", request.Mount)
+		}
+	}
+
+	// Get .devpodignore files to exclude
+	var excludes []string
+	if t.workspace != nil {
+		excludes, err = filesync.ReadExcludes(t.workspace.Source.LocalFolder)
+		if err != nil {
+			t.log.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("error reading .devpodignore file")
+		}
+	}
+
+	buf := bufio.NewWriterSize(&chunkWriter{stream: stream}, 64*1024)
+	err = upload.Send(buf, request, root, excludes, cacheFile)
+	if err != nil {
+		return err
+	}
+
+	// make sure buffer is flushed
+	err = buf.Flush()
+	if err != nil {
+		return err
+	}
+
+	if request.Mount == "" {
+		t.recordUpload()
+	}
+	return nil
+}
+
+func (t *tunnelServer) SyncWatch(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
+	if t.sync == nil {
+		return nil, fmt.Errorf("sync forbidden")
+	}
+
+	out, err := json.Marshal(t.sync.Watch(ctx, message.Message))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tunnel.Message{Message: string(out)}, nil
+}
+
+func (t *tunnelServer) SyncIndex(message *tunnel.Message, stream tunnel.Tunnel_SyncIndexServer) error {
+	if t.sync == nil {
+		return fmt.Errorf("sync forbidden")
+	}
+
+	request := &filesync.IndexRequest{}
+	err := json.Unmarshal([]byte(message.Message), request)
+	if err != nil {
+		return fmt.Errorf("decode index request %w", err)
+	}
+
+	index, err := t.sync.Index(request)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriterSize(&chunkWriter{stream: stream}, 10*1024)
+	err = json.NewEncoder(buf).Encode(index)
+	if err != nil {
+		return err
+	}
+
+	return buf.Flush()
+}
+
+func (t *tunnelServer) SyncDownload(message *tunnel.Message, stream tunnel.Tunnel_SyncDownloadServer) error {
+	if t.sync == nil {
+		return fmt.Errorf("sync forbidden")
+	}
+
+	paths := []string{}
+	err := json.Unmarshal([]byte(message.Message), &paths)
+	if err != nil {
+		return fmt.Errorf("decode download request %w", err)
+	}
+
+	buf := bufio.NewWriterSize(&chunkWriter{stream: stream}, 10*1024)
+	err = t.sync.WriteTar(buf, paths)
+	if err != nil {
+		return err
+	}
+
+	return buf.Flush()
+}
+
+func (t *tunnelServer) SyncUpload(stream tunnel.Tunnel_SyncUploadServer) error {
+	if t.sync == nil {
+		return fmt.Errorf("sync forbidden")
+	}
+
+	err := t.sync.ExtractTar(NewStreamReader(stream, t.log))
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(&tunnel.Empty{})
+}
+
+func (t *tunnelServer) SyncDelete(ctx context.Context, message *tunnel.Message) (*tunnel.Message, error) {
+	if t.sync == nil {
+		return nil, fmt.Errorf("sync forbidden")
+	}
+
+	paths := []string{}
+	err := json.Unmarshal([]byte(message.Message), &paths)
+	if err != nil {
+		return nil, fmt.Errorf("decode delete request %w", err)
+	}
+
+	failed, err := t.sync.Delete(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := json.Marshal(failed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tunnel.Message{Message: string(out)}, nil
+}
+
+func (t *tunnelServer) SyncStatus(ctx context.Context, message *tunnel.Message) (*tunnel.Empty, error) {
+	if t.sync == nil {
+		return nil, fmt.Errorf("sync forbidden")
+	}
+
+	status := &filesync.Status{}
+	err := json.Unmarshal([]byte(message.Message), status)
+	if err != nil {
+		return nil, fmt.Errorf("decode sync status %w", err)
+	}
+
+	err = t.sync.SaveStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tunnel.Empty{}, nil
+}
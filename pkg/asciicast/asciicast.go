@@ -0,0 +1,172 @@
+package asciicast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Version is the asciicast version that is written, see https://docs.asciinema.org/manual/asciicast/v2/
+const Version = 2
+
+const (
+	// EventOutput is data written to the terminal
+	EventOutput = "o"
+
+	// EventResize is a change of the terminal size in the format COLSxROWS
+	EventResize = "r"
+)
+
+// Header is the first line of a recording
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a single line after the header, it is encoded as [time, type, data]
+type Event struct {
+	// Time is the number of seconds since the start of the recording
+	Time float64
+	Type string
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	fields := []any{}
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	} else if len(fields) != 3 {
+		return fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+
+	var ok bool
+	e.Time, ok = fields[0].(float64)
+	if !ok {
+		return fmt.Errorf("unexpected event time %v", fields[0])
+	}
+	e.Type, ok = fields[1].(string)
+	if !ok {
+		return fmt.Errorf("unexpected event type %v", fields[1])
+	}
+	e.Data, ok = fields[2].(string)
+	if !ok {
+		return fmt.Errorf("unexpected event data %v", fields[2])
+	}
+
+	return nil
+}
+
+// Writer records terminal output as asciicast
+type Writer struct {
+	m       sync.Mutex
+	writer  io.WriteCloser
+	start   time.Time
+	pending []byte
+}
+
+// NewWriter writes the header and returns a writer that records everything written to it as output events
+func NewWriter(writer io.WriteCloser, header *Header) (*Writer, error) {
+	start := time.Now()
+	header.Version = Version
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+
+	out, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writer.Write(append(out, '\n'))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		writer: writer,
+		start:  start,
+	}, nil
+}
+
+// Write records an output event. Incomplete utf-8 characters at the end are recorded with the next write.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	data := append(w.pending, p...)
+	n := completePrefix(data)
+	w.pending = append([]byte(nil), data[n:]...)
+	if n == 0 {
+		return len(p), nil
+	}
+
+	err := w.event(EventOutput, string(data[:n]))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Resize records a change of the terminal size
+func (w *Writer) Resize(width, height int) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	return w.event(EventResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close records the remaining output and closes the underlying writer
+func (w *Writer) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if len(w.pending) > 0 {
+		_ = w.event(EventOutput, string(w.pending))
+		w.pending = nil
+	}
+
+	return w.writer.Close()
+}
+
+func (w *Writer) event(eventType string, data string) error {
+	out, err := json.Marshal(Event{
+		Time: float64(time.Since(w.start).Microseconds()) / 1e6,
+		Type: eventType,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.writer.Write(append(out, '\n'))
+	return err
+}
+
+// completePrefix returns the length of data without an incomplete utf-8 character at the end
+func completePrefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+
+			break
+		}
+	}
+
+	return len(data)
+}
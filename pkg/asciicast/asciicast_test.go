@@ -0,0 +1,55 @@
+package asciicast
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndPlay(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "session"+Extension))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := NewWriter(f, &Header{Width: 80, Height: 24, Title: "vscode"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the euro sign is split across two writes
+	euro := []byte("€")
+	_, _ = writer.Write([]byte("price: "))
+	_, _ = writer.Write(euro[:1])
+	_ = writer.Resize(120, 40)
+	_, _ = writer.Write(euro[1:])
+	_, _ = writer.Write([]byte("\r\n"))
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recordings, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(recordings) != 1 || recordings[0].Name != "session" || recordings[0].Width != 80 || recordings[0].Title != "vscode" {
+		t.Fatalf("unexpected recordings %+v", recordings)
+	}
+
+	recording, err := os.ReadFile(filepath.Join(dir, "session"+Extension))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := &bytes.Buffer{}
+	err = Play(context.Background(), bytes.NewReader(recording), output, PlayOptions{Speed: 10, IdleTimeLimit: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	} else if output.String() != "price: €\r\n" {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
@@ -0,0 +1,122 @@
+package asciicast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Extension is the file extension of recordings
+const Extension = ".cast"
+
+var nameRegEx = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// ValidateName checks that the name of a recording doesn't point outside of its folder
+func ValidateName(name string) error {
+	if !nameRegEx.MatchString(name) {
+		return fmt.Errorf("invalid recording name %s", name)
+	}
+
+	return nil
+}
+
+// Recording describes a recording in a folder
+type Recording struct {
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	Duration string    `json:"duration"`
+	Width    int       `json:"width"`
+	Height   int       `json:"height"`
+	Title    string    `json:"title,omitempty"`
+	Command  string    `json:"command,omitempty"`
+	Size     int64     `json:"size"`
+}
+
+// List returns the recordings in the folder, the newest first. Files that are not a recording are skipped.
+func List(dir string) ([]*Recording, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	recordings := []*Recording{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), Extension) {
+			continue
+		}
+
+		recording, err := readRecording(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		recordings = append(recordings, recording)
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].Start.After(recordings[j].Start)
+	})
+
+	return recordings, nil
+}
+
+func readRecording(file string) (*Recording, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := ReadHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recording{
+		Name:     strings.TrimSuffix(filepath.Base(file), Extension),
+		Start:    time.Unix(header.Timestamp, 0),
+		Duration: lastEventTime(f, stat.Size()).Round(time.Second).String(),
+		Width:    header.Width,
+		Height:   header.Height,
+		Title:    header.Title,
+		Command:  header.Command,
+		Size:     stat.Size(),
+	}, nil
+}
+
+// lastEventTime reads the time of the last event at the end of the file
+func lastEventTime(f *os.File, size int64) time.Duration {
+	offset := max(0, size-64*1024)
+	tail := make([]byte, size-offset)
+	_, err := f.ReadAt(tail, offset)
+	if err != nil && err != io.EOF {
+		return 0
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(tail), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		event := &Event{}
+		err := json.Unmarshal(lines[i], event)
+		if err == nil {
+			return time.Duration(event.Time * float64(time.Second))
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,87 @@
+package asciicast
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PlayOptions configure the playback of a recording
+type PlayOptions struct {
+	// Speed multiplies the playback speed, defaults to 1
+	Speed float64
+
+	// IdleTimeLimit caps the pauses between events, if zero the original pauses are kept
+	IdleTimeLimit time.Duration
+}
+
+// ReadHeader reads the header of a recording
+func ReadHeader(reader *bufio.Reader) (*Header, error) {
+	line, err := reader.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	header := &Header{}
+	err = json.Unmarshal(line, header)
+	if err != nil {
+		return nil, fmt.Errorf("parse asciicast header %w", err)
+	} else if header.Version != Version {
+		return nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	return header, nil
+}
+
+// Play writes the output of the recording to the writer with the original timing
+func Play(ctx context.Context, reader io.Reader, writer io.Writer, options PlayOptions) error {
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+
+	bufReader := bufio.NewReader(reader)
+	_, err := ReadHeader(bufReader)
+	if err != nil {
+		return err
+	}
+
+	last := 0.0
+	for {
+		line, err := bufReader.ReadBytes('\n')
+		if len(line) > 0 {
+			event := &Event{}
+			parseErr := json.Unmarshal(line, event)
+			if parseErr != nil {
+				return fmt.Errorf("parse asciicast event %w", parseErr)
+			}
+
+			pause := time.Duration((event.Time - last) / options.Speed * float64(time.Second))
+			if options.IdleTimeLimit > 0 {
+				pause = min(pause, options.IdleTimeLimit)
+			}
+			last = event.Time
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pause):
+			}
+
+			if event.Type == EventOutput {
+				_, writeErr := io.WriteString(writer, event.Data)
+				if writeErr != nil {
+					return writeErr
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/config"
 	"github.com/skevetter/devpod/pkg/copy"
 	"github.com/skevetter/devpod/pkg/download"
@@ -55,7 +56,7 @@ func GetBinariesFrom(config *provider2.ProviderConfig, binariesDir string) (map[
 			targetFolder := filepath.Join(binariesDir, strings.ToLower(binaryName))
 			binaryPath := getBinaryPath(binary, targetFolder)
 			_, err := os.Stat(binaryPath)
-			if err != nil {
+			if err != nil && !fromCache(binary, targetFolder, log.Discard) {
 				return nil, fmt.Errorf("error trying to find binary %s %w", binaryName, err)
 			}
 
@@ -182,7 +183,7 @@ func fromCache(binary *provider2.ProviderBinary, targetFolder string, log log.Lo
 }
 
 func getCachedBinaryPath(url string) string {
-	return filepath.Join(os.TempDir(), "devpod-binaries", hash.String(url)[:16])
+	return cache.Path(cache.BinariesFolder, url)
 }
 
 func verifyBinary(binaryPath, checksum string) bool {
@@ -0,0 +1,345 @@
+package cache
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/config"
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/hash"
+)
+
+// DEVPOD_OFFLINE makes every download use the cache only
+const DEVPOD_OFFLINE = "DEVPOD_OFFLINE"
+
+// DEVPOD_CACHE_DIR overrides the cache folder
+const DEVPOD_CACHE_DIR = "DEVPOD_CACHE_DIR"
+
+const (
+	FeaturesFolder = "features"
+	AgentFolder    = "agent"
+	BinariesFolder = "binaries"
+	IDEFolder      = "ide"
+)
+
+// imagesFile lists the images of the cache, the images themselves are kept by docker
+const imagesFile = "images.json"
+
+// imagesArchive holds the saved images in an exported cache
+const imagesArchive = "images.tar"
+
+// ErrOffline is returned by downloads that are not possible in offline mode
+var ErrOffline = errors.New("offline mode")
+
+// Offline returns true if downloads should only use the cache
+func Offline() bool {
+	return os.Getenv(DEVPOD_OFFLINE) == "true"
+}
+
+// OfflineError returns the error for content that would have to be downloaded in offline mode
+func OfflineError(content string) error {
+	return fmt.Errorf("%w: %s is not cached, run devpod cache warm or devpod cache import first", ErrOffline, content)
+}
+
+// Dir returns the cache folder, by default the cache folder in the devpod home
+func Dir() string {
+	if cacheDir := os.Getenv(DEVPOD_CACHE_DIR); cacheDir != "" {
+		return cacheDir
+	}
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "devpod-cache")
+	}
+
+	return filepath.Join(configDir, "cache")
+}
+
+// Folder returns the folder of a kind of content in the cache, e.g. features
+func Folder(name string) string {
+	return filepath.Join(Dir(), name)
+}
+
+// Path returns where a download from the url is kept in the given cache folder
+func Path(folder, url string) string {
+	return filepath.Join(Folder(folder), hash.String(url)[:16])
+}
+
+// Images returns the images that were cached
+func Images() ([]string, error) {
+	out, err := os.ReadFile(filepath.Join(Dir(), imagesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	images := []string{}
+	err = json.Unmarshal(out, &images)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s %w", imagesFile, err)
+	}
+
+	return images, nil
+}
+
+// AddImages records images that were pulled into the cache
+func AddImages(images ...string) error {
+	existing, err := Images()
+	if err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		if !slices.Contains(existing, image) {
+			existing = append(existing, image)
+		}
+	}
+
+	out, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(Dir(), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(Dir(), imagesFile), out, 0644)
+}
+
+// Export writes the cache as tar.gz into the writer, the cached images are saved into the archive as well
+func Export(ctx context.Context, dockerHelper *docker.DockerHelper, writer io.Writer, log log.Logger) error {
+	err := os.MkdirAll(Dir(), 0755)
+	if err != nil {
+		return err
+	}
+
+	images, err := Images()
+	if err != nil {
+		return err
+	}
+	if len(images) > 0 {
+		archive := filepath.Join(Dir(), imagesArchive)
+		defer func() { _ = os.Remove(archive) }()
+
+		log.Infof("Save images %v", images)
+		err = dockerHelper.Run(ctx, append([]string{"save", "-o", archive}, images...), nil, io.Discard, io.Discard)
+		if err != nil {
+			return fmt.Errorf("save images %w", err)
+		}
+	}
+
+	return extract.WriteTar(writer, Dir(), true)
+}
+
+// Import extracts an exported cache into a temporary folder, validates it and moves its content into the
+// cache folder. The saved images of the export are loaded into docker.
+func Import(ctx context.Context, dockerHelper *docker.DockerHelper, reader io.Reader, log log.Logger) error {
+	err := os.MkdirAll(Dir(), 0755)
+	if err != nil {
+		return err
+	}
+
+	// the temporary folder is next to the cache, so its content can be renamed into it
+	tempDir, err := os.MkdirTemp(filepath.Dir(Dir()), ".cache-import-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	err = extractImport(reader, tempDir)
+	if err != nil {
+		return fmt.Errorf("invalid cache archive: %w", err)
+	}
+
+	importedImages, err := validateImport(tempDir)
+	if err != nil {
+		return fmt.Errorf("invalid cache archive: %w", err)
+	}
+
+	archive := filepath.Join(tempDir, imagesArchive)
+	if _, err := os.Stat(archive); err == nil {
+		log.Info("Load images")
+		err = dockerHelper.Run(ctx, []string{"load", "-i", archive}, nil, io.Discard, io.Discard)
+		if err != nil {
+			return fmt.Errorf("load images %w", err)
+		}
+	}
+
+	for _, folder := range folders {
+		err = moveFolder(filepath.Join(tempDir, folder), Folder(folder))
+		if err != nil {
+			return fmt.Errorf("import %s %w", folder, err)
+		}
+	}
+
+	return AddImages(importedImages...)
+}
+
+// extractImport extracts the archive of an exported cache into the folder. The archive is untrusted, so only
+// folders and regular files within the folder are extracted, any other entry fails the import.
+func extractImport(reader io.Reader, dir string) error {
+	bufioReader := bufio.NewReader(reader)
+	magic, err := bufioReader.Peek(2)
+	if err != nil {
+		return err
+	}
+
+	var tarStream io.Reader = bufioReader
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufioReader)
+		if err != nil {
+			return fmt.Errorf("decompress %w", err)
+		}
+		defer func() { _ = gzipReader.Close() }()
+
+		tarStream = gzipReader
+	}
+
+	tarReader := tar.NewReader(tarStream)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(strings.ReplaceAll(header.Name, "\\", "/"))
+		if name == "." && header.Typeflag == tar.TypeDir {
+			continue
+		} else if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("entry %s is outside of the cache", header.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = extractImportFile(tarReader, target, header.FileInfo().Mode().Perm())
+		default:
+			return fmt.Errorf("unexpected file type of %s", name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractImportFile(reader io.Reader, target string, perm os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = io.Copy(f, reader)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// folders are the folders of the cache, an exported cache contains nothing else than them and the images
+var folders = []string{FeaturesFolder, AgentFolder, BinariesFolder, IDEFolder}
+
+// validateImport checks that the extracted cache only contains the folders of the cache and the images and
+// returns the listed images
+func validateImport(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []string{}
+	for _, entry := range entries {
+		switch {
+		case entry.Name() == imagesFile && entry.Type().IsRegular():
+			out, err := os.ReadFile(filepath.Join(dir, imagesFile))
+			if err != nil {
+				return nil, err
+			}
+
+			err = json.Unmarshal(out, &images)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s %w", imagesFile, err)
+			}
+		case entry.Name() == imagesArchive && entry.Type().IsRegular():
+		case slices.Contains(folders, entry.Name()) && entry.IsDir():
+		default:
+			return nil, fmt.Errorf("unexpected entry %s", entry.Name())
+		}
+	}
+
+	// the content of the folders is read as is later, so only plain files and folders are allowed
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if !entry.IsDir() && !entry.Type().IsRegular() {
+			return fmt.Errorf("unexpected file type of %s", strings.TrimPrefix(path, dir+string(filepath.Separator)))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// moveFolder moves the entries of the source folder into the target folder, replacing existing entries
+func moveFolder(source, target string) error {
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	err = os.MkdirAll(target, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = os.RemoveAll(filepath.Join(target, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = os.Rename(filepath.Join(source, entry.Name()), filepath.Join(target, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/log"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+}
+
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+func (suite *CacheTestSuite) SetupTest() {
+	suite.T().Setenv(DEVPOD_CACHE_DIR, suite.T().TempDir())
+}
+
+func (suite *CacheTestSuite) TestOffline() {
+	suite.T().Setenv(DEVPOD_OFFLINE, "")
+	suite.False(Offline())
+
+	suite.T().Setenv(DEVPOD_OFFLINE, "true")
+	suite.True(Offline())
+	suite.True(errors.Is(OfflineError("https://example.com/feature.tgz"), ErrOffline))
+}
+
+func (suite *CacheTestSuite) TestPath() {
+	path := Path(IDEFolder, "https://example.com/server.tar.gz")
+	suite.Equal(filepath.Join(Dir(), IDEFolder), filepath.Dir(path))
+	suite.Equal(path, Path(IDEFolder, "https://example.com/server.tar.gz"))
+	suite.NotEqual(path, Path(IDEFolder, "https://example.com/other.tar.gz"))
+}
+
+func (suite *CacheTestSuite) TestImages() {
+	images, err := Images()
+	suite.Require().NoError(err)
+	suite.Empty(images)
+
+	suite.Require().NoError(AddImages("ubuntu:24.04", "debian:12"))
+	suite.Require().NoError(AddImages("ubuntu:24.04"))
+	images, err = Images()
+	suite.Require().NoError(err)
+	suite.Equal([]string{"ubuntu:24.04", "debian:12"}, images)
+}
+
+func (suite *CacheTestSuite) TestExportImport() {
+	cachedFile := Path(FeaturesFolder, "ghcr.io/devcontainers/features/go:1")
+	suite.Require().NoError(os.MkdirAll(filepath.Dir(cachedFile), 0755))
+	suite.Require().NoError(os.WriteFile(cachedFile, []byte("feature"), 0644))
+
+	dockerHelper := &docker.DockerHelper{DockerCommand: "docker", Log: log.Discard}
+	archive := &bytes.Buffer{}
+	suite.Require().NoError(Export(context.Background(), dockerHelper, archive, log.Discard))
+
+	suite.T().Setenv(DEVPOD_CACHE_DIR, suite.T().TempDir())
+	suite.Require().NoError(Import(context.Background(), dockerHelper, archive, log.Discard))
+
+	content, err := os.ReadFile(Path(FeaturesFolder, "ghcr.io/devcontainers/features/go:1"))
+	suite.Require().NoError(err)
+	suite.Equal("feature", string(content))
+}
+
+func (suite *CacheTestSuite) TestImportInvalid() {
+	dockerHelper := &docker.DockerHelper{DockerCommand: "docker", Log: log.Discard}
+	tests := map[string]*tar.Header{
+		"unexpected entry": {Name: "config.yaml", Typeflag: tar.TypeReg, Mode: 0644},
+		"symlink":          {Name: FeaturesFolder + "/link", Typeflag: tar.TypeSymlink, Linkname: "/etc", Mode: 0777},
+		"hardlink":         {Name: FeaturesFolder + "/link", Typeflag: tar.TypeLink, Linkname: "/etc/passwd", Mode: 0644},
+		"traversal":        {Name: FeaturesFolder + "/../../escaped.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		"absolute":         {Name: "/../../escaped.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	}
+
+	for name, header := range tests {
+		archive := &bytes.Buffer{}
+		writer := tar.NewWriter(archive)
+		suite.Require().NoError(writer.WriteHeader(&tar.Header{Name: FeaturesFolder + "/", Typeflag: tar.TypeDir, Mode: 0755}))
+		suite.Require().NoError(writer.WriteHeader(header), name)
+		suite.Require().NoError(writer.Close())
+
+		err := Import(context.Background(), dockerHelper, archive, log.Discard)
+		suite.ErrorContains(err, "invalid cache archive", name)
+		_, err = os.Stat(Folder(FeaturesFolder))
+		suite.True(os.IsNotExist(err), name)
+		_, err = os.Stat(filepath.Join(filepath.Dir(Dir()), "escaped.txt"))
+		suite.True(os.IsNotExist(err), name)
+	}
+}
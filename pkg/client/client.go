@@ -9,6 +9,7 @@ import (
 	"github.com/loft-sh/api/v4/pkg/devpod"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/types"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -23,7 +24,7 @@ type BaseClient interface {
 	RefreshOptions(ctx context.Context, userOptions []string, reconfigure bool) error
 
 	// Status retrieves the workspace status
-	Status(ctx context.Context, options StatusOptions) (Status, error)
+	Status(ctx context.Context, options StatusOptions) (*WorkspaceStatus, error)
 
 	// Stop stops the workspace
 	Stop(ctx context.Context, options StopOptions) error
@@ -210,7 +211,58 @@ type WorkspaceStatus struct {
 	ID       string `json:"id,omitempty"`
 	Context  string `json:"context,omitempty"`
 	Provider string `json:"provider,omitempty"`
-	State    string `json:"state,omitempty"`
+	State    Status `json:"state,omitempty"`
+
+	// Container holds the details of the workspace container, only set if the container status was requested
+	Container *ContainerStatus `json:"container,omitempty"`
+
+	// LastActivity is the last time the workspace was accessed through the agent
+	LastActivity *types.Time `json:"lastActivity,omitempty"`
+
+	// IDE is the ide of the workspace and whether it was installed in the container
+	IDE *IDEStatus `json:"ide,omitempty"`
+
+	// Agent is the version of the agent that reported the status
+	Agent *AgentStatus `json:"agent,omitempty"`
+
+	// LifecycleHooks is the state of the lifecycle hooks in the workspace container
+	LifecycleHooks []config.LifecycleHookStatus `json:"lifecycleHooks,omitempty"`
+
+	// Ports are the configured ports of the workspace with their labels and auto forward actions
+	Ports []config.PortStatus `json:"ports,omitempty"`
+}
+
+type ContainerStatus struct {
+	// ID is the id of the workspace container
+	ID string `json:"id,omitempty"`
+
+	// Image is the image the workspace container was created from
+	Image string `json:"image,omitempty"`
+
+	// Driver is the driver that runs the workspace container, e.g. docker or kubernetes
+	Driver string `json:"driver,omitempty"`
+
+	// Status is the raw status of the container as reported by the driver
+	Status string `json:"status,omitempty"`
+
+	// StartedAt is the time the container was started
+	StartedAt string `json:"startedAt,omitempty"`
+
+	// Uptime is the time since the container was started, if it is running
+	Uptime string `json:"uptime,omitempty"`
+}
+
+type IDEStatus struct {
+	Name      string `json:"name,omitempty"`
+	Installed bool   `json:"installed,omitempty"`
+}
+
+type AgentStatus struct {
+	// Version is the version of the agent
+	Version string `json:"version,omitempty"`
+
+	// Outdated is true if the agent version is older than the version of the cli
+	Outdated bool `json:"outdated,omitempty"`
 }
 
 type User struct {
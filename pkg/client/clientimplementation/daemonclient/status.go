@@ -8,22 +8,26 @@ import (
 	"github.com/skevetter/devpod/pkg/platform"
 )
 
-func (c *client) Status(ctx context.Context, opt clientpkg.StatusOptions) (clientpkg.Status, error) {
+func (c *client) Status(ctx context.Context, opt clientpkg.StatusOptions) (*clientpkg.WorkspaceStatus, error) {
 	c.m.Lock()
 	defer c.m.Unlock()
 
-	status := clientpkg.Status(clientpkg.StatusNotFound)
 	baseClient, err := c.initPlatformClient(ctx)
 	if err != nil {
-		return status, err
+		return nil, err
 	}
 
 	instance, err := platform.FindInstance(ctx, baseClient, c.workspace.UID)
 	if err != nil {
-		return status, err
+		return nil, err
 	} else if instance == nil {
-		return status, fmt.Errorf("couldn't find workspace")
+		return nil, fmt.Errorf("couldn't find workspace")
 	}
 
-	return clientpkg.Status(instance.Status.LastWorkspaceStatus), nil
+	return &clientpkg.WorkspaceStatus{
+		ID:       c.workspace.ID,
+		Context:  c.workspace.Context,
+		Provider: c.workspace.Provider.Name,
+		State:    clientpkg.Status(instance.Status.LastWorkspaceStatus),
+	}, nil
 }
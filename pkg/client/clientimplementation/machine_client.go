@@ -190,7 +190,7 @@ func (s *machineClient) Command(ctx context.Context, commandOptions client.Comma
 	})
 }
 
-func (s *machineClient) Status(ctx context.Context, options client.StatusOptions) (client.Status, error) {
+func (s *machineClient) Status(ctx context.Context, options client.StatusOptions) (*client.WorkspaceStatus, error) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 
@@ -201,15 +201,20 @@ func (s *machineClient) Status(ctx context.Context, options client.StatusOptions
 		stderr:  io.MultiWriter(stderr, s.log.Writer(logrus.InfoLevel, true)),
 	})
 	if err != nil {
-		return client.StatusNotFound, fmt.Errorf("get status: %s%s", strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String()))
+		return nil, fmt.Errorf("get status: %s%s", strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String()))
 	}
 
 	parsedStatus, err := client.ParseStatus(stdout.String())
 	if err != nil {
-		return client.StatusNotFound, err
+		return nil, err
 	}
 
-	return parsedStatus, nil
+	return &client.WorkspaceStatus{
+		ID:       s.machine.ID,
+		Context:  s.machine.Context,
+		Provider: s.config.Name,
+		State:    parsedStatus,
+	}, nil
 }
 
 func (s *machineClient) Delete(ctx context.Context, options client.DeleteOptions) error {
@@ -337,7 +337,7 @@ func (s *proxyClient) Delete(ctx context.Context, opt client.DeleteOptions) erro
 	}, s.log)
 }
 
-func (s *proxyClient) Status(ctx context.Context, options client.StatusOptions) (client.Status, error) {
+func (s *proxyClient) Status(ctx context.Context, options client.StatusOptions) (*client.WorkspaceStatus, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
 
@@ -359,18 +359,23 @@ func (s *proxyClient) Status(ctx context.Context, options client.StatusOptions)
 		Log:       s.log.ErrorStreamOnly(),
 	})
 	if err != nil {
-		return client.StatusNotFound, fmt.Errorf("error retrieving container status: %s%w", buf.String(), err)
+		return nil, fmt.Errorf("error retrieving container status: %s%w", buf.String(), err)
 	}
 
 	devpodlog.ReadJSONStream(bytes.NewReader(buf.Bytes()), s.log.ErrorStreamOnly())
 	status := &client.WorkspaceStatus{}
 	err = json.Unmarshal(stdout.Bytes(), status)
 	if err != nil {
-		return client.StatusNotFound, fmt.Errorf("error parsing proxy command response: %s%w", stdout.String(), err)
+		return nil, fmt.Errorf("error parsing proxy command response: %s%w", stdout.String(), err)
 	}
 
 	// parse status
-	return client.ParseStatus(status.State)
+	status.State, err = client.ParseStatus(string(status.State))
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
 }
 
 func (s *proxyClient) updateInstance(ctx context.Context) error {
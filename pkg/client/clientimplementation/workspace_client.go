@@ -10,14 +10,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/blang/semver/v4"
 	"github.com/gofrs/flock"
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/pkg/agent"
 	"github.com/skevetter/devpod/pkg/agent/tunnelserver"
 	"github.com/skevetter/devpod/pkg/binaries"
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/client"
 	"github.com/skevetter/devpod/pkg/compress"
 	"github.com/skevetter/devpod/pkg/config"
@@ -27,6 +30,7 @@ import (
 	"github.com/skevetter/devpod/pkg/shell"
 	"github.com/skevetter/devpod/pkg/ssh"
 	"github.com/skevetter/devpod/pkg/types"
+	"github.com/skevetter/devpod/pkg/version"
 	"github.com/skevetter/log"
 )
 
@@ -234,6 +238,9 @@ func (s *workspaceClient) agentInfo(cliOptions provider.CLIOptions) *provider.Ag
 	// Set registry cache from context option
 	agentInfo.RegistryCache = s.devPodConfig.ContextOption(config.ContextOptionRegistryCache)
 
+	// forward offline mode to the agent
+	agentInfo.CLIOptions.Offline = cache.Offline()
+
 	return agentInfo
 }
 
@@ -325,7 +332,7 @@ func (s *workspaceClient) Create(ctx context.Context, options client.CreateOptio
 	machineStatus, err := machineClient.Status(ctx, client.StatusOptions{})
 	if err != nil {
 		return err
-	} else if machineStatus != client.StatusNotFound {
+	} else if machineStatus.State != client.StatusNotFound {
 		return nil
 	}
 
@@ -435,7 +442,7 @@ func (s *workspaceClient) isMachineRunning(ctx context.Context) (bool, error) {
 	status, err := machineClient.Status(ctx, client.StatusOptions{})
 	if err != nil {
 		return false, fmt.Errorf("retrieve machine status %w", err)
-	} else if status == client.StatusRunning {
+	} else if status.State == client.StatusRunning {
 		return true, nil
 	}
 
@@ -535,32 +542,32 @@ func (s *workspaceClient) Command(ctx context.Context, commandOptions client.Com
 	})
 }
 
-func (s *workspaceClient) Status(ctx context.Context, options client.StatusOptions) (client.Status, error) {
+func (s *workspaceClient) Status(ctx context.Context, options client.StatusOptions) (*client.WorkspaceStatus, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
 
 	// check if provider has status command
 	if s.isMachineProvider() && len(s.config.Exec.Status) > 0 {
 		if s.machine == nil {
-			return client.StatusNotFound, nil
+			return s.status(client.StatusNotFound), nil
 		}
 
 		machineClient, err := NewMachineClient(s.devPodConfig, s.config, s.machine, s.log)
 		if err != nil {
-			return client.StatusNotFound, err
+			return nil, err
 		}
 
-		status, err := machineClient.Status(ctx, options)
+		machineStatus, err := machineClient.Status(ctx, options)
 		if err != nil {
-			return status, err
+			return nil, err
 		}
 
 		// try to check container status and if that fails check workspace folder
-		if status == client.StatusRunning && options.ContainerStatus {
+		if machineStatus.State == client.StatusRunning && options.ContainerStatus {
 			return s.getContainerStatus(ctx)
 		}
 
-		return status, err
+		return s.status(machineStatus.State), nil
 	}
 
 	// try to check container status and if that fails check workspace folder
@@ -573,27 +580,93 @@ func (s *workspaceClient) Status(ctx context.Context, options client.StatusOptio
 	// - if workspace folder doesn't exist -> NotFound
 	workspaceFolder, err := provider.GetWorkspaceDir(s.workspace.Context, s.workspace.ID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// does workspace folder exist?
 	_, err = os.Stat(workspaceFolder)
 	if err == nil {
-		return client.StatusRunning, nil
+		return s.status(client.StatusRunning), nil
 	}
 
-	return client.StatusNotFound, nil
+	return s.status(client.StatusNotFound), nil
 }
 
-func (s *workspaceClient) getContainerStatus(ctx context.Context) (client.Status, error) {
-	stdout := &bytes.Buffer{}
-	buf := &bytes.Buffer{}
+func (s *workspaceClient) status(state client.Status) *client.WorkspaceStatus {
+	status := &client.WorkspaceStatus{
+		ID:       s.workspace.ID,
+		Context:  s.workspace.Context,
+		Provider: s.config.Name,
+		State:    state,
+	}
+	if s.workspace.IDE.Name != "" {
+		status.IDE = &client.IDEStatus{Name: s.workspace.IDE.Name}
+	}
+
+	return status
+}
+
+func (s *workspaceClient) getContainerStatus(ctx context.Context) (*client.WorkspaceStatus, error) {
 	compressed, info, err := s.compressedAgentInfo(provider.CLIOptions{})
 	if err != nil {
-		return "", fmt.Errorf("get agent info")
+		return nil, fmt.Errorf("get agent info")
+	}
+
+	return containerStatus(ctx, s.runStatusCommand, info.Agent.Path, compressed, s.status(client.StatusNotFound), version.GetVersion(), s.log)
+}
+
+// statusCommandRunner runs the agent status command and returns its stdout and its combined output
+type statusCommandRunner func(ctx context.Context, command string) (string, string, error)
+
+// containerStatus runs the agent status command and parses its output into the given status. Agents that don't
+// support a structured status only report the state.
+func containerStatus(ctx context.Context, run statusCommandRunner, agentPath, compressed string, status *client.WorkspaceStatus, cliVersion string, log log.Logger) (*client.WorkspaceStatus, error) {
+	command := fmt.Sprintf("'%s' agent workspace status --output json --workspace-info '%s'", agentPath, compressed)
+	stdout, stderr, err := run(ctx, command)
+	if err != nil && strings.Contains(stderr, "unknown flag: --output") {
+		// agents before the structured status only print the state
+		log.Debugf("Agent doesn't support a structured status, falling back to the state")
+		command = fmt.Sprintf("'%s' agent workspace status --workspace-info '%s'", agentPath, compressed)
+		stdout, stderr, err = run(ctx, command)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving container status: %s%w", stderr, err)
+		}
+
+		status.State, err = client.ParseStatus(strings.TrimSpace(stdout))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing container status: %s%w", stderr, err)
+		}
+
+		return status, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error retrieving container status: %s%w", stderr, err)
+	}
+
+	err = json.Unmarshal([]byte(stdout), status)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing container status: %s%w", stderr, err)
 	}
-	command := fmt.Sprintf("'%s' agent workspace status --workspace-info '%s'", info.Agent.Path, compressed)
-	err = RunCommandWithBinaries(CommandOptions{
+	status.State, err = client.ParseStatus(string(status.State))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing container status: %s%w", stderr, err)
+	}
+	if status.Agent != nil {
+		status.Agent.Outdated = isOutdatedAgent(status.Agent.Version, cliVersion)
+	}
+
+	log.WithFields(logrus.Fields{
+		"stdout": stdout,
+		"stderr": stderr,
+		"parsed": status.State,
+	}).Debug("container status command output")
+	return status, nil
+}
+
+// runStatusCommand runs the agent status command and returns its stdout and its combined output
+func (s *workspaceClient) runStatusCommand(ctx context.Context, command string) (string, string, error) {
+	stdout := &bytes.Buffer{}
+	buf := &bytes.Buffer{}
+	err := RunCommandWithBinaries(CommandOptions{
 		Ctx:       ctx,
 		Name:      "command",
 		Command:   s.config.Exec.Command,
@@ -610,21 +683,23 @@ func (s *workspaceClient) getContainerStatus(ctx context.Context) (client.Status
 		Stderr: buf,
 		Log:    s.log.ErrorStreamOnly(),
 	})
+
+	return stdout.String(), buf.String(), err
+}
+
+// isOutdatedAgent returns true if the agent version is older than the cli version. Versions that aren't
+// semantic versions, e.g. of development builds, are never outdated.
+func isOutdatedAgent(agentVersion string, cliVersion string) bool {
+	agent, err := semver.Parse(strings.TrimPrefix(agentVersion, "v"))
 	if err != nil {
-		return client.StatusNotFound, fmt.Errorf("error retrieving container status: %s%w", buf.String(), err)
+		return false
 	}
-
-	parsed, err := client.ParseStatus(stdout.String())
+	cli, err := semver.Parse(strings.TrimPrefix(cliVersion, "v"))
 	if err != nil {
-		return client.StatusNotFound, fmt.Errorf("error parsing container status: %s%w", buf.String(), err)
+		return false
 	}
 
-	s.log.WithFields(logrus.Fields{
-		"stdout": buf.String(),
-		"stderr": stdout.String(),
-		"parsed": parsed,
-	}).Debug("container status command output")
-	return parsed, nil
+	return agent.LT(cli)
 }
 
 func (s *workspaceClient) isMachineProvider() bool {
@@ -787,7 +862,7 @@ func StartWait(
 			return err
 		}
 
-		switch instanceStatus {
+		switch instanceStatus.State {
 		case client.StatusBusy:
 			if handleBusyStatus(&startWaiting, log) {
 				time.Sleep(pollInterval)
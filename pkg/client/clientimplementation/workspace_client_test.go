@@ -0,0 +1,140 @@
+package clientimplementation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/client"
+	"github.com/skevetter/log"
+)
+
+func TestIsOutdatedAgent(t *testing.T) {
+	tests := []struct {
+		name         string
+		agentVersion string
+		cliVersion   string
+		want         bool
+	}{
+		{name: "older", agentVersion: "0.5.0", cliVersion: "0.6.0", want: true},
+		{name: "older patch", agentVersion: "v0.6.0", cliVersion: "v0.6.1", want: true},
+		{name: "equal", agentVersion: "0.6.0", cliVersion: "v0.6.0"},
+		{name: "newer", agentVersion: "0.7.0", cliVersion: "0.6.0"},
+		{name: "pre-release of the cli version", agentVersion: "0.6.0-alpha.1", cliVersion: "0.6.0", want: true},
+		{name: "release of the pre-release", agentVersion: "0.6.0", cliVersion: "0.6.0-alpha.1"},
+		{name: "older pre-release", agentVersion: "0.6.0-alpha.1", cliVersion: "0.6.0-alpha.2", want: true},
+		{name: "dev agent", agentVersion: "dev", cliVersion: "0.6.0"},
+		{name: "dev cli", agentVersion: "0.5.0", cliVersion: "dev"},
+		{name: "unknown agent", agentVersion: "", cliVersion: "0.6.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOutdatedAgent(tt.agentVersion, tt.cliVersion); got != tt.want {
+				t.Fatalf("expected outdated %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestContainerStatus(t *testing.T) {
+	type output struct {
+		stdout string
+		stderr string
+		err    error
+	}
+
+	unknownFlag := output{stderr: "Error: unknown flag: --output", err: errors.New("exit status 1")}
+	tests := []struct {
+		name    string
+		outputs []output
+
+		wantErr      bool
+		wantCommands int
+		wantState    client.Status
+		wantAgent    *client.AgentStatus
+	}{
+		{
+			name:         "structured",
+			outputs:      []output{{stdout: `{"state":"Running","agent":{"version":"0.5.0"}}`}},
+			wantCommands: 1,
+			wantState:    client.StatusRunning,
+			wantAgent:    &client.AgentStatus{Version: "0.5.0", Outdated: true},
+		},
+		{
+			name:         "structured current agent",
+			outputs:      []output{{stdout: `{"state":"Stopped","agent":{"version":"0.6.0"}}`}},
+			wantCommands: 1,
+			wantState:    client.StatusStopped,
+			wantAgent:    &client.AgentStatus{Version: "0.6.0"},
+		},
+		{
+			name:         "fallback",
+			outputs:      []output{unknownFlag, {stdout: "Running\n"}},
+			wantCommands: 2,
+			wantState:    client.StatusRunning,
+		},
+		{
+			name:         "fallback fails",
+			outputs:      []output{unknownFlag, {stderr: "connection refused", err: errors.New("exit status 1")}},
+			wantErr:      true,
+			wantCommands: 2,
+		},
+		{
+			name:         "fallback invalid state",
+			outputs:      []output{unknownFlag, {stdout: "Unknown"}},
+			wantErr:      true,
+			wantCommands: 2,
+		},
+		{
+			name:         "other error",
+			outputs:      []output{{stderr: "connection refused", err: errors.New("exit status 1")}},
+			wantErr:      true,
+			wantCommands: 1,
+		},
+		{
+			name:         "invalid json",
+			outputs:      []output{{stdout: "Running"}},
+			wantErr:      true,
+			wantCommands: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := []string{}
+			run := func(ctx context.Context, command string) (string, string, error) {
+				out := tt.outputs[len(commands)]
+				commands = append(commands, command)
+				return out.stdout, out.stdout + out.stderr, out.err
+			}
+
+			base := &client.WorkspaceStatus{ID: "workspace", State: client.StatusNotFound}
+			status, err := containerStatus(context.Background(), run, "/usr/local/bin/devpod", "info", base, "0.6.0", log.Discard)
+			if len(commands) != tt.wantCommands {
+				t.Fatalf("expected %d commands, got %v", tt.wantCommands, commands)
+			} else if !strings.Contains(commands[0], "--output json") {
+				t.Fatalf("expected a structured status command, got %s", commands[0])
+			} else if len(commands) > 1 && strings.Contains(commands[1], "--output") {
+				t.Fatalf("expected the fallback command without --output, got %s", commands[1])
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", status)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if status.ID != "workspace" {
+				t.Fatalf("expected the workspace id to be kept, got %q", status.ID)
+			} else if status.State != tt.wantState {
+				t.Fatalf("expected state %s, got %s", tt.wantState, status.State)
+			} else if (status.Agent == nil) != (tt.wantAgent == nil) || (status.Agent != nil && *status.Agent != *tt.wantAgent) {
+				t.Fatalf("expected agent %+v, got %+v", tt.wantAgent, status.Agent)
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package command
 
+import (
+	"os/exec"
+	"time"
+)
+
 func IsRunning(pid string) (bool, error) {
 	return isRunning(pid)
 }
@@ -7,3 +12,9 @@ func IsRunning(pid string) (bool, error) {
 func Kill(pid string) error {
 	return kill(pid)
 }
+
+// SetProcessGroup starts the command in its own process group. If the context of the command is cancelled,
+// the whole group is terminated and killed after the wait delay.
+func SetProcessGroup(cmd *exec.Cmd, waitDelay time.Duration) {
+	setProcessGroup(cmd, waitDelay)
+}
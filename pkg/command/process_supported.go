@@ -3,7 +3,9 @@
 package command
 
 import (
+	"errors"
 	"os"
+	"os/exec"
 	"strconv"
 	"syscall"
 	"time"
@@ -20,8 +22,9 @@ func isRunning(pid string) (bool, error) {
 		return false, err
 	}
 
+	// a process of another user can't be signaled, but exists
 	err = process.Signal(syscall.Signal(0))
-	if err != nil {
+	if err != nil && !errors.Is(err, syscall.EPERM) {
 		return false, nil
 	}
 
@@ -39,3 +42,14 @@ func kill(pid string) error {
 	_ = syscall.Kill(parsedPid, syscall.SIGKILL)
 	return nil
 }
+
+func setProcessGroup(cmd *exec.Cmd, waitDelay time.Duration) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		err := syscall.Kill(pgid, syscall.SIGTERM)
+		time.AfterFunc(waitDelay, func() { _ = syscall.Kill(pgid, syscall.SIGKILL) })
+		return err
+	}
+	cmd.WaitDelay = waitDelay
+}
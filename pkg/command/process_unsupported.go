@@ -2,10 +2,33 @@
 
 package command
 
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
 func isRunning(pid string) (bool, error) {
-	panic("unsupported")
+	parsedPid, err := strconv.Atoi(pid)
+	if err != nil {
+		return false, err
+	}
+
+	// on windows finding the process fails if it doesn't exist
+	process, err := os.FindProcess(parsedPid)
+	if err != nil {
+		return false, nil
+	}
+	_ = process.Release()
+
+	return true, nil
 }
 
 func kill(pid string) error {
 	panic("unsupported")
 }
+
+func setProcessGroup(cmd *exec.Cmd, waitDelay time.Duration) {
+	cmd.WaitDelay = waitDelay
+}
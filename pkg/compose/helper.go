@@ -48,6 +48,9 @@ type ComposeHelper struct {
 	Version string
 	Args    []string
 	Docker  *docker.DockerHelper
+
+	// Extensions are added to the compose project that starts the devcontainer
+	Extensions map[string]any
 }
 
 // NewComposeHelper creates a new ComposeHelper instance after detecting whether Docker
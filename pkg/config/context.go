@@ -23,6 +23,10 @@ const (
 	ContextOptionAgentInjectTimeout         = "AGENT_INJECT_TIMEOUT"
 	ContextOptionRegistryCache              = "REGISTRY_CACHE"
 	ContextOptionSSHStrictHostKeyChecking   = "SSH_STRICT_HOST_KEY_CHECKING"
+	ContextOptionSecretsBackend             = "SECRETS_BACKEND"
+	ContextOptionSecretsDir                 = "SECRETS_DIR"
+	ContextOptionSecretsCommand             = "SECRETS_COMMAND"
+	ContextOptionSecretsAllowed             = "SECRETS_ALLOWED"
 )
 
 var ContextOptions = []ContextOption{
@@ -110,6 +114,24 @@ var ContextOptions = []ContextOption{
 		Default:     "false",
 		Enum:        []string{"true", "false"},
 	},
+	{
+		Name:        ContextOptionSecretsBackend,
+		Description: "Specifies the default backend to resolve secret:// references with",
+		Default:     "env",
+		Enum:        []string{"env", "file", "pass", "command"},
+	},
+	{
+		Name:        ContextOptionSecretsDir,
+		Description: "Specifies the directory the file secrets backend reads secrets from, defaults to the secrets folder in the DevPod home",
+	},
+	{
+		Name:        ContextOptionSecretsCommand,
+		Description: "Specifies the command the command secrets backend runs to resolve a secret, the secret name is passed as DEVPOD_SECRET_NAME",
+	},
+	{
+		Name:        ContextOptionSecretsAllowed,
+		Description: "Specifies a comma separated list of secrets, e.g. github-token,pass:work/db, that the remoteEnv of a devcontainer.json may reference",
+	},
 }
 
 func MergeContextOptions(contextConfig *ContextConfig, environ []string) {
@@ -172,6 +172,21 @@ func (r *runner) getDockerfilePath(parsedConfig *config.DevContainerConfig) (str
 	return dockerfilePath, nil
 }
 
+// snapshotBuildInfo returns the build info for a workspace snapshot image. Snapshots are committed
+// from a devcontainer, so they already contain all features and metadata and are used as is.
+func (r *runner) snapshotBuildInfo(ctx context.Context, substitutionContext *config.SubstitutionContext, image string) (*config.BuildInfo, error) {
+	imageBuildInfo, err := r.getImageBuildInfoFromImage(ctx, substitutionContext, image)
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot image info %w", err)
+	}
+
+	return &config.BuildInfo{
+		ImageDetails:  imageBuildInfo.ImageDetails,
+		ImageMetadata: imageBuildInfo.Metadata,
+		ImageName:     image,
+	}, nil
+}
+
 func (r *runner) getImageBuildInfoFromImage(ctx context.Context, substitutionContext *config.SubstitutionContext, imageName string) (*config.ImageBuildInfo, error) {
 	imageDetails, err := r.inspectImage(ctx, imageName)
 	if err != nil {
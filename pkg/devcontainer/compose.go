@@ -224,7 +224,7 @@ func (r *runner) runDockerCompose(
 	}
 
 	// setup container
-	return r.setupContainer(ctx, parsedConfig.Raw, containerDetails, mergedConfig, substitutionContext, timeout)
+	return r.setupContainer(ctx, parsedConfig.Raw, containerDetails, mergedConfig, substitutionContext, options.SecretsClient, timeout)
 }
 
 // onlyRunServices appends the services defined in .devcontainer.json runServices to the upArgs
@@ -385,6 +385,11 @@ func (r *runner) startContainer(
 			return nil, fmt.Errorf("merge configuration %w", err)
 		}
 
+		err = r.checkHostRequirements(ctx, mergedConfig.HostRequirements, options.StrictHostRequirements)
+		if err != nil {
+			return nil, err
+		}
+
 		additionalLabels := map[string]string{
 			metadata.ImageMetadataLabel: metadataLabel,
 			config.UserLabel:            imageDetails.Config.User,
@@ -778,6 +783,7 @@ exec "$$@"
 
 	gpuSupportEnabled, _ := composeHelper.Docker.GPUSupportEnabled()
 	r.configureGPUResources(parsedConfig, gpuSupportEnabled, overrideService)
+	r.configureHostResources(mergedConfig.HostRequirements, overrideService)
 
 	for _, mount := range mergedConfig.Mounts {
 		overrideService.Volumes = append(overrideService.Volumes, composetypes.ServiceVolumeConfig{
@@ -791,6 +797,9 @@ exec "$$@"
 	project.Services = map[string]composetypes.ServiceConfig{
 		overrideService.Name: *overrideService,
 	}
+	if len(composeHelper.Extensions) > 0 {
+		project.Extensions = composeHelper.Extensions
+	}
 
 	// Configure volumes
 	var volumeMounts []composetypes.VolumeConfig
@@ -835,6 +844,31 @@ func (r *runner) configureGPUResources(parsedConfig *config.SubstitutedConfig, g
 	}
 }
 
+func (r *runner) configureHostResources(requirements *config.HostRequirements, overrideService *composetypes.ServiceConfig) {
+	if requirements.IsEmpty() {
+		return
+	}
+
+	limits := &composetypes.Resource{}
+	if requirements.CPUs > 0 {
+		limits.NanoCPUs = composetypes.NanoCPUs(requirements.CPUs)
+	}
+	memory, err := requirements.MemoryBytes()
+	if err != nil {
+		r.Log.Warnf("skipping memory host requirement: %v", err)
+	} else if memory > 0 {
+		limits.MemoryBytes = composetypes.UnitBytes(memory)
+	}
+	if limits.NanoCPUs == 0 && limits.MemoryBytes == 0 {
+		return
+	}
+
+	if overrideService.Deploy == nil {
+		overrideService.Deploy = &composetypes.DeployConfig{}
+	}
+	overrideService.Deploy.Resources.Limits = limits
+}
+
 func checkForPersistedFile(files []string, prefix string) (foundLabel bool, fileExists bool, filePath string, err error) {
 	for _, file := range files {
 		if !strings.HasPrefix(file, prefix) {
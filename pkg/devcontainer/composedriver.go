@@ -0,0 +1,227 @@
+package devcontainer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"time"
+
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/skevetter/devpod/pkg/compose"
+	"github.com/skevetter/devpod/pkg/daemon/agent"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/metadata"
+	"github.com/skevetter/devpod/pkg/driver"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+)
+
+// runComposeDriver runs docker compose devcontainers with drivers that translate the compose project
+// themselves instead of running docker compose. The devcontainer service is built like a single
+// container, the driver then runs it together with the other services.
+func (r *runner) runComposeDriver(
+	ctx context.Context,
+	composeDriver driver.ComposeDriver,
+	parsedConfig *config.SubstitutedConfig,
+	substitutionContext *config.SubstitutionContext,
+	options UpOptions,
+	timeout time.Duration,
+) (*config.Result, error) {
+	composeFiles, envFiles, _, err := r.dockerComposeProjectFiles(parsedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("get compose/env files %w", err)
+	}
+
+	r.Log.Debugf("Loading docker compose project %+v", composeFiles)
+	project, err := compose.LoadDockerComposeProject(ctx, composeFiles, envFiles)
+	if err != nil {
+		return nil, fmt.Errorf("load docker compose project %w", err)
+	}
+
+	serviceConfig, err := getComposeServiceConfig(parsedConfig, project)
+	if err != nil {
+		return nil, err
+	}
+
+	containerDetails, err := r.Driver.FindDevContainer(ctx, r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("find dev container %w", err)
+	}
+
+	var mergedConfig *config.MergedDevContainerConfig
+	if !options.Recreate && containerDetails != nil {
+		// start container if not running
+		if strings.ToLower(containerDetails.State.Status) != "running" {
+			err = r.Driver.StartDevContainer(ctx, r.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		imageMetadataConfig, err := metadata.GetImageMetadataFromContainer(containerDetails, substitutionContext, r.Log)
+		if err != nil {
+			return nil, err
+		}
+
+		mergedConfig, err = config.MergeConfiguration(serviceConfig.Config, imageMetadataConfig.Config)
+		if err != nil {
+			return nil, fmt.Errorf("merge config %w", err)
+		}
+
+		// If driver can reprovision, rerun the devcontainer and let the driver handle follow-up steps
+		if d, ok := r.Driver.(driver.ReprovisioningDriver); ok && d.CanReprovision() {
+			err = r.Driver.RunDevContainer(ctx, r.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("runner driver run dev container %w", err)
+			}
+		}
+	} else {
+		buildInfo, err := r.build(ctx, serviceConfig, substitutionContext, provider2.BuildOptions{
+			CLIOptions: provider2.CLIOptions{
+				PrebuildRepositories:  options.PrebuildRepositories,
+				ForceDockerless:       options.ForceDockerless,
+				Platform:              options.Platform,
+				ExtraDevContainerPath: options.ExtraDevContainerPath,
+			},
+			NoBuild:       options.NoBuild,
+			RegistryCache: options.RegistryCache,
+			ExportCache:   false,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("build image %w", err)
+		}
+
+		// stop the old services on recreation
+		if options.Recreate && containerDetails != nil {
+			err = r.Driver.StopDevContainer(ctx, r.ID)
+			if err != nil {
+				return nil, fmt.Errorf("stop devcontainer %w", err)
+			}
+		}
+
+		mergedConfig, err = config.MergeConfiguration(serviceConfig.Config, buildInfo.ImageMetadata.Config)
+		if err != nil {
+			return nil, fmt.Errorf("merge config %w", err)
+		}
+
+		// Inject the daemon entrypoint if platform configuration is provided.
+		if options.Platform.AccessKey != "" {
+			data, err := agent.GetEncodedWorkspaceDaemonConfig(options.Platform, r.WorkspaceConfig.Workspace, substitutionContext, mergedConfig)
+			if err != nil {
+				r.Log.Errorf("Failed to marshal daemon config: %v", err)
+			} else {
+				mergedConfig.ContainerEnv[config.WorkspaceDaemonConfigExtraEnvVar] = data
+			}
+		}
+
+		err = r.checkHostRequirements(ctx, mergedConfig.HostRequirements, options.StrictHostRequirements)
+		if err != nil {
+			return nil, err
+		}
+
+		var runOptions *driver.RunOptions
+		if buildInfo.Dockerless != nil {
+			runOptions, err = r.getDockerlessRunOptions(mergedConfig, substitutionContext, buildInfo)
+		} else {
+			runOptions, err = r.getRunOptions(mergedConfig, substitutionContext, buildInfo)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("build run options %w", err)
+		}
+		runOptions.Env = r.addExtraEnvVars(runOptions.Env)
+
+		err = composeDriver.RunComposeDevContainer(ctx, &driver.RunComposeDevContainerParams{
+			WorkspaceID: r.ID,
+			Options:     runOptions,
+			Project:     project,
+			Service:     parsedConfig.Config.Service,
+			RunServices: parsedConfig.Config.RunServices,
+
+			LocalWorkspaceFolder: substitutionContext.LocalWorkspaceFolder,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("runner run compose dev container %w", err)
+		}
+	}
+
+	containerDetails, err = r.Driver.FindDevContainer(ctx, r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("find dev container %w", err)
+	} else if containerDetails == nil {
+		return nil, fmt.Errorf("couldn't find container after start")
+	}
+
+	// setup container
+	return r.setupContainer(ctx, parsedConfig.Raw, containerDetails, mergedConfig, substitutionContext, options.SecretsClient, timeout)
+}
+
+// getComposeServiceConfig returns the devcontainer service as a single container config, so that
+// drivers without docker compose can build it like an image or Dockerfile devcontainer
+func getComposeServiceConfig(parsedConfig *config.SubstitutedConfig, project *composetypes.Project) (*config.SubstitutedConfig, error) {
+	service, err := project.GetService(parsedConfig.Config.Service)
+	if err != nil {
+		return nil, fmt.Errorf("service '%s' configured in devcontainer.json not found in Docker Compose configuration", parsedConfig.Config.Service)
+	}
+
+	serviceConfig := config.CloneDevContainerConfig(parsedConfig.Config)
+	serviceConfig.ComposeContainer = config.ComposeContainer{}
+	serviceConfig.ImageContainer = config.ImageContainer{}
+	serviceConfig.DockerfileContainer = config.DockerfileContainer{}
+	if service.Build != nil {
+		if service.Build.DockerfileInline != "" {
+			return nil, fmt.Errorf("inline Dockerfile of service '%s' is not supported by this provider", service.Name)
+		}
+
+		contextPath := service.Build.Context
+		if !filepath.IsAbs(contextPath) {
+			contextPath = filepath.Join(project.WorkingDir, contextPath)
+		}
+		dockerfile := service.Build.Dockerfile
+		if dockerfile == "" {
+			dockerfile = "Dockerfile"
+		}
+		if !filepath.IsAbs(dockerfile) {
+			dockerfile = filepath.Join(contextPath, dockerfile)
+		}
+
+		args := map[string]string{}
+		for name, value := range service.Build.Args {
+			if value != nil {
+				args[name] = *value
+			}
+		}
+
+		serviceConfig.Build = &config.ConfigBuildOptions{
+			Dockerfile: dockerfile,
+			Context:    contextPath,
+			Target:     service.Build.Target,
+			Args:       args,
+			CacheFrom:  []string(service.Build.CacheFrom),
+		}
+	} else {
+		serviceConfig.Image = service.Image
+	}
+
+	// docker compose sets these on the container, the devcontainer.json takes precedence
+	env := map[string]string{}
+	for name, value := range service.Environment {
+		if value != nil {
+			env[name] = *value
+		}
+	}
+	maps.Copy(env, serviceConfig.ContainerEnv)
+	serviceConfig.ContainerEnv = env
+	if serviceConfig.ContainerUser == "" {
+		serviceConfig.ContainerUser = service.User
+	}
+	if serviceConfig.Privileged == nil && service.Privileged {
+		serviceConfig.Privileged = &service.Privileged
+	}
+	serviceConfig.CapAdd = append(serviceConfig.CapAdd, service.CapAdd...)
+
+	return &config.SubstitutedConfig{
+		Config: serviceConfig,
+		Raw:    parsedConfig.Raw,
+	}, nil
+}
@@ -339,13 +339,36 @@ type PortAttribute struct {
 	// When true, a modal dialog will show if the chosen local port isn't used for forwarding.
 	RequireLocalPort bool `json:"requireLocalPort,omitempty"`
 
-	// The protocol to use when forwarding this port.
+	// The protocol to use when forwarding this port. Besides http and https of the spec, DevPod accepts
+	// tcp and udp to only forward listeners of that protocol.
 	Protocol string `json:"protocol,omitempty"`
 }
 
 type DevPodCustomizations struct {
 	PrebuildRepository         types.StrArray    `json:"prebuildRepository,omitempty"`
 	FeatureDownloadHTTPHeaders map[string]string `json:"featureDownloadHTTPHeaders,omitempty"`
+
+	// ForwardSockets are glob patterns of unix sockets in the container that are forwarded automatically
+	ForwardSockets types.StrArray `json:"forwardSockets,omitempty"`
+
+	// SSH restricts what can be forwarded through the ssh server in the container
+	SSH *SSHCustomizations `json:"ssh,omitempty"`
+
+	// LifecycleHookConcurrency limits how many named commands of a lifecycle hook run in parallel
+	LifecycleHookConcurrency int `json:"lifecycleHookConcurrency,omitempty"`
+}
+
+type SSHCustomizations struct {
+	// AllowForwards and DenyForwards are host:port patterns of forwarded ports
+	AllowForwards types.StrArray `json:"allowForwards,omitempty"`
+	DenyForwards  types.StrArray `json:"denyForwards,omitempty"`
+
+	// AllowSockets and DenySockets are glob patterns of forwarded unix sockets
+	AllowSockets types.StrArray `json:"allowSockets,omitempty"`
+	DenySockets  types.StrArray `json:"denySockets,omitempty"`
+
+	// RecordSessions records the output of interactive sessions
+	RecordSessions bool `json:"recordSessions,omitempty"`
 }
 
 type VSCodeCustomizations struct {
@@ -14,10 +14,28 @@ type ImageDetailsConfig struct {
 }
 
 type ContainerDetails struct {
-	ID      string                 `json:"ID,omitempty"`
-	Created string                 `json:"Created,omitempty"`
-	State   ContainerDetailsState  `json:"State"`
-	Config  ContainerDetailsConfig `json:"Config"`
+	ID      string                  `json:"ID,omitempty"`
+	Created string                  `json:"Created,omitempty"`
+	State   ContainerDetailsState   `json:"State"`
+	Config  ContainerDetailsConfig  `json:"Config"`
+	Mounts  []ContainerDetailsMount `json:"Mounts,omitempty"`
+
+	// Image is the id of the image the container was created from
+	Image string `json:"Image,omitempty"`
+}
+
+type ContainerDetailsMount struct {
+	// Type is the type of the mount, e.g. volume or bind
+	Type string `json:"Type,omitempty"`
+
+	// Name is the name of the volume for volume mounts
+	Name string `json:"Name,omitempty"`
+
+	// Source is the path of the mount on the host
+	Source string `json:"Source,omitempty"`
+
+	// Destination is the path of the mount in the container
+	Destination string `json:"Destination,omitempty"`
 }
 
 type ContainerDetailsConfig struct {
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hostRequirementBytesRegExp = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kmgt]b)?$`)
+
+var hostRequirementUnits = map[string]float64{
+	"":   1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+}
+
+// MemoryBytes returns the required amount of memory in bytes or 0 if none is required
+func (h *HostRequirements) MemoryBytes() (int64, error) {
+	if h == nil {
+		return 0, nil
+	}
+
+	return ParseHostRequirementBytes(h.Memory)
+}
+
+// StorageBytes returns the required amount of disk space in bytes or 0 if none is required
+func (h *HostRequirements) StorageBytes() (int64, error) {
+	if h == nil {
+		return 0, nil
+	}
+
+	return ParseHostRequirementBytes(h.Storage)
+}
+
+// IsEmpty returns true if no cpu, memory or storage requirements are specified
+func (h *HostRequirements) IsEmpty() bool {
+	return h == nil || (h.CPUs <= 0 && h.Memory == "" && h.Storage == "")
+}
+
+// ParseHostRequirementBytes parses a host requirement size such as 8gb into bytes.
+// Supported units are tb, gb, mb and kb, a value without unit is interpreted as bytes.
+func ParseHostRequirementBytes(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+
+	match := hostRequirementBytesRegExp.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("invalid host requirement size '%s', expected a number followed by one of tb, gb, mb or kb", value)
+	}
+
+	number, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse host requirement size '%s' %w", value, err)
+	}
+
+	return int64(number * hostRequirementUnits[match[2]]), nil
+}
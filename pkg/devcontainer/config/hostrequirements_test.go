@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestParseHostRequirementBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: 0},
+		{name: "plain bytes", value: "1024", want: 1024},
+		{name: "kilobytes", value: "4kb", want: 4 << 10},
+		{name: "megabytes", value: "512mb", want: 512 << 20},
+		{name: "gigabytes upper case", value: "16GB", want: 16 << 30},
+		{name: "fractional gigabytes", value: "1.5gb", want: 3 << 29},
+		{name: "terabytes with space", value: "1 tb", want: 1 << 40},
+		{name: "unknown unit", value: "8gib", wantErr: true},
+		{name: "negative", value: "-1gb", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHostRequirementBytes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHostRequirementBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHostRequirementBytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import "time"
+
+type LifecycleHookState string
+
+const (
+	LifecycleHookPending LifecycleHookState = "Pending"
+	LifecycleHookRunning LifecycleHookState = "Running"
+	LifecycleHookDone    LifecycleHookState = "Done"
+	LifecycleHookSkipped LifecycleHookState = "Skipped"
+	LifecycleHookFailed  LifecycleHookState = "Failed"
+)
+
+// LifecycleHookStatus is the state of a single lifecycle hook such as postCreateCommand
+type LifecycleHookStatus struct {
+	Name       string             `json:"name"`
+	State      LifecycleHookState `json:"state"`
+	Background bool               `json:"background,omitempty"`
+	StartedAt  *time.Time         `json:"startedAt,omitempty"`
+	FinishedAt *time.Time         `json:"finishedAt,omitempty"`
+	Error      string             `json:"error,omitempty"`
+
+	// Commands are the named commands of the hook that ran
+	Commands []LifecycleCommandStatus `json:"commands,omitempty"`
+}
+
+// LifecycleCommandStatus is the state of a single named command of a lifecycle hook
+type LifecycleCommandStatus struct {
+	Name       string     `json:"name"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Duration returns how long the hook ran, or zero if it didn't finish
+func (s LifecycleHookStatus) Duration() time.Duration {
+	return duration(s.StartedAt, s.FinishedAt)
+}
+
+// Duration returns how long the command ran, or zero if it didn't finish
+func (s LifecycleCommandStatus) Duration() time.Duration {
+	return duration(s.StartedAt, s.FinishedAt)
+}
+
+func duration(startedAt, finishedAt *time.Time) time.Duration {
+	if startedAt == nil || finishedAt == nil {
+		return 0
+	}
+
+	return finishedAt.Sub(*startedAt)
+}
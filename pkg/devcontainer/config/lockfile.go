@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DEVCONTAINER_LOCK_FILE_NAME = "devcontainer-lock.json"
+
+// LockFile pins the features of a devcontainer.json to exact versions, see
+// https://github.com/devcontainers/spec/blob/main/docs/specs/devcontainer-lockfile.md
+type LockFile struct {
+	// Features maps the feature ids as specified in the devcontainer.json to their locked version
+	Features map[string]*LockedFeature `json:"features"`
+}
+
+type LockedFeature struct {
+	// Version is the semantic version of the resolved feature
+	Version string `json:"version,omitempty"`
+
+	// Resolved is the fully qualified reference of the feature, e.g. an OCI reference with digest or a tarball URL
+	Resolved string `json:"resolved"`
+
+	// Integrity is the digest of the manifest of an OCI feature or the sha256 digest of a feature tarball
+	Integrity string `json:"integrity"`
+
+	// DependsOn are the ids of the features this feature depends on
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// Get returns the locked feature for the given id or nil if the feature is not locked
+func (l *LockFile) Get(featureID string) *LockedFeature {
+	if l == nil || l.Features == nil {
+		return nil
+	}
+
+	return l.Features[featureID]
+}
+
+// GetLockFilePath returns the path of the lock file belonging to the given devcontainer.json. A lock file
+// for a hidden .devcontainer.json is hidden as well.
+func GetLockFilePath(devContainerOrigin string) string {
+	dir := filepath.Dir(devContainerOrigin)
+	if strings.HasPrefix(filepath.Base(devContainerOrigin), ".") {
+		return filepath.Join(dir, "."+DEVCONTAINER_LOCK_FILE_NAME)
+	}
+
+	return filepath.Join(dir, DEVCONTAINER_LOCK_FILE_NAME)
+}
+
+// ReadLockFile reads the lock file next to the given devcontainer.json. Returns nil if no lock file exists.
+func ReadLockFile(devContainerOrigin string) (*LockFile, error) {
+	if devContainerOrigin == "" {
+		return nil, nil
+	}
+
+	lockFilePath := GetLockFilePath(devContainerOrigin)
+	out, err := os.ReadFile(lockFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read lock file %w", err)
+	}
+
+	lockFile := &LockFile{}
+	err = json.Unmarshal(out, lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("parse lock file %s %w", lockFilePath, err)
+	}
+	if lockFile.Features == nil {
+		lockFile.Features = map[string]*LockedFeature{}
+	}
+
+	return lockFile, nil
+}
+
+// WriteLockFile writes the lock file next to the given devcontainer.json
+func WriteLockFile(devContainerOrigin string, lockFile *LockFile) error {
+	if devContainerOrigin == "" {
+		return fmt.Errorf("no origin in config")
+	}
+
+	out, err := json.MarshalIndent(lockFile, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(GetLockFilePath(devContainerOrigin), append(out, '\n'), 0644)
+}
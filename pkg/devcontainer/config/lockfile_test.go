@@ -0,0 +1,56 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetLockFilePath(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{origin: "/project/.devcontainer/devcontainer.json", want: "/project/.devcontainer/devcontainer-lock.json"},
+		{origin: "/project/.devcontainer.json", want: "/project/.devcontainer-lock.json"},
+	}
+	for _, tt := range tests {
+		if got := GetLockFilePath(filepath.FromSlash(tt.origin)); got != filepath.FromSlash(tt.want) {
+			t.Errorf("GetLockFilePath(%s) = %s, want %s", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestReadWriteLockFile(t *testing.T) {
+	origin := filepath.Join(t.TempDir(), "devcontainer.json")
+
+	lockFile, err := ReadLockFile(origin)
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	} else if lockFile != nil {
+		t.Fatalf("ReadLockFile() = %v, want nil for missing lock file", lockFile)
+	}
+
+	want := &LockFile{Features: map[string]*LockedFeature{
+		"ghcr.io/devcontainers/features/node:1": {
+			Version:   "1.6.1",
+			Resolved:  "ghcr.io/devcontainers/features/node@sha256:71590121aaf7b2b97b2bef4e4d1a9fb3e0c89da3a1b2f2a29e1b0c4a7fd7bb5e",
+			Integrity: "sha256:71590121aaf7b2b97b2bef4e4d1a9fb3e0c89da3a1b2f2a29e1b0c4a7fd7bb5e",
+			DependsOn: []string{"ghcr.io/devcontainers/features/common-utils"},
+		},
+	}}
+	if err := WriteLockFile(origin, want); err != nil {
+		t.Fatalf("WriteLockFile() error = %v", err)
+	}
+
+	got, err := ReadLockFile(origin)
+	if err != nil {
+		t.Fatalf("ReadLockFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLockFile() = %+v, want %+v", got, want)
+	}
+	if got.Get("ghcr.io/devcontainers/features/go:1") != nil {
+		t.Errorf("Get() returned an entry for a feature that is not locked")
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Values of PortAttribute.OnAutoForward
+const (
+	OnAutoForwardNotify          = "notify"
+	OnAutoForwardOpenBrowser     = "openBrowser"
+	OnAutoForwardOpenBrowserOnce = "openBrowserOnce"
+	OnAutoForwardOpenPreview     = "openPreview"
+	OnAutoForwardSilent          = "silent"
+	OnAutoForwardIgnore          = "ignore"
+)
+
+// PortStatus describes a port of the devcontainer that is either forwarded or has attributes
+type PortStatus struct {
+	// Port is the port number or port range
+	Port string `json:"port"`
+
+	// Label is the label of the port from portsAttributes
+	Label string `json:"label,omitempty"`
+
+	// OnAutoForward is the action taken when the port is discovered for automatic forwarding
+	OnAutoForward string `json:"onAutoForward,omitempty"`
+
+	// Protocol is the protocol of the port from portsAttributes
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// GetPortAttributes returns the attributes for the given port. Keys of portsAttributes are either a single
+// port like 3000 or an inclusive range like 5000-6000. If no key matches, otherPortsAttributes is returned
+// and explicit is false.
+func GetPortAttributes(portsAttributes map[string]PortAttribute, otherPortsAttributes *PortAttribute, port int) (attributes *PortAttribute, explicit bool) {
+	// exact matches take precedence over ranges
+	if attribute, ok := portsAttributes[strconv.Itoa(port)]; ok {
+		return &attribute, true
+	}
+
+	for key, attribute := range portsAttributes {
+		from, to, ok := strings.Cut(key, "-")
+		if !ok {
+			continue
+		}
+
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			continue
+		}
+		end, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			continue
+		}
+		if port >= start && port <= end {
+			return &attribute, true
+		}
+	}
+
+	if otherPortsAttributes != nil {
+		return otherPortsAttributes, false
+	}
+
+	return &PortAttribute{}, false
+}
+
+// GetPortsStatus returns the ports of forwardPorts and portsAttributes of the merged config sorted by port
+func GetPortsStatus(mergedConfig *MergedDevContainerConfig) []PortStatus {
+	ports := []string{}
+	for _, port := range mergedConfig.ForwardPorts {
+		// forwardPorts can be host:port
+		if i := strings.LastIndex(port, ":"); i >= 0 {
+			port = port[i+1:]
+		}
+		if !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	for port := range mergedConfig.PortsAttributes {
+		if !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	slices.SortFunc(ports, func(a, b string) int {
+		return portSortKey(a) - portSortKey(b)
+	})
+
+	retPorts := []PortStatus{}
+	for _, port := range ports {
+		attributes, ok := mergedConfig.PortsAttributes[port]
+		if !ok {
+			portNumber, err := strconv.Atoi(port)
+			if err == nil {
+				portAttributes, _ := GetPortAttributes(mergedConfig.PortsAttributes, mergedConfig.OtherPortsAttributes, portNumber)
+				attributes = *portAttributes
+			}
+		}
+
+		onAutoForward := attributes.OnAutoForward
+		if onAutoForward == "" {
+			onAutoForward = OnAutoForwardNotify
+		}
+		retPorts = append(retPorts, PortStatus{
+			Port:          port,
+			Label:         attributes.Label,
+			OnAutoForward: onAutoForward,
+			Protocol:      attributes.Protocol,
+		})
+	}
+
+	return retPorts
+}
+
+func portSortKey(port string) int {
+	start, _, _ := strings.Cut(port, "-")
+	number, _ := strconv.Atoi(strings.TrimSpace(start))
+	return number
+}
+
+// GetForwardSockets returns the glob patterns of unix sockets that should be forwarded automatically
+func GetForwardSockets(mergedConfig *MergedDevContainerConfig) []string {
+	if mergedConfig.Customizations == nil || mergedConfig.Customizations["devpod"] == nil {
+		return nil
+	}
+
+	var patterns []string
+	for _, customization := range mergedConfig.Customizations["devpod"] {
+		devPod := &DevPodCustomizations{}
+		err := Convert(customization, devPod)
+		if err != nil {
+			continue
+		}
+
+		for _, pattern := range devPod.ForwardSockets {
+			if contains(patterns, pattern) {
+				continue
+			}
+
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
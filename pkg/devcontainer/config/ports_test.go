@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestGetPortAttributes(t *testing.T) {
+	portsAttributes := map[string]PortAttribute{
+		"3000":      {Label: "exact"},
+		"5000-6000": {Label: "range"},
+		"5500":      {Label: "exact in range"},
+	}
+	tests := []struct {
+		name         string
+		other        *PortAttribute
+		port         int
+		wantLabel    string
+		wantExplicit bool
+	}{
+		{name: "exact match", port: 3000, wantLabel: "exact", wantExplicit: true},
+		{name: "range start", port: 5000, wantLabel: "range", wantExplicit: true},
+		{name: "range end", port: 6000, wantLabel: "range", wantExplicit: true},
+		{name: "exact match wins over range", port: 5500, wantLabel: "exact in range", wantExplicit: true},
+		{name: "no match", port: 8080, wantLabel: ""},
+		{name: "other ports attributes", other: &PortAttribute{Label: "other"}, port: 8080, wantLabel: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, explicit := GetPortAttributes(portsAttributes, tt.other, tt.port)
+			if got.Label != tt.wantLabel || explicit != tt.wantExplicit {
+				t.Errorf("GetPortAttributes() = %q, %t, want %q, %t", got.Label, explicit, tt.wantLabel, tt.wantExplicit)
+			}
+		})
+	}
+}
+
+func TestGetPortsStatus(t *testing.T) {
+	mergedConfig := &MergedDevContainerConfig{
+		DevContainerConfigBase: DevContainerConfigBase{
+			ForwardPorts: []string{"8080", "db:5432"},
+			PortsAttributes: map[string]PortAttribute{
+				"5432":      {Label: "Postgres", OnAutoForward: OnAutoForwardIgnore},
+				"3000-3010": {Label: "Web", OnAutoForward: OnAutoForwardOpenBrowser},
+			},
+		},
+	}
+
+	got := GetPortsStatus(mergedConfig)
+	want := []PortStatus{
+		{Port: "3000-3010", Label: "Web", OnAutoForward: OnAutoForwardOpenBrowser},
+		{Port: "5432", Label: "Postgres", OnAutoForward: OnAutoForwardIgnore},
+		{Port: "8080", OnAutoForward: OnAutoForwardNotify},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetPortsStatus() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetPortsStatus()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
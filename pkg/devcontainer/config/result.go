@@ -12,6 +12,9 @@ type Result struct {
 	MergedConfig               *MergedDevContainerConfig   `json:"MergedConfig"`
 	SubstitutionContext        *SubstitutionContext        `json:"SubstitutionContext"`
 	ContainerDetails           *ContainerDetails           `json:"ContainerDetails"`
+
+	// LifecycleHooks are the lifecycle hooks that ran during the setup of the container
+	LifecycleHooks []LifecycleHookStatus `json:"LifecycleHooks,omitempty"`
 }
 
 type DevContainerConfigWithPath struct {
@@ -139,6 +142,63 @@ func GetJetBrainsConfiguration(mergedConfig *MergedDevContainerConfig) *JetBrain
 	return retJetBrainsCustomizations
 }
 
+func GetSSHConfiguration(mergedConfig *MergedDevContainerConfig) *SSHCustomizations {
+	retSSHCustomizations := &SSHCustomizations{}
+	if mergedConfig.Customizations == nil || mergedConfig.Customizations["devpod"] == nil {
+		return retSSHCustomizations
+	}
+
+	for _, customization := range mergedConfig.Customizations["devpod"] {
+		devPod := &DevPodCustomizations{}
+		err := Convert(customization, devPod)
+		if err != nil || devPod.SSH == nil {
+			continue
+		}
+
+		retSSHCustomizations.AllowForwards = appendUnique(retSSHCustomizations.AllowForwards, devPod.SSH.AllowForwards)
+		retSSHCustomizations.DenyForwards = appendUnique(retSSHCustomizations.DenyForwards, devPod.SSH.DenyForwards)
+		retSSHCustomizations.AllowSockets = appendUnique(retSSHCustomizations.AllowSockets, devPod.SSH.AllowSockets)
+		retSSHCustomizations.DenySockets = appendUnique(retSSHCustomizations.DenySockets, devPod.SSH.DenySockets)
+		retSSHCustomizations.RecordSessions = retSSHCustomizations.RecordSessions || devPod.SSH.RecordSessions
+	}
+
+	return retSSHCustomizations
+}
+
+// GetLifecycleHookConcurrency returns how many named commands of a lifecycle hook may run in parallel,
+// zero means no limit
+func GetLifecycleHookConcurrency(mergedConfig *MergedDevContainerConfig) int {
+	concurrency := 0
+	if mergedConfig.Customizations == nil || mergedConfig.Customizations["devpod"] == nil {
+		return concurrency
+	}
+
+	for _, customization := range mergedConfig.Customizations["devpod"] {
+		devPod := &DevPodCustomizations{}
+		err := Convert(customization, devPod)
+		if err != nil || devPod.LifecycleHookConcurrency <= 0 {
+			continue
+		}
+
+		// the strictest limit wins
+		if concurrency == 0 || devPod.LifecycleHookConcurrency < concurrency {
+			concurrency = devPod.LifecycleHookConcurrency
+		}
+	}
+
+	return concurrency
+}
+
+func appendUnique(stack []string, values []string) []string {
+	for _, value := range values {
+		if !contains(stack, value) {
+			stack = append(stack, value)
+		}
+	}
+
+	return stack
+}
+
 func contains(stack []string, k string) bool {
 	return slices.Contains(stack, k)
 }
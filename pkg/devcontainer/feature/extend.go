@@ -3,9 +3,11 @@ package feature
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -228,33 +230,48 @@ func findContainerUsers(baseImageMetadata *config.ImageMetadataConfig, composeSe
 }
 
 func fetchFeatures(devContainerConfig *config.DevContainerConfig, log log.Logger, forceBuild bool) ([]*config.FeatureSet, error) {
-	processor := &featureProcessor{
-		devContainerConfig: devContainerConfig,
-		log:                log,
-		forceBuild:         forceBuild,
+	lockFile, err := config.ReadLockFile(devContainerConfig.Origin)
+	if err != nil {
+		return nil, err
 	}
 
-	userFeatures, err := getUserFeatures(processor, devContainerConfig)
+	processor := newFeatureProcessor(devContainerConfig, lockFile, log, forceBuild)
+	featureSets, err := processor.fetchFeatures()
 	if err != nil {
 		return nil, err
 	}
 
-	allFeatures, err := resolveDependencies(processor, userFeatures)
-	if err != nil {
-		return nil, fmt.Errorf("resolve dependencies %w", err)
+	// the lock file is only verified, devpod features lock updates it
+	if lockFile != nil {
+		for _, featureID := range slices.Sorted(maps.Keys(processor.resolved)) {
+			if lockFile.Get(featureID) == nil {
+				log.Warnf("Feature %s is not locked in %s, run 'devpod features lock' to add it", featureID, config.GetLockFilePath(devContainerConfig.Origin))
+			}
+		}
 	}
 
-	featureSets := make([]*config.FeatureSet, 0, len(allFeatures))
-	for _, featureSet := range allFeatures {
-		featureSets = append(featureSets, featureSet)
+	return featureSets, nil
+}
+
+// LockFeatures resolves the features of the given devcontainer.json and returns the resulting lock file.
+// If upgrade is true, an existing lock file is ignored and every feature is resolved to its latest matching version.
+func LockFeatures(devContainerConfig *config.DevContainerConfig, upgrade bool, log log.Logger) (*config.LockFile, error) {
+	var lockFile *config.LockFile
+	if !upgrade {
+		var err error
+		lockFile, err = config.ReadLockFile(devContainerConfig.Origin)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	featureSets, err = getSortedFeatureSets(devContainerConfig, featureSets)
+	processor := newFeatureProcessor(devContainerConfig, lockFile, log, upgrade)
+	_, err := processor.fetchFeatures()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get sorted feature sets %w", err)
+		return nil, err
 	}
 
-	return featureSets, nil
+	return processor.lockFile(), nil
 }
 
 func getUserFeatures(processor *featureProcessor, devContainerConfig *config.DevContainerConfig) (map[string]*config.FeatureSet, error) {
@@ -273,10 +290,52 @@ type featureProcessor struct {
 	devContainerConfig *config.DevContainerConfig
 	log                log.Logger
 	forceBuild         bool
+
+	// locked are the features pinned by the lock file, resolved are the lock entries of all processed features
+	locked   *config.LockFile
+	resolved map[string]*config.LockedFeature
+}
+
+func newFeatureProcessor(devContainerConfig *config.DevContainerConfig, lockFile *config.LockFile, log log.Logger, forceBuild bool) *featureProcessor {
+	return &featureProcessor{
+		devContainerConfig: devContainerConfig,
+		log:                log,
+		forceBuild:         forceBuild,
+		locked:             lockFile,
+		resolved:           map[string]*config.LockedFeature{},
+	}
+}
+
+func (p *featureProcessor) fetchFeatures() ([]*config.FeatureSet, error) {
+	userFeatures, err := getUserFeatures(p, p.devContainerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	allFeatures, err := resolveDependencies(p, userFeatures)
+	if err != nil {
+		return nil, fmt.Errorf("resolve dependencies %w", err)
+	}
+
+	featureSets := make([]*config.FeatureSet, 0, len(allFeatures))
+	for _, featureSet := range allFeatures {
+		featureSets = append(featureSets, featureSet)
+	}
+
+	featureSets, err = getSortedFeatureSets(p.devContainerConfig, featureSets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sorted feature sets %w", err)
+	}
+
+	return featureSets, nil
+}
+
+func (p *featureProcessor) lockFile() *config.LockFile {
+	return &config.LockFile{Features: p.resolved}
 }
 
 func (p *featureProcessor) processFeature(featureID string, featureOptions any) (*config.FeatureSet, error) {
-	featureFolder, err := ProcessFeatureID(featureID, p.devContainerConfig, p.log, p.forceBuild)
+	featureFolder, lockedFeature, err := processFeatureID(featureID, p.devContainerConfig, p.locked.Get(featureID), p.log, p.forceBuild)
 	if err != nil {
 		return nil, fmt.Errorf("process feature ID %s: %w", featureID, err)
 	}
@@ -287,6 +346,12 @@ func (p *featureProcessor) processFeature(featureID string, featureOptions any)
 		return nil, fmt.Errorf("parse feature %w", err)
 	}
 
+	if lockedFeature != nil {
+		lockedFeature.Version = featureConfig.Version
+		lockedFeature.DependsOn = slices.Sorted(maps.Keys(featureConfig.DependsOn))
+		p.resolved[featureID] = lockedFeature
+	}
+
 	return &config.FeatureSet{
 		ConfigID: normalizeFeatureID(featureID),
 		Folder:   featureFolder,
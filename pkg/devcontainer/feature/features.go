@@ -1,6 +1,11 @@
 package feature
 
 import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,6 +21,7 @@ import (
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/extract"
 	devpodhttp "github.com/skevetter/devpod/pkg/http"
@@ -25,6 +31,9 @@ import (
 
 const DEVCONTAINER_MANIFEST_MEDIATYPE = "application/vnd.devcontainers"
 
+// featureLockFileName holds the lock information of a cached OCI feature
+const featureLockFileName = "lock.json"
+
 var directTarballRegEx = regexp.MustCompile("devcontainer-feature-([a-zA-Z0-9_-]+).tgz")
 
 func getFeatureInstallWrapperScript(idWithoutVersion string, feature *config.FeatureConfig, options []string) string {
@@ -93,62 +102,89 @@ func escapeQuotesForShell(str string) string {
 }
 
 func ProcessFeatureID(id string, devContainerConfig *config.DevContainerConfig, log log.Logger, forceBuild bool) (string, error) {
+	featureFolder, _, err := processFeatureID(id, devContainerConfig, nil, log, forceBuild)
+	return featureFolder, err
+}
+
+// processFeatureID downloads the given feature and returns its folder together with the lock entry describing
+// the downloaded content. If locked is not nil, the feature is downloaded from the locked reference and its
+// integrity is verified. Local features are never locked.
+func processFeatureID(id string, devContainerConfig *config.DevContainerConfig, locked *config.LockedFeature, log log.Logger, forceBuild bool) (string, *config.LockedFeature, error) {
 	if strings.HasPrefix(id, "https://") || strings.HasPrefix(id, "http://") {
 		log.WithFields(logrus.Fields{"type": "url", "id": id}).Debug("process feature")
-		return processDirectTarFeature(id, config.GetDevPodCustomizations(devContainerConfig).FeatureDownloadHTTPHeaders, log, forceBuild)
+		return processDirectTarFeature(id, config.GetDevPodCustomizations(devContainerConfig).FeatureDownloadHTTPHeaders, locked, log, forceBuild)
 	} else if strings.HasPrefix(id, "./") || strings.HasPrefix(id, "../") {
 		log.WithFields(logrus.Fields{"type": "local", "id": id}).Debug("process feature")
-		return filepath.Abs(path.Join(filepath.ToSlash(filepath.Dir(devContainerConfig.Origin)), id))
+		featureFolder, err := filepath.Abs(path.Join(filepath.ToSlash(filepath.Dir(devContainerConfig.Origin)), id))
+		return featureFolder, nil, err
 	}
 
 	// get oci feature
 	log.WithFields(logrus.Fields{"type": "oci", "id": id}).Debug("process feature")
-	return processOCIFeature(id, log)
+	return processOCIFeature(id, locked, log, forceBuild)
 }
 
-func processOCIFeature(id string, log log.Logger) (string, error) {
-	log.WithFields(logrus.Fields{"featureId": id}).Debug("processing OCI feature")
+func processOCIFeature(id string, locked *config.LockedFeature, log log.Logger, forceDownload bool) (string, *config.LockedFeature, error) {
+	log.WithFields(logrus.Fields{"featureId": id, "forceDownload": forceDownload}).Debug("processing OCI feature")
+
+	// pull the exact digest if the feature is locked
+	reference := lockedReference(id, locked)
+	if reference != id {
+		log.WithFields(logrus.Fields{"featureId": id, "resolved": reference}).Debug("using locked feature reference")
+	}
 
 	// feature already exists?
-	featureFolder := getFeaturesTempFolder(id)
+	featureFolder := getFeaturesCacheFolder(reference)
 	featureExtractedFolder := filepath.Join(featureFolder, "extracted")
 	_, err := os.Stat(featureExtractedFolder)
-	if err == nil {
+	if err == nil && forceDownload && reference == id && !cache.Offline() {
+		// only refresh tags, digests can't change
+		_ = os.RemoveAll(featureFolder)
+	} else if err == nil {
 		// make sure feature.json is there as well
 		_, err = os.Stat(filepath.Join(featureExtractedFolder, config.DEVCONTAINER_FEATURE_FILE_NAME))
 		if err == nil {
-			log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("feature already cached")
-			return featureExtractedFolder, nil
+			cachedLock, err := readFeatureLock(featureFolder)
+			if err == nil && verifyIntegrity(locked, cachedLock.Integrity) == nil {
+				log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("feature already cached")
+				return featureExtractedFolder, cachedLock, nil
+			}
+
+			log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("cached feature has no matching lock information")
 		} else {
 			log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("feature folder exists but seems empty")
-			_ = os.RemoveAll(featureFolder)
 		}
+		_ = os.RemoveAll(featureFolder)
 	}
 
-	ref, err := name.ParseReference(id)
+	ref, err := name.ParseReference(reference)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "featureId": id}).Error("failed to parse OCI reference")
-		return "", err
+		return "", nil, err
 	}
 
-	log.WithFields(logrus.Fields{"reference": ref.String()}).Debug("fetching OCI image")
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	destFile := filepath.Join(featureFolder, "feature.tgz")
+	digest, err := pullOCIArtifact(ref, destFile, log)
 	if err != nil {
-		log.WithFields(logrus.Fields{"error": err, "reference": ref.String()}).Error("failed to fetch OCI image")
-		return "", err
+		log.WithFields(logrus.Fields{"error": err, "featureId": id}).Error("failed to download feature layer")
+		return "", nil, err
 	}
 
-	destFile := filepath.Join(featureFolder, "feature.tgz")
-	err = downloadLayer(img, id, destFile, log)
+	// the integrity of OCI features is the digest of their manifest
+	lockedFeature := &config.LockedFeature{
+		Resolved:  ref.Context().Name() + "@" + digest,
+		Integrity: digest,
+	}
+	err = verifyIntegrity(locked, lockedFeature.Integrity)
 	if err != nil {
-		log.WithFields(logrus.Fields{"error": err, "featureId": id}).Error("failed to download feature layer")
-		return "", err
+		_ = os.RemoveAll(featureFolder)
+		return "", nil, fmt.Errorf("feature %s %w", id, err)
 	}
 
 	file, err := os.Open(destFile)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "file": destFile}).Error("failed to open downloaded feature file")
-		return "", err
+		return "", nil, err
 	}
 	defer func() { _ = file.Close() }()
 
@@ -157,11 +193,79 @@ func processOCIFeature(id string, log log.Logger) (string, error) {
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "destination": featureExtractedFolder}).Error("failed to extract feature")
 		_ = os.RemoveAll(featureExtractedFolder)
-		return "", err
+		return "", nil, err
+	}
+
+	err = writeFeatureLock(featureFolder, lockedFeature)
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err, "folder": featureFolder}).Debug("failed to cache feature lock information")
 	}
 
 	log.WithFields(logrus.Fields{"featureId": id, "path": featureExtractedFolder}).Info("OCI feature processed successfully")
-	return featureExtractedFolder, nil
+	return featureExtractedFolder, lockedFeature, nil
+}
+
+// PullOCIArtifact downloads the content of a devcontainer OCI artifact, such as a feature, a template or
+// the metadata of a collection, into destFile
+func PullOCIArtifact(reference, destFile string, log log.Logger) error {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return err
+	}
+
+	_, err = pullOCIArtifact(ref, destFile, log)
+	return err
+}
+
+// lockedReference returns the reference to pull the feature from. A locked feature is pulled by the digest of
+// its manifest, either from the resolved reference or the integrity of the lock.
+func lockedReference(id string, locked *config.LockedFeature) string {
+	if locked == nil {
+		return id
+	}
+
+	reference := id
+	if locked.Resolved != "" {
+		reference = locked.Resolved
+	}
+
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return reference
+	} else if _, ok := ref.(name.Digest); !ok && strings.HasPrefix(locked.Integrity, "sha256:") {
+		return ref.Context().Digest(locked.Integrity).String()
+	}
+
+	return reference
+}
+
+// pullOCIArtifact downloads the first layer of the artifact and returns the digest of its manifest. The
+// downloaded content is verified against the digests of the manifest.
+func pullOCIArtifact(ref name.Reference, destFile string, log log.Logger) (string, error) {
+	if cache.Offline() {
+		return "", cache.OfflineError(ref.String())
+	}
+
+	log.WithFields(logrus.Fields{"reference": ref.String()}).Debug("fetching OCI image")
+	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		log.WithFields(logrus.Fields{"error": err, "reference": ref.String()}).Error("failed to fetch OCI image")
+		return "", err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("get digest of %s %w", ref.String(), err)
+	} else if refDigest, ok := ref.(name.Digest); ok && refDigest.DigestStr() != digest.String() {
+		return "", fmt.Errorf("digest of %s doesn't match, got %s", ref.String(), digest.String())
+	}
+
+	err = downloadLayer(img, ref.String(), destFile, log)
+	if err != nil {
+		return "", err
+	}
+
+	return digest.String(), nil
 }
 
 func downloadLayer(img v1.Image, id, destFile string, log log.Logger) error {
@@ -185,11 +289,25 @@ func downloadLayer(img v1.Image, id, destFile string, log log.Logger) error {
 		return fmt.Errorf("retrieve layer %w", err)
 	}
 
-	data, err := layer.Uncompressed()
+	compressed, err := layer.Compressed()
 	if err != nil {
 		return fmt.Errorf("download %w", err)
 	}
-	defer func() { _ = data.Close() }()
+	defer func() { _ = compressed.Close() }()
+
+	// hash what is actually downloaded, so the content is verified against the digest of the manifest
+	h := sha256.New()
+	hashed := bufio.NewReader(io.TeeReader(compressed, h))
+	var data io.Reader = hashed
+	if magic, err := hashed.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(hashed)
+		if err != nil {
+			return fmt.Errorf("decompress layer %w", err)
+		}
+		defer func() { _ = gzipReader.Close() }()
+
+		data = gzipReader
+	}
 
 	err = os.MkdirAll(filepath.Dir(destFile), 0755)
 	if err != nil {
@@ -206,41 +324,66 @@ func downloadLayer(img v1.Image, id, destFile string, log log.Logger) error {
 	if err != nil {
 		return fmt.Errorf("download layer %w", err)
 	}
+	_, err = io.Copy(io.Discard, hashed)
+	if err != nil {
+		return fmt.Errorf("download layer %w", err)
+	}
+
+	digest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+	if digest != manifest.Layers[0].Digest.String() {
+		return fmt.Errorf("digest of layer %s doesn't match, got %s", manifest.Layers[0].Digest.String(), digest)
+	}
 
 	return nil
 }
 
-func processDirectTarFeature(id string, httpHeaders map[string]string, log log.Logger, forceDownload bool) (string, error) {
+func processDirectTarFeature(id string, httpHeaders map[string]string, locked *config.LockedFeature, log log.Logger, forceDownload bool) (string, *config.LockedFeature, error) {
 	log.WithFields(logrus.Fields{"featureId": id, "forceDownload": forceDownload}).Debug("processing direct tar feature")
 
 	downloadBase := id[strings.LastIndex(id, "/"):]
 	if !directTarballRegEx.MatchString(downloadBase) {
 		log.WithFields(logrus.Fields{"filename": downloadBase}).Error("invalid tarball filename format")
-		return "", fmt.Errorf("expected tarball name to follow 'devcontainer-feature-<feature-id>.tgz' format.  Received '%s' ", downloadBase)
+		return "", nil, fmt.Errorf("expected tarball name to follow 'devcontainer-feature-<feature-id>.tgz' format.  Received '%s' ", downloadBase)
 	}
 
 	// feature already exists?
-	featureFolder := getFeaturesTempFolder(id)
+	featureFolder := getFeaturesCacheFolder(id)
 	featureExtractedFolder := filepath.Join(featureFolder, "extracted")
+	downloadFile := filepath.Join(featureFolder, "feature.tgz")
 	_, err := os.Stat(featureExtractedFolder)
-	if err == nil && !forceDownload {
-		log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("direct tar feature already cached")
-		return featureExtractedFolder, nil
+	if err == nil && (!forceDownload || cache.Offline()) {
+		integrity, err := fileIntegrity(downloadFile)
+		if err == nil && verifyIntegrity(locked, integrity) == nil {
+			log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("direct tar feature already cached")
+			return featureExtractedFolder, &config.LockedFeature{Resolved: id, Integrity: integrity}, nil
+		}
+
+		log.WithFields(logrus.Fields{"folder": featureExtractedFolder}).Debug("cached direct tar feature does not match lock")
+		_ = os.RemoveAll(featureExtractedFolder)
 	}
 
 	// download feature tarball
-	downloadFile := filepath.Join(featureFolder, "feature.tgz")
 	err = downloadFeatureFromURL(id, downloadFile, httpHeaders, log)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "url": id}).Error("failed to download feature tarball")
-		return "", err
+		return "", nil, err
+	}
+
+	integrity, err := fileIntegrity(downloadFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("hash feature tarball %w", err)
+	}
+	err = verifyIntegrity(locked, integrity)
+	if err != nil {
+		_ = os.RemoveAll(featureFolder)
+		return "", nil, fmt.Errorf("feature %s %w", id, err)
 	}
 
 	// extract file
 	file, err := os.Open(downloadFile)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "file": downloadFile}).Error("failed to open downloaded tarball")
-		return "", err
+		return "", nil, err
 	}
 	defer func() { _ = file.Close() }()
 
@@ -249,14 +392,18 @@ func processDirectTarFeature(id string, httpHeaders map[string]string, log log.L
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err, "destination": featureExtractedFolder}).Error("failed to extract tarball")
 		_ = os.RemoveAll(featureExtractedFolder)
-		return "", fmt.Errorf("extract folder %w", err)
+		return "", nil, fmt.Errorf("extract folder %w", err)
 	}
 
 	log.WithFields(logrus.Fields{"featureId": id, "path": featureExtractedFolder}).Info("Direct tar feature processed successfully")
-	return featureExtractedFolder, nil
+	return featureExtractedFolder, &config.LockedFeature{Resolved: id, Integrity: integrity}, nil
 }
 
 func downloadFeatureFromURL(url string, destFile string, httpHeaders map[string]string, log log.Logger) error {
+	if cache.Offline() {
+		return cache.OfflineError(url)
+	}
+
 	log.WithFields(logrus.Fields{"url": url, "destFile": destFile}).Debug("starting feature download")
 
 	err := os.MkdirAll(filepath.Dir(destFile), 0755)
@@ -323,7 +470,56 @@ func tryDownload(url, destFile string, httpHeaders map[string]string) error {
 	return nil
 }
 
-func getFeaturesTempFolder(id string) string {
+// verifyIntegrity returns an error if the given integrity does not match the locked feature
+func verifyIntegrity(locked *config.LockedFeature, integrity string) error {
+	if locked == nil || locked.Integrity == "" || locked.Integrity == integrity {
+		return nil
+	}
+
+	return fmt.Errorf("integrity check failed: expected %s, got %s", locked.Integrity, integrity)
+}
+
+func fileIntegrity(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readFeatureLock(featureFolder string) (*config.LockedFeature, error) {
+	out, err := os.ReadFile(filepath.Join(featureFolder, featureLockFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	lockedFeature := &config.LockedFeature{}
+	err = json.Unmarshal(out, lockedFeature)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockedFeature, nil
+}
+
+func writeFeatureLock(featureFolder string, lockedFeature *config.LockedFeature) error {
+	out, err := json.Marshal(lockedFeature)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(featureFolder, featureLockFileName), out, 0644)
+}
+
+func getFeaturesCacheFolder(id string) string {
 	hashedID := hash.String(id)[:10]
-	return filepath.Join(os.TempDir(), "devpod", "features", hashedID)
+	return filepath.Join(cache.Folder(cache.FeaturesFolder), hashedID)
 }
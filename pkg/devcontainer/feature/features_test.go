@@ -0,0 +1,152 @@
+package feature
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/skevetter/devpod/pkg/cache"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/log"
+	"github.com/stretchr/testify/suite"
+)
+
+type FeaturesTestSuite struct {
+	suite.Suite
+}
+
+func TestFeaturesTestSuite(t *testing.T) {
+	suite.Run(t, new(FeaturesTestSuite))
+}
+
+func (suite *FeaturesTestSuite) SetupTest() {
+	suite.T().Setenv(cache.DEVPOD_CACHE_DIR, suite.T().TempDir())
+}
+
+func (suite *FeaturesTestSuite) TestOfflineOCIFeature() {
+	suite.T().Setenv(cache.DEVPOD_OFFLINE, "true")
+
+	_, _, err := processOCIFeature("ghcr.io/devcontainers/features/go:1", nil, log.Discard, false)
+	suite.True(errors.Is(err, cache.ErrOffline))
+}
+
+func (suite *FeaturesTestSuite) TestOfflineDirectTarFeature() {
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), folder, "color", "1.0.0")
+	tarball := &bytes.Buffer{}
+	suite.Require().NoError(extract.WriteTar(tarball, filepath.Join(folder, "color"), true))
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(tarball.Bytes())
+	}))
+	defer server.Close()
+	id := server.URL + "/devcontainer-feature-color.tgz"
+
+	suite.T().Setenv(cache.DEVPOD_OFFLINE, "true")
+	_, _, err := processDirectTarFeature(id, nil, nil, log.Discard, false)
+	suite.True(errors.Is(err, cache.ErrOffline))
+	suite.Equal(0, requests)
+
+	// warm the cache
+	suite.T().Setenv(cache.DEVPOD_OFFLINE, "")
+	_, _, err = processDirectTarFeature(id, nil, nil, log.Discard, false)
+	suite.Require().NoError(err)
+	suite.Equal(1, requests)
+
+	// offline uses the cache, even if a download is forced
+	suite.T().Setenv(cache.DEVPOD_OFFLINE, "true")
+	featureFolder, _, err := processDirectTarFeature(id, nil, nil, log.Discard, true)
+	suite.Require().NoError(err)
+	suite.FileExists(filepath.Join(featureFolder, "install.sh"))
+	suite.Equal(1, requests)
+}
+
+func (suite *FeaturesTestSuite) TestLockFeatures() {
+	server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+	defer server.Close()
+	namespace := strings.TrimPrefix(server.URL, "http://") + "/my-org/features"
+
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), folder, "color", "1.0.0")
+	suite.Require().NoError(PublishFeatures(folder, namespace, log.Discard))
+
+	workspace := suite.T().TempDir()
+	devContainerConfig := &config.DevContainerConfig{
+		Origin: filepath.Join(workspace, ".devcontainer", "devcontainer.json"),
+	}
+	devContainerConfig.Features = map[string]any{namespace + "/color:1": map[string]any{}}
+	lockFile, err := LockFeatures(devContainerConfig, false, log.Discard)
+	suite.Require().NoError(err)
+
+	// the integrity is the digest of the manifest
+	lockedFeature := lockFile.Get(namespace + "/color:1")
+	suite.Require().NotNil(lockedFeature)
+	suite.Equal("1.0.0", lockedFeature.Version)
+	suite.True(strings.HasPrefix(lockedFeature.Integrity, "sha256:"))
+	suite.Equal(namespace+"/color@"+lockedFeature.Integrity, lockedFeature.Resolved)
+
+	// up only verifies the lock file
+	suite.Require().NoError(os.MkdirAll(filepath.Dir(devContainerConfig.Origin), 0755))
+	suite.Require().NoError(config.WriteLockFile(devContainerConfig.Origin, &config.LockFile{Features: map[string]*config.LockedFeature{}}))
+	content, err := os.ReadFile(config.GetLockFilePath(devContainerConfig.Origin))
+	suite.Require().NoError(err)
+	_, err = fetchFeatures(devContainerConfig, log.Discard, false)
+	suite.Require().NoError(err)
+	after, err := os.ReadFile(config.GetLockFilePath(devContainerConfig.Origin))
+	suite.Require().NoError(err)
+	suite.Equal(string(content), string(after))
+
+	suite.Require().NoError(config.WriteLockFile(devContainerConfig.Origin, lockFile))
+	_, err = fetchFeatures(devContainerConfig, log.Discard, false)
+	suite.Require().NoError(err)
+
+	// a locked feature is pulled by its digest, even if the tag moved
+	writeTestFeature(suite.T(), folder, "color", "1.1.0")
+	suite.Require().NoError(PublishFeatures(folder, namespace, log.Discard))
+	featureFolder, locked, err := processOCIFeature(namespace+"/color:1", &config.LockedFeature{Integrity: lockedFeature.Integrity}, log.Discard, true)
+	suite.Require().NoError(err)
+	suite.Equal(lockedFeature.Integrity, locked.Integrity)
+	featureConfig, err := config.ParseDevContainerFeature(featureFolder)
+	suite.Require().NoError(err)
+	suite.Equal("1.0.0", featureConfig.Version)
+
+	// a changed feature fails the integrity check, even if it is cached
+	lockedFeature.Integrity = "sha256:0000"
+	suite.Require().NoError(config.WriteLockFile(devContainerConfig.Origin, lockFile))
+	_, err = fetchFeatures(devContainerConfig, log.Discard, false)
+	suite.ErrorContains(err, "integrity check failed")
+}
+
+func (suite *FeaturesTestSuite) TestDirectTarFeatureIntegrityMismatch() {
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), folder, "color", "1.0.0")
+	tarball := &bytes.Buffer{}
+	suite.Require().NoError(extract.WriteTar(tarball, filepath.Join(folder, "color"), true))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(tarball.Bytes())
+	}))
+	defer server.Close()
+	id := server.URL + "/devcontainer-feature-color.tgz"
+
+	_, lockedFeature, err := processDirectTarFeature(id, nil, nil, log.Discard, false)
+	suite.Require().NoError(err)
+	suite.Equal(id, lockedFeature.Resolved)
+
+	_, _, err = processDirectTarFeature(id, nil, &config.LockedFeature{Resolved: id, Integrity: lockedFeature.Integrity}, log.Discard, true)
+	suite.Require().NoError(err)
+
+	_, _, err = processDirectTarFeature(id, nil, &config.LockedFeature{Resolved: id, Integrity: "sha256:0000"}, log.Discard, true)
+	suite.ErrorContains(err, "integrity check failed")
+}
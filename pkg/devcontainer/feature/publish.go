@@ -0,0 +1,386 @@
+package feature
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/log"
+)
+
+const (
+	// DEVCONTAINER_FEATURE_LAYER_MEDIATYPE is the media type of the layer holding the feature tarball
+	DEVCONTAINER_FEATURE_LAYER_MEDIATYPE = "application/vnd.devcontainers.layer.v1+tar"
+
+	// DEVCONTAINER_COLLECTION_LAYER_MEDIATYPE is the media type of the layer holding the collection metadata
+	DEVCONTAINER_COLLECTION_LAYER_MEDIATYPE = "application/vnd.devcontainers.collection.layer.v1+json"
+
+	// DEVCONTAINER_COLLECTION_FILE_NAME is the metadata of a collection of features or templates
+	DEVCONTAINER_COLLECTION_FILE_NAME = "devcontainer-collection.json"
+
+	annotationTitle       = "org.opencontainers.image.title"
+	annotationPackageType = "com.github.package.type"
+	annotationMetadata    = "dev.containers.metadata"
+)
+
+// Collection is the devcontainer-collection.json published next to the features of a namespace
+type Collection struct {
+	SourceInformation map[string]string       `json:"sourceInformation,omitempty"`
+	Features          []*config.FeatureConfig `json:"features"`
+}
+
+// PackagedFeature is a feature tarball written by PackageFeatures
+type PackagedFeature struct {
+	Config  *config.FeatureConfig
+	Tarball string
+}
+
+// FindFeatures returns the folders of the features in the given folder. The folder can be a single feature,
+// the src folder of a collection or the collection itself.
+func FindFeatures(folder string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(folder, config.DEVCONTAINER_FEATURE_FILE_NAME)); err == nil {
+		return []string{folder}, nil
+	}
+	if stat, err := os.Stat(filepath.Join(folder, "src")); err == nil && stat.IsDir() {
+		folder = filepath.Join(folder, "src")
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	featureFolders := []string{}
+	for _, entry := range entries {
+		featureFolder := filepath.Join(folder, entry.Name())
+		if !entry.IsDir() {
+			continue
+		} else if _, err := os.Stat(filepath.Join(featureFolder, config.DEVCONTAINER_FEATURE_FILE_NAME)); err != nil {
+			continue
+		}
+
+		featureFolders = append(featureFolders, featureFolder)
+	}
+	if len(featureFolders) == 0 {
+		return nil, fmt.Errorf("couldn't find any %s in %s", config.DEVCONTAINER_FEATURE_FILE_NAME, folder)
+	}
+
+	return featureFolders, nil
+}
+
+// PackageFeatures writes a devcontainer-feature-<id>.tgz for every feature in the folder together with the
+// devcontainer-collection.json into the output folder
+func PackageFeatures(folder, outputFolder string, log log.Logger) ([]*PackagedFeature, error) {
+	featureFolders, err := FindFeatures(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(outputFolder, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	packaged := []*PackagedFeature{}
+	collection := &Collection{SourceInformation: map[string]string{"source": "devpod"}}
+	for _, featureFolder := range featureFolders {
+		featureConfig, err := validateFeature(featureFolder)
+		if err != nil {
+			return nil, err
+		}
+
+		tarball := filepath.Join(outputFolder, "devcontainer-feature-"+featureConfig.ID+".tgz")
+		err = writeFeatureTarball(featureFolder, tarball)
+		if err != nil {
+			return nil, fmt.Errorf("package feature %s %w", featureConfig.ID, err)
+		}
+
+		log.WithFields(logrus.Fields{"feature": featureConfig.ID, "version": featureConfig.Version}).Debug("packaged feature")
+		packaged = append(packaged, &PackagedFeature{Config: featureConfig, Tarball: tarball})
+		collection.Features = append(collection.Features, featureConfig)
+	}
+
+	out, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(outputFolder, DEVCONTAINER_COLLECTION_FILE_NAME), out, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return packaged, nil
+}
+
+// PublishFeatures packages the features in the folder and pushes them as OCI artifacts to
+// <namespace>/<id>, tagged with their version. The features are merged into the collection metadata at
+// <namespace>:latest, so features published from other folders are kept.
+func PublishFeatures(folder, namespace string, log log.Logger) error {
+	outputFolder, err := os.MkdirTemp("", "devpod-features-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(outputFolder) }()
+
+	packaged, err := PackageFeatures(folder, outputFolder, log)
+	if err != nil {
+		return err
+	}
+
+	namespace = strings.TrimSuffix(namespace, "/")
+	for _, packagedFeature := range packaged {
+		repository, err := name.NewRepository(namespace + "/" + packagedFeature.Config.ID)
+		if err != nil {
+			return err
+		}
+
+		published, err := listTags(repository)
+		if err != nil {
+			return fmt.Errorf("list tags of %s %w", repository.String(), err)
+		}
+
+		tags, err := tagsToPublish(packagedFeature.Config.Version, published)
+		if err != nil {
+			return fmt.Errorf("feature %s %w", packagedFeature.Config.ID, err)
+		} else if len(tags) == 0 {
+			log.Infof("Skip %s, version %s is already published", repository.String(), packagedFeature.Config.Version)
+			continue
+		}
+
+		content, err := os.ReadFile(packagedFeature.Tarball)
+		if err != nil {
+			return err
+		}
+		metadata, err := json.Marshal(packagedFeature.Config)
+		if err != nil {
+			return err
+		}
+
+		err = pushOCIArtifact(repository, tags, content, DEVCONTAINER_FEATURE_LAYER_MEDIATYPE, filepath.Base(packagedFeature.Tarball), map[string]string{
+			annotationPackageType: "devcontainer_feature",
+			annotationMetadata:    string(metadata),
+		})
+		if err != nil {
+			return fmt.Errorf("push feature %s %w", packagedFeature.Config.ID, err)
+		}
+
+		log.Donef("Published %s:%s", repository.String(), strings.Join(tags, ", "))
+	}
+
+	repository, err := name.NewRepository(namespace)
+	if err != nil {
+		return err
+	}
+	collection, err := mergeCollection(repository, packaged)
+	if err != nil {
+		return err
+	}
+	err = pushOCIArtifact(repository, []string{"latest"}, collection, DEVCONTAINER_COLLECTION_LAYER_MEDIATYPE, DEVCONTAINER_COLLECTION_FILE_NAME, map[string]string{
+		annotationPackageType: "devcontainer_collection",
+	})
+	if err != nil {
+		return fmt.Errorf("push collection metadata %w", err)
+	}
+
+	log.Donef("Published collection metadata to %s:latest", repository.String())
+	return nil
+}
+
+// mergeCollection adds the packaged features to the collection metadata published at <namespace>:latest,
+// features with the same id are replaced
+func mergeCollection(repository name.Repository, packaged []*PackagedFeature) ([]byte, error) {
+	collection, err := pullCollection(repository)
+	if err != nil {
+		return nil, fmt.Errorf("pull collection metadata %w", err)
+	}
+
+	for _, packagedFeature := range packaged {
+		index := slices.IndexFunc(collection.Features, func(featureConfig *config.FeatureConfig) bool {
+			return featureConfig.ID == packagedFeature.Config.ID
+		})
+		if index >= 0 {
+			collection.Features[index] = packagedFeature.Config
+		} else {
+			collection.Features = append(collection.Features, packagedFeature.Config)
+		}
+	}
+
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+// pullCollection returns the collection metadata published at <namespace>:latest, which is empty if
+// nothing was published yet
+func pullCollection(repository name.Repository) (*Collection, error) {
+	collection := &Collection{SourceInformation: map[string]string{"source": "devpod"}, Features: []*config.FeatureConfig{}}
+	img, err := remote.Image(repository.Tag("latest"), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		if isNotFound(err) {
+			return collection, nil
+		}
+
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	} else if len(layers) == 0 {
+		return nil, fmt.Errorf("%s:latest has no layers", repository.String())
+	}
+
+	reader, err := layers[0].Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	err = json.NewDecoder(reader).Decode(collection)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s %w", DEVCONTAINER_COLLECTION_FILE_NAME, err)
+	}
+
+	return collection, nil
+}
+
+// validateFeature parses the devcontainer-feature.json and checks the feature can be published
+func validateFeature(featureFolder string) (*config.FeatureConfig, error) {
+	featureConfig, err := config.ParseDevContainerFeature(featureFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	if featureConfig.ID != filepath.Base(featureFolder) {
+		return nil, fmt.Errorf("feature id %s doesn't match its folder %s", featureConfig.ID, featureFolder)
+	} else if _, err := semver.Parse(featureConfig.Version); err != nil {
+		return nil, fmt.Errorf("feature %s has an invalid version %s: %w", featureConfig.ID, featureConfig.Version, err)
+	} else if _, err := os.Stat(filepath.Join(featureFolder, "install.sh")); err != nil {
+		return nil, fmt.Errorf("feature %s has no install.sh", featureConfig.ID)
+	}
+
+	return featureConfig, nil
+}
+
+func writeFeatureTarball(featureFolder, tarball string) error {
+	file, err := os.Create(tarball)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	// the layer media type of features is a plain tar
+	return extract.WriteTar(file, featureFolder, false)
+}
+
+// tagsToPublish returns the tags for a new version: the version itself and the major, minor and latest tags
+// if no greater version was published for them. It returns no tags if the version is already published.
+func tagsToPublish(version string, published []string) ([]string, error) {
+	newVersion, err := semver.Parse(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %s: %w", version, err)
+	} else if slices.Contains(published, newVersion.String()) {
+		return nil, nil
+	}
+
+	major := fmt.Sprintf("%d", newVersion.Major)
+	minor := fmt.Sprintf("%d.%d", newVersion.Major, newVersion.Minor)
+	tags := []string{newVersion.String()}
+	if len(newVersion.Pre) > 0 {
+		return tags, nil
+	}
+
+	latestMajor, latestMinor, latest := true, true, true
+	for _, tag := range published {
+		publishedVersion, err := semver.Parse(tag)
+		if err != nil || publishedVersion.LTE(newVersion) {
+			continue
+		}
+
+		latest = false
+		if publishedVersion.Major == newVersion.Major {
+			latestMajor = false
+			if publishedVersion.Minor == newVersion.Minor {
+				latestMinor = false
+			}
+		}
+	}
+
+	if latestMinor {
+		tags = append(tags, minor)
+	}
+	if latestMajor {
+		tags = append(tags, major)
+	}
+	if latest {
+		tags = append(tags, "latest")
+	}
+
+	return tags, nil
+}
+
+// listTags returns the tags of the repository, a repository that doesn't exist yet has no tags
+func listTags(repository name.Repository) ([]string, error) {
+	tags, err := remote.List(repository, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// pushOCIArtifact pushes the content as single layer artifact with the devcontainer config media type
+func pushOCIArtifact(repository name.Repository, tags []string, content []byte, layerMediaType, title string, annotations map[string]string) error {
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, DEVCONTAINER_MANIFEST_MEDIATYPE)
+	img, err := mutate.Append(img, mutate.Addendum{
+		Layer:       static.NewLayer(content, types.MediaType(layerMediaType)),
+		Annotations: map[string]string{annotationTitle: title},
+	})
+	if err != nil {
+		return err
+	}
+	img, ok := mutate.Annotations(img, annotations).(v1.Image)
+	if !ok {
+		return fmt.Errorf("annotate artifact")
+	}
+
+	for i, tag := range tags {
+		ref := repository.Tag(tag)
+		if i == 0 {
+			err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+		} else {
+			err = remote.Tag(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+		}
+		if err != nil {
+			return fmt.Errorf("push %s %w", ref.String(), err)
+		}
+	}
+
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,162 @@
+package feature
+
+import (
+	"encoding/json"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/log"
+	"github.com/stretchr/testify/suite"
+)
+
+type PublishTestSuite struct {
+	suite.Suite
+}
+
+func TestPublishTestSuite(t *testing.T) {
+	suite.Run(t, new(PublishTestSuite))
+}
+
+func (suite *PublishTestSuite) TestTagsToPublish() {
+	tests := []struct {
+		name      string
+		version   string
+		published []string
+		expected  []string
+	}{
+		{name: "first version", version: "1.0.0", expected: []string{"1.0.0", "1.0", "1", "latest"}},
+		{name: "already published", version: "1.0.0", published: []string{"1.0.0", "1.0", "1", "latest"}, expected: nil},
+		{name: "patch of old major", version: "1.2.1", published: []string{"1.2.0", "2.0.0"}, expected: []string{"1.2.1", "1.2", "1"}},
+		{name: "patch of old minor", version: "1.1.5", published: []string{"1.1.4", "1.2.0"}, expected: []string{"1.1.5", "1.1"}},
+		{name: "pre-release", version: "2.0.0-beta.1", published: []string{"1.0.0"}, expected: []string{"2.0.0-beta.1"}},
+	}
+
+	for _, test := range tests {
+		tags, err := tagsToPublish(test.version, test.published)
+		suite.NoError(err, test.name)
+		suite.Equal(test.expected, tags, test.name)
+	}
+
+	_, err := tagsToPublish("latest", nil)
+	suite.Error(err)
+}
+
+func (suite *PublishTestSuite) TestPackageFeatures() {
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), folder, "color", "1.0.0")
+	writeTestFeature(suite.T(), folder, "hello", "2.1.0")
+
+	outputFolder := filepath.Join(suite.T().TempDir(), "output")
+	packaged, err := PackageFeatures(folder, outputFolder, log.Discard)
+	suite.Require().NoError(err)
+	suite.Len(packaged, 2)
+
+	// the layer media type of features is a plain tar
+	content, err := os.ReadFile(filepath.Join(outputFolder, "devcontainer-feature-color.tgz"))
+	suite.Require().NoError(err)
+	suite.NotEqual([]byte{0x1f, 0x8b}, content[:2])
+
+	extracted := suite.T().TempDir()
+	file, err := os.Open(filepath.Join(outputFolder, "devcontainer-feature-color.tgz"))
+	suite.Require().NoError(err)
+	defer func() { _ = file.Close() }()
+	suite.Require().NoError(extract.Extract(file, extracted))
+	featureConfig, err := config.ParseDevContainerFeature(extracted)
+	suite.Require().NoError(err)
+	suite.Equal("color", featureConfig.ID)
+	suite.FileExists(filepath.Join(extracted, "install.sh"))
+
+	out, err := os.ReadFile(filepath.Join(outputFolder, DEVCONTAINER_COLLECTION_FILE_NAME))
+	suite.Require().NoError(err)
+	collection := &Collection{}
+	suite.Require().NoError(json.Unmarshal(out, collection))
+	suite.Len(collection.Features, 2)
+	suite.Equal("hello", collection.Features[1].ID)
+}
+
+func (suite *PublishTestSuite) TestPackageFeaturesInvalid() {
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), folder, "color", "one")
+
+	_, err := PackageFeatures(folder, suite.T().TempDir(), log.Discard)
+	suite.ErrorContains(err, "invalid version")
+}
+
+func (suite *PublishTestSuite) TestPublishFeatures() {
+	server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+	defer server.Close()
+	namespace := strings.TrimPrefix(server.URL, "http://") + "/my-org/features"
+
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), folder, "color", "1.0.0")
+	suite.Require().NoError(PublishFeatures(folder, namespace, log.Discard))
+
+	repository, err := name.NewRepository(namespace + "/color")
+	suite.Require().NoError(err)
+	tags, err := remote.List(repository)
+	suite.Require().NoError(err)
+	suite.ElementsMatch([]string{"1.0.0", "1.0", "1", "latest"}, tags)
+
+	img, err := remote.Image(repository.Tag("1"))
+	suite.Require().NoError(err)
+	manifest, err := img.Manifest()
+	suite.Require().NoError(err)
+	suite.Equal(DEVCONTAINER_MANIFEST_MEDIATYPE, string(manifest.Config.MediaType))
+	suite.Equal(DEVCONTAINER_FEATURE_LAYER_MEDIATYPE, string(manifest.Layers[0].MediaType))
+	suite.Equal("devcontainer_feature", manifest.Annotations[annotationPackageType])
+
+	destFile := filepath.Join(suite.T().TempDir(), DEVCONTAINER_COLLECTION_FILE_NAME)
+	suite.Require().NoError(PullOCIArtifact(namespace+":latest", destFile, log.Discard))
+	out, err := os.ReadFile(destFile)
+	suite.Require().NoError(err)
+	collection := &Collection{}
+	suite.Require().NoError(json.Unmarshal(out, collection))
+	suite.Equal("color", collection.Features[0].ID)
+
+	// publishing the same version again skips the feature
+	suite.Require().NoError(PublishFeatures(folder, namespace, log.Discard))
+
+	// features of other folders are merged into the collection
+	otherFolder := suite.T().TempDir()
+	writeTestFeature(suite.T(), otherFolder, "hello", "1.0.0")
+	suite.Require().NoError(PublishFeatures(otherFolder, namespace, log.Discard))
+	suite.Require().NoError(PullOCIArtifact(namespace+":latest", destFile, log.Discard))
+	out, err = os.ReadFile(destFile)
+	suite.Require().NoError(err)
+	collection = &Collection{}
+	suite.Require().NoError(json.Unmarshal(out, collection))
+	suite.Len(collection.Features, 2)
+	suite.Equal("hello", collection.Features[1].ID)
+}
+
+func writeTestFeature(t *testing.T, folder, id, version string) {
+	featureConfig, err := json.Marshal(&config.FeatureConfig{ID: id, Version: version, Name: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, filepath.Join(folder, id, config.DEVCONTAINER_FEATURE_FILE_NAME), string(featureConfig))
+	writeTestFile(t, filepath.Join(folder, id, "install.sh"), "#!/bin/sh\necho installed\n")
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,197 @@
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+)
+
+const (
+	// ScenariosFileName holds the scenarios of a feature in its test folder
+	ScenariosFileName = "scenarios.json"
+
+	// FeatureTestLibFileName is sourced by the test scripts of a feature
+	FeatureTestLibFileName = "dev-container-features-test-lib"
+)
+
+// FeatureTestLib provides the check and reportResults helpers to the test scripts of a feature
+const FeatureTestLib = `#!/bin/bash
+FAILED=()
+
+echoStderr() {
+    echo "$@" 1>&2
+}
+
+check() {
+    LABEL=$1
+    shift
+    echo -e "\nTesting '${LABEL}'"
+    if "$@"; then
+        echo "Passed '${LABEL}'"
+        return 0
+    else
+        echoStderr "Check '${LABEL}' failed"
+        FAILED+=("${LABEL}")
+        return 1
+    fi
+}
+
+reportResults() {
+    if [ ${#FAILED[@]} -ne 0 ]; then
+        echoStderr -e "\nFailed checks: ${FAILED[*]}"
+        exit 1
+    fi
+
+    echo -e "\nAll checks passed"
+    exit 0
+}
+`
+
+// FeatureTest installs features into a base image and verifies the result with a script of the
+// feature's test folder
+type FeatureTest struct {
+	// Feature is the id of the tested feature
+	Feature string
+
+	// Name of the scenario, the name of the base image for autogenerated tests
+	Name string
+
+	// Config is the devcontainer config the image is built from. Local features are referenced as ./<id>.
+	Config *config.DevContainerConfig
+
+	// TestFolder is the test folder of the feature
+	TestFolder string
+
+	// Script is the test script relative to the test folder
+	Script string
+}
+
+// FeatureTestOptions select the tests of a collection
+type FeatureTestOptions struct {
+	// Features limits the tests to the given feature ids
+	Features []string
+
+	// BaseImages are the images the autogenerated tests install the feature into
+	BaseImages []string
+
+	SkipAutogenerated bool
+	SkipScenarios     bool
+}
+
+// FindFeatureTests returns the tests of the features in the collection folder, which holds the features in
+// src/<id> and their tests in test/<id>. Every feature gets an autogenerated test per base image that
+// runs test.sh, and a test per entry of scenarios.json that runs the script named after the scenario.
+func FindFeatureTests(collectionFolder string, options FeatureTestOptions) ([]*FeatureTest, error) {
+	srcFolder := filepath.Join(collectionFolder, "src")
+	featureFolders, err := FindFeatures(srcFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	localFeatures := map[string]bool{}
+	for _, featureFolder := range featureFolders {
+		localFeatures[filepath.Base(featureFolder)] = true
+	}
+	for _, id := range options.Features {
+		if !localFeatures[id] {
+			return nil, fmt.Errorf("couldn't find feature %s in %s", id, srcFolder)
+		}
+	}
+
+	tests := []*FeatureTest{}
+	for _, id := range slices.Sorted(maps.Keys(localFeatures)) {
+		if len(options.Features) > 0 && !slices.Contains(options.Features, id) {
+			continue
+		}
+
+		testFolder := filepath.Join(collectionFolder, "test", id)
+		if _, err := os.Stat(testFolder); err != nil {
+			return nil, fmt.Errorf("feature %s has no tests in %s", id, testFolder)
+		}
+
+		if !options.SkipAutogenerated {
+			if _, err := os.Stat(filepath.Join(testFolder, "test.sh")); err == nil {
+				for _, baseImage := range options.BaseImages {
+					tests = append(tests, &FeatureTest{
+						Feature: id,
+						Name:    baseImage,
+						Config: &config.DevContainerConfig{
+							DevContainerConfigBase: config.DevContainerConfigBase{
+								Features: map[string]any{"./" + id: map[string]any{}},
+							},
+							ImageContainer: config.ImageContainer{Image: baseImage},
+						},
+						TestFolder: testFolder,
+						Script:     "test.sh",
+					})
+				}
+			}
+		}
+
+		if !options.SkipScenarios {
+			scenarios, err := parseScenarios(testFolder, localFeatures)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s %w", id, err)
+			}
+
+			for _, scenario := range slices.Sorted(maps.Keys(scenarios)) {
+				script := scenario + ".sh"
+				if _, err := os.Stat(filepath.Join(testFolder, script)); err != nil {
+					return nil, fmt.Errorf("scenario %s of feature %s has no test script %s", scenario, id, script)
+				}
+
+				tests = append(tests, &FeatureTest{
+					Feature:    id,
+					Name:       scenario,
+					Config:     scenarios[scenario],
+					TestFolder: testFolder,
+					Script:     script,
+				})
+			}
+		}
+	}
+
+	return tests, nil
+}
+
+// parseScenarios parses the scenarios.json of the test folder, features of the collection are referenced
+// by their id and rewritten to local features
+func parseScenarios(testFolder string, localFeatures map[string]bool) (map[string]*config.DevContainerConfig, error) {
+	out, err := os.ReadFile(filepath.Join(testFolder, ScenariosFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	scenarios := map[string]*config.DevContainerConfig{}
+	err = json.Unmarshal(out, &scenarios)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s %w", ScenariosFileName, err)
+	}
+
+	for name, scenario := range scenarios {
+		if scenario.Image == "" {
+			return nil, fmt.Errorf("scenario %s has no image", name)
+		}
+
+		features := map[string]any{}
+		for id, options := range scenario.Features {
+			if localFeatures[id] {
+				id = "./" + id
+			}
+
+			features[id] = options
+		}
+		scenario.Features = features
+	}
+
+	return scenarios, nil
+}
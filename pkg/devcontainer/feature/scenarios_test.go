@@ -0,0 +1,50 @@
+package feature
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScenariosTestSuite struct {
+	suite.Suite
+}
+
+func TestScenariosTestSuite(t *testing.T) {
+	suite.Run(t, new(ScenariosTestSuite))
+}
+
+func (suite *ScenariosTestSuite) TestFindFeatureTests() {
+	folder := suite.T().TempDir()
+	writeTestFeature(suite.T(), filepath.Join(folder, "src"), "color", "1.0.0")
+	writeTestFeature(suite.T(), filepath.Join(folder, "src"), "hello", "1.0.0")
+	writeTestFile(suite.T(), filepath.Join(folder, "test", "color", "test.sh"), "#!/bin/bash\n")
+	writeTestFile(suite.T(), filepath.Join(folder, "test", "color", "green.sh"), "#!/bin/bash\n")
+	writeTestFile(suite.T(), filepath.Join(folder, "test", "color", ScenariosFileName), `{
+  "green": {
+    "image": "ubuntu:24.04",
+    "features": {
+      "color": {"favorite": "green"},
+      "hello": {}
+    }
+  }
+}`)
+
+	tests, err := FindFeatureTests(folder, FeatureTestOptions{
+		Features:   []string{"color"},
+		BaseImages: []string{"debian:12", "ubuntu:24.04"},
+	})
+	suite.Require().NoError(err)
+	suite.Len(tests, 3)
+	suite.Equal("debian:12", tests[0].Name)
+	suite.Equal("test.sh", tests[0].Script)
+	suite.Contains(tests[0].Config.Features, "./color")
+	suite.Equal("green", tests[2].Name)
+	suite.Equal("green.sh", tests[2].Script)
+	suite.Equal(map[string]any{"favorite": "green"}, tests[2].Config.Features["./color"])
+	suite.Contains(tests[2].Config.Features, "./hello")
+
+	_, err = FindFeatureTests(folder, FeatureTestOptions{Features: []string{"hello"}})
+	suite.ErrorContains(err, "has no tests")
+}
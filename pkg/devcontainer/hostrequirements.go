@@ -0,0 +1,53 @@
+package devcontainer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+)
+
+// checkHostRequirements verifies that the machine the devcontainer runs on satisfies the cpu and memory
+// host requirements. If strict is true unsatisfied requirements fail the run, otherwise only a warning is printed.
+func (r *runner) checkHostRequirements(ctx context.Context, requirements *config.HostRequirements, strict bool) error {
+	if requirements.IsEmpty() {
+		return nil
+	}
+
+	resourcesDriver, ok := r.Driver.(driver.HostResourcesDriver)
+	if !ok {
+		return nil
+	}
+
+	resources, err := resourcesDriver.HostResources(ctx)
+	if err != nil {
+		r.Log.Debugf("error retrieving host resources, skipping host requirements check: %v", err)
+		return nil
+	}
+
+	problems := []string{}
+	if requirements.CPUs > 0 && resources.CPUs > 0 && resources.CPUs < requirements.CPUs {
+		problems = append(problems, fmt.Sprintf("%d cpus required but only %d available", requirements.CPUs, resources.CPUs))
+	}
+
+	memory, err := requirements.MemoryBytes()
+	if err != nil {
+		return fmt.Errorf("parse host requirements %w", err)
+	} else if memory > 0 && resources.Memory > 0 && resources.Memory < memory {
+		problems = append(problems, fmt.Sprintf("%.1fgb memory required but only %.1fgb available", float64(memory)/(1<<30), float64(resources.Memory)/(1<<30)))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	message := "host does not satisfy the devcontainer hostRequirements: " + strings.Join(problems, ", ")
+	if strict {
+		return fmt.Errorf("%s", message)
+	}
+
+	r.Log.Warn(message + ". The container might be slow or killed because of insufficient resources")
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/agent/tunnel"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/driver"
 	"github.com/skevetter/devpod/pkg/driver/drivercreate"
@@ -86,6 +87,12 @@ type UpOptions struct {
 	NoBuild       bool
 	ForceBuild    bool
 	RegistryCache string
+
+	// SecretsClient resolves the secrets of the workspace for the container, if nil the container can't resolve secrets
+	SecretsClient tunnel.TunnelClient
+
+	// SnapshotImage is the snapshot image to create the container from instead of building it
+	SnapshotImage string
 }
 
 func (r *runner) Up(ctx context.Context, options UpOptions, timeout time.Duration) (*config.Result, error) {
@@ -118,6 +125,10 @@ func (r *runner) Up(ctx context.Context, options UpOptions, timeout time.Duratio
 			timeout,
 		)
 	case isDockerComposeConfig(substitutedConfig.Config):
+		if composeDriver, ok := r.Driver.(driver.ComposeDriver); ok {
+			return r.runComposeDriver(ctx, composeDriver, substitutedConfig, substitutionContext, options, timeout)
+		}
+
 		return r.runDockerCompose(ctx, substitutedConfig, substitutionContext, options, timeout)
 	default:
 		return r.runDefaultContainer(ctx, options, substitutedConfig, substitutionContext, timeout)
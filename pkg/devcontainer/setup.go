@@ -13,6 +13,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/agent/tunnel"
 	"github.com/skevetter/devpod/pkg/agent/tunnelserver"
 	"github.com/skevetter/devpod/pkg/compress"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
@@ -30,6 +31,7 @@ func (r *runner) setupContainer(
 	containerDetails *config.ContainerDetails,
 	mergedConfig *config.MergedDevContainerConfig,
 	substitutionContext *config.SubstitutionContext,
+	secretsClient tunnel.TunnelClient,
 	timeout time.Duration,
 ) (*config.Result, error) {
 	// inject agent
@@ -142,6 +144,7 @@ func (r *runner) setupContainer(
 			config.GetMounts(result),
 			r.Log,
 			tunnelserver.WithPlatformOptions(&r.WorkspaceConfig.CLIOptions.Platform),
+			tunnelserver.WithSecretsClient(secretsClient),
 		)
 	}
 
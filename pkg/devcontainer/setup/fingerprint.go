@@ -0,0 +1,161 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/filesync"
+	"github.com/skevetter/log"
+)
+
+// maxFolderFingerprintEntries limits the entries of a folder without git that are hashed, so that large
+// workspaces don't delay the start of the container
+const maxFolderFingerprintEntries = 10000
+
+// folderFingerprintExcludes are dependency folders that are installed rather than edited, they are never
+// hashed in folders without git
+var folderFingerprintExcludes = map[string]bool{
+	"node_modules": true,
+	".venv":        true,
+	"__pycache__":  true,
+}
+
+// contentFingerprint identifies the content of the workspace folder. For git repositories this is the
+// checked out commit together with the uncommitted changes, otherwise the entries of the folder. The image
+// of the container is included, so that a new prebuild counts as new content as well.
+func contentFingerprint(ctx context.Context, setupInfo *config.Result, log log.Logger) string {
+	folder := setupInfo.SubstitutionContext.ContainerWorkspaceFolder
+	h := sha256.New()
+
+	var err error
+	if _, statErr := os.Stat(filepath.Join(folder, ".git")); statErr == nil {
+		err = writeGitFingerprint(ctx, h, folder)
+		if err != nil {
+			log.Debugf("Error fingerprinting git repository, falling back to the folder entries: %v", err)
+			h.Reset()
+			err = writeFolderFingerprint(h, folder)
+		}
+	} else {
+		err = writeFolderFingerprint(h, folder)
+	}
+	if err != nil {
+		log.Debugf("Error fingerprinting workspace content: %v", err)
+		return ""
+	}
+
+	if setupInfo.ContainerDetails != nil {
+		_, _ = fmt.Fprintf(h, "image %s\n", setupInfo.ContainerDetails.Image)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeGitFingerprint hashes the HEAD commit, the status and the diff of the tracked files. Only the size
+// and modification time of untracked files are hashed, so the files of the repository are never walked.
+func writeGitFingerprint(ctx context.Context, h hash.Hash, folder string) error {
+	// the repository might belong to another user than the agent and the index of the user is left as is
+	git := func(stdout io.Writer, args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"--no-optional-locks", "-c", "safe.directory=*", "-C", folder}, args...)...)
+		cmd.Stdout = stdout
+		err := cmd.Run()
+		if err != nil {
+			return fmt.Errorf("git %s %w", args[0], err)
+		}
+
+		return nil
+	}
+
+	_, _ = fmt.Fprint(h, "head ")
+	err := git(h, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	status := &bytes.Buffer{}
+	err = git(status, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return err
+	}
+	_, _ = h.Write(status.Bytes())
+
+	// the diff is streamed into the hash, so large changes are not held in memory
+	err = git(h, "diff", "HEAD", "--no-ext-diff", "--no-textconv", "--binary")
+	if err != nil {
+		return err
+	}
+
+	for entry := range bytes.SplitSeq(status.Bytes(), []byte{0}) {
+		if file, ok := bytes.CutPrefix(entry, []byte("?? ")); ok {
+			writeFileFingerprint(h, filepath.Join(folder, string(file)), string(file))
+		}
+	}
+
+	return nil
+}
+
+// writeFolderFingerprint hashes the entries of the folder that are not excluded by the .devpodignore,
+// dependency folders and entries beyond maxFolderFingerprintEntries are skipped
+func writeFolderFingerprint(h hash.Hash, folder string) error {
+	excludes, err := filesync.ReadExcludes(folder)
+	if err != nil {
+		return err
+	}
+
+	// WalkDir visits the entries in lexical order, so the fingerprint is stable
+	entries := 0
+	return filepath.WalkDir(folder, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		relPath, err := filepath.Rel(folder, absPath)
+		if err != nil {
+			return err
+		} else if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+		if (d.IsDir() && folderFingerprintExcludes[d.Name()]) || filesync.IsExcluded(excludes, relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		entries++
+		if entries > maxFolderFingerprintEntries {
+			return filepath.SkipAll
+		}
+
+		writeFileFingerprint(h, absPath, relPath)
+		return nil
+	})
+}
+
+// writeFileFingerprint hashes the type, mode, size and modification time of the file, a deleted file is
+// hashed as missing
+func writeFileFingerprint(h hash.Hash, absPath, relPath string) {
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(h, "%s missing\n", relPath)
+		return
+	}
+
+	_, _ = fmt.Fprintf(h, "%s %s %d %d\n", relPath, info.Mode(), info.Size(), info.ModTime().UnixNano())
+}
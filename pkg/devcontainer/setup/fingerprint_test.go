@@ -0,0 +1,129 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/log"
+)
+
+func TestContentFingerprintGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	folder := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", folder, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init")
+	writeFile("README.md", "hello")
+	git("add", ".")
+	git("commit", "-m", "initial")
+
+	setupInfo := &config.Result{
+		SubstitutionContext: &config.SubstitutionContext{ContainerWorkspaceFolder: folder},
+		ContainerDetails:    &config.ContainerDetails{},
+	}
+	fingerprint := func() string {
+		return contentFingerprint(context.Background(), setupInfo, log.Discard)
+	}
+
+	initial := fingerprint()
+	if initial == "" || initial != fingerprint() {
+		t.Fatalf("fingerprint of an unchanged repository should be stable, got %q", initial)
+	}
+
+	// only the content of tracked files counts, not their modification time
+	touched := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(folder, "README.md"), touched, touched); err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint() != initial {
+		t.Errorf("touching a file should not change the fingerprint")
+	}
+
+	writeFile("README.md", "changed")
+	dirty := fingerprint()
+	if dirty == initial {
+		t.Errorf("uncommitted changes should change the fingerprint")
+	}
+
+	writeFile("README.md", "edited!")
+	if edited := fingerprint(); edited == dirty {
+		t.Errorf("further uncommitted changes should change the fingerprint")
+	}
+	writeFile("README.md", "changed")
+
+	git("commit", "-am", "change")
+	committed := fingerprint()
+	if committed == initial || committed == dirty {
+		t.Errorf("a new commit should change the fingerprint")
+	}
+
+	writeFile("new.txt", "untracked")
+	if untracked := fingerprint(); untracked == committed {
+		t.Errorf("untracked files should change the fingerprint")
+	}
+}
+
+func TestContentFingerprintFolder(t *testing.T) {
+	folder := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(folder, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(".devpodignore", "build\n")
+	writeFile("main.go", "package main")
+
+	setupInfo := &config.Result{
+		SubstitutionContext: &config.SubstitutionContext{ContainerWorkspaceFolder: folder},
+		ContainerDetails:    &config.ContainerDetails{Image: "sha256:1"},
+	}
+	fingerprint := func() string {
+		return contentFingerprint(context.Background(), setupInfo, log.Discard)
+	}
+
+	initial := fingerprint()
+	if initial == "" || initial != fingerprint() {
+		t.Fatalf("fingerprint of an unchanged folder should be stable, got %q", initial)
+	}
+
+	writeFile("build/out.bin", "generated")
+	if excluded := fingerprint(); excluded != initial {
+		t.Errorf("excluded files should not change the fingerprint")
+	}
+
+	writeFile("node_modules/left-pad/index.js", "module.exports = {}")
+	if dependencies := fingerprint(); dependencies != initial {
+		t.Errorf("dependency folders should not change the fingerprint")
+	}
+
+	writeFile("main.go", "package main\n\nfunc main() {}")
+	changed := fingerprint()
+	if changed == initial {
+		t.Errorf("changed files should change the fingerprint")
+	}
+
+	setupInfo.ContainerDetails.Image = "sha256:2"
+	if rebuilt := fingerprint(); rebuilt == changed {
+		t.Errorf("a new image should change the fingerprint")
+	}
+}
@@ -3,174 +3,525 @@ package setup
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"os"
 	"os/exec"
 	"os/user"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/skevetter/devpod/pkg/command"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/envfile"
+	"github.com/skevetter/devpod/pkg/secrets"
 	"github.com/skevetter/devpod/pkg/types"
 	"github.com/skevetter/log"
+	"github.com/skevetter/log/hash"
 )
 
+const (
+	// LifecycleHooksStatusFile holds the state of the lifecycle hooks inside the container
+	LifecycleHooksStatusFile = "/var/devpod/lifecycle-hooks.json"
+
+	// LifecycleHooksLogFile holds the output of the lifecycle hooks that ran in the background
+	LifecycleHooksLogFile = "/var/devpod/lifecycle-hooks.log"
+)
+
+type lifecycleHook struct {
+	// name is the devcontainer.json property of the hook
+	name string
+	// markerName is the name of the marker file that tracks if the hook already ran
+	markerName string
+	commands   func(mergedConfig *config.MergedDevContainerConfig) []types.LifecycleHook
+	// markerContent returns the content the hook is keyed on, the hook is rerun if it changes
+	markerContent func(ctx context.Context, setupInfo *config.Result, log log.Logger) string
+}
+
+var lifecycleHooks = []lifecycleHook{
+	{
+		// only run once per container run
+		name:          "onCreateCommand",
+		markerName:    "onCreateCommands",
+		commands:      func(c *config.MergedDevContainerConfig) []types.LifecycleHook { return c.OnCreateCommands },
+		markerContent: containerCreated,
+	},
+	{
+		// run once per container run and again when the content of the workspace changed
+		name:       "updateContentCommand",
+		markerName: "updateContentCommands",
+		commands:   func(c *config.MergedDevContainerConfig) []types.LifecycleHook { return c.UpdateContentCommands },
+		markerContent: func(ctx context.Context, setupInfo *config.Result, log log.Logger) string {
+			return containerCreated(ctx, setupInfo, log) + "\n" + contentFingerprint(ctx, setupInfo, log)
+		},
+	},
+	{
+		// only run once per container run
+		name:          "postCreateCommand",
+		markerName:    "postCreateCommands",
+		commands:      func(c *config.MergedDevContainerConfig) []types.LifecycleHook { return c.PostCreateCommands },
+		markerContent: containerCreated,
+	},
+	{
+		// run when the container was restarted
+		name:          "postStartCommand",
+		markerName:    "postStartCommands",
+		commands:      func(c *config.MergedDevContainerConfig) []types.LifecycleHook { return c.PostStartCommands },
+		markerContent: containerStarted,
+	},
+	{
+		// run always when attaching to the container
+		name:          "postAttachCommand",
+		markerName:    "postAttachCommands",
+		commands:      func(c *config.MergedDevContainerConfig) []types.LifecycleHook { return c.PostAttachCommands },
+		markerContent: func(context.Context, *config.Result, log.Logger) string { return "" },
+	},
+}
+
+func containerCreated(_ context.Context, setupInfo *config.Result, _ log.Logger) string {
+	return setupInfo.ContainerDetails.Created
+}
+
+func containerStarted(_ context.Context, setupInfo *config.Result, _ log.Logger) string {
+	return setupInfo.ContainerDetails.State.StartedAt
+}
+
+const defaultWaitFor = "updateContentCommand"
+
+// waitForIndex returns the number of lifecycle hooks that need to finish before the workspace is ready.
+// The remaining hooks can run in the background.
+func waitForIndex(waitFor string, log log.Logger) int {
+	if waitFor == "" {
+		waitFor = defaultWaitFor
+	}
+	// initializeCommand runs on the host, so every container hook can run in the background
+	if waitFor == "initializeCommand" {
+		return 0
+	}
+
+	for i, hook := range lifecycleHooks {
+		if hook.name == waitFor && hook.name != "postAttachCommand" {
+			return i + 1
+		}
+	}
+
+	log.Warnf("Unsupported waitFor value '%s', falling back to %s", waitFor, defaultWaitFor)
+	return waitForIndex(defaultWaitFor, log)
+}
+
+// RunLifecycleHooks runs the lifecycle hooks up to and including the hook specified by waitFor
 func RunLifecycleHooks(ctx context.Context, setupInfo *config.Result, log log.Logger) error {
+	idx := waitForIndex(setupInfo.MergedConfig.WaitFor, log)
+	return runLifecycleHooks(ctx, setupInfo, lifecycleHooks[:idx], idx, log)
+}
+
+// RunBackgroundLifecycleHooks runs the lifecycle hooks after the hook specified by waitFor
+func RunBackgroundLifecycleHooks(ctx context.Context, setupInfo *config.Result, log log.Logger) error {
+	idx := waitForIndex(setupInfo.MergedConfig.WaitFor, log)
+	return runLifecycleHooks(ctx, setupInfo, lifecycleHooks[idx:], idx, log)
+}
+
+// HasBackgroundLifecycleHooks returns true if there are lifecycle hooks that can run in the background
+func HasBackgroundLifecycleHooks(setupInfo *config.Result) bool {
+	idx := waitForIndex(setupInfo.MergedConfig.WaitFor, log.Discard)
+	for _, hook := range lifecycleHooks[idx:] {
+		if len(hook.commands(setupInfo.MergedConfig)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BackgroundLifecycleHooksHash returns a hash of the lifecycle hooks that run in the background. A background
+// run is keyed on it, so changed hooks start a new run instead of being skipped while the previous one runs.
+func BackgroundLifecycleHooksHash(setupInfo *config.Result) string {
+	idx := waitForIndex(setupInfo.MergedConfig.WaitFor, log.Discard)
+	hooks := map[string][]types.LifecycleHook{}
+	for _, hook := range lifecycleHooks[idx:] {
+		hooks[hook.name] = hook.commands(setupInfo.MergedConfig)
+	}
+
+	out, _ := json.Marshal(hooks)
+	return hash.String(string(out))
+}
+
+func runLifecycleHooks(ctx context.Context, setupInfo *config.Result, hooks []lifecycleHook, waitForIdx int, log log.Logger) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
 	mergedConfig := setupInfo.MergedConfig
 	remoteUser := config.GetRemoteUser(setupInfo)
 	probedEnv, err := config.ProbeUserEnv(ctx, mergedConfig.UserEnvProbe, remoteUser, log)
 	if err != nil {
 		log.WithFields(logrus.Fields{"error": err}).Error("failed to probe environment, this might lead to an incomplete setup of your workspace")
 	}
-	remoteEnv := mergeRemoteEnv(mergedConfig.RemoteEnv, probedEnv, remoteUser)
+	remoteEnv := mergeRemoteEnv(secrets.WithoutReferences(mergedConfig.RemoteEnv), probedEnv, remoteUser)
+	maps.Copy(remoteEnv, config.ListToObject(envfile.Secrets()))
 
 	workspaceFolder := setupInfo.SubstitutionContext.ContainerWorkspaceFolder
-	containerDetails := setupInfo.ContainerDetails
 
-	// only run once per container run
-	err = run(mergedConfig.OnCreateCommands, remoteUser, workspaceFolder, remoteEnv,
-		"onCreateCommands", containerDetails.Created, log)
-	if err != nil {
-		return err
+	status := newLifecycleHooksStatus(waitForIdx, log)
+	for _, hook := range hooks {
+		commands := hook.commands(mergedConfig)
+		if len(commands) == 0 {
+			status.update(hook.name, config.LifecycleHookSkipped, nil)
+			continue
+		}
+
+		// check marker file
+		content := hook.markerContent(ctx, setupInfo, log)
+		if content != "" {
+			exists, err := markerFileExists(hook.markerName, content)
+			if err != nil {
+				return err
+			} else if exists {
+				status.alreadyRan(hook.name)
+				continue
+			}
+		}
+
+		status.update(hook.name, config.LifecycleHookRunning, nil)
+		commandStatus, err := run(ctx, commands, remoteUser, workspaceFolder, remoteEnv, config.GetLifecycleHookConcurrency(mergedConfig), log)
+		status.setCommands(hook.name, commandStatus)
+		if err != nil {
+			status.update(hook.name, config.LifecycleHookFailed, err)
+			setupInfo.LifecycleHooks = append(setupInfo.LifecycleHooks, status.get(hook.name))
+			return err
+		}
+
+		// the commands might have changed the content the hook is keyed on, e.g. updateContentCommand
+		// updating a lock file, which shouldn't rerun the hook next time
+		if content != "" {
+			err = writeMarkerFile(hook.markerName, hook.markerContent(ctx, setupInfo, log))
+			if err != nil {
+				return err
+			}
+		}
+
+		status.update(hook.name, config.LifecycleHookDone, nil)
+		setupInfo.LifecycleHooks = append(setupInfo.LifecycleHooks, status.get(hook.name))
 	}
 
-	// TODO: rerun when contents changed
-	err = run(mergedConfig.UpdateContentCommands, remoteUser, workspaceFolder, remoteEnv,
-		"updateContentCommands", containerDetails.Created, log)
+	return nil
+}
+
+// ReadLifecycleHooksStatus reads the state of the lifecycle hooks
+func ReadLifecycleHooksStatus() ([]config.LifecycleHookStatus, error) {
+	out, err := os.ReadFile(LifecycleHooksStatusFile)
 	if err != nil {
-		return err
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
 	}
 
-	// only run once per container run
-	err = run(mergedConfig.PostCreateCommands, remoteUser, workspaceFolder, remoteEnv,
-		"postCreateCommands", containerDetails.Created, log)
+	hooks := []config.LifecycleHookStatus{}
+	err = json.Unmarshal(out, &hooks)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("parse lifecycle hooks status %w", err)
 	}
 
-	// run when the container was restarted
-	err = run(mergedConfig.PostStartCommands, remoteUser, workspaceFolder, remoteEnv,
-		"postStartCommands", containerDetails.State.StartedAt, log)
+	return hooks, nil
+}
+
+type lifecycleHooksStatus struct {
+	hooks []config.LifecycleHookStatus
+	log   log.Logger
+}
+
+func newLifecycleHooksStatus(waitForIdx int, log log.Logger) *lifecycleHooksStatus {
+	existing, err := ReadLifecycleHooksStatus()
 	if err != nil {
-		return err
+		log.Debugf("Error reading lifecycle hooks status: %v", err)
 	}
 
-	// run always when attaching to the container
-	err = run(mergedConfig.PostAttachCommands, remoteUser, workspaceFolder, remoteEnv,
-		"postAttachCommands", "", log)
-	if err != nil {
-		return err
+	status := &lifecycleHooksStatus{log: log}
+	for i, hook := range lifecycleHooks {
+		hookStatus := config.LifecycleHookStatus{
+			Name:       hook.name,
+			State:      config.LifecycleHookPending,
+			Background: i >= waitForIdx,
+		}
+		for _, existingStatus := range existing {
+			if existingStatus.Name == hook.name && existingStatus.State != config.LifecycleHookRunning {
+				hookStatus = existingStatus
+				hookStatus.Background = i >= waitForIdx
+			}
+		}
+
+		status.hooks = append(status.hooks, hookStatus)
 	}
 
-	return nil
+	return status
 }
 
-func run(commands []types.LifecycleHook, remoteUser, dir string, remoteEnv map[string]string, name, content string, log log.Logger) error {
-	if len(commands) == 0 {
-		return nil
+// alreadyRan keeps the state of a hook that ran in a previous setup
+func (s *lifecycleHooksStatus) alreadyRan(name string) {
+	for i := range s.hooks {
+		if s.hooks[i].Name == name && s.hooks[i].State == config.LifecycleHookPending {
+			s.update(name, config.LifecycleHookDone, nil)
+			return
+		}
+	}
+}
+
+func (s *lifecycleHooksStatus) setCommands(name string, commands []config.LifecycleCommandStatus) {
+	for i := range s.hooks {
+		if s.hooks[i].Name == name {
+			s.hooks[i].Commands = commands
+		}
+	}
+}
+
+func (s *lifecycleHooksStatus) get(name string) config.LifecycleHookStatus {
+	for _, hook := range s.hooks {
+		if hook.Name == name {
+			return hook
+		}
 	}
 
-	// check marker file
-	if content != "" {
-		exists, err := markerFileExists(name, content)
+	return config.LifecycleHookStatus{Name: name}
+}
+
+func (s *lifecycleHooksStatus) update(name string, state config.LifecycleHookState, err error) {
+	now := time.Now()
+	for i := range s.hooks {
+		if s.hooks[i].Name != name {
+			continue
+		}
+
+		s.hooks[i].State = state
+		s.hooks[i].Error = ""
+		if state == config.LifecycleHookRunning {
+			s.hooks[i].StartedAt = &now
+			s.hooks[i].FinishedAt = nil
+			s.hooks[i].Commands = nil
+		} else {
+			s.hooks[i].FinishedAt = &now
+		}
 		if err != nil {
-			return err
-		} else if exists {
-			return nil
+			s.hooks[i].Error = err.Error()
 		}
 	}
 
+	out, err := json.Marshal(s.hooks)
+	if err != nil {
+		return
+	}
+
+	_ = os.MkdirAll(filepath.Dir(LifecycleHooksStatusFile), 0777)
+	err = os.WriteFile(LifecycleHooksStatusFile, out, 0644)
+	if err != nil {
+		s.log.Debugf("Error writing lifecycle hooks status: %v", err)
+	}
+}
+
+// run runs the lifecycle hook commands in order. The named commands of a hook in object form run in parallel,
+// at most concurrency at a time if it is positive.
+func run(ctx context.Context, commands []types.LifecycleHook, remoteUser, dir string, remoteEnv map[string]string, concurrency int, log log.Logger) ([]config.LifecycleCommandStatus, error) {
 	remoteEnvArr := []string{}
 	for k, v := range remoteEnv {
 		remoteEnvArr = append(remoteEnvArr, k+"="+v)
 	}
 
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	commandStatus := []config.LifecycleCommandStatus{}
 	for _, cmd := range commands {
 		if len(cmd) == 0 {
 			continue
 		}
 
-		for k, c := range cmd {
-			log.WithFields(logrus.Fields{"command": k, "args": strings.Join(c, " ")}).Info("lifecycle hook run command")
-			currentUser, err := user.Current()
-			if err != nil {
-				return err
-			}
-			args := []string{}
-			if remoteUser != currentUser.Username {
-				args = append(args, "su", remoteUser, "-c", command.Quote(c))
-			} else {
-				args = append(args, "sh", "-c", command.Quote(c))
-			}
+		names := slices.Sorted(maps.Keys(cmd))
+		results := make([]config.LifecycleCommandStatus, len(names))
+		errs := make([]error, len(names))
+		limit := concurrency
+		if limit <= 0 || limit > len(names) {
+			limit = len(names)
+		}
+		semaphore := make(chan struct{}, limit)
 
-			// create command
-			cmd := exec.Command(args[0], args[1:]...)
-			cmd.Dir = dir
-			cmd.Env = os.Environ()
-			cmd.Env = append(cmd.Env, remoteEnvArr...)
+		// the other commands of the hook are cancelled as soon as one of them fails
+		hookCtx, cancelHook := context.WithCancelCause(ctx)
+		var wg sync.WaitGroup
+		for i, name := range names {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
 
-			// Create pipes for stdout and stderr
-			stdoutPipe, err := cmd.StdoutPipe()
-			if err != nil {
-				return fmt.Errorf("failed to get stdout pipe %w", err)
-			}
-			stderrPipe, err := cmd.StderrPipe()
-			if err != nil {
-				return fmt.Errorf("failed to get stderr pipe %w", err)
-			}
+				select {
+				case semaphore <- struct{}{}:
+					defer func() { <-semaphore }()
+				case <-hookCtx.Done():
+					errs[i] = fmt.Errorf("%s: %w", commandName(name), context.Cause(hookCtx))
+					results[i] = config.LifecycleCommandStatus{Name: name, Error: errs[i].Error()}
+					return
+				}
 
-			// Start the command
-			if err := cmd.Start(); err != nil {
-				return fmt.Errorf("failed to start command %w", err)
-			}
+				startedAt := time.Now()
+				errs[i] = runCommand(hookCtx, name, cmd[name], remoteUser, currentUser.Username, dir, remoteEnvArr, log)
+				finishedAt := time.Now()
+				if errs[i] != nil {
+					if hookCtx.Err() != nil {
+						errs[i] = fmt.Errorf("%s: %w", commandName(name), context.Cause(hookCtx))
+					} else {
+						cancelHook(errCommandFailed)
+					}
+				}
+				results[i] = config.LifecycleCommandStatus{Name: name, StartedAt: &startedAt, FinishedAt: &finishedAt}
+				if errs[i] != nil {
+					results[i].Error = errs[i].Error()
+				}
+			}()
+		}
+		wg.Wait()
+		cancelHook(nil)
 
-			// Use WaitGroup to wait for both stdout and stderr processing
-			var wg sync.WaitGroup
-			wg.Add(2)
+		commandStatus = append(commandStatus, results...)
+		err = errors.Join(errs...)
+		if err != nil {
+			return commandStatus, err
+		}
+	}
 
-			go func() {
-				defer wg.Done()
-				logPipeOutput(log, stdoutPipe, logrus.InfoLevel)
-			}()
+	return commandStatus, nil
+}
 
-			go func() {
-				defer wg.Done()
-				logPipeOutput(log, stderrPipe, logrus.ErrorLevel)
-			}()
+// errCommandFailed cancels the other commands of a lifecycle hook if one of them failed
+var errCommandFailed = errors.New("cancelled because another command failed")
 
-			// Wait for command to finish
-			wg.Wait()
-			err = cmd.Wait()
-			if err != nil {
-				log.WithFields(logrus.Fields{"command": cmd.Args, "error": err}).Debug("failed running postCreateCommand lifecycle script")
-				return fmt.Errorf("failed to run: %s, error %w", strings.Join(c, " "), err)
-			}
+// commandWaitDelay is how long a cancelled command has to exit before it is killed
+const commandWaitDelay = 10 * time.Second
+
+// runCommand runs a single named command, its output is logged with the name as prefix
+func runCommand(ctx context.Context, name string, c []string, remoteUser, currentUser, dir string, remoteEnv []string, log log.Logger) error {
+	log.WithFields(logrus.Fields{"command": name, "args": strings.Join(c, " ")}).Info("lifecycle hook run command")
+	args := []string{}
+	if remoteUser != currentUser {
+		args = append(args, "su", remoteUser, "-c", command.Quote(c))
+	} else {
+		args = append(args, "sh", "-c", command.Quote(c))
+	}
+
+	// create command, cancelling it stops the processes it started as well
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	command.SetProcessGroup(cmd, commandWaitDelay)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, remoteEnv...)
+
+	// Create pipes for stdout and stderr
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stderr pipe %w", err)
+	}
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command %w", err)
+	}
+
+	// Use WaitGroup to wait for both stdout and stderr processing
+	prefix := ""
+	if name != "" {
+		prefix = "[" + name + "] "
+	}
+	tail := &outputTail{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		logPipeOutput(log, stdoutPipe, logrus.InfoLevel, prefix, tail)
+	}()
+
+	go func() {
+		defer wg.Done()
+		logPipeOutput(log, stderrPipe, logrus.ErrorLevel, prefix, tail)
+	}()
 
-			log.WithFields(logrus.Fields{"command": k, "args": strings.Join(c, " ")}).Done("ran command")
+	// Wait for command to finish
+	wg.Wait()
+	err = cmd.Wait()
+	if err != nil {
+		log.WithFields(logrus.Fields{"command": cmd.Args, "error": err}).Debug("failed running lifecycle script")
+		err = fmt.Errorf("%s failed to run: %s, error %w", commandName(name), strings.Join(c, " "), err)
+		if output := tail.String(); output != "" {
+			err = fmt.Errorf("%w, output:\n%s", err, output)
 		}
+
+		return err
 	}
 
+	log.WithFields(logrus.Fields{"command": name, "args": strings.Join(c, " ")}).Done("ran command")
 	return nil
 }
 
-func logPipeOutput(log log.Logger, pipe io.ReadCloser, level logrus.Level) {
+func commandName(name string) string {
+	if name == "" {
+		return "command"
+	}
+
+	return "command " + name
+}
+
+// outputTailLines is the number of output lines that are reported if a command fails
+const outputTailLines = 10
+
+// outputTail keeps the last lines of the output of a command
+type outputTail struct {
+	m     sync.Mutex
+	lines []string
+}
+
+func (t *outputTail) add(line string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.lines = append(t.lines, line)
+	if len(t.lines) > outputTailLines {
+		t.lines = t.lines[len(t.lines)-outputTailLines:]
+	}
+}
+
+func (t *outputTail) String() string {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return strings.Join(t.lines, "\n")
+}
+
+func logPipeOutput(log log.Logger, pipe io.ReadCloser, level logrus.Level, prefix string, tail *outputTail) {
 	scanner := bufio.NewScanner(pipe)
 	for scanner.Scan() {
 		line := scanner.Text()
+		tail.add(line)
 		switch level {
 		case logrus.InfoLevel:
-			log.Info(line)
+			log.Info(prefix + line)
 		case logrus.ErrorLevel:
 			if containsError(line) {
-				log.Error(line)
+				log.Error(prefix + line)
 			} else {
-				log.Warn(line)
+				log.Warn(prefix + line)
 			}
 		}
 	}
@@ -0,0 +1,130 @@
+package setup
+
+import (
+	"context"
+	"os/user"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/types"
+	"github.com/skevetter/log"
+)
+
+func TestWaitForIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		waitFor string
+		want    int
+	}{
+		{name: "default", waitFor: "", want: 2},
+		{name: "initializeCommand", waitFor: "initializeCommand", want: 0},
+		{name: "onCreateCommand", waitFor: "onCreateCommand", want: 1},
+		{name: "postCreateCommand", waitFor: "postCreateCommand", want: 3},
+		{name: "postStartCommand", waitFor: "postStartCommand", want: 4},
+		{name: "postAttachCommand is not supported", waitFor: "postAttachCommand", want: 2},
+		{name: "unknown", waitFor: "unknown", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitForIndex(tt.waitFor, log.Discard); got != tt.want {
+				t.Errorf("waitForIndex() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackgroundLifecycleHooksHash(t *testing.T) {
+	setupInfo := func(onCreate, postStart string) *config.Result {
+		mergedConfig := &config.MergedDevContainerConfig{}
+		mergedConfig.OnCreateCommands = []types.LifecycleHook{{"": {"sh", "-c", onCreate}}}
+		mergedConfig.PostStartCommands = []types.LifecycleHook{{"": {"sh", "-c", postStart}}}
+		return &config.Result{MergedConfig: mergedConfig}
+	}
+
+	hash := BackgroundLifecycleHooksHash(setupInfo("install", "serve"))
+	if got := BackgroundLifecycleHooksHash(setupInfo("install --force", "serve")); got != hash {
+		t.Errorf("hash changed with a hook that doesn't run in the background")
+	}
+	if got := BackgroundLifecycleHooksHash(setupInfo("install", "serve --watch")); got == hash {
+		t.Errorf("hash didn't change with a changed background hook")
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := []types.LifecycleHook{{
+		"first":  {"sh", "-c", "sleep 0.5"},
+		"second": {"sh", "-c", "sleep 0.5"},
+	}}
+
+	start := time.Now()
+	status, err := run(context.Background(), commands, currentUser.Username, t.TempDir(), nil, 0, log.Discard)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 900*time.Millisecond {
+		t.Errorf("run() took %s, named commands should run in parallel", elapsed)
+	}
+	if len(status) != 2 || status[0].Name != "first" || status[1].Name != "second" || status[0].FinishedAt == nil {
+		t.Errorf("run() status = %+v", status)
+	}
+
+	start = time.Now()
+	_, err = run(context.Background(), commands, currentUser.Username, t.TempDir(), nil, 1, log.Discard)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("run() took %s, named commands should run one at a time", elapsed)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := []types.LifecycleHook{
+		{
+			"ok":     {"sh", "-c", "true"},
+			"broken": {"sh", "-c", "echo boom; exit 1"},
+		},
+		{"": {"sh", "-c", "echo never"}},
+	}
+
+	status, err := run(context.Background(), commands, currentUser.Username, t.TempDir(), nil, 0, log.Discard)
+	if err == nil || !strings.Contains(err.Error(), "command broken") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("run() error = %v, want failure of command broken with its output", err)
+	}
+	if len(status) != 2 || status[0].Error == "" {
+		t.Errorf("run() status = %+v", status)
+	}
+}
+
+func TestRunCancelsSiblings(t *testing.T) {
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := []types.LifecycleHook{{
+		"broken": {"sh", "-c", "sleep 0.2; exit 1"},
+		"slow":   {"sh", "-c", "sleep 30; echo done"},
+	}}
+
+	start := time.Now()
+	status, err := run(context.Background(), commands, currentUser.Username, t.TempDir(), nil, 0, log.Discard)
+	if err == nil || !strings.Contains(err.Error(), "command broken") {
+		t.Fatalf("run() error = %v, want failure of command broken", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Errorf("run() took %s, the slow command should be cancelled", elapsed)
+	}
+	if len(status) != 2 || !strings.Contains(status[1].Error, errCommandFailed.Error()) {
+		t.Errorf("run() status = %+v", status)
+	}
+}
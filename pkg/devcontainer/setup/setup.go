@@ -22,6 +22,7 @@ import (
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/envfile"
 	"github.com/skevetter/devpod/pkg/gitcredentials"
+	"github.com/skevetter/devpod/pkg/secrets"
 	"github.com/skevetter/log"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -29,6 +30,12 @@ import (
 
 const (
 	ResultLocation = "/var/run/devpod/result.json"
+
+	// IDEInstalledFile holds the name of the ide that was installed in the container
+	IDEInstalledFile = "/var/devpod/ide"
+
+	// SecretReferencesFile holds the secret references of the workspace env, never the secret values
+	SecretReferencesFile = "/var/devpod/secrets.json"
 )
 
 func SetupContainer(ctx context.Context, setupInfo *config.Result, extraWorkspaceEnv []string, chownProjects bool, platformOptions *devpod.PlatformOptions, tunnelClient tunnel.TunnelClient, log log.Logger) error {
@@ -41,6 +48,18 @@ func SetupContainer(ctx context.Context, setupInfo *config.Result, extraWorkspac
 		return fmt.Errorf("failed to chown workspace %w", err)
 	}
 
+	// record secret references, these are resolved by the client on every connect
+	err = WriteSecretReferences(secrets.References(extraWorkspaceEnv, setupInfo.MergedConfig.RemoteEnv))
+	if err != nil {
+		return fmt.Errorf("write secret references %w", err)
+	}
+	if tunnelClient != nil {
+		err = FetchSecrets(ctx, tunnelClient, config.GetRemoteUser(setupInfo))
+		if err != nil {
+			log.Warnf("Error retrieving secrets, lifecycle hooks will run without them: %v", err)
+		}
+	}
+
 	// patch remote env
 	log.Debugf("Patch etc environment & profile...")
 	err = PatchEtcEnvironment(setupInfo.MergedConfig, log)
@@ -118,6 +137,111 @@ func WriteResult(setupInfo *config.Result, log log.Logger) {
 	}
 }
 
+// ReadResult reads the result written by WriteResult
+func ReadResult() (*config.Result, error) {
+	out, err := os.ReadFile(ResultLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &config.Result{}
+	err = json.Unmarshal(out, result)
+	if err != nil {
+		return nil, fmt.Errorf("parse result %w", err)
+	}
+
+	return result, nil
+}
+
+// WriteIDEInstalled records that the given ide was installed in the container
+func WriteIDEInstalled(ide string) error {
+	err := os.MkdirAll(filepath.Dir(IDEInstalledFile), 0777)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(IDEInstalledFile, []byte(ide), 0644)
+}
+
+// ReadIDEInstalled returns the name of the ide that was installed in the container
+func ReadIDEInstalled() string {
+	out, err := os.ReadFile(IDEInstalledFile)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// WriteSecretReferences records the variables of the workspace that reference a secret
+func WriteSecretReferences(references map[string]string) error {
+	if len(references) == 0 {
+		err := os.Remove(SecretReferencesFile)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	out, err := json.Marshal(references)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(SecretReferencesFile), 0777)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(SecretReferencesFile, out, 0600)
+}
+
+// FetchSecrets lets the client resolve the secret references of the workspace and makes the values available
+// to the given user
+func FetchSecrets(ctx context.Context, client tunnel.TunnelClient, userName string) error {
+	references, err := ReadSecretReferences()
+	if err != nil {
+		return err
+	} else if len(references) == 0 {
+		return nil
+	}
+
+	// the client only resolves the references it knows itself
+	response, err := client.Secrets(ctx, &tunnel.Message{})
+	if err != nil {
+		return fmt.Errorf("resolve secrets %w", err)
+	}
+
+	values := map[string]string{}
+	err = json.Unmarshal([]byte(response.Message), &values)
+	if err != nil {
+		return fmt.Errorf("decode secrets %w", err)
+	}
+
+	return envfile.WriteSecrets(values, userName)
+}
+
+// ReadSecretReferences returns the variables of the workspace that reference a secret
+func ReadSecretReferences() (map[string]string, error) {
+	out, err := os.ReadFile(SecretReferencesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	references := map[string]string{}
+	err = json.Unmarshal(out, &references)
+	if err != nil {
+		return nil, fmt.Errorf("parse secret references %w", err)
+	}
+
+	return references, nil
+}
+
 func LinkRootHome(setupInfo *config.Result) error {
 	user := config.GetRemoteUser(setupInfo)
 	if user != "root" {
@@ -219,8 +343,8 @@ func PatchEtcEnvironmentFlags(workspaceEnv []string, log log.Logger) error {
 		return nil
 	}
 
-	// update env
-	envfile.MergeAndApply(config.ListToObject(workspaceEnv), log)
+	// update env, secrets are never written to disk
+	envfile.MergeAndApply(secrets.WithoutReferences(config.ListToObject(workspaceEnv)), log)
 	return nil
 }
 
@@ -244,8 +368,8 @@ func PatchEtcEnvironment(mergedConfig *config.MergedDevContainerConfig, log log.
 		return nil
 	}
 
-	// update env
-	envfile.MergeAndApply(mergedConfig.RemoteEnv, log)
+	// update env, secrets are never written to disk
+	envfile.MergeAndApply(secrets.WithoutReferences(mergedConfig.RemoteEnv), log)
 	return nil
 }
 
@@ -332,8 +456,7 @@ func SetupKubeConfig(ctx context.Context, setupInfo *config.Result, tunnelClient
 }
 
 func markerFileExists(markerName string, markerContent string) (bool, error) {
-	markerName = filepath.Join("/var/devpod", markerName+".marker")
-	t, err := os.ReadFile(markerName)
+	t, err := os.ReadFile(markerFilePath(markerName))
 	if err != nil && !os.IsNotExist(err) {
 		return false, err
 	} else if err == nil && (markerContent == "" || string(t) == markerContent) {
@@ -341,15 +464,29 @@ func markerFileExists(markerName string, markerContent string) (bool, error) {
 	}
 
 	// write marker
-	_ = os.MkdirAll(filepath.Dir(markerName), 0777)
-	err = os.WriteFile(markerName, []byte(markerContent), 0644)
+	err = writeMarkerFile(markerName, markerContent)
 	if err != nil {
-		return false, fmt.Errorf("write marker %w", err)
+		return false, err
 	}
 
 	return false, nil
 }
 
+func writeMarkerFile(markerName string, markerContent string) error {
+	markerPath := markerFilePath(markerName)
+	_ = os.MkdirAll(filepath.Dir(markerPath), 0777)
+	err := os.WriteFile(markerPath, []byte(markerContent), 0644)
+	if err != nil {
+		return fmt.Errorf("write marker %w", err)
+	}
+
+	return nil
+}
+
+func markerFilePath(markerName string) string {
+	return filepath.Join("/var/devpod", markerName+".marker")
+}
+
 func setupPlatformGitCredentials(userName string, platformOptions *devpod.PlatformOptions, log log.Logger) error {
 	// platform is not enabled, skip
 	if !platformOptions.Enabled {
@@ -54,7 +54,12 @@ func (r *runner) runSingleContainer(
 	var containerDetails *config.ContainerDetails
 	var err error
 	dockerCmd := "docker"
-	if r.WorkspaceConfig.Agent.Docker.Path != "" {
+	if r.WorkspaceConfig.Agent.Driver == provider2.PodmanDriver {
+		dockerCmd = "podman"
+		if r.WorkspaceConfig.Agent.Podman.Path != "" {
+			dockerCmd = r.WorkspaceConfig.Agent.Podman.Path
+		}
+	} else if r.WorkspaceConfig.Agent.Docker.Path != "" {
 		dockerCmd = r.WorkspaceConfig.Agent.Docker.Path
 	}
 	if command.Exists(dockerCmd) {
@@ -121,18 +126,23 @@ func (r *runner) runSingleContainer(
 			}
 		}
 	} else {
-		// we need to build the container
-		buildInfo, err := r.build(ctx, parsedConfig, substitutionContext, provider2.BuildOptions{
-			CLIOptions: provider2.CLIOptions{
-				PrebuildRepositories:  options.PrebuildRepositories,
-				ForceDockerless:       options.ForceDockerless,
-				Platform:              options.Platform,
-				ExtraDevContainerPath: options.ExtraDevContainerPath,
-			},
-			NoBuild:       options.NoBuild,
-			RegistryCache: options.RegistryCache,
-			ExportCache:   false,
-		})
+		// we need to build the container or restore it from a snapshot
+		var buildInfo *config.BuildInfo
+		if options.SnapshotImage != "" {
+			buildInfo, err = r.snapshotBuildInfo(ctx, substitutionContext, options.SnapshotImage)
+		} else {
+			buildInfo, err = r.build(ctx, parsedConfig, substitutionContext, provider2.BuildOptions{
+				CLIOptions: provider2.CLIOptions{
+					PrebuildRepositories:  options.PrebuildRepositories,
+					ForceDockerless:       options.ForceDockerless,
+					Platform:              options.Platform,
+					ExtraDevContainerPath: options.ExtraDevContainerPath,
+				},
+				NoBuild:       options.NoBuild,
+				RegistryCache: options.RegistryCache,
+				ExportCache:   false,
+			})
+		}
 		if err != nil {
 			return nil, fmt.Errorf("build image %w", err)
 		}
@@ -170,6 +180,11 @@ func (r *runner) runSingleContainer(
 			}
 		}
 
+		err = r.checkHostRequirements(ctx, mergedConfig.HostRequirements, options.StrictHostRequirements)
+		if err != nil {
+			return nil, err
+		}
+
 		// run dev container
 		err = r.runContainer(ctx, parsedConfig, substitutionContext, mergedConfig, buildInfo)
 		if err != nil {
@@ -186,7 +201,7 @@ func (r *runner) runSingleContainer(
 	}
 
 	// setup container
-	return r.setupContainer(ctx, parsedConfig.Raw, containerDetails, mergedConfig, substitutionContext, timeout)
+	return r.setupContainer(ctx, parsedConfig.Raw, containerDetails, mergedConfig, substitutionContext, options.SecretsClient, timeout)
 }
 
 func (r *runner) runContainer(
@@ -301,13 +316,14 @@ func (r *runner) getDockerlessRunOptions(
 			metadata.ImageMetadataLabel + "=" + string(marshalled),
 			config.UserLabel + "=" + buildInfo.Dockerless.User,
 		},
-		Privileged:     mergedConfig.Privileged,
-		Init:           mergedConfig.Init,
-		WorkspaceMount: &workspaceMountParsed,
-		Mounts:         mounts,
-		Userns:         substitutionContext.Userns,
-		UidMap:         substitutionContext.UidMap,
-		GidMap:         substitutionContext.GidMap,
+		Privileged:       mergedConfig.Privileged,
+		Init:             mergedConfig.Init,
+		WorkspaceMount:   &workspaceMountParsed,
+		Mounts:           mounts,
+		Userns:           substitutionContext.Userns,
+		UidMap:           substitutionContext.UidMap,
+		GidMap:           substitutionContext.GidMap,
+		HostRequirements: mergedConfig.HostRequirements,
 	}, nil
 }
 
@@ -343,22 +359,23 @@ func (r *runner) getRunOptions(
 	}
 
 	return &driver.RunOptions{
-		UID:            uid,
-		Image:          buildInfo.ImageName,
-		User:           user,
-		Entrypoint:     entrypoint,
-		Cmd:            cmd,
-		Env:            mergedConfig.ContainerEnv,
-		CapAdd:         mergedConfig.CapAdd,
-		Labels:         labels,
-		Privileged:     mergedConfig.Privileged,
-		Init:           mergedConfig.Init,
-		WorkspaceMount: &workspaceMountParsed,
-		SecurityOpt:    mergedConfig.SecurityOpt,
-		Mounts:         mergedConfig.Mounts,
-		Userns:         substitutionContext.Userns,
-		UidMap:         substitutionContext.UidMap,
-		GidMap:         substitutionContext.GidMap,
+		UID:              uid,
+		Image:            buildInfo.ImageName,
+		User:             user,
+		Entrypoint:       entrypoint,
+		Cmd:              cmd,
+		Env:              mergedConfig.ContainerEnv,
+		CapAdd:           mergedConfig.CapAdd,
+		Labels:           labels,
+		Privileged:       mergedConfig.Privileged,
+		Init:             mergedConfig.Init,
+		WorkspaceMount:   &workspaceMountParsed,
+		SecurityOpt:      mergedConfig.SecurityOpt,
+		Mounts:           mergedConfig.Mounts,
+		Userns:           substitutionContext.Userns,
+		UidMap:           substitutionContext.UidMap,
+		GidMap:           substitutionContext.GidMap,
+		HostRequirements: mergedConfig.HostRequirements,
 	}, nil
 }
 
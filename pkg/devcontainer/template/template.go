@@ -0,0 +1,291 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/devcontainer/feature"
+	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/log"
+	"github.com/skevetter/log/hash"
+)
+
+const (
+	// DefaultCollection is the collection templates without registry are resolved against
+	DefaultCollection = "ghcr.io/devcontainers/templates"
+
+	// DEVCONTAINER_TEMPLATE_FILE_NAME is the metadata file of a template
+	DEVCONTAINER_TEMPLATE_FILE_NAME = "devcontainer-template.json"
+
+	// DEVCONTAINER_COLLECTION_FILE_NAME is the metadata of a collection of templates or features
+	DEVCONTAINER_COLLECTION_FILE_NAME = feature.DEVCONTAINER_COLLECTION_FILE_NAME
+)
+
+// templateMetadataFiles describe a template and are not copied into the workspace
+var templateMetadataFiles = []string{DEVCONTAINER_TEMPLATE_FILE_NAME, "README.md", "NOTES.md"}
+
+// TemplateConfig is the devcontainer-template.json of a template
+type TemplateConfig struct {
+	// ID of the template, it matches the name of the folder the template was published from.
+	ID string `json:"id"`
+
+	// The version of the template. Follows the semantic versioning (semver) specification.
+	Version string `json:"version,omitempty"`
+
+	// Display name of the template.
+	Name string `json:"name,omitempty"`
+
+	// Description of the template.
+	Description string `json:"description,omitempty"`
+
+	// URL to documentation for the template.
+	DocumentationURL string `json:"documentationURL,omitempty"`
+
+	// Name of the publisher of the template.
+	Publisher string `json:"publisher,omitempty"`
+
+	// Keywords to search for the template.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// Options replace the ${templateOption:name} variables in the files of the template.
+	Options map[string]config.FeatureConfigOption `json:"options,omitempty"`
+
+	// OptionalPaths are files or folders of the template that can be left out.
+	OptionalPaths []string `json:"optionalPaths,omitempty"`
+}
+
+// Collection is the devcontainer-collection.json published with a collection of templates
+type Collection struct {
+	Templates []*TemplateConfig `json:"templates,omitempty"`
+}
+
+// ListTemplates returns the templates of the given collection, e.g. ghcr.io/devcontainers/templates
+func ListTemplates(collection string, log log.Logger) ([]*TemplateConfig, error) {
+	if collection == "" {
+		collection = DefaultCollection
+	}
+
+	destFile := filepath.Join(getTemplatesTempFolder(collection), DEVCONTAINER_COLLECTION_FILE_NAME)
+	err := feature.PullOCIArtifact(collection+":latest", destFile, log)
+	if err != nil {
+		return nil, fmt.Errorf("pull collection %s %w", collection, err)
+	}
+
+	out, err := os.ReadFile(destFile)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &Collection{}
+	err = json.Unmarshal(out, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s %w", DEVCONTAINER_COLLECTION_FILE_NAME, err)
+	}
+
+	return metadata.Templates, nil
+}
+
+// ResolveTemplateID returns the OCI reference of a template. IDs without registry, e.g. go or go:1, are
+// resolved against the default collection.
+func ResolveTemplateID(id string) string {
+	if strings.Contains(id, "/") {
+		return id
+	}
+
+	return DefaultCollection + "/" + id
+}
+
+// PullTemplate downloads the template and returns the folder it was extracted to together with its config
+func PullTemplate(id string, log log.Logger) (string, *TemplateConfig, error) {
+	reference := ResolveTemplateID(id)
+	templateFolder := getTemplatesTempFolder(reference)
+	extractedFolder := filepath.Join(templateFolder, "extracted")
+	_ = os.RemoveAll(templateFolder)
+
+	destFile := filepath.Join(templateFolder, "template.tgz")
+	err := feature.PullOCIArtifact(reference, destFile, log)
+	if err != nil {
+		return "", nil, fmt.Errorf("pull template %s %w", reference, err)
+	}
+
+	file, err := os.Open(destFile)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	err = extract.Extract(file, extractedFolder)
+	if err != nil {
+		return "", nil, fmt.Errorf("extract template %w", err)
+	}
+
+	templateConfig, err := ParseTemplateConfig(extractedFolder)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return extractedFolder, templateConfig, nil
+}
+
+// ParseTemplateConfig parses the devcontainer-template.json in the given folder
+func ParseTemplateConfig(folder string) (*TemplateConfig, error) {
+	out, err := os.ReadFile(filepath.Join(folder, DEVCONTAINER_TEMPLATE_FILE_NAME))
+	if err != nil {
+		return nil, fmt.Errorf("read %s %w", DEVCONTAINER_TEMPLATE_FILE_NAME, err)
+	}
+
+	templateConfig := &TemplateConfig{}
+	err = json.Unmarshal(out, templateConfig)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s %w", DEVCONTAINER_TEMPLATE_FILE_NAME, err)
+	}
+
+	return templateConfig, nil
+}
+
+// ResolveOptions returns the value of every option of the template. Values that are not given are asked
+// through the question function if it is not nil, otherwise the default value is used.
+func ResolveOptions(templateConfig *TemplateConfig, values map[string]string, question func(name string, option config.FeatureConfigOption) (string, error)) (map[string]string, error) {
+	for name := range values {
+		if _, ok := templateConfig.Options[name]; !ok {
+			return nil, fmt.Errorf("template %s has no option %s", templateConfig.ID, name)
+		}
+	}
+
+	resolved := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(templateConfig.Options)) {
+		option := templateConfig.Options[name]
+		value, ok := values[name]
+		if !ok && question != nil {
+			var err error
+			value, err = question(name, option)
+			if err != nil {
+				return nil, err
+			}
+		} else if !ok {
+			value = string(option.Default)
+		}
+
+		if len(option.Enum) > 0 && !slices.Contains(option.Enum, value) {
+			return nil, fmt.Errorf("invalid value %s for option %s, expected one of %s", value, name, strings.Join(option.Enum, ", "))
+		}
+		resolved[name] = value
+	}
+
+	return resolved, nil
+}
+
+// Apply copies the files of the extracted template into the target folder and replaces the
+// ${templateOption:name} variables with the given option values. Optional paths that are omitted are
+// not copied. Existing files are only overwritten if force is true.
+func Apply(templateFolder, targetFolder string, options map[string]string, omitPaths []string, force bool) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(templateFolder, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(templateFolder, absPath)
+		if err != nil {
+			return err
+		} else if relPath == "." {
+			return nil
+		}
+		if isOmitted(filepath.ToSlash(relPath), omitPaths) || (!d.IsDir() && slices.Contains(templateMetadataFiles, relPath)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.Type().IsRegular() {
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apply template %w", err)
+	}
+
+	if !force {
+		conflicts := []string{}
+		for _, relPath := range files {
+			if _, err := os.Lstat(filepath.Join(targetFolder, relPath)); err == nil {
+				conflicts = append(conflicts, relPath)
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, fmt.Errorf("files of the template already exist in %s: %s, use --force to overwrite them", targetFolder, strings.Join(conflicts, ", "))
+		}
+	}
+
+	for _, relPath := range files {
+		err = applyFile(filepath.Join(templateFolder, relPath), filepath.Join(targetFolder, relPath), options)
+		if err != nil {
+			return nil, fmt.Errorf("apply template %w", err)
+		}
+	}
+
+	return files, nil
+}
+
+func applyFile(sourcePath, targetPath string, options map[string]string) error {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return err
+	}
+
+	content = []byte(substituteOptions(string(content), options))
+	return os.WriteFile(targetPath, content, info.Mode().Perm())
+}
+
+// substituteOptions replaces ${templateOption:name} with the value of the option, other variables are kept
+func substituteOptions(content string, options map[string]string) string {
+	return config.ResolveString(content, func(match, variable string, args []string) string {
+		if variable != "templateOption" || len(args) == 0 {
+			return match
+		}
+
+		value, ok := options[strings.TrimSpace(args[0])]
+		if !ok {
+			return match
+		}
+
+		return value
+	})
+}
+
+// isOmitted returns true if the path is one of the omitted optional paths. Optional paths ending with /*
+// omit the contents of a folder.
+func isOmitted(relPath string, omitPaths []string) bool {
+	for _, omitPath := range omitPaths {
+		omitPath = strings.TrimSuffix(strings.TrimSuffix(omitPath, "*"), "/")
+		if relPath == omitPath || strings.HasPrefix(relPath, omitPath+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getTemplatesTempFolder(id string) string {
+	hashedID := hash.String(id)[:10]
+	return filepath.Join(os.TempDir(), "devpod", "templates", hashedID)
+}
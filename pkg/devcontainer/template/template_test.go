@@ -0,0 +1,98 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"gotest.tools/assert"
+)
+
+func TestApply(t *testing.T) {
+	templateFolder := t.TempDir()
+	files := map[string]string{
+		DEVCONTAINER_TEMPLATE_FILE_NAME:    `{"id": "go"}`,
+		"README.md":                        "# Go",
+		".devcontainer/devcontainer.json":  `{"image": "mcr.microsoft.com/devcontainers/go:${templateOption:imageVariant}", "workspaceFolder": "${localWorkspaceFolder}"}`,
+		".github/dependabot.yml":           "version: 2",
+		".devcontainer/scripts/install.sh": "echo ${templateOption:unknown}",
+	}
+	for name, content := range files {
+		path := filepath.Join(templateFolder, name)
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	target := t.TempDir()
+	written, err := Apply(templateFolder, target, map[string]string{"imageVariant": "1.22"}, []string{".github/*"}, false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(written), 2)
+
+	out, err := os.ReadFile(filepath.Join(target, ".devcontainer", "devcontainer.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), `{"image": "mcr.microsoft.com/devcontainers/go:1.22", "workspaceFolder": "${localWorkspaceFolder}"}`)
+
+	out, err = os.ReadFile(filepath.Join(target, ".devcontainer", "scripts", "install.sh"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "echo ${templateOption:unknown}")
+
+	for _, name := range []string{DEVCONTAINER_TEMPLATE_FILE_NAME, "README.md", ".github"} {
+		_, err = os.Stat(filepath.Join(target, name))
+		assert.Assert(t, os.IsNotExist(err), name)
+	}
+}
+
+func TestApplyConflict(t *testing.T) {
+	templateFolder := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(templateFolder, ".devcontainer"), 0755))
+	assert.NilError(t, os.WriteFile(filepath.Join(templateFolder, ".devcontainer", "devcontainer.json"), []byte(`{"image": "ubuntu"}`), 0644))
+	assert.NilError(t, os.WriteFile(filepath.Join(templateFolder, "Makefile"), []byte("template"), 0644))
+
+	target := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(target, "Makefile"), []byte("mine"), 0644))
+
+	_, err := Apply(templateFolder, target, nil, nil, false)
+	assert.ErrorContains(t, err, "Makefile")
+	out, err := os.ReadFile(filepath.Join(target, "Makefile"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "mine")
+	_, err = os.Stat(filepath.Join(target, ".devcontainer"))
+	assert.Assert(t, os.IsNotExist(err))
+
+	_, err = Apply(templateFolder, target, nil, nil, true)
+	assert.NilError(t, err)
+	out, err = os.ReadFile(filepath.Join(target, "Makefile"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "template")
+}
+
+func TestResolveOptions(t *testing.T) {
+	templateConfig := &TemplateConfig{
+		ID: "go",
+		Options: map[string]config.FeatureConfigOption{
+			"imageVariant": {Type: "string", Default: "1.22", Proposals: []string{"1.22", "1.21"}},
+			"installNode":  {Type: "boolean", Default: "false"},
+			"distro":       {Type: "string", Default: "bookworm", Enum: []string{"bookworm", "bullseye"}},
+		},
+	}
+
+	options, err := ResolveOptions(templateConfig, map[string]string{"imageVariant": "1.23"}, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, options, map[string]string{"imageVariant": "1.23", "installNode": "false", "distro": "bookworm"})
+
+	asked := []string{}
+	options, err = ResolveOptions(templateConfig, nil, func(name string, option config.FeatureConfigOption) (string, error) {
+		asked = append(asked, name)
+		return string(option.Default), nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, asked, []string{"distro", "imageVariant", "installNode"})
+	assert.Equal(t, options["imageVariant"], "1.22")
+
+	_, err = ResolveOptions(templateConfig, map[string]string{"distro": "alpine"}, nil)
+	assert.ErrorContains(t, err, "invalid value alpine")
+
+	_, err = ResolveOptions(templateConfig, map[string]string{"unknown": "value"}, nil)
+	assert.ErrorContains(t, err, "has no option unknown")
+}
@@ -66,6 +66,28 @@ func (r *DockerHelper) GPUSupportEnabled() (bool, error) {
 	return strings.Contains(string(out), "nvidia-container-runtime"), nil
 }
 
+// DaemonInfo holds the resources and storage driver reported by `docker info`
+type DaemonInfo struct {
+	NCPU     int    `json:"NCPU,omitempty"`
+	MemTotal int64  `json:"MemTotal,omitempty"`
+	Driver   string `json:"Driver,omitempty"`
+}
+
+func (r *DockerHelper) Info(ctx context.Context) (*DaemonInfo, error) {
+	out, err := r.buildCmd(ctx, "info", "-f", "{{json .}}").Output()
+	if err != nil {
+		return nil, command.WrapCommandError(out, err)
+	}
+
+	info := &DaemonInfo{}
+	err = json.Unmarshal(out, info)
+	if err != nil {
+		return nil, fmt.Errorf("parse docker info %w", err)
+	}
+
+	return info, nil
+}
+
 func (r *DockerHelper) FindDevContainer(ctx context.Context, labels []string) (*config.ContainerDetails, error) {
 	containers, err := r.FindContainer(ctx, labels)
 	if err != nil {
@@ -142,6 +164,33 @@ func (r *DockerHelper) Remove(ctx context.Context, id string) error {
 	return nil
 }
 
+// Commit creates a new image from the given container
+func (r *DockerHelper) Commit(ctx context.Context, id string, image string) error {
+	out, err := r.buildCmd(ctx, "commit", id, image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %w", string(out), err)
+	}
+
+	return nil
+}
+
+// RemoveImage removes the given image if it exists
+func (r *DockerHelper) RemoveImage(ctx context.Context, image string) error {
+	out, err := r.buildCmd(ctx, "image", "ls", "-q", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %w", string(out), err)
+	} else if len(bytes.TrimSpace(out)) == 0 {
+		return nil
+	}
+
+	out, err = r.buildCmd(ctx, "rmi", image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %w", string(out), err)
+	}
+
+	return nil
+}
+
 func (r *DockerHelper) Run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 	return r.RunWithDir(ctx, "", args, stdin, stdout, stderr)
 }
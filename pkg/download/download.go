@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/gitcredentials"
 	devpodhttp "github.com/skevetter/devpod/pkg/http"
 	"github.com/skevetter/log"
@@ -28,6 +29,10 @@ func Head(rawURL string) (int, error) {
 }
 
 func File(rawURL string, log log.Logger) (io.ReadCloser, error) {
+	if cache.Offline() {
+		return nil, cache.OfflineError(rawURL)
+	}
+
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
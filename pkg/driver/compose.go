@@ -0,0 +1,34 @@
+package driver
+
+import (
+	"context"
+
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+)
+
+type RunComposeDevContainerParams struct {
+	WorkspaceID string
+
+	// Options are the run options of the devcontainer service
+	Options *RunOptions
+
+	// Project is the docker compose project of the devcontainer
+	Project *composetypes.Project
+
+	// Service is the name of the devcontainer service in the project
+	Service string
+
+	// LocalWorkspaceFolder is the local folder of the workspace that binds of the project refer to
+	LocalWorkspaceFolder string
+
+	// RunServices are the services to start besides the devcontainer service, all if empty
+	RunServices []string
+}
+
+// ComposeDriver is implemented by drivers that run docker compose devcontainers without docker compose
+type ComposeDriver interface {
+	Driver
+
+	// RunComposeDevContainer runs the devcontainer service together with the other services of the project
+	RunComposeDevContainer(ctx context.Context, params *RunComposeDevContainerParams) error
+}
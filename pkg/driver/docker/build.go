@@ -5,14 +5,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/devcontainer/build"
 	"github.com/skevetter/devpod/pkg/devcontainer/buildkit"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/devcontainer/feature"
 	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/devpod/pkg/dockerfile"
 	"github.com/skevetter/devpod/pkg/provider"
 )
 
@@ -65,6 +68,14 @@ func (d *dockerDriver) BuildDevContainer(
 		"registry_cache": options.RegistryCache,
 	}).Debug("using registry cache")
 
+	// docker would try to pull missing images
+	if cache.Offline() {
+		err = d.checkOfflineImages(ctx, buildOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// build image
 	writer := d.Log.Writer(logrus.InfoLevel, false)
 	defer func() { _ = writer.Close() }()
@@ -121,6 +132,37 @@ func (d *dockerDriver) BuildDevContainer(
 	}, nil
 }
 
+// checkOfflineImages returns an error if the base image or the dockerfile frontend of the build are not
+// available locally
+func (d *dockerDriver) checkOfflineImages(ctx context.Context, options *build.BuildOptions) error {
+	content, err := os.ReadFile(options.Dockerfile)
+	if err != nil {
+		return err
+	}
+
+	parsedDockerfile, err := dockerfile.Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("parse dockerfile %w", err)
+	}
+
+	images := []string{}
+	if parsedDockerfile.Syntax != "" {
+		images = append(images, parsedDockerfile.Syntax)
+	}
+	if baseImage := parsedDockerfile.FindBaseImage(options.BuildArgs, options.Target); baseImage != "" && baseImage != "scratch" {
+		images = append(images, baseImage)
+	}
+
+	for _, image := range images {
+		_, err := d.Docker.InspectImage(ctx, image, false)
+		if err != nil {
+			return cache.OfflineError("image " + image)
+		}
+	}
+
+	return nil
+}
+
 func (d *dockerDriver) buildxExists(ctx context.Context) bool {
 	buf := &bytes.Buffer{}
 	err := d.Docker.Run(ctx, []string{"buildx", "version"}, nil, buf, buf)
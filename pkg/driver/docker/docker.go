@@ -249,7 +249,7 @@ func (d *dockerDriver) RunDockerDevContainer(ctx context.Context, params *driver
 		return err
 	}
 
-	args, err := d.buildRunArgs(params, helper)
+	args, err := d.buildRunArgs(ctx, params, helper)
 	if err != nil {
 		return err
 	}
@@ -270,7 +270,7 @@ type runArgsBuilder struct {
 	params *driver.RunDockerDevContainerParams
 }
 
-func (d *dockerDriver) buildRunArgs(params *driver.RunDockerDevContainerParams, helper *docker.DockerHelper) ([]string, error) {
+func (d *dockerDriver) buildRunArgs(ctx context.Context, params *driver.RunDockerDevContainerParams, helper *docker.DockerHelper) ([]string, error) {
 	b := &runArgsBuilder{
 		args:   []string{"run"},
 		driver: d,
@@ -301,6 +301,7 @@ func (d *dockerDriver) buildRunArgs(params *driver.RunDockerDevContainerParams,
 	b.addIDEMount().
 		addLabels().
 		addGPU().
+		addResources(ctx, helper).
 		addRunArgs().
 		addDetached().
 		addEntrypoint().
@@ -340,7 +341,7 @@ func (b *runArgsBuilder) addPrivileged() *runArgsBuilder {
 }
 
 func (b *runArgsBuilder) addPodmanArgs() error {
-	podmanArgs, err := b.driver.getPodmanArgs(b.params.Options, b.params.ParsedConfig)
+	podmanArgs, err := b.driver.getPodmanArgs(b.params.Options)
 	if err != nil {
 		return err
 	}
@@ -377,6 +378,11 @@ func (b *runArgsBuilder) addGPU() *runArgsBuilder {
 	return b
 }
 
+func (b *runArgsBuilder) addResources(ctx context.Context, helper *docker.DockerHelper) *runArgsBuilder {
+	b.args = b.driver.addResourceArgs(ctx, b.args, b.params.Options, helper)
+	return b
+}
+
 func (b *runArgsBuilder) addRunArgs() *runArgsBuilder {
 	b.args = append(b.args, b.params.ParsedConfig.RunArgs...)
 	return b
@@ -537,6 +543,60 @@ func appendGPUOptions(parsedConfig *config.DevContainerConfig, d *dockerDriver,
 	return args
 }
 
+// storageOptDrivers are the docker storage drivers that support the size storage option
+var storageOptDrivers = map[string]bool{
+	"btrfs":         true,
+	"zfs":           true,
+	"devicemapper":  true,
+	"windowsfilter": true,
+}
+
+func (d *dockerDriver) addResourceArgs(ctx context.Context, args []string, options *driver.RunOptions, helper *docker.DockerHelper) []string {
+	requirements := options.HostRequirements
+	if requirements.IsEmpty() {
+		return args
+	}
+
+	if requirements.CPUs > 0 {
+		args = append(args, "--cpus", strconv.Itoa(requirements.CPUs))
+	}
+
+	memory, err := requirements.MemoryBytes()
+	if err != nil {
+		d.Log.Warnf("skipping memory host requirement: %v", err)
+	} else if memory > 0 {
+		args = append(args, "--memory", strconv.FormatInt(memory, 10))
+	}
+
+	storage, err := requirements.StorageBytes()
+	if err != nil {
+		d.Log.Warnf("skipping storage host requirement: %v", err)
+	} else if storage > 0 {
+		info, err := helper.Info(ctx)
+		if err != nil {
+			d.Log.Debugf("error retrieving docker info, skipping storage host requirement: %v", err)
+		} else if !storageOptDrivers[info.Driver] {
+			d.Log.Debugf("storage driver %s does not support size limits, skipping storage host requirement", info.Driver)
+		} else {
+			args = append(args, "--storage-opt", "size="+strconv.FormatInt(storage, 10))
+		}
+	}
+
+	return args
+}
+
+func (d *dockerDriver) HostResources(ctx context.Context) (*driver.HostResources, error) {
+	info, err := d.Docker.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &driver.HostResources{
+		CPUs:   info.NCPU,
+		Memory: info.MemTotal,
+	}, nil
+}
+
 func (d *dockerDriver) EnsureImage(
 	ctx context.Context,
 	options *driver.RunOptions,
@@ -585,7 +645,7 @@ func (d *dockerDriver) GetDevContainerLogs(ctx context.Context, workspaceId stri
 	return d.Docker.GetContainerLogs(ctx, container.ID, stdout, stderr)
 }
 
-func (d *dockerDriver) getPodmanArgs(options *driver.RunOptions, parsedConfig *config.DevContainerConfig) ([]string, error) {
+func (d *dockerDriver) getPodmanArgs(options *driver.RunOptions) ([]string, error) {
 	if !d.Docker.IsPodman() {
 		return []string{}, nil
 	}
@@ -593,7 +653,6 @@ func (d *dockerDriver) getPodmanArgs(options *driver.RunOptions, parsedConfig *c
 	var args []string
 	args = d.addUsernsArgs(args, options)
 	args = d.addIdMappingArgs(args, options)
-	args = d.addKeepIdArgs(args, options, parsedConfig)
 	return args, nil
 }
 
@@ -614,48 +673,6 @@ func (d *dockerDriver) addIdMappingArgs(args []string, options *driver.RunOption
 	return args
 }
 
-func (d *dockerDriver) addKeepIdArgs(args []string, options *driver.RunOptions, parsedConfig *config.DevContainerConfig) []string {
-	if d.hasIdMapping(options, parsedConfig) || options.Userns != "" {
-		return args
-	}
-
-	remoteUser := d.getRemoteUser(options, parsedConfig)
-	if remoteUser != "root" && remoteUser != "0" && os.Getuid() != 0 {
-		args = append(args, "--userns=keep-id")
-	}
-	return args
-}
-
-func (d *dockerDriver) hasIdMapping(options *driver.RunOptions, parsedConfig *config.DevContainerConfig) bool {
-	if len(options.UidMap) > 0 || len(options.GidMap) > 0 {
-		return true
-	}
-
-	if parsedConfig != nil {
-		for _, arg := range parsedConfig.RunArgs {
-			if strings.Contains(arg, "--uidmap") || strings.Contains(arg, "--gidmap") {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func (d *dockerDriver) getRemoteUser(options *driver.RunOptions, parsedConfig *config.DevContainerConfig) string {
-	if parsedConfig != nil {
-		if parsedConfig.RemoteUser != "" {
-			return parsedConfig.RemoteUser
-		}
-		if parsedConfig.ContainerUser != "" {
-			return parsedConfig.ContainerUser
-		}
-	}
-	if options.User != "" {
-		return options.User
-	}
-	return "root"
-}
-
 func (d *dockerDriver) shouldUpdateUserUID(parsedConfig *config.DevContainerConfig) bool {
 	isLinux := runtime.GOOS == "linux"
 	hasUser := parsedConfig.ContainerUser != "" || parsedConfig.RemoteUser != ""
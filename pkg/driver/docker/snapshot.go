@@ -0,0 +1,240 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+)
+
+var _ driver.SnapshotDriver = (*dockerDriver)(nil)
+
+// snapshotVolumePath is the path volumes are mounted to in the helper containers
+const snapshotVolumePath = "/volume"
+
+func (d *dockerDriver) CreateSnapshot(ctx context.Context, workspaceId, image, volumesFolder string) ([]string, error) {
+	container, err := d.findSnapshotContainer(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit the container filesystem
+	d.Log.WithFields(logrus.Fields{
+		"containerID": container.ID,
+		"image":       image,
+	}).Debug("committing devcontainer")
+	err = d.Docker.Commit(ctx, container.ID, image)
+	if err != nil {
+		return nil, fmt.Errorf("commit container %w", err)
+	}
+
+	// archive named volumes
+	err = os.MkdirAll(volumesFolder, 0755)
+	if err != nil {
+		return nil, err
+	}
+	volumes := []string{}
+	for _, mount := range container.Mounts {
+		if mount.Type != "volume" || mount.Name == "" {
+			continue
+		}
+
+		d.Log.WithFields(logrus.Fields{
+			"volume": mount.Name,
+		}).Debug("archiving volume")
+		err = d.archiveVolume(ctx, image, mount.Name, filepath.Join(volumesFolder, mount.Name+".tar"))
+		if err != nil {
+			return nil, fmt.Errorf("archive volume %s %w", mount.Name, err)
+		}
+
+		volumes = append(volumes, mount.Name)
+	}
+
+	return volumes, nil
+}
+
+func (d *dockerDriver) RestoreSnapshot(ctx context.Context, workspaceId, image, volumesFolder string, volumes []string) error {
+	container, err := d.findSnapshotContainer(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	// the volumes are replaced, so the container using them has to be removed. It is recreated from the
+	// snapshot image afterwards.
+	return d.restoreVolumes(ctx, image, volumesFolder, volumes, func() error {
+		if strings.ToLower(container.State.Status) == "running" {
+			err := d.Docker.Stop(ctx, container.ID)
+			if err != nil {
+				return fmt.Errorf("stop container %w", err)
+			}
+		}
+
+		err := d.Docker.Remove(ctx, container.ID)
+		if err != nil {
+			return fmt.Errorf("remove container %w", err)
+		}
+
+		return nil
+	})
+}
+
+// restoreVolumes restores the archived volumes into staging volumes first, so a failed restore keeps the
+// container and its volumes. Only once all archives are restored, the container is removed and the volumes are
+// replaced with the content of the staging volumes.
+func (d *dockerDriver) restoreVolumes(ctx context.Context, image, volumesFolder string, volumes []string, removeContainer func() error) error {
+	staged := []string{}
+	defer func() {
+		for _, stagingVolume := range staged {
+			err := d.Docker.DeleteVolume(ctx, stagingVolume)
+			if err != nil {
+				d.Log.Debugf("Error deleting staging volume %s: %v", stagingVolume, err)
+			}
+		}
+	}()
+
+	for _, volume := range volumes {
+		d.Log.WithFields(logrus.Fields{
+			"volume": volume,
+		}).Debug("restoring volume")
+		stagingVolume := restoreStagingVolume(volume)
+		staged = append(staged, stagingVolume)
+		err := d.restoreVolume(ctx, image, stagingVolume, filepath.Join(volumesFolder, volume+".tar"))
+		if err != nil {
+			return fmt.Errorf("restore volume %s %w", volume, err)
+		}
+	}
+
+	err := removeContainer()
+	if err != nil {
+		return err
+	}
+
+	for i, volume := range volumes {
+		err = d.replaceVolume(ctx, image, volume, staged[i])
+		if err != nil {
+			// keep the staging volumes, they hold the restored data of the volumes that weren't replaced yet
+			staged = nil
+			return fmt.Errorf("replace volume %s, the restored content is kept in the volume %s: %w", volume, restoreStagingVolume(volume), err)
+		}
+	}
+
+	return nil
+}
+
+func (d *dockerDriver) DeleteSnapshot(ctx context.Context, image string) error {
+	return d.Docker.RemoveImage(ctx, image)
+}
+
+func (d *dockerDriver) findSnapshotContainer(ctx context.Context, workspaceId string) (*config.ContainerDetails, error) {
+	if d.Docker.ContainerID != "" {
+		return nil, fmt.Errorf("snapshots are not supported for containers not created by DevPod")
+	}
+
+	container, err := d.FindDevContainer(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	} else if container == nil {
+		return nil, fmt.Errorf("container not found")
+	} else if _, ok := container.Config.Labels["com.docker.compose.project"]; ok {
+		return nil, fmt.Errorf("snapshots are not supported for docker compose workspaces")
+	}
+
+	return container, nil
+}
+
+// archiveVolume writes the contents of the volume as tar archive to the given file
+func (d *dockerDriver) archiveVolume(ctx context.Context, image, volume, archive string) error {
+	file, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	return d.withVolumeContainer(ctx, image, volume, func(containerID string) error {
+		stderr := d.Log.Writer(logrus.DebugLevel, false)
+		defer func() { _ = stderr.Close() }()
+
+		return d.Docker.Run(ctx, []string{"cp", containerID + ":" + snapshotVolumePath + "/.", "-"}, nil, file, stderr)
+	})
+}
+
+// restoreVolume recreates the volume with the contents of the given tar archive
+func (d *dockerDriver) restoreVolume(ctx context.Context, image, volume, archive string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	err = d.Docker.DeleteVolume(ctx, volume)
+	if err != nil {
+		return fmt.Errorf("delete volume %w", err)
+	}
+
+	return d.copyIntoVolume(ctx, image, volume, file)
+}
+
+// replaceVolume recreates the volume with the contents of the source volume
+func (d *dockerDriver) replaceVolume(ctx context.Context, image, volume, sourceVolume string) error {
+	err := d.Docker.DeleteVolume(ctx, volume)
+	if err != nil {
+		return fmt.Errorf("delete volume %w", err)
+	}
+
+	return d.withVolumeContainer(ctx, image, sourceVolume, func(containerID string) error {
+		reader, writer := io.Pipe()
+		go func() {
+			stderr := d.Log.Writer(logrus.DebugLevel, false)
+			defer func() { _ = stderr.Close() }()
+
+			err := d.Docker.Run(ctx, []string{"cp", containerID + ":" + snapshotVolumePath + "/.", "-"}, nil, writer, stderr)
+			_ = writer.CloseWithError(err)
+		}()
+		defer func() { _ = reader.Close() }()
+
+		return d.copyIntoVolume(ctx, image, volume, reader)
+	})
+}
+
+// copyIntoVolume extracts the tar archive into the volume
+func (d *dockerDriver) copyIntoVolume(ctx context.Context, image, volume string, archive io.Reader) error {
+	return d.withVolumeContainer(ctx, image, volume, func(containerID string) error {
+		stderr := d.Log.Writer(logrus.DebugLevel, false)
+		defer func() { _ = stderr.Close() }()
+
+		return d.Docker.Run(ctx, []string{"cp", "-", containerID + ":" + snapshotVolumePath}, archive, stderr, stderr)
+	})
+}
+
+// restoreStagingVolume returns the volume the archive of the given volume is restored into before it replaces it
+func restoreStagingVolume(volume string) string {
+	return volume + "-devpod-restore"
+}
+
+// withVolumeContainer creates a container that mounts the volume, but never starts it. The snapshot image is used
+// as it is guaranteed to exist locally, docker cp copies from and into the volume without running anything in it.
+func (d *dockerDriver) withVolumeContainer(ctx context.Context, image, volume string, fn func(containerID string) error) error {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := d.Docker.Run(ctx, []string{"create", "-v", volume + ":" + snapshotVolumePath, image}, nil, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("create helper container: %s %w", stderr.String(), err)
+	}
+
+	containerID := strings.TrimSpace(stdout.String())
+	defer func() {
+		err := d.Docker.Remove(ctx, containerID)
+		if err != nil {
+			d.Log.Debugf("Error removing helper container %s: %v", containerID, err)
+		}
+	}()
+
+	return fn(containerID)
+}
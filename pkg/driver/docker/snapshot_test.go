@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/docker"
+	"github.com/skevetter/devpod/pkg/extract"
+	"github.com/skevetter/log"
+)
+
+// fakeDocker emulates the docker commands used to restore volumes, volumes are folders in $FAKE_DOCKER_DIR/volumes
+// and copying into the volume $FAKE_DOCKER_FAIL fails
+const fakeDocker = `#!/bin/sh
+set -e
+dir="$FAKE_DOCKER_DIR"
+case "$1" in
+create)
+	volume="${3%%:*}"
+	mkdir -p "$dir/volumes/$volume" "$dir/containers"
+	id="helper-$(ls "$dir/containers" | wc -l | tr -d ' ')"
+	echo "$volume" > "$dir/containers/$id"
+	echo "$id"
+	;;
+cp)
+	if [ "$2" = "-" ]; then
+		volume="$(cat "$dir/containers/${3%%:*}")"
+		[ "$volume" != "$FAKE_DOCKER_FAIL" ] || { cat > /dev/null; echo "copy failed" >&2; exit 1; }
+		tar -C "$dir/volumes/$volume" -xf -
+	else
+		volume="$(cat "$dir/containers/${2%%:*}")"
+		tar -C "$dir/volumes/$volume" -cf - .
+	fi
+	;;
+rm)
+	rm -f "$dir/containers/$2"
+	;;
+volume)
+	if [ "$2" = "list" ]; then
+		volume="${5#name=}"
+		[ ! -d "$dir/volumes/$volume" ] || echo "$volume"
+	else
+		rm -rf "$dir/volumes/$3"
+	fi
+	;;
+esac
+`
+
+func TestRestoreVolumes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker command needs a shell")
+	}
+
+	tests := []struct {
+		name string
+		fail string
+
+		wantErr     bool
+		wantRemoved bool
+		want        map[string]string
+	}{
+		{
+			name:        "restored",
+			wantRemoved: true,
+			want:        map[string]string{"data/file": "new data", "cache/file": "new cache"},
+		},
+		{
+			name:    "failed restore keeps the volumes",
+			fail:    restoreStagingVolume("cache"),
+			wantErr: true,
+			want:    map[string]string{"data/file": "old data", "data/old": "old", "cache/file": "old cache"},
+		},
+		{
+			name:        "failed replace keeps the restored content",
+			fail:        "cache",
+			wantErr:     true,
+			wantRemoved: true,
+			want:        map[string]string{"data/file": "new data", restoreStagingVolume("cache") + "/file": "new cache"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dockerCommand := filepath.Join(dir, "docker")
+			writeFile(t, dockerCommand, fakeDocker)
+			if err := os.Chmod(dockerCommand, 0755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("FAKE_DOCKER_DIR", dir)
+			t.Setenv("FAKE_DOCKER_FAIL", tt.fail)
+
+			// the current volumes and the archives of the snapshot
+			writeFile(t, filepath.Join(dir, "volumes", "data", "file"), "old data")
+			writeFile(t, filepath.Join(dir, "volumes", "data", "old"), "old")
+			writeFile(t, filepath.Join(dir, "volumes", "cache", "file"), "old cache")
+			volumesFolder := filepath.Join(dir, "snapshot")
+			writeArchive(t, filepath.Join(volumesFolder, "data.tar"), "file", "new data")
+			writeArchive(t, filepath.Join(volumesFolder, "cache.tar"), "file", "new cache")
+
+			d := &dockerDriver{Docker: &docker.DockerHelper{DockerCommand: dockerCommand, Log: log.Discard}, Log: log.Discard}
+			removed := false
+			err := d.restoreVolumes(context.Background(), "snapshot", volumesFolder, []string{"data", "cache"}, func() error {
+				removed = true
+				return nil
+			})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			} else if removed != tt.wantRemoved {
+				t.Fatalf("expected container removed %t, got %t", tt.wantRemoved, removed)
+			}
+
+			for file, content := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dir, "volumes", file))
+				if err != nil {
+					t.Fatal(err)
+				} else if string(got) != content {
+					t.Fatalf("expected %q in %s, got %q", content, file, got)
+				}
+			}
+			if !tt.wantErr {
+				if _, err := os.Stat(filepath.Join(dir, "volumes", "data", "old")); !errors.Is(err, os.ErrNotExist) {
+					t.Fatal("volume wasn't replaced")
+				}
+				if _, err := os.Stat(filepath.Join(dir, "volumes", restoreStagingVolume("data"))); !errors.Is(err, os.ErrNotExist) {
+					t.Fatal("staging volume wasn't deleted")
+				}
+			}
+		})
+	}
+}
+
+func writeArchive(t *testing.T, archive, name, content string) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, name), content)
+	err := os.MkdirAll(filepath.Dir(archive), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+
+	err = extract.WriteTar(file, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, file, content string) {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(file, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/skevetter/devpod/pkg/driver/custom"
 	"github.com/skevetter/devpod/pkg/driver/docker"
 	"github.com/skevetter/devpod/pkg/driver/kubernetes"
+	"github.com/skevetter/devpod/pkg/driver/podman"
 	provider2 "github.com/skevetter/devpod/pkg/provider"
 	"github.com/skevetter/log"
 )
@@ -16,12 +17,14 @@ func NewDriver(workspaceInfo *provider2.AgentWorkspaceInfo, log log.Logger) (dri
 	switch driver {
 	case "", provider2.DockerDriver:
 		return docker.NewDockerDriver(workspaceInfo, log)
+	case provider2.PodmanDriver:
+		return podman.NewPodmanDriver(workspaceInfo, log)
 	case provider2.CustomDriver:
 		return custom.NewCustomDriver(workspaceInfo, log), nil
 	case provider2.KubernetesDriver:
 		return kubernetes.NewKubernetesDriver(workspaceInfo, log)
 	}
 
-	return nil, fmt.Errorf("unrecognized driver '%s', possible values are %s, %s or %s",
-		driver, provider2.DockerDriver, provider2.CustomDriver, provider2.KubernetesDriver)
+	return nil, fmt.Errorf("unrecognized driver '%s', possible values are %s, %s, %s or %s",
+		driver, provider2.DockerDriver, provider2.PodmanDriver, provider2.CustomDriver, provider2.KubernetesDriver)
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -15,9 +16,12 @@ import (
 )
 
 type Client struct {
-	client *kubernetes.Clientset
+	client  kubernetes.Interface
+	dynamic dynamic.Interface
 
 	config *rest.Config
+
+	portForwards portForwardConnections
 }
 
 // NewClient constructs a struct wrapping the kubernetes client that is used by the kubernetes driver
@@ -55,16 +59,26 @@ func NewClient(kubeConfig, kubeContext string) (*Client, string, error) {
 		return nil, "", err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
 	return &Client{
-		client: kubeClient,
-		config: clientConfig,
+		client:  kubeClient,
+		dynamic: dynamicClient,
+		config:  clientConfig,
 	}, namespace, nil
 }
 
-func (c *Client) Client() *kubernetes.Clientset {
+func (c *Client) Client() kubernetes.Interface {
 	return c.client
 }
 
+func (c *Client) Dynamic() dynamic.Interface {
+	return c.dynamic
+}
+
 func (c *Client) Config() *rest.Config {
 	return c.config
 }
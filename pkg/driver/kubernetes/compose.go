@@ -0,0 +1,498 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+var invalidContainerNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// ComposePod holds the parts of the workspace pod that are translated from a docker compose project
+type ComposePod struct {
+	// Sidecars are the other services of the project in the order they have to be started in
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+
+	// Volumes are the volumes of the services that aren't stored in the workspace volume
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// HostAliases resolve the service names to the pod, like the compose network does
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
+
+	// VolumeMounts are the compose volumes of the devcontainer service
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// Ports are the ports of the devcontainer service
+	Ports []corev1.ContainerPort `json:"ports,omitempty"`
+
+	// ReadinessProbe is the healthcheck of the devcontainer service
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+}
+
+func (k *KubernetesDriver) RunComposeDevContainer(ctx context.Context, params *driver.RunComposeDevContainerParams) error {
+	k.Log.Debugf("Running compose devcontainer for workspace '%s'", params.WorkspaceID)
+
+	composePod, err := getComposePod(params, k.options.StrictSecurity == "true", k.Log)
+	if err != nil {
+		return fmt.Errorf("translate docker compose project %w", err)
+	}
+
+	return k.runDevContainer(ctx, params.WorkspaceID, params.Options, composePod)
+}
+
+// applyComposePod adds the compose services to the pod. Every service runs as a native sidecar that
+// is started before the devcontainer, in the order of their depends_on relations.
+func applyComposePod(pod *corev1.Pod, composePod *ComposePod) {
+	if composePod == nil {
+		return
+	}
+
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, composePod.Sidecars...)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, composePod.Volumes...)
+	pod.Spec.HostAliases = append(pod.Spec.HostAliases, composePod.HostAliases...)
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if container.Name != DevContainerName {
+			continue
+		}
+
+		container.VolumeMounts = append(container.VolumeMounts, composePod.VolumeMounts...)
+		container.Ports = append(container.Ports, composePod.Ports...)
+		if container.ReadinessProbe == nil {
+			container.ReadinessProbe = composePod.ReadinessProbe
+		}
+	}
+}
+
+type composePodBuilder struct {
+	workspaceMount       *config.Mount
+	localWorkspaceFolder string
+	strictSecurity       bool
+
+	volumes []corev1.Volume
+	log     log.Logger
+}
+
+func getComposePod(params *driver.RunComposeDevContainerParams, strictSecurity bool, log log.Logger) (*ComposePod, error) {
+	project := params.Project
+	devService, err := project.GetService(params.Service)
+	if err != nil {
+		return nil, fmt.Errorf("service '%s' configured in devcontainer.json not found in Docker Compose configuration", params.Service)
+	}
+
+	services := params.RunServices
+	if len(services) == 0 {
+		services = project.ServiceNames()
+	}
+	order, err := sortComposeServices(project, append([]string{params.Service}, services...))
+	if err != nil {
+		return nil, err
+	}
+
+	builder := &composePodBuilder{
+		workspaceMount:       params.Options.WorkspaceMount,
+		localWorkspaceFolder: params.LocalWorkspaceFolder,
+		strictSecurity:       strictSecurity,
+		log:                  log,
+	}
+
+	// find the strongest condition other services wait for
+	conditionRanks := map[string]int{
+		composetypes.ServiceConditionStarted:               1,
+		composetypes.ServiceConditionHealthy:               2,
+		composetypes.ServiceConditionCompletedSuccessfully: 3,
+	}
+	conditions := map[string]string{}
+	for _, name := range order {
+		service, _ := project.GetService(name)
+		for dependency, dependsOn := range service.DependsOn {
+			if name != params.Service && dependency == params.Service {
+				log.Warnf("Service '%s' depends on the devcontainer service '%s', which is started last in kubernetes", name, dependency)
+				continue
+			}
+			if conditionRanks[dependsOn.Condition] > conditionRanks[conditions[dependency]] {
+				conditions[dependency] = dependsOn.Condition
+			}
+		}
+	}
+
+	composePod := &ComposePod{}
+	for _, name := range order {
+		if name == params.Service {
+			continue
+		}
+
+		service, _ := project.GetService(name)
+		sidecar, err := builder.container(service, conditions[name])
+		if err != nil {
+			return nil, err
+		}
+
+		composePod.Sidecars = append(composePod.Sidecars, *sidecar)
+	}
+
+	// the workspace is already mounted into the devcontainer
+	for _, volumeMount := range builder.volumeMounts(devService) {
+		if params.Options.WorkspaceMount != nil && volumeMount.MountPath == params.Options.WorkspaceMount.Target {
+			continue
+		}
+
+		composePod.VolumeMounts = append(composePod.VolumeMounts, volumeMount)
+	}
+	composePod.Ports = getComposePorts(devService)
+	composePod.ReadinessProbe = getComposeProbe(devService.HealthCheck)
+	composePod.Volumes = builder.volumes
+	composePod.HostAliases = getComposeHostAliases(project, order)
+	return composePod, nil
+}
+
+// sortComposeServices returns the given services and their dependencies in the order they have to be started in
+func sortComposeServices(project *composetypes.Project, names []string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	order := []string{}
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("docker compose service '%s' has a circular dependency", name)
+		case visited:
+			return nil
+		}
+
+		service, err := project.GetService(name)
+		if err != nil {
+			return fmt.Errorf("find docker compose service %w", err)
+		}
+
+		state[name] = visiting
+		for _, dependency := range slices.Sorted(maps.Keys(service.DependsOn)) {
+			err = visit(dependency)
+			if err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		order = append(order, name)
+		return nil
+	}
+
+	// visit the devcontainer service first, so its dependencies are started as early as possible
+	err := visit(names[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range slices.Sorted(slices.Values(names[1:])) {
+		err = visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// container translates the compose service into a sidecar. Services other services wait to complete
+// successfully run as regular init containers instead.
+func (b *composePodBuilder) container(service composetypes.ServiceConfig, condition string) (*corev1.Container, error) {
+	if service.Image == "" {
+		return nil, fmt.Errorf("docker compose service '%s' has no image, building services besides the devcontainer service is not supported by the kubernetes driver", service.Name)
+	}
+
+	container := &corev1.Container{
+		Name:         getComposeContainerName(service.Name),
+		Image:        service.Image,
+		Command:      service.Entrypoint,
+		Args:         service.Command,
+		WorkingDir:   service.WorkingDir,
+		Env:          getComposeEnv(service.Environment),
+		Ports:        getComposePorts(service),
+		VolumeMounts: b.volumeMounts(service),
+	}
+	if service.PullPolicy == composetypes.PullPolicyAlways {
+		container.ImagePullPolicy = corev1.PullAlways
+	} else if service.PullPolicy == composetypes.PullPolicyNever {
+		container.ImagePullPolicy = corev1.PullNever
+	}
+	if !b.strictSecurity {
+		container.SecurityContext = b.securityContext(service)
+	}
+	if condition == composetypes.ServiceConditionCompletedSuccessfully {
+		return container, nil
+	}
+
+	container.RestartPolicy = ptr.To(corev1.ContainerRestartPolicyAlways)
+	container.ReadinessProbe = getComposeProbe(service.HealthCheck)
+	if condition == composetypes.ServiceConditionHealthy && container.ReadinessProbe != nil {
+		// the next containers only start after the startup probe of a sidecar succeeded
+		container.StartupProbe = container.ReadinessProbe.DeepCopy()
+		container.StartupProbe.FailureThreshold = max(container.StartupProbe.FailureThreshold, 30)
+	}
+
+	return container, nil
+}
+
+func (b *composePodBuilder) securityContext(service composetypes.ServiceConfig) *corev1.SecurityContext {
+	securityContext := &corev1.SecurityContext{}
+	if service.Privileged {
+		securityContext.Privileged = ptr.To(true)
+	}
+	if len(service.CapAdd) > 0 {
+		securityContext.Capabilities = &corev1.Capabilities{}
+		for _, capability := range service.CapAdd {
+			securityContext.Capabilities.Add = append(securityContext.Capabilities.Add, corev1.Capability(capability))
+		}
+	}
+	if service.User != "" {
+		user, group, _ := strings.Cut(service.User, ":")
+		uid, err := strconv.ParseInt(user, 10, 64)
+		if err != nil {
+			b.log.Warnf("Ignoring user '%s' of docker compose service '%s', only numeric users are supported by the kubernetes driver", service.User, service.Name)
+		} else {
+			securityContext.RunAsUser = &uid
+			gid, err := strconv.ParseInt(group, 10, 64)
+			if err == nil {
+				securityContext.RunAsGroup = &gid
+			}
+		}
+	}
+
+	if *securityContext == (corev1.SecurityContext{}) {
+		return nil
+	}
+
+	return securityContext
+}
+
+// volumeMounts maps the volumes of the compose service to volume mounts. Named volumes and binds of
+// folders within the workspace are stored in the workspace volume, other binds, anonymous volumes and
+// tmpfs mounts are replaced with empty dirs.
+func (b *composePodBuilder) volumeMounts(service composetypes.ServiceConfig) []corev1.VolumeMount {
+	volumeMounts := []corev1.VolumeMount{}
+	for _, volume := range service.Volumes {
+		volumeMount := corev1.VolumeMount{
+			MountPath: volume.Target,
+			ReadOnly:  volume.ReadOnly,
+		}
+
+		switch volume.Type {
+		case composetypes.VolumeTypeVolume:
+			if volume.Source == "" {
+				volumeMount.Name = b.emptyDir(service.Name, "")
+			} else {
+				// named volumes are stored like the volume mounts of the devcontainer
+				volumeMount = getVolumeMount(0, &config.Mount{Type: composetypes.VolumeTypeVolume, Source: volume.Source, Target: volume.Target})
+				volumeMount.ReadOnly = volume.ReadOnly
+			}
+		case composetypes.VolumeTypeBind:
+			workspaceVolumeMount, ok := b.workspaceVolumeMount(volume.Source)
+			if ok {
+				volumeMount.Name = workspaceVolumeMount.Name
+				volumeMount.SubPath = workspaceVolumeMount.SubPath
+			} else {
+				b.log.Warnf("Replacing bind mount '%s' of docker compose service '%s' with an empty dir, only folders within the workspace can be mounted with the kubernetes driver", volume.String(), service.Name)
+				volumeMount.Name = b.emptyDir(service.Name, "")
+			}
+		case composetypes.VolumeTypeTmpfs:
+			volumeMount.Name = b.emptyDir(service.Name, corev1.StorageMediumMemory)
+		default:
+			b.log.Warnf("Skipping unsupported volume '%s' of docker compose service '%s'", volume.String(), service.Name)
+			continue
+		}
+
+		volumeMounts = append(volumeMounts, volumeMount)
+	}
+
+	for _, tmpfs := range service.Tmpfs {
+		target, _, _ := strings.Cut(tmpfs, ":")
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      b.emptyDir(service.Name, corev1.StorageMediumMemory),
+			MountPath: target,
+		})
+	}
+
+	return volumeMounts
+}
+
+// workspaceVolumeMount returns the volume mount of the local folder in the workspace volume
+func (b *composePodBuilder) workspaceVolumeMount(source string) (corev1.VolumeMount, bool) {
+	if b.workspaceMount == nil {
+		return corev1.VolumeMount{}, false
+	}
+
+	// the source of a named workspace volume isn't a local folder
+	localWorkspaceFolder := b.localWorkspaceFolder
+	if localWorkspaceFolder == "" && b.workspaceMount.Type != composetypes.VolumeTypeVolume {
+		localWorkspaceFolder = b.workspaceMount.Source
+	}
+	if localWorkspaceFolder == "" {
+		return corev1.VolumeMount{}, false
+	}
+
+	rel, err := filepath.Rel(localWorkspaceFolder, source)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return corev1.VolumeMount{}, false
+	}
+
+	// the workspace is stored in the sub path of the first volume mount
+	volumeMount := getVolumeMount(0, b.workspaceMount)
+	volumeMount.SubPath = path.Join(volumeMount.SubPath, filepath.ToSlash(rel))
+	return volumeMount, true
+}
+
+func (b *composePodBuilder) emptyDir(service string, medium corev1.StorageMedium) string {
+	name := fmt.Sprintf("compose-%s-%d", getComposeContainerName(service), len(b.volumes))
+	b.volumes = append(b.volumes, corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{Medium: medium},
+		},
+	})
+
+	return name
+}
+
+func getComposeEnv(environment composetypes.MappingWithEquals) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{}
+	for _, name := range slices.Sorted(maps.Keys(environment)) {
+		// unset variables are not passed to the container
+		if environment[name] == nil {
+			continue
+		}
+
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  name,
+			Value: *environment[name],
+		})
+	}
+
+	return envVars
+}
+
+func getComposePorts(service composetypes.ServiceConfig) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{}
+	addPort := func(port int32, protocol string) {
+		containerPort := corev1.ContainerPort{
+			ContainerPort: port,
+			Protocol:      corev1.Protocol(strings.ToUpper(protocol)),
+		}
+		if containerPort.Protocol == "" {
+			containerPort.Protocol = corev1.ProtocolTCP
+		}
+		if !slices.Contains(ports, containerPort) {
+			ports = append(ports, containerPort)
+		}
+	}
+
+	for _, port := range service.Ports {
+		addPort(int32(port.Target), port.Protocol)
+	}
+	for _, expose := range service.Expose {
+		port, protocol, _ := strings.Cut(expose, "/")
+		number, err := strconv.ParseInt(port, 10, 32)
+		if err != nil {
+			// port ranges are not supported by kubernetes
+			continue
+		}
+
+		addPort(int32(number), protocol)
+	}
+
+	return ports
+}
+
+// getComposeProbe translates the healthcheck of a compose service into a probe
+func getComposeProbe(healthCheck *composetypes.HealthCheckConfig) *corev1.Probe {
+	if healthCheck == nil || healthCheck.Disable || len(healthCheck.Test) == 0 {
+		return nil
+	}
+
+	var command []string
+	switch healthCheck.Test[0] {
+	case "NONE":
+		return nil
+	case "CMD":
+		command = healthCheck.Test[1:]
+	case "CMD-SHELL":
+		command = []string{"/bin/sh", "-c", strings.Join(healthCheck.Test[1:], " ")}
+	default:
+		command = []string{"/bin/sh", "-c", strings.Join(healthCheck.Test, " ")}
+	}
+
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: command},
+		},
+	}
+	if healthCheck.Interval != nil {
+		probe.PeriodSeconds = durationSeconds(*healthCheck.Interval)
+	}
+	if healthCheck.Timeout != nil {
+		probe.TimeoutSeconds = durationSeconds(*healthCheck.Timeout)
+	}
+	if healthCheck.StartPeriod != nil {
+		probe.InitialDelaySeconds = durationSeconds(*healthCheck.StartPeriod)
+	}
+	if healthCheck.Retries != nil {
+		probe.FailureThreshold = int32(*healthCheck.Retries)
+	}
+
+	return probe
+}
+
+// getComposeHostAliases resolves the names of the services to the pod, as all of them share its network
+func getComposeHostAliases(project *composetypes.Project, services []string) []corev1.HostAlias {
+	hostnames := []string{}
+	for _, name := range services {
+		service, _ := project.GetService(name)
+		hostnames = append(hostnames, name, service.Hostname, service.ContainerName)
+		for _, network := range service.Networks {
+			if network != nil {
+				hostnames = append(hostnames, network.Aliases...)
+			}
+		}
+	}
+
+	hostnames = slices.DeleteFunc(hostnames, func(hostname string) bool { return hostname == "" })
+	slices.Sort(hostnames)
+	return []corev1.HostAlias{{
+		IP:        "127.0.0.1",
+		Hostnames: slices.Compact(hostnames),
+	}}
+}
+
+// getComposeContainerName returns a valid container name for the compose service
+func getComposeContainerName(service string) string {
+	name := strings.Trim(invalidContainerNameChars.ReplaceAllString(strings.ToLower(service), "-"), "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == DevContainerName || name == InitContainerName {
+		name = "compose-" + name
+	}
+
+	return name
+}
+
+func durationSeconds(duration composetypes.Duration) int32 {
+	return int32(max(time.Duration(duration).Round(time.Second)/time.Second, 1))
+}
@@ -0,0 +1,178 @@
+package kubernetes
+
+import (
+	"testing"
+
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/log"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetComposePod(t *testing.T) {
+	retries := uint64(5)
+	password := "secret"
+	project := &composetypes.Project{
+		Name: "test",
+		Services: composetypes.Services{
+			"app": {
+				Name:  "app",
+				Build: &composetypes.BuildConfig{Context: "/workspace"},
+				DependsOn: composetypes.DependsOnConfig{
+					"db":      {Condition: composetypes.ServiceConditionHealthy},
+					"migrate": {Condition: composetypes.ServiceConditionCompletedSuccessfully},
+				},
+				Ports: []composetypes.ServicePortConfig{{Target: 3000}},
+				Volumes: []composetypes.ServiceVolumeConfig{
+					{Type: composetypes.VolumeTypeBind, Source: "/workspace", Target: "/workspaces/test"},
+					{Type: composetypes.VolumeTypeVolume, Source: "node-modules", Target: "/workspaces/test/node_modules"},
+				},
+			},
+			"db": {
+				Name:        "db",
+				Image:       "postgres:16",
+				Environment: composetypes.MappingWithEquals{"POSTGRES_PASSWORD": &password, "UNSET": nil},
+				Expose:      composetypes.StringOrNumberList{"5432"},
+				HealthCheck: &composetypes.HealthCheckConfig{
+					Test:    composetypes.HealthCheckTest{"CMD-SHELL", "pg_isready"},
+					Retries: &retries,
+				},
+				Volumes: []composetypes.ServiceVolumeConfig{
+					{Type: composetypes.VolumeTypeVolume, Source: "db-data", Target: "/var/lib/postgresql/data"},
+					{Type: composetypes.VolumeTypeBind, Source: "/workspace/db/init", Target: "/docker-entrypoint-initdb.d", ReadOnly: true},
+					{Type: composetypes.VolumeTypeBind, Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"},
+				},
+			},
+			"migrate": {
+				Name:      "migrate",
+				Image:     "migrate:latest",
+				Command:   composetypes.ShellCommand{"up"},
+				DependsOn: composetypes.DependsOnConfig{"db": {Condition: composetypes.ServiceConditionStarted}},
+			},
+			"Cache_1": {
+				Name:  "Cache_1",
+				Image: "redis",
+				Networks: map[string]*composetypes.ServiceNetworkConfig{
+					"default": {Aliases: []string{"redis"}},
+				},
+			},
+		},
+	}
+
+	composePod, err := getComposePod(&driver.RunComposeDevContainerParams{
+		WorkspaceID: "test",
+		Options: &driver.RunOptions{
+			WorkspaceMount: &config.Mount{Type: "bind", Source: "/workspace", Target: "/workspaces/test"},
+		},
+		Project: project,
+		Service: "app",
+	}, false, log.Discard)
+	assert.NilError(t, err)
+
+	// dependencies of the devcontainer service start first
+	names := []string{}
+	for _, sidecar := range composePod.Sidecars {
+		names = append(names, sidecar.Name)
+	}
+	assert.DeepEqual(t, names, []string{"db", "migrate", "cache-1"})
+
+	db := composePod.Sidecars[0]
+	assert.Equal(t, *db.RestartPolicy, corev1.ContainerRestartPolicyAlways)
+	assert.DeepEqual(t, db.Env, []corev1.EnvVar{{Name: "POSTGRES_PASSWORD", Value: "secret"}})
+	assert.DeepEqual(t, db.Ports, []corev1.ContainerPort{{ContainerPort: 5432, Protocol: corev1.ProtocolTCP}})
+	assert.DeepEqual(t, db.ReadinessProbe.Exec.Command, []string{"/bin/sh", "-c", "pg_isready"})
+	assert.Equal(t, db.ReadinessProbe.FailureThreshold, int32(5))
+	assert.Assert(t, db.StartupProbe != nil, "the devcontainer waits for db to be healthy")
+	assert.DeepEqual(t, db.VolumeMounts, []corev1.VolumeMount{
+		{Name: "devpod", MountPath: "/var/lib/postgresql/data", SubPath: "devpod/db-data"},
+		{Name: "devpod", MountPath: "/docker-entrypoint-initdb.d", SubPath: "devpod/0/db/init", ReadOnly: true},
+		{Name: "compose-db-0", MountPath: "/var/run/docker.sock"},
+	})
+
+	migrate := composePod.Sidecars[1]
+	assert.Assert(t, migrate.RestartPolicy == nil, "migrate has to complete before the devcontainer starts")
+	assert.DeepEqual(t, migrate.Args, []string{"up"})
+
+	cache := composePod.Sidecars[2]
+	assert.Assert(t, cache.StartupProbe == nil)
+
+	assert.DeepEqual(t, composePod.VolumeMounts, []corev1.VolumeMount{
+		{Name: "devpod", MountPath: "/workspaces/test/node_modules", SubPath: "devpod/node-modules"},
+	})
+	assert.DeepEqual(t, composePod.Ports, []corev1.ContainerPort{{ContainerPort: 3000, Protocol: corev1.ProtocolTCP}})
+	assert.Equal(t, len(composePod.Volumes), 1)
+	assert.DeepEqual(t, composePod.HostAliases, []corev1.HostAlias{{
+		IP:        "127.0.0.1",
+		Hostnames: []string{"Cache_1", "app", "db", "migrate", "redis"},
+	}})
+}
+
+func TestGetComposePodRunServices(t *testing.T) {
+	project := &composetypes.Project{
+		Services: composetypes.Services{
+			"app":   {Name: "app", Image: "app", DependsOn: composetypes.DependsOnConfig{"db": {}}},
+			"db":    {Name: "db", Image: "postgres"},
+			"extra": {Name: "extra", Image: "extra"},
+		},
+	}
+
+	composePod, err := getComposePod(&driver.RunComposeDevContainerParams{
+		Options:     &driver.RunOptions{},
+		Project:     project,
+		Service:     "app",
+		RunServices: []string{"app"},
+	}, false, log.Discard)
+	assert.NilError(t, err)
+	assert.Equal(t, len(composePod.Sidecars), 1)
+	assert.Equal(t, composePod.Sidecars[0].Name, "db")
+}
+
+func TestGetComposePodWorkspaceVolume(t *testing.T) {
+	project := &composetypes.Project{
+		Services: composetypes.Services{
+			"app": {Name: "app", Image: "app"},
+			"db": {
+				Name:  "db",
+				Image: "postgres",
+				Volumes: []composetypes.ServiceVolumeConfig{
+					{Type: composetypes.VolumeTypeBind, Source: "/workspace/db/init", Target: "/docker-entrypoint-initdb.d"},
+				},
+			},
+		},
+	}
+
+	composePod, err := getComposePod(&driver.RunComposeDevContainerParams{
+		Options: &driver.RunOptions{
+			WorkspaceMount: &config.Mount{Type: "volume", Source: "test-workspace", Target: "/workspaces/test"},
+		},
+		Project:              project,
+		Service:              "app",
+		LocalWorkspaceFolder: "/workspace",
+	}, false, log.Discard)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, composePod.Sidecars[0].VolumeMounts, []corev1.VolumeMount{
+		{Name: "devpod", MountPath: "/docker-entrypoint-initdb.d", SubPath: "devpod/test-workspace/db/init"},
+	})
+}
+
+func TestGetComposePodErrors(t *testing.T) {
+	cyclic := &composetypes.Project{
+		Services: composetypes.Services{
+			"app": {Name: "app", Image: "app", DependsOn: composetypes.DependsOnConfig{"db": {}}},
+			"db":  {Name: "db", Image: "postgres", DependsOn: composetypes.DependsOnConfig{"app": {}}},
+		},
+	}
+	_, err := getComposePod(&driver.RunComposeDevContainerParams{Options: &driver.RunOptions{}, Project: cyclic, Service: "app"}, false, log.Discard)
+	assert.ErrorContains(t, err, "circular dependency")
+
+	build := &composetypes.Project{
+		Services: composetypes.Services{
+			"app": {Name: "app", Image: "app"},
+			"api": {Name: "api", Build: &composetypes.BuildConfig{Context: "."}},
+		},
+	}
+	_, err = getComposePod(&driver.RunComposeDevContainerParams{Options: &driver.RunOptions{}, Project: build, Service: "app"}, false, log.Discard)
+	assert.ErrorContains(t, err, "has no image")
+}
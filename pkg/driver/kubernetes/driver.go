@@ -33,11 +33,22 @@ func NewKubernetesDriver(workspaceInfo *provider2.AgentWorkspaceInfo, log log.Lo
 		log.Debugf("Using Explicit Kubernetes Namespace")
 		namespace = options.KubernetesNamespace
 	}
+	sharedNamespace, workspaceNamespace, workspaceUID := namespace, "", ""
+	if workspaceInfo.Workspace != nil {
+		workspaceNamespace = getID(workspaceInfo.Workspace.ID)
+		workspaceUID = workspaceInfo.Workspace.UID
+	}
+	if options.WorkspaceNamespace == "true" && workspaceNamespace != "" {
+		namespace = workspaceNamespace
+	}
 	log.Debugf("Use Kubernetes Namespace '%s'", namespace)
 
 	return &KubernetesDriver{
-		client:    client,
-		namespace: namespace,
+		client:             client,
+		namespace:          namespace,
+		sharedNamespace:    sharedNamespace,
+		workspaceNamespace: workspaceNamespace,
+		workspaceUID:       workspaceUID,
 
 		options: &options,
 		Log:     log,
@@ -47,6 +58,12 @@ func NewKubernetesDriver(workspaceInfo *provider2.AgentWorkspaceInfo, log log.Lo
 type KubernetesDriver struct {
 	namespace string
 
+	// sharedNamespace and workspaceNamespace are the namespaces of the workspace without and with the
+	// workspace namespace option
+	sharedNamespace    string
+	workspaceNamespace string
+	workspaceUID       string
+
 	client *Client
 
 	options *provider2.ProviderKubernetesDriverConfig
@@ -86,13 +103,8 @@ func (k *KubernetesDriver) StopDevContainer(ctx context.Context, workspaceId str
 
 	workspaceId = getID(workspaceId)
 
-	// delete pod
-	err := k.waitPodDeleted(ctx, workspaceId)
-	if err != nil {
-		return fmt.Errorf("delete pod %w", err)
-	}
-
-	return nil
+	// delete pod, but remember it for the next start
+	return k.suspendPod(ctx, workspaceId)
 }
 
 func (k *KubernetesDriver) DeleteDevContainer(ctx context.Context, workspaceId string) error {
@@ -108,6 +120,23 @@ func (k *KubernetesDriver) DeleteDevContainer(ctx context.Context, workspaceId s
 		return err
 	}
 
+	// delete pod template
+	err = k.deletePodTemplate(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	// delete volume snapshots
+	if k.options.VolumeSnapshotClass != "" {
+		pvc, err := k.client.Client().CoreV1().PersistentVolumeClaims(k.namespace).Get(ctx, workspaceId, metav1.GetOptions{})
+		if err == nil {
+			err = k.deleteVolumeSnapshots(ctx, pvc, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// delete pvc
 	k.Log.Infof("Delete persistent volume claim '%s'...", workspaceId)
 	err = k.client.Client().CoreV1().PersistentVolumeClaims(k.namespace).Delete(ctx, workspaceId, metav1.DeleteOptions{
@@ -144,6 +173,14 @@ func (k *KubernetesDriver) DeleteDevContainer(ctx context.Context, workspaceId s
 		}
 	}
 
+	// delete the namespace of the workspace
+	if k.options.WorkspaceNamespace == "true" {
+		err = k.deleteWorkspaceNamespace(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
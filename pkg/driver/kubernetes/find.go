@@ -28,16 +28,22 @@ func (k *KubernetesDriver) FindDevContainer(ctx context.Context, workspaceId str
 		pod = nil
 	}
 
-	// determine status
+	// determine status, the workspace is stopped if there is no pod
 	status := "exited"
-	if pod != nil && isPodRunning(pod) {
-		status = "running"
+	if pod != nil {
+		status = getPodStatus(pod)
 	}
 
 	// check started
 	startedAt := pvc.CreationTimestamp.String()
+	imageID := ""
 	if pod != nil {
 		startedAt = pod.CreationTimestamp.String()
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.Name == DevContainerName {
+				imageID = containerStatus.ImageID
+			}
+		}
 	}
 
 	return &config.ContainerDetails{
@@ -50,9 +56,22 @@ func (k *KubernetesDriver) FindDevContainer(ctx context.Context, workspaceId str
 		Config: config.ContainerDetailsConfig{
 			Labels: config.ListToObject(containerInfo.Options.Labels),
 		},
+		Image: imageID,
 	}, nil
 }
 
-func isPodRunning(pod *corev1.Pod) bool {
-	return pod.Status.Phase == corev1.PodRunning
+// getPodStatus maps the pod phase to a docker container status
+func getPodStatus(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "removing"
+	}
+
+	switch pod.Status.Phase {
+	case corev1.PodRunning:
+		return "running"
+	case corev1.PodSucceeded, corev1.PodFailed:
+		return "exited"
+	default:
+		return "created"
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/ghodss/yaml"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/log"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -55,6 +56,40 @@ func parseResources(resourceString string, log log.Logger) corev1.ResourceRequir
 	}
 }
 
+// applyHostRequirements raises the cpu and memory requests to the devcontainer host requirements.
+// Existing limits that are lower than the requirements are raised as well, as a request may not exceed its limit.
+func applyHostRequirements(resources corev1.ResourceRequirements, requirements *config.HostRequirements, log log.Logger) corev1.ResourceRequirements {
+	if requirements.IsEmpty() {
+		return resources
+	}
+
+	required := corev1.ResourceList{}
+	if requirements.CPUs > 0 {
+		required[corev1.ResourceCPU] = *resource.NewQuantity(int64(requirements.CPUs), resource.DecimalSI)
+	}
+	memory, err := requirements.MemoryBytes()
+	if err != nil {
+		log.Warnf("Skipping memory host requirement: %v", err)
+	} else if memory > 0 {
+		required[corev1.ResourceMemory] = *resource.NewQuantity(memory, resource.BinarySI)
+	}
+
+	if resources.Requests == nil {
+		resources.Requests = corev1.ResourceList{}
+	}
+	for name, quantity := range required {
+		if request, ok := resources.Requests[name]; !ok || request.Cmp(quantity) < 0 {
+			resources.Requests[name] = quantity
+		}
+		if limit, ok := resources.Limits[name]; ok && limit.Cmp(quantity) < 0 {
+			log.Warnf("Raising %s limit from %s to %s to satisfy the devcontainer host requirements", name, limit.String(), quantity.String())
+			resources.Limits[name] = quantity
+		}
+	}
+
+	return resources
+}
+
 func getPodTemplate(manifest string) (*corev1.Pod, error) {
 	// check if manifest is inline yaml
 	pod := &corev1.Pod{}
@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/skevetter/devpod/pkg/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var _ driver.HostResourcesDriver = (*KubernetesDriver)(nil)
+
+// HostResources returns the allocatable cpus and memory of the largest schedulable node that matches the
+// node selector of the workspace pod, so unsatisfiable host requirements fail before the pod stays pending
+func (k *KubernetesDriver) HostResources(ctx context.Context) (*driver.HostResources, error) {
+	pod := &corev1.Pod{}
+	if len(k.options.PodManifestTemplate) > 0 {
+		var err error
+		pod, err = getPodTemplate(k.options.PodManifestTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodeSelector, err := getNodeSelector(pod, k.options.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := k.client.Client().CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(nodeSelector).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list nodes %w", err)
+	}
+
+	resources := &driver.HostResources{}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+
+		if cpu, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+			resources.CPUs = max(resources.CPUs, int(cpu.MilliValue()/1000))
+		}
+		if memory, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+			resources.Memory = max(resources.Memory, memory.Value())
+		}
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHostResources(t *testing.T) {
+	k, _ := newFakeDriver(
+		&provider2.ProviderKubernetesDriverConfig{NodeSelector: "pool=workspaces"},
+		newTestNode("small", map[string]string{"pool": "workspaces"}, "2", "4Gi", false),
+		newTestNode("large", map[string]string{"pool": "workspaces"}, "7500m", "16Gi", false),
+		newTestNode("cordoned", map[string]string{"pool": "workspaces"}, "64", "256Gi", true),
+		newTestNode("other", map[string]string{"pool": "system"}, "32", "128Gi", false),
+	)
+
+	resources, err := k.HostResources(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, resources.CPUs, 7)
+	assert.Equal(t, resources.Memory, int64(16<<30))
+}
+
+func newTestNode(name string, labels map[string]string, cpu string, memory string, unschedulable bool) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		},
+	}
+}
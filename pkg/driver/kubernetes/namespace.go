@@ -0,0 +1,311 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/log"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	WorkspaceResourceQuotaName = "devpod"
+	WorkspaceLimitRangeName    = "devpod"
+	WorkspaceNetworkPolicyName = "devpod-default-deny"
+)
+
+// ensureWorkspaceNamespace creates the dedicated namespace of the workspace together with its resource quota,
+// limit range and network policy. Objects that exist already are left untouched.
+func (k *KubernetesDriver) ensureWorkspaceNamespace(ctx context.Context, options *driver.RunOptions) error {
+	labels := map[string]string{}
+	labels[DevPodWorkspaceUIDLabel] = options.UID
+	maps.Copy(labels, ExtraDevPodLabels)
+
+	// namespace
+	namespace, err := k.client.Client().CoreV1().Namespaces().Get(ctx, k.namespace, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("get namespace %w", err)
+	} else if err == nil && namespace.Labels[DevPodWorkspaceUIDLabel] != options.UID {
+		return fmt.Errorf("namespace %s already exists and doesn't belong to the workspace", k.namespace)
+	} else if kerrors.IsNotFound(err) {
+		k.Log.Infof("Create namespace '%s'", k.namespace)
+		_, err := k.client.Client().CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   k.namespace,
+				Labels: labels,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil && !kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create namespace %w", err)
+		}
+	}
+
+	// resource quota
+	hard, err := k.getResourceQuota(options)
+	if err != nil {
+		return err
+	}
+	if len(hard) > 0 {
+		k.Log.Debugf("Ensure resource quota '%s'", WorkspaceResourceQuotaName)
+		_, err = k.client.Client().CoreV1().ResourceQuotas(k.namespace).Create(ctx, &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   WorkspaceResourceQuotaName,
+				Labels: labels,
+			},
+			Spec: corev1.ResourceQuotaSpec{
+				Hard: hard,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil && !kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create resource quota %w", err)
+		}
+	}
+
+	// limit range
+	limits := getLimitRange(hard, k.options.NamespaceLimitRange, k.Log)
+	if limits != nil {
+		k.Log.Debugf("Ensure limit range '%s'", WorkspaceLimitRangeName)
+		_, err = k.client.Client().CoreV1().LimitRanges(k.namespace).Create(ctx, &corev1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   WorkspaceLimitRangeName,
+				Labels: labels,
+			},
+			Spec: corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{*limits},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil && !kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create limit range %w", err)
+		}
+	}
+
+	// network policy
+	egress, err := getNetworkPolicyEgress(k.options.NetworkPolicyEgress)
+	if err != nil {
+		return err
+	}
+	k.Log.Debugf("Ensure network policy '%s'", WorkspaceNetworkPolicyName)
+	_, err = k.client.Client().NetworkingV1().NetworkPolicies(k.namespace).Create(ctx, &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   WorkspaceNetworkPolicyName,
+			Labels: labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create network policy %w", err)
+	}
+
+	return nil
+}
+
+// deleteWorkspaceNamespace deletes the namespace of the workspace, namespaces that weren't created for the
+// workspace are left untouched
+func (k *KubernetesDriver) deleteWorkspaceNamespace(ctx context.Context) error {
+	namespace, err := k.client.Client().CoreV1().Namespaces().Get(ctx, k.namespace, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get namespace %w", err)
+	} else if k.workspaceUID == "" || namespace.Labels[DevPodWorkspaceUIDLabel] != k.workspaceUID {
+		k.Log.Warnf("Skip deleting namespace '%s', as it wasn't created for the workspace", k.namespace)
+		return nil
+	}
+
+	k.Log.Infof("Delete namespace '%s'...", k.namespace)
+	err = k.client.Client().CoreV1().Namespaces().Delete(ctx, k.namespace, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &namespace.UID},
+	})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("delete namespace %w", err)
+	}
+
+	return nil
+}
+
+// checkNamespaceOption refuses to run a workspace whose volume is in the namespace of the other setting of the
+// workspace namespace option, as the workspace would otherwise start with a new, empty volume
+func (k *KubernetesDriver) checkNamespaceOption(ctx context.Context, workspaceId string, options *driver.RunOptions) error {
+	if k.options.WorkspaceNamespace == "true" {
+		if k.sharedNamespace == "" || k.sharedNamespace == k.namespace {
+			return nil
+		}
+
+		pvc, err := k.client.Client().CoreV1().PersistentVolumeClaims(k.sharedNamespace).Get(ctx, workspaceId, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) || kerrors.IsForbidden(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("get persistent volume claim %w", err)
+		} else if pvc.Labels[DevPodWorkspaceUIDLabel] == options.UID {
+			return fmt.Errorf("the workspace volume is in namespace %s, which isn't used with WORKSPACE_NAMESPACE enabled. Disable the option again or delete and recreate the workspace", k.sharedNamespace)
+		}
+
+		return nil
+	}
+
+	if k.workspaceNamespace == "" || k.workspaceNamespace == k.namespace {
+		return nil
+	}
+	namespace, err := k.client.Client().CoreV1().Namespaces().Get(ctx, k.workspaceNamespace, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) || kerrors.IsForbidden(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get namespace %w", err)
+	} else if namespace.Labels[DevPodWorkspaceUIDLabel] == options.UID {
+		return fmt.Errorf("the workspace volume is in namespace %s, which is only used with WORKSPACE_NAMESPACE enabled. Enable the option again or delete and recreate the workspace", k.workspaceNamespace)
+	}
+
+	return nil
+}
+
+// getResourceQuota returns the configured quota of the workspace namespace. Without one, the namespace
+// may request as much as the devcontainer and use a single persistent volume.
+func (k *KubernetesDriver) getResourceQuota(options *driver.RunOptions) (corev1.ResourceList, error) {
+	if k.options.NamespaceResourceQuota != "" {
+		hard := corev1.ResourceList{}
+		for entry := range strings.SplitSeq(k.options.NamespaceResourceQuota, ",") {
+			name, quantity, err := parseResource(strings.TrimSpace(entry))
+			if err != nil {
+				return nil, fmt.Errorf("parse resource quota %w", err)
+			}
+
+			hard[corev1.ResourceName(name)] = quantity
+		}
+
+		return hard, nil
+	}
+
+	hard := corev1.ResourceList{}
+	resources := applyHostRequirements(parseResources(k.options.Resources, k.Log), options.HostRequirements, k.Log)
+	for name, quantity := range resources.Requests {
+		hard[corev1.ResourceName(requestsPrefix+string(name))] = quantity
+	}
+
+	storage, err := k.getPersistentVolumeSize(options)
+	if err != nil {
+		return nil, err
+	}
+	hard[corev1.ResourceRequestsStorage] = storage
+	hard[corev1.ResourcePersistentVolumeClaims] = *resource.NewQuantity(1, resource.DecimalSI)
+
+	return hard, nil
+}
+
+// getLimitRange returns the container defaults of the workspace namespace. Without configured defaults,
+// containers that don't specify resources request nothing, so that the quota doesn't reject them.
+func getLimitRange(hard corev1.ResourceList, rawLimitRange string, log log.Logger) *corev1.LimitRangeItem {
+	limits := &corev1.LimitRangeItem{
+		Type:           corev1.LimitTypeContainer,
+		Default:        corev1.ResourceList{},
+		DefaultRequest: corev1.ResourceList{},
+	}
+	if rawLimitRange != "" {
+		resources := parseResources(rawLimitRange, log)
+		maps.Copy(limits.Default, resources.Limits)
+		maps.Copy(limits.DefaultRequest, resources.Requests)
+	} else {
+		for name, quantity := range hard {
+			resourceName, isLimit := strings.CutPrefix(string(name), limitsPrefix)
+			resourceName = strings.TrimPrefix(resourceName, requestsPrefix)
+			switch corev1.ResourceName(resourceName) {
+			case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+			default:
+				continue
+			}
+
+			if isLimit {
+				limits.Default[corev1.ResourceName(resourceName)] = quantity
+			} else {
+				limits.DefaultRequest[corev1.ResourceName(resourceName)] = resource.MustParse("0")
+			}
+		}
+	}
+
+	if len(limits.Default) == 0 && len(limits.DefaultRequest) == 0 {
+		return nil
+	}
+
+	return limits
+}
+
+// getNetworkPolicyEgress returns the egress rules of the workspace namespace. Besides DNS, pods may only
+// connect to the configured destinations in the form cidr[:port[/protocol]].
+func getNetworkPolicyEgress(rawEgress string) ([]networkingv1.NetworkPolicyEgressRule, error) {
+	dnsPort := intstr.FromInt32(53)
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}},
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolUDP), Port: &dnsPort},
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: &dnsPort},
+			},
+		},
+	}
+
+	for entry := range strings.SplitSeq(rawEgress, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		rule, err := parseEgressRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		egress = append(egress, rule)
+	}
+
+	return egress, nil
+}
+
+func parseEgressRule(entry string) (networkingv1.NetworkPolicyEgressRule, error) {
+	rule := networkingv1.NetworkPolicyEgressRule{}
+
+	// the port follows the prefix length, so that ipv6 addresses can be used as well
+	address, rest, ok := strings.Cut(entry, "/")
+	if !ok {
+		return rule, fmt.Errorf("invalid network policy egress '%s', expected format cidr[:port[/protocol]]", entry)
+	}
+	prefixLength, portSpec, hasPort := strings.Cut(rest, ":")
+	cidr := address + "/" + prefixLength
+	_, _, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return rule, fmt.Errorf("invalid network policy egress '%s' %w", entry, err)
+	}
+	rule.To = []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}}
+	if !hasPort {
+		return rule, nil
+	}
+
+	rawPort, rawProtocol, _ := strings.Cut(portSpec, "/")
+	port, err := strconv.ParseInt(rawPort, 10, 32)
+	if err != nil || port <= 0 || port > 65535 {
+		return rule, fmt.Errorf("invalid port in network policy egress '%s'", entry)
+	}
+	protocol := corev1.ProtocolTCP
+	if rawProtocol != "" {
+		protocol = corev1.Protocol(strings.ToUpper(rawProtocol))
+		if protocol != corev1.ProtocolTCP && protocol != corev1.ProtocolUDP && protocol != corev1.ProtocolSCTP {
+			return rule, fmt.Errorf("invalid protocol in network policy egress '%s'", entry)
+		}
+	}
+	policyPort := intstr.FromInt32(int32(port))
+	rule.Ports = []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &policyPort}}
+
+	return rule, nil
+}
@@ -0,0 +1,141 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEnsureWorkspaceNamespace(t *testing.T) {
+	ctx := context.Background()
+	k, clientset := newFakeDriver(&provider2.ProviderKubernetesDriverConfig{
+		WorkspaceNamespace:  "true",
+		Resources:           "requests.cpu=1,limits.cpu=4",
+		DiskSize:            "10Gi",
+		NetworkPolicyEgress: "0.0.0.0/0:443",
+	})
+	options := &driver.RunOptions{
+		UID:              "uid",
+		HostRequirements: &config.HostRequirements{CPUs: 2},
+	}
+
+	// existing objects are left untouched
+	assert.NilError(t, k.ensureWorkspaceNamespace(ctx, options))
+	assert.NilError(t, k.ensureWorkspaceNamespace(ctx, options))
+
+	namespace, err := clientset.CoreV1().Namespaces().Get(ctx, testNamespace, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, namespace.Labels[DevPodWorkspaceUIDLabel], "uid")
+
+	quota, err := clientset.CoreV1().ResourceQuotas(testNamespace).Get(ctx, WorkspaceResourceQuotaName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, quota.Spec.Hard, corev1.ResourceList{
+		corev1.ResourceRequestsCPU:            resource.MustParse("2"),
+		corev1.ResourceRequestsStorage:        resource.MustParse("10Gi"),
+		corev1.ResourcePersistentVolumeClaims: resource.MustParse("1"),
+	})
+
+	limitRange, err := clientset.CoreV1().LimitRanges(testNamespace).Get(ctx, WorkspaceLimitRangeName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, limitRange.Spec.Limits[0].DefaultRequest, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0")})
+
+	networkPolicy, err := clientset.NetworkingV1().NetworkPolicies(testNamespace).Get(ctx, WorkspaceNetworkPolicyName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(networkPolicy.Spec.PolicyTypes), 2)
+	assert.Equal(t, len(networkPolicy.Spec.Egress), 2)
+	assert.Equal(t, networkPolicy.Spec.Egress[1].To[0].IPBlock.CIDR, "0.0.0.0/0")
+
+	// namespaces of other workspaces are neither used nor deleted
+	assert.ErrorContains(t, k.ensureWorkspaceNamespace(ctx, &driver.RunOptions{UID: "other"}), "doesn't belong to the workspace")
+	k.workspaceUID = "other"
+	assert.NilError(t, k.DeleteDevContainer(ctx, "test"))
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, testNamespace, metav1.GetOptions{})
+	assert.NilError(t, err)
+
+	k.workspaceUID = "uid"
+	err = k.DeleteDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, testNamespace, metav1.GetOptions{})
+	assert.Assert(t, kerrors.IsNotFound(err))
+}
+
+func TestCheckNamespaceOption(t *testing.T) {
+	ctx := context.Background()
+	options := &driver.RunOptions{UID: "uid"}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "shared", Labels: map[string]string{DevPodWorkspaceUIDLabel: "uid"}},
+	}
+
+	// enabling the option on a workspace with a volume in the shared namespace is refused
+	k, _ := newFakeDriver(&provider2.ProviderKubernetesDriverConfig{WorkspaceNamespace: "true"}, pvc)
+	k.sharedNamespace = "shared"
+	assert.ErrorContains(t, k.checkNamespaceOption(ctx, "test", options), "Disable the option again")
+	assert.NilError(t, k.checkNamespaceOption(ctx, "test", &driver.RunOptions{UID: "other"}))
+
+	// disabling the option on a workspace with its own namespace is refused
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "workspace", Labels: map[string]string{DevPodWorkspaceUIDLabel: "uid"}}}
+	k, _ = newFakeDriver(&provider2.ProviderKubernetesDriverConfig{}, namespace)
+	k.workspaceNamespace = "workspace"
+	assert.ErrorContains(t, k.checkNamespaceOption(ctx, "test", options), "Enable the option again")
+}
+
+func TestGetLimitRange(t *testing.T) {
+	limits := getLimitRange(corev1.ResourceList{
+		"limits.memory": resource.MustParse("8Gi"),
+		"pods":          resource.MustParse("5"),
+	}, "", log.Discard)
+	assert.DeepEqual(t, limits.Default, corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")})
+	assert.Equal(t, len(limits.DefaultRequest), 0)
+
+	limits = getLimitRange(nil, "requests.cpu=100m,limits.cpu=1", log.Discard)
+	assert.DeepEqual(t, limits.DefaultRequest, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")})
+	assert.DeepEqual(t, limits.Default, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")})
+
+	assert.Assert(t, getLimitRange(corev1.ResourceList{"pods": resource.MustParse("5")}, "", log.Discard) == nil)
+}
+
+func TestParseEgressRule(t *testing.T) {
+	tests := map[string]struct {
+		entry    string
+		cidr     string
+		port     int
+		protocol corev1.Protocol
+		err      string
+	}{
+		"cidr":      {entry: "10.0.0.0/8", cidr: "10.0.0.0/8"},
+		"port":      {entry: "0.0.0.0/0:443", cidr: "0.0.0.0/0", port: 443, protocol: corev1.ProtocolTCP},
+		"protocol":  {entry: "10.0.0.1/32:53/udp", cidr: "10.0.0.1/32", port: 53, protocol: corev1.ProtocolUDP},
+		"ipv6":      {entry: "::/0:22", cidr: "::/0", port: 22, protocol: corev1.ProtocolTCP},
+		"no prefix": {entry: "10.0.0.1", err: "expected format"},
+		"bad cidr":  {entry: "10.0.0.300/32", err: "invalid network policy egress"},
+		"bad port":  {entry: "10.0.0.0/8:http", err: "invalid port"},
+		"bad proto": {entry: "10.0.0.0/8:80/icmp", err: "invalid protocol"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rule, err := parseEgressRule(test.entry)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, rule.To[0].IPBlock.CIDR, test.cidr)
+			if test.port == 0 {
+				assert.Equal(t, len(rule.Ports), 0)
+				return
+			}
+			assert.Equal(t, rule.Ports[0].Port.IntValue(), test.port)
+			assert.Equal(t, *rule.Ports[0].Protocol, test.protocol)
+		})
+	}
+}
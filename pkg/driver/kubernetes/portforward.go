@@ -0,0 +1,129 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+func (k *KubernetesDriver) PortForward(ctx context.Context, workspaceId string, port int, stream io.ReadWriter) error {
+	workspaceId = getID(workspaceId)
+
+	k.Log.Debugf("Forward port %d of pod '%s'", port, workspaceId)
+	return k.client.PortForward(ctx, k.namespace, workspaceId, port, stream)
+}
+
+// portForwardConnections holds one portforward connection per pod, the forwarded tcp connections are
+// streams of it like with kubectl port-forward
+type portForwardConnections struct {
+	m           sync.Mutex
+	connections map[string]httpstream.Connection
+	requestID   int
+}
+
+// PortForward connects the stream to the port of the pod through the portforward subresource, like a single
+// connection of kubectl port-forward
+func (c *Client) PortForward(ctx context.Context, namespace, pod string, port int, stream io.ReadWriter) error {
+	streamConn, requestID, err := c.portForwardConnection(namespace, pod)
+	if err != nil {
+		return err
+	}
+
+	// the error stream reports failures to connect to the port
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("create error stream %w", err)
+	}
+	_ = errorStream.Close()
+	defer streamConn.RemoveStreams(errorStream)
+
+	errChan := make(chan error, 1)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		if err != nil {
+			errChan <- fmt.Errorf("read error stream %w", err)
+		} else if len(message) > 0 {
+			errChan <- fmt.Errorf("forward port %d: %s", port, string(message))
+		}
+		close(errChan)
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("create data stream %w", err)
+	}
+	defer streamConn.RemoveStreams(dataStream)
+
+	remoteDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(stream, dataStream)
+		close(remoteDone)
+	}()
+	go func() {
+		// closing the data stream tells the pod that no more data is coming
+		_, _ = io.Copy(dataStream, stream)
+		_ = dataStream.Close()
+	}()
+
+	select {
+	case <-remoteDone:
+	case <-ctx.Done():
+		_ = dataStream.Reset()
+		return nil
+	}
+
+	return <-errChan
+}
+
+// portForwardConnection returns the portforward connection to the pod together with a new request id,
+// the connection is dialed again if it was closed
+func (c *Client) portForwardConnection(namespace, pod string) (httpstream.Connection, int, error) {
+	c.portForwards.m.Lock()
+	defer c.portForwards.m.Unlock()
+
+	c.portForwards.requestID++
+	key := namespace + "/" + pod
+	if streamConn, ok := c.portForwards.connections[key]; ok {
+		select {
+		case <-streamConn.CloseChan():
+		default:
+			return streamConn, c.portForwards.requestID, nil
+		}
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	url := c.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("dial pod %w", err)
+	}
+
+	if c.portForwards.connections == nil {
+		c.portForwards.connections = map[string]httpstream.Connection{}
+	}
+	c.portForwards.connections[key] = streamConn
+	return streamConn, c.portForwards.requestID, nil
+}
@@ -16,8 +16,9 @@ func (k *KubernetesDriver) createPersistentVolumeClaim(
 	ctx context.Context,
 	id string,
 	options *driver.RunOptions,
+	composePod *ComposePod,
 ) error {
-	pvc, err := k.buildPersistentVolumeClaim(id, options)
+	pvc, err := k.buildPersistentVolumeClaim(id, options, composePod)
 	if err != nil {
 		return err
 	}
@@ -34,16 +35,16 @@ func (k *KubernetesDriver) createPersistentVolumeClaim(
 func (k *KubernetesDriver) buildPersistentVolumeClaim(
 	id string,
 	options *driver.RunOptions,
+	composePod *ComposePod,
 ) (*corev1.PersistentVolumeClaim, error) {
-	containerInfo, err := k.getDevContainerInformation(id, options)
+	containerInfo, err := k.getDevContainerInformation(id, options, composePod)
 	if err != nil {
 		return nil, err
 	}
 
-	size := k.options.DiskSize
-	quantity, err := resource.ParseQuantity(size)
+	quantity, err := k.getPersistentVolumeSize(options)
 	if err != nil {
-		return nil, fmt.Errorf("parse persistent volume size '%s' %w", size, err)
+		return nil, err
 	}
 
 	var storageClassName *string
@@ -100,13 +101,53 @@ func (k *KubernetesDriver) buildPersistentVolumeClaim(
 	}, nil
 }
 
+// getPersistentVolumeSize returns the configured disk size, raised to the storage host requirement of the devcontainer
+func (k *KubernetesDriver) getPersistentVolumeSize(options *driver.RunOptions) (resource.Quantity, error) {
+	size := k.options.DiskSize
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("parse persistent volume size '%s' %w", size, err)
+	}
+	storage, err := options.HostRequirements.StorageBytes()
+	if err != nil {
+		k.Log.Warnf("Skipping storage host requirement: %v", err)
+	} else if required := resource.NewQuantity(storage, resource.BinarySI); storage > 0 && quantity.Cmp(*required) < 0 {
+		k.Log.Infof("Increasing persistent volume size from %s to %s to satisfy the devcontainer host requirements", quantity.String(), required.String())
+		quantity = *required
+	}
+
+	return quantity, nil
+}
+
+func (k *KubernetesDriver) updateDevContainerInformation(
+	ctx context.Context,
+	pvc *corev1.PersistentVolumeClaim,
+	options *driver.RunOptions,
+	composePod *ComposePod,
+) error {
+	containerInfo, err := k.getDevContainerInformation(pvc.Name, options, composePod)
+	if err != nil {
+		return err
+	}
+
+	pvc.Annotations[DevPodInfoAnnotation] = containerInfo
+	_, err = k.client.Client().CoreV1().PersistentVolumeClaims(k.namespace).Update(ctx, pvc, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("update pvc %w", err)
+	}
+
+	return nil
+}
+
 func (k *KubernetesDriver) getDevContainerInformation(
 	id string,
 	options *driver.RunOptions,
+	composePod *ComposePod,
 ) (string, error) {
 	containerInfo, err := json.Marshal(&DevContainerInfo{
 		WorkspaceID: id,
 		Options:     options,
+		Compose:     composePod,
 	})
 	if err != nil {
 		return "", err
@@ -28,6 +28,7 @@ const (
 
 	DevPodInfoAnnotation                   = "devpod.sh/info"
 	DevPodLastAppliedAnnotation            = "devpod.sh/last-applied-configuration"
+	DevPodSuspendedNodeAnnotation          = "devpod.sh/suspended-node"
 	ClusterAutoscalerSaveToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
 )
 
@@ -38,6 +39,9 @@ var ExtraDevPodLabels = map[string]string{
 type DevContainerInfo struct {
 	WorkspaceID string
 	Options     *driver.RunOptions
+
+	// Compose holds the other services of docker compose devcontainers
+	Compose *ComposePod `json:",omitempty"`
 }
 
 func (k *KubernetesDriver) RunDevContainer(
@@ -46,10 +50,30 @@ func (k *KubernetesDriver) RunDevContainer(
 	options *driver.RunOptions,
 ) error {
 	k.Log.Debugf("Running devcontainer for workspace '%s'", workspaceId)
+	return k.runDevContainer(ctx, workspaceId, options, nil)
+}
+
+func (k *KubernetesDriver) runDevContainer(
+	ctx context.Context,
+	workspaceId string,
+	options *driver.RunOptions,
+	composePod *ComposePod,
+) error {
 	workspaceId = getID(workspaceId)
 
 	// namespace
-	if k.namespace != "" && k.options.CreateNamespace == "true" {
+	if options != nil {
+		err := k.checkNamespaceOption(ctx, workspaceId, options)
+		if err != nil {
+			return err
+		}
+	}
+	if k.options.WorkspaceNamespace == "true" && options != nil {
+		err := k.ensureWorkspaceNamespace(ctx, options)
+		if err != nil {
+			return err
+		}
+	} else if k.namespace != "" && k.options.CreateNamespace == "true" {
 		err := k.createNamespace(ctx)
 		if err != nil {
 			return err
@@ -67,21 +91,28 @@ func (k *KubernetesDriver) RunDevContainer(
 		}
 
 		// create persistent volume claim
-		err = k.createPersistentVolumeClaim(ctx, workspaceId, options)
+		err = k.createPersistentVolumeClaim(ctx, workspaceId, options, composePod)
 		if err != nil {
 			return err
 		}
 
 		initialize = true
+	} else if options != nil && (composePod != nil || containerInfo.Compose != nil) {
+		// remember the compose services for later starts of the workspace
+		err = k.updateDevContainerInformation(ctx, pvc, options, composePod)
+		if err != nil {
+			return err
+		}
 	}
 
 	// reuse driver.RunOptions from existing workspace if none provided
 	if options == nil && containerInfo != nil && containerInfo.Options != nil {
 		options = containerInfo.Options
+		composePod = containerInfo.Compose
 	}
 
 	// create dev container
-	err = k.runContainer(ctx, workspaceId, options, initialize)
+	err = k.runContainer(ctx, workspaceId, options, composePod, initialize)
 	if err != nil {
 		return err
 	}
@@ -93,6 +124,7 @@ func (k *KubernetesDriver) runContainer(
 	ctx context.Context,
 	id string,
 	options *driver.RunOptions,
+	composePod *ComposePod,
 	initialize bool,
 ) (err error) {
 	// get workspace mount
@@ -201,6 +233,7 @@ func (k *KubernetesDriver) runContainer(
 	if k.options.Resources != "" {
 		resources = parseResources(k.options.Resources, k.Log)
 	}
+	resources = applyHostRequirements(resources, options.HostRequirements, k.Log)
 
 	// ensure daemon config secret
 	daemonConfigSecretName := ""
@@ -230,6 +263,7 @@ func (k *KubernetesDriver) runContainer(
 	pod.Spec.InitContainers = initContainers
 	pod.Spec.Containers = getContainers(pod, options.Image, options.Entrypoint, options.Cmd, envVars, volumeMounts, capabilities, resources, options.Privileged, k.options.StrictSecurity, daemonConfigSecretName)
 	pod.Spec.Volumes = getVolumes(pod, id, daemonConfigSecretName)
+	applyComposePod(pod, composePod)
 	// avoids a problem where attaching volumes with large repositories would cause an extremely long pod startup time
 	// because changing the ownership of all files takes longer than the kubelet expects it to
 	if pod.Spec.SecurityContext == nil {
@@ -247,6 +281,16 @@ func (k *KubernetesDriver) runContainer(
 		return fmt.Errorf("get pod: %s %w", id, err)
 	}
 
+	// replace pods that terminated or are terminating
+	if existingPod != nil && (existingPod.DeletionTimestamp != nil || getPodStatus(existingPod) == "exited") {
+		k.Log.Debugf("Replace pod '%s' in phase %s", id, existingPod.Status.Phase)
+		err = k.waitPodDeleted(ctx, id)
+		if err != nil {
+			return fmt.Errorf("delete pod: %s %w", id, err)
+		}
+		existingPod = nil
+	}
+
 	if existingPod != nil {
 		existingOptions := &provider2.ProviderKubernetesDriverConfig{}
 		err := json.Unmarshal([]byte(existingPod.GetAnnotations()[DevPodLastAppliedAnnotation]), existingOptions)
@@ -268,6 +312,11 @@ func (k *KubernetesDriver) runContainer(
 		}
 	}
 
+	err = k.savePodTemplate(ctx, id, pod)
+	if err != nil {
+		return err
+	}
+
 	err = k.runPod(ctx, id, pod)
 	if err != nil {
 		return err
@@ -306,6 +355,12 @@ func (k *KubernetesDriver) runPod(ctx context.Context, id string, pod *corev1.Po
 		return fmt.Errorf("create pod %w", err)
 	}
 
+	// the pod replaces any suspended one
+	err = k.clearSuspendedPod(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	// wait for pod running
 	k.Log.Infof("Waiting for DevContainer Pod '%s' to come up...", id)
 	_, err = k.waitPodRunning(ctx, id)
@@ -475,10 +530,19 @@ func (k *KubernetesDriver) StartDevContainer(ctx context.Context, workspaceId st
 		return fmt.Errorf("persistent volume '%s' not found", workspaceId)
 	}
 
+	// recreate the pod of a stopped workspace as it was
+	resumed, err := k.resumePod(ctx, workspaceId, containerInfo.Options)
+	if err != nil {
+		return err
+	} else if resumed {
+		return nil
+	}
+
 	return k.runContainer(
 		ctx,
 		workspaceId,
 		containerInfo.Options,
+		containerInfo.Compose,
 		false,
 	)
 }
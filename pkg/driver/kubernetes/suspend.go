@@ -0,0 +1,380 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/driver"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var volumeSnapshotResource = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshots",
+}
+
+// podTemplateKey and podOptionsKey hold the pod as it was sent to the cluster and the provider options
+// it was built from in the pod template config map
+const (
+	podTemplateKey = "pod"
+	podOptionsKey  = "options"
+)
+
+// volumeSnapshotTimeout is the time to wait until a volume snapshot is ready to use
+const volumeSnapshotTimeout = 10 * time.Minute
+
+// savePodTemplate records the pod as it is sent to the cluster in a config map. Admission controllers might
+// change the pod when it is created, so resumePod recreates the pod from this template instead of the
+// running pod.
+func (k *KubernetesDriver) savePodTemplate(ctx context.Context, id string, pod *corev1.Pod) error {
+	rawPod, err := json.Marshal(pod)
+	if err != nil {
+		return fmt.Errorf("marshal pod template %w", err)
+	}
+	rawOptions, err := json.Marshal(k.options)
+	if err != nil {
+		return fmt.Errorf("marshal provider options %w", err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   getPodTemplateName(id),
+			Labels: pod.Labels,
+		},
+		Data: map[string]string{
+			podTemplateKey: string(rawPod),
+			podOptionsKey:  string(rawOptions),
+		},
+	}
+
+	configMaps := k.client.Client().CoreV1().ConfigMaps(k.namespace)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	if kerrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("save pod template %w", err)
+	}
+
+	return nil
+}
+
+// getPodTemplate returns the pod template config map of the workspace or nil if there is none
+func (k *KubernetesDriver) getPodTemplate(ctx context.Context, id string) (*corev1.ConfigMap, error) {
+	configMap, err := k.client.Client().CoreV1().ConfigMaps(k.namespace).Get(ctx, getPodTemplateName(id), metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("get pod template %w", err)
+	}
+
+	return configMap, nil
+}
+
+// deletePodTemplate deletes the pod template config map of the workspace
+func (k *KubernetesDriver) deletePodTemplate(ctx context.Context, id string) error {
+	err := k.client.Client().CoreV1().ConfigMaps(k.namespace).Delete(ctx, getPodTemplateName(id), metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("delete pod template %w", err)
+	}
+
+	return nil
+}
+
+// suspendPod marks the pod template of the workspace as suspended before deleting the pod, so that
+// resumePod can recreate the same pod without building it again
+func (k *KubernetesDriver) suspendPod(ctx context.Context, id string) error {
+	pod, err := k.getPod(ctx, id)
+	if err != nil {
+		return err
+	} else if pod == nil {
+		return nil
+	}
+
+	pvc, _, err := k.getDevContainerPvc(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// pods that already terminated have nothing worth resuming
+	if pvc != nil && pod.DeletionTimestamp == nil && getPodStatus(pod) != "exited" {
+		template, err := k.getPodTemplate(ctx, id)
+		if err != nil {
+			return err
+		} else if template != nil {
+			if template.Annotations == nil {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[DevPodSuspendedNodeAnnotation] = pod.Spec.NodeName
+			_, err = k.client.Client().CoreV1().ConfigMaps(k.namespace).Update(ctx, template, metav1.UpdateOptions{})
+			if err != nil {
+				return fmt.Errorf("update pod template %w", err)
+			}
+		}
+	}
+
+	k.Log.Infof("Delete pod '%s'...", id)
+	err = k.waitPodDeleted(ctx, id)
+	if err != nil {
+		return fmt.Errorf("delete pod %w", err)
+	}
+
+	if pvc != nil {
+		return k.snapshotPersistentVolumeClaim(ctx, pvc)
+	}
+
+	return nil
+}
+
+// resumePod recreates the pod of a workspace suspended by suspendPod from its template. It returns false if
+// there is nothing to resume or the provider options changed in the meantime, the pod has to be built from
+// the options then.
+func (k *KubernetesDriver) resumePod(ctx context.Context, id string, options *driver.RunOptions) (bool, error) {
+	template, err := k.getPodTemplate(ctx, id)
+	if err != nil {
+		return false, err
+	} else if template == nil {
+		return false, nil
+	}
+
+	nodeName, suspended := template.Annotations[DevPodSuspendedNodeAnnotation]
+	if !suspended {
+		return false, nil
+	}
+
+	existingPod, err := k.getPod(ctx, id)
+	if err != nil {
+		return false, err
+	} else if existingPod != nil {
+		return false, nil
+	}
+
+	pod := &corev1.Pod{}
+	err = json.Unmarshal([]byte(template.Data[podTemplateKey]), pod)
+	if err != nil {
+		k.Log.Warnf("Error decoding pod template, recreating pod: %v", err)
+		return false, nil
+	}
+
+	lastAppliedOptions := &provider2.ProviderKubernetesDriverConfig{}
+	err = json.Unmarshal([]byte(template.Data[podOptionsKey]), lastAppliedOptions)
+	if err != nil || !optionsEqual(lastAppliedOptions, k.options) {
+		k.Log.Debug("Provider options changed, recreating pod")
+		return false, nil
+	}
+
+	// registry credentials might have expired while the workspace was stopped
+	if len(pod.Spec.ImagePullSecrets) > 0 && k.options.KubernetesPullSecretsEnabled == "true" && options != nil {
+		_, err = k.EnsurePullSecret(ctx, getPullSecretsName(id), options.Image)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	k.Log.Infof("Resume pod '%s'", id)
+	err = k.runPod(ctx, id, getResumedPod(pod, nodeName))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// clearSuspendedPod removes the suspended mark from the pod template once a pod was created for the workspace
+func (k *KubernetesDriver) clearSuspendedPod(ctx context.Context, id string) error {
+	template, err := k.getPodTemplate(ctx, id)
+	if err != nil {
+		return err
+	} else if template == nil {
+		return nil
+	} else if _, suspended := template.Annotations[DevPodSuspendedNodeAnnotation]; !suspended {
+		return nil
+	}
+
+	delete(template.Annotations, DevPodSuspendedNodeAnnotation)
+	_, err = k.client.Client().CoreV1().ConfigMaps(k.namespace).Update(ctx, template, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("update pod template %w", err)
+	}
+
+	return nil
+}
+
+// getResumedPod returns the pod template without the parts that are only needed when the workspace is created
+func getResumedPod(pod *corev1.Pod, nodeName string) *corev1.Pod {
+	// the workspace volume is initialized already
+	initContainers := []corev1.Container{}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name != InitContainerName {
+			initContainers = append(initContainers, container)
+		}
+	}
+	pod.Spec.InitContainers = initContainers
+
+	// prefer the node the pod ran on, it has the images cached already
+	if nodeName != "" {
+		pod.Spec.Affinity = preferNode(pod.Spec.Affinity, nodeName)
+	}
+
+	return pod
+}
+
+func getPodTemplateName(id string) string {
+	return fmt.Sprintf("devpod-pod-template-%s", id)
+}
+
+func preferNode(affinity *corev1.Affinity, nodeName string) *corev1.Affinity {
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+
+	// replace the node preference of the pod template
+	terms := []corev1.PreferredSchedulingTerm{}
+	for _, term := range affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if len(term.Preference.MatchFields) == 1 && term.Preference.MatchFields[0].Key == "metadata.name" {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(terms, corev1.PreferredSchedulingTerm{
+		Weight: 100,
+		Preference: corev1.NodeSelectorTerm{
+			MatchFields: []corev1.NodeSelectorRequirement{{
+				Key:      "metadata.name",
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{nodeName},
+			}},
+		},
+	})
+
+	return affinity
+}
+
+// snapshotPersistentVolumeClaim creates a volume snapshot of the stopped workspace if a volume snapshot class
+// is configured. Only the latest snapshot of a workspace is kept, the previous snapshots are deleted once the new
+// one is ready to use.
+func (k *KubernetesDriver) snapshotPersistentVolumeClaim(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
+	if k.options.VolumeSnapshotClass == "" {
+		return nil
+	} else if !k.volumeSnapshotsSupported() {
+		k.Log.Warnf("Skipping snapshot of persistent volume claim '%s', the cluster doesn't support volume snapshots", pvc.Name)
+		return nil
+	}
+
+	labels := map[string]string{}
+	labels[DevPodWorkspaceUIDLabel] = pvc.Labels[DevPodWorkspaceUIDLabel]
+	maps.Copy(labels, ExtraDevPodLabels)
+
+	name := fmt.Sprintf("%s-%s", pvc.Name, rand.String(5))
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetAPIVersion(volumeSnapshotResource.GroupVersion().String())
+	snapshot.SetKind("VolumeSnapshot")
+	snapshot.SetName(name)
+	snapshot.SetLabels(labels)
+	snapshot.Object["spec"] = map[string]interface{}{
+		"volumeSnapshotClassName": k.options.VolumeSnapshotClass,
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": pvc.Name,
+		},
+	}
+
+	k.Log.Infof("Create volume snapshot '%s'", name)
+	_, err := k.client.Dynamic().Resource(volumeSnapshotResource).Namespace(k.namespace).Create(ctx, snapshot, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("create volume snapshot %w", err)
+	}
+
+	k.Log.Infof("Wait for volume snapshot '%s' to be ready...", name)
+	err = k.waitVolumeSnapshotReady(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return k.deleteVolumeSnapshots(ctx, pvc, name)
+}
+
+// waitVolumeSnapshotReady waits until the volume snapshot can be used to restore a volume
+func (k *KubernetesDriver) waitVolumeSnapshotReady(ctx context.Context, name string) error {
+	snapshots := k.client.Dynamic().Resource(volumeSnapshotResource).Namespace(k.namespace)
+	err := wait.PollUntilContextTimeout(ctx, time.Second, volumeSnapshotTimeout, true, func(ctx context.Context) (bool, error) {
+		snapshot, err := snapshots.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		message, _, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message")
+		if message != "" {
+			return false, fmt.Errorf("%s", message)
+		}
+
+		ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+		return ready, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for volume snapshot '%s' %w", name, err)
+	}
+
+	return nil
+}
+
+// deleteVolumeSnapshots deletes the snapshots of the workspace except the given one
+func (k *KubernetesDriver) deleteVolumeSnapshots(ctx context.Context, pvc *corev1.PersistentVolumeClaim, keep string) error {
+	uid := pvc.Labels[DevPodWorkspaceUIDLabel]
+	if uid == "" || !k.volumeSnapshotsSupported() {
+		return nil
+	}
+
+	snapshots := k.client.Dynamic().Resource(volumeSnapshotResource).Namespace(k.namespace)
+	list, err := snapshots.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", DevPodWorkspaceUIDLabel, uid),
+	})
+	if err != nil {
+		return fmt.Errorf("list volume snapshots %w", err)
+	}
+
+	for _, snapshot := range list.Items {
+		if snapshot.GetName() == keep {
+			continue
+		}
+
+		k.Log.Infof("Delete volume snapshot '%s'", snapshot.GetName())
+		err = snapshots.Delete(ctx, snapshot.GetName(), metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("delete volume snapshot %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (k *KubernetesDriver) volumeSnapshotsSupported() bool {
+	resources, err := k.client.Client().Discovery().ServerResourcesForGroupVersion(volumeSnapshotResource.GroupVersion().String())
+	if err != nil {
+		k.Log.Debugf("Error discovering volume snapshots: %v", err)
+		return false
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Name == volumeSnapshotResource.Resource {
+			return true
+		}
+	}
+
+	return false
+}
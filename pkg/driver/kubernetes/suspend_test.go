@@ -0,0 +1,317 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/driver"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+const testNamespace = "devpod"
+
+func newFakeDriver(options *provider2.ProviderKubernetesDriverConfig, objects ...runtime.Object) (*KubernetesDriver, *fake.Clientset) {
+	clientset := fake.NewClientset(objects...)
+
+	// the fake clientset doesn't run pods, so pretend they start right away
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status = getRunningPodStatus(pod)
+		return false, nil, nil
+	})
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		volumeSnapshotResource: "VolumeSnapshotList",
+	})
+
+	// the fake cluster has no snapshot controller, so pretend snapshots are ready right away
+	dynamicClient.PrependReactor("create", volumeSnapshotResource.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		snapshot := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		if _, found := snapshot.Object["status"]; !found {
+			snapshot.Object["status"] = map[string]interface{}{"readyToUse": true}
+		}
+		return false, nil, nil
+	})
+
+	options.PodTimeout = "10s"
+	return &KubernetesDriver{
+		namespace: testNamespace,
+		client: &Client{
+			client:  clientset,
+			dynamic: dynamicClient,
+		},
+		options: options,
+		Log:     log.Discard,
+	}, clientset
+}
+
+func getRunningPodStatus(pod *corev1.Pod) corev1.PodStatus {
+	status := corev1.PodStatus{Phase: corev1.PodRunning}
+	for _, container := range pod.Spec.InitContainers {
+		containerStatus := corev1.ContainerStatus{Name: container.Name}
+		if restartableInitContainer(container.RestartPolicy) {
+			containerStatus.Started = ptr.To(true)
+			containerStatus.Ready = true
+			containerStatus.State.Running = &corev1.ContainerStateRunning{}
+		} else {
+			containerStatus.State.Terminated = &corev1.ContainerStateTerminated{}
+		}
+		status.InitContainerStatuses = append(status.InitContainerStatuses, containerStatus)
+	}
+	for _, container := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, corev1.ContainerStatus{
+			Name:  container.Name,
+			Ready: true,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		})
+	}
+
+	return status
+}
+
+func newTestPvc(t *testing.T, id string) *corev1.PersistentVolumeClaim {
+	containerInfo, err := json.Marshal(&DevContainerInfo{
+		WorkspaceID: id,
+		Options:     &driver.RunOptions{UID: "uid", Image: "alpine"},
+	})
+	assert.NilError(t, err)
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        id,
+			Namespace:   testNamespace,
+			Labels:      map[string]string{DevPodWorkspaceUIDLabel: "uid"},
+			Annotations: map[string]string{DevPodInfoAnnotation: string(containerInfo)},
+		},
+	}
+}
+
+func newTestPod(t *testing.T, id string, options *provider2.ProviderKubernetesDriverConfig) *corev1.Pod {
+	lastApplied, err := json.Marshal(options)
+	assert.NilError(t, err)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        id,
+			Namespace:   testNamespace,
+			Labels:      map[string]string{DevPodWorkspaceUIDLabel: "uid"},
+			Annotations: map[string]string{DevPodLastAppliedAnnotation: string(lastApplied)},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      "node-1",
+			Priority:      ptr.To(int32(0)),
+			RestartPolicy: corev1.RestartPolicyNever,
+			InitContainers: []corev1.Container{
+				{Name: InitContainerName, Image: "alpine"},
+				{Name: "db", Image: "postgres", RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways)},
+			},
+			Containers: []corev1.Container{{Name: DevContainerName, Image: "alpine"}},
+			ImagePullSecrets: []corev1.LocalObjectReference{
+				{Name: getPullSecretsName(id)},
+			},
+		},
+	}
+	pod.Status = getRunningPodStatus(pod)
+
+	return pod
+}
+
+// newTestPodTemplate records the pod as it was sent to the cluster, admission added a sidecar and the priority
+// to the running pod
+func newTestPodTemplate(t *testing.T, k *KubernetesDriver, id string, pod *corev1.Pod) {
+	template := pod.DeepCopy()
+	template.Status = corev1.PodStatus{}
+	template.Spec.NodeName = ""
+	template.Spec.Priority = nil
+	template.Spec.Containers = template.Spec.Containers[:1]
+	assert.NilError(t, k.savePodTemplate(context.Background(), id, template))
+}
+
+func getTestSuspendedNode(t *testing.T, k *KubernetesDriver, id string) (string, bool) {
+	template, err := k.getPodTemplate(context.Background(), id)
+	assert.NilError(t, err)
+	assert.Assert(t, template != nil)
+
+	nodeName, suspended := template.Annotations[DevPodSuspendedNodeAnnotation]
+	return nodeName, suspended
+}
+
+func TestStopAndStartDevContainer(t *testing.T) {
+	ctx := context.Background()
+	id := getID("test")
+	options := &provider2.ProviderKubernetesDriverConfig{}
+	runningPod := newTestPod(t, id, options)
+	runningPod.Spec.Containers = append(runningPod.Spec.Containers, corev1.Container{Name: "sidecar", Image: "proxy"})
+	k, clientset := newFakeDriver(options, newTestPvc(t, id), runningPod)
+	newTestPodTemplate(t, k, id, runningPod)
+
+	err := k.StopDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	_, err = clientset.CoreV1().Pods(testNamespace).Get(ctx, id, metav1.GetOptions{})
+	assert.Assert(t, kerrors.IsNotFound(err))
+
+	containerDetails, err := k.FindDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	assert.Equal(t, containerDetails.State.Status, "exited")
+
+	nodeName, suspended := getTestSuspendedNode(t, k, id)
+	assert.Assert(t, suspended)
+	assert.Equal(t, nodeName, "node-1")
+
+	// the pod is recreated from the template without initializing the workspace again
+	err = k.StartDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	pod, err := clientset.CoreV1().Pods(testNamespace).Get(ctx, id, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(pod.Spec.InitContainers), 1)
+	assert.Equal(t, pod.Spec.InitContainers[0].Name, "db")
+	assert.Equal(t, len(pod.Spec.Containers), 1)
+	assert.Assert(t, pod.Spec.Priority == nil)
+	assert.DeepEqual(t, pod.Spec.ImagePullSecrets, []corev1.LocalObjectReference{{Name: getPullSecretsName(id)}})
+	preferred := pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	assert.Equal(t, len(preferred), 1)
+	assert.DeepEqual(t, preferred[0].Preference.MatchFields[0].Values, []string{"node-1"})
+	_, suspended = getTestSuspendedNode(t, k, id)
+	assert.Assert(t, !suspended)
+
+	containerDetails, err = k.FindDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	assert.Equal(t, containerDetails.State.Status, "running")
+
+	// stopping again replaces the node preference
+	pod.Spec.NodeName = "node-2"
+	_, err = clientset.CoreV1().Pods(testNamespace).Update(ctx, pod, metav1.UpdateOptions{})
+	assert.NilError(t, err)
+	err = k.StopDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	err = k.StartDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	pod, err = clientset.CoreV1().Pods(testNamespace).Get(ctx, id, metav1.GetOptions{})
+	assert.NilError(t, err)
+	preferred = pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	assert.Equal(t, len(preferred), 1)
+	assert.DeepEqual(t, preferred[0].Preference.MatchFields[0].Values, []string{"node-2"})
+
+	// deleting the workspace deletes the template
+	err = k.DeleteDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	template, err := k.getPodTemplate(ctx, id)
+	assert.NilError(t, err)
+	assert.Assert(t, template == nil)
+}
+
+func TestResumePodOptionsChanged(t *testing.T) {
+	ctx := context.Background()
+	id := getID("test")
+	options := &provider2.ProviderKubernetesDriverConfig{Labels: "team=a"}
+	pod := newTestPod(t, id, options)
+	k, _ := newFakeDriver(options, newTestPvc(t, id), pod)
+	newTestPodTemplate(t, k, id, pod)
+	k.options = &provider2.ProviderKubernetesDriverConfig{PodTimeout: k.options.PodTimeout}
+
+	err := k.suspendPod(ctx, id)
+	assert.NilError(t, err)
+	resumed, err := k.resumePod(ctx, id, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, !resumed, "the pod has to be built from the new options")
+}
+
+func TestFindDevContainerStatus(t *testing.T) {
+	ctx := context.Background()
+	id := getID("test")
+
+	k, _ := newFakeDriver(&provider2.ProviderKubernetesDriverConfig{})
+	containerDetails, err := k.FindDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	assert.Assert(t, containerDetails == nil, "workspace without volume is not found")
+
+	tests := map[string]struct {
+		phase    corev1.PodPhase
+		deleting bool
+		expected string
+	}{
+		"pending":     {phase: corev1.PodPending, expected: "created"},
+		"running":     {phase: corev1.PodRunning, expected: "running"},
+		"succeeded":   {phase: corev1.PodSucceeded, expected: "exited"},
+		"failed":      {phase: corev1.PodFailed, expected: "exited"},
+		"terminating": {phase: corev1.PodRunning, deleting: true, expected: "removing"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pod := newTestPod(t, id, &provider2.ProviderKubernetesDriverConfig{})
+			pod.Status.Phase = test.phase
+			if test.deleting {
+				pod.DeletionTimestamp = ptr.To(metav1.Now())
+			}
+
+			k, _ := newFakeDriver(&provider2.ProviderKubernetesDriverConfig{}, newTestPvc(t, id), pod)
+			containerDetails, err := k.FindDevContainer(ctx, "test")
+			assert.NilError(t, err)
+			assert.Equal(t, containerDetails.State.Status, test.expected)
+		})
+	}
+}
+
+func TestSnapshotPersistentVolumeClaim(t *testing.T) {
+	ctx := context.Background()
+	id := getID("test")
+	pvc := newTestPvc(t, id)
+	k, clientset := newFakeDriver(&provider2.ProviderKubernetesDriverConfig{VolumeSnapshotClass: "csi-snapshots"}, pvc)
+	snapshots := k.client.Dynamic().Resource(volumeSnapshotResource).Namespace(testNamespace)
+
+	// skipped if the cluster doesn't serve volume snapshots
+	err := k.snapshotPersistentVolumeClaim(ctx, pvc)
+	assert.NilError(t, err)
+	list, err := snapshots.List(ctx, metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(list.Items), 0)
+
+	clientset.Resources = []*metav1.APIResourceList{{
+		GroupVersion: volumeSnapshotResource.GroupVersion().String(),
+		APIResources: []metav1.APIResource{{Name: volumeSnapshotResource.Resource, Kind: "VolumeSnapshot", Namespaced: true}},
+	}}
+
+	// only the latest snapshot is kept
+	assert.NilError(t, k.snapshotPersistentVolumeClaim(ctx, pvc))
+	assert.NilError(t, k.snapshotPersistentVolumeClaim(ctx, pvc))
+	list, err = snapshots.List(ctx, metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(list.Items), 1)
+	source, _, err := unstructured.NestedString(list.Items[0].Object, "spec", "source", "persistentVolumeClaimName")
+	assert.NilError(t, err)
+	assert.Equal(t, source, id)
+
+	// the previous snapshot is kept if the new one fails
+	previous := list.Items[0].GetName()
+	k.client.dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("create", volumeSnapshotResource.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		snapshot := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		snapshot.Object["status"] = map[string]interface{}{
+			"readyToUse": false,
+			"error":      map[string]interface{}{"message": "snapshot failed"},
+		}
+		return false, nil, nil
+	})
+	err = k.snapshotPersistentVolumeClaim(ctx, pvc)
+	assert.ErrorContains(t, err, "snapshot failed")
+	_, err = snapshots.Get(ctx, previous, metav1.GetOptions{})
+	assert.NilError(t, err)
+
+	err = k.DeleteDevContainer(ctx, "test")
+	assert.NilError(t, err)
+	list, err = snapshots.List(ctx, metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(list.Items), 0)
+}
@@ -0,0 +1,345 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/compose"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/devpod/pkg/driver/docker"
+	"github.com/skevetter/devpod/pkg/podman"
+	provider2 "github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+)
+
+var (
+	_ driver.DockerDriver        = (*podmanDriver)(nil)
+	_ driver.HostResourcesDriver = (*podmanDriver)(nil)
+	_ driver.SnapshotDriver      = (*podmanDriver)(nil)
+	_ driver.KubeExportDriver    = (*podmanDriver)(nil)
+)
+
+// NewPodmanDriver creates a driver that runs devcontainers like the docker driver with the podman cli.
+// Finding, starting, stopping and removing containers, logs, host resources and kube exports use the
+// podman API, which shares its storage with the cli.
+func NewPodmanDriver(workspaceInfo *provider2.AgentWorkspaceInfo, log log.Logger) (driver.DockerDriver, error) {
+	podmanCommand := "podman"
+	if workspaceInfo.Agent.Podman.Path != "" {
+		podmanCommand = workspaceInfo.Agent.Podman.Path
+	}
+
+	env := map[string]string{}
+	for k, v := range workspaceInfo.Agent.Podman.Env {
+		env[k] = v
+	}
+	socket := workspaceInfo.Agent.Podman.Socket
+	if socket != "" {
+		// let the cli talk to the same service as the driver
+		env["CONTAINER_HOST"] = "unix://" + strings.TrimPrefix(socket, "unix://")
+	}
+
+	pod, err := workspaceInfo.Agent.Podman.Pod.Bool()
+	if err != nil {
+		return nil, fmt.Errorf("parse podman pod option %w", err)
+	}
+
+	// the docker driver runs the podman cli for everything that isn't available in the API
+	cliWorkspaceInfo := *workspaceInfo
+	cliWorkspaceInfo.Agent.Docker = provider2.ProviderDockerDriverConfig{
+		Path: podmanCommand,
+		Env:  env,
+	}
+	cliDriver, err := docker.NewDockerDriver(&cliWorkspaceInfo, log)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"command": podmanCommand,
+		"socket":  socket,
+	}).Debug("using podman driver")
+	return &podmanDriver{
+		DockerDriver: cliDriver,
+		Command:      podmanCommand,
+		Socket:       socket,
+		Env:          config.ObjectToList(env),
+		Pod:          pod,
+		ContainerID:  workspaceInfo.Workspace.Source.Container,
+		Log:          log,
+	}, nil
+}
+
+type podmanDriver struct {
+	driver.DockerDriver
+
+	Command     string
+	Socket      string
+	Env         []string
+	Pod         bool
+	ContainerID string
+
+	client *podman.Client
+
+	Log log.Logger
+}
+
+func (d *podmanDriver) podman(ctx context.Context) (*podman.Client, error) {
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	client, err := podman.NewClient(ctx, d.Command, d.Socket, d.Env, d.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	d.client = client
+	return client, nil
+}
+
+func (d *podmanDriver) FindDevContainer(ctx context.Context, workspaceId string) (*config.ContainerDetails, error) {
+	client, err := d.podman(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{d.ContainerID}
+	if d.ContainerID == "" {
+		ids, err = client.FindContainers(ctx, []string{config.DockerIDLabel + "=" + workspaceId})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range ids {
+		containerDetails, err := client.InspectContainer(ctx, id)
+		if err != nil {
+			return nil, err
+		} else if containerDetails == nil || containerDetails.State.Status == "removing" {
+			continue
+		}
+
+		if containerDetails.Config.User != "" {
+			if containerDetails.Config.Labels == nil {
+				containerDetails.Config.Labels = map[string]string{}
+			}
+			if containerDetails.Config.Labels[config.UserLabel] == "" {
+				containerDetails.Config.Labels[config.UserLabel] = containerDetails.Config.User
+			}
+		}
+
+		return containerDetails, nil
+	}
+
+	return nil, nil
+}
+
+func (d *podmanDriver) findContainer(ctx context.Context, workspaceId string) (*podman.Client, *config.ContainerDetails, error) {
+	container, err := d.FindDevContainer(ctx, workspaceId)
+	if err != nil {
+		return nil, nil, err
+	} else if container == nil {
+		return nil, nil, fmt.Errorf("container not found")
+	}
+
+	return d.client, container, nil
+}
+
+func (d *podmanDriver) DeleteDevContainer(ctx context.Context, workspaceId string) error {
+	container, err := d.FindDevContainer(ctx, workspaceId)
+	if err != nil {
+		return err
+	} else if container == nil {
+		return nil
+	}
+
+	return d.client.RemoveContainer(ctx, container.ID)
+}
+
+func (d *podmanDriver) StartDevContainer(ctx context.Context, workspaceId string) error {
+	client, container, err := d.findContainer(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	return client.StartContainer(ctx, container.ID)
+}
+
+func (d *podmanDriver) StopDevContainer(ctx context.Context, workspaceId string) error {
+	client, container, err := d.findContainer(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	return client.StopContainer(ctx, container.ID)
+}
+
+func (d *podmanDriver) GetDevContainerLogs(ctx context.Context, workspaceId string, stdout io.Writer, stderr io.Writer) error {
+	client, container, err := d.findContainer(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	return client.ContainerLogs(ctx, container.ID, stdout, stderr)
+}
+
+func (d *podmanDriver) HostResources(ctx context.Context) (*driver.HostResources, error) {
+	client, err := d.podman(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &driver.HostResources{
+		CPUs:   info.Host.CPUs,
+		Memory: info.Host.MemTotal,
+	}, nil
+}
+
+func (d *podmanDriver) RunDockerDevContainer(ctx context.Context, params *driver.RunDockerDevContainerParams) error {
+	if !hasUserNamespace(params) {
+		client, err := d.podman(ctx)
+		if err != nil {
+			return err
+		}
+		info, err := client.Info(ctx)
+		if err != nil {
+			return err
+		}
+
+		if userns := keepIDUserNamespace(params, info.Host.Security.Rootless); userns != "" {
+			d.Log.WithFields(logrus.Fields{
+				"userns": userns,
+				"uid":    os.Getuid(),
+			}).Debug("keeping the uid of the user in the rootless container")
+			options := *params.Options
+			options.Userns = userns
+			runParams := *params
+			runParams.Options = &options
+			params = &runParams
+		}
+	}
+
+	return d.DockerDriver.RunDockerDevContainer(ctx, params)
+}
+
+// hasUserNamespace returns true if the user namespace of the devcontainer is configured by the options or the
+// runArgs of the devcontainer.json
+func hasUserNamespace(params *driver.RunDockerDevContainerParams) bool {
+	options := params.Options
+	if options.Userns != "" || len(options.UidMap) > 0 || len(options.GidMap) > 0 {
+		return true
+	}
+	if params.ParsedConfig != nil {
+		for _, arg := range params.ParsedConfig.RunArgs {
+			if strings.HasPrefix(arg, "--userns") || strings.HasPrefix(arg, "--uidmap") || strings.HasPrefix(arg, "--gidmap") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// keepIDUserNamespace returns keep-id if the devcontainer runs with a non-root user in rootless podman. Rootless
+// podman maps the user running podman to root in the container, so files of the workspace would be owned by
+// root. With keep-id the user keeps its uid in the container instead, which DevPod then assigns to the remote
+// user. Rootful podman runs in the host user namespace, where the remote user uid is updated like with docker.
+func keepIDUserNamespace(params *driver.RunDockerDevContainerParams, rootless bool) string {
+	if !rootless {
+		return ""
+	}
+
+	remoteUser := params.Options.User
+	if params.ParsedConfig != nil {
+		if params.ParsedConfig.RemoteUser != "" {
+			remoteUser = params.ParsedConfig.RemoteUser
+		} else if params.ParsedConfig.ContainerUser != "" {
+			remoteUser = params.ParsedConfig.ContainerUser
+		}
+	}
+	if remoteUser == "" || remoteUser == "root" || remoteUser == "0" {
+		return ""
+	}
+
+	return "keep-id"
+}
+
+func (d *podmanDriver) ComposeHelper() (*compose.ComposeHelper, error) {
+	helper, err := d.DockerDriver.ComposeHelper()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Pod && helper.Extensions == nil {
+		// podman compose runs docker-compose or podman-compose, only podman-compose creates the pod
+		cmd := exec.Command(d.Command, "compose", "version")
+		cmd.Env = append(os.Environ(), d.Env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("podman compose version: %s %w", string(out), err)
+		} else if !strings.Contains(string(out), "podman-compose") {
+			return nil, fmt.Errorf("grouping the services into a pod requires podman-compose as compose provider of podman compose")
+		}
+
+		helper.Extensions = map[string]any{
+			"x-podman": map[string]any{
+				"in_pod": true,
+			},
+		}
+	}
+
+	return helper, nil
+}
+
+// GenerateKube exports the devcontainer as kubernetes yaml. Containers of compose devcontainers that
+// run in a pod are exported together with the other services of the pod.
+func (d *podmanDriver) GenerateKube(ctx context.Context, workspaceId string) ([]byte, error) {
+	client, container, err := d.findContainer(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	name := container.ID
+	if project := container.Config.Labels[compose.ProjectLabel]; project != "" {
+		exists, err := client.PodExists(ctx, PodName(project))
+		if err != nil {
+			return nil, err
+		} else if exists {
+			name = PodName(project)
+		}
+	}
+
+	return client.GenerateKube(ctx, []string{name})
+}
+
+// PodName returns the name of the pod podman-compose creates for the compose project
+func PodName(project string) string {
+	return "pod_" + project
+}
+
+func (d *podmanDriver) CreateSnapshot(ctx context.Context, workspaceId, image, volumesFolder string) ([]string, error) {
+	return d.snapshotDriver().CreateSnapshot(ctx, workspaceId, image, volumesFolder)
+}
+
+func (d *podmanDriver) RestoreSnapshot(ctx context.Context, workspaceId, image, volumesFolder string, volumes []string) error {
+	return d.snapshotDriver().RestoreSnapshot(ctx, workspaceId, image, volumesFolder, volumes)
+}
+
+func (d *podmanDriver) DeleteSnapshot(ctx context.Context, image string) error {
+	return d.snapshotDriver().DeleteSnapshot(ctx, image)
+}
+
+func (d *podmanDriver) snapshotDriver() driver.SnapshotDriver {
+	return d.DockerDriver.(driver.SnapshotDriver)
+}
@@ -0,0 +1,115 @@
+package podman
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/driver"
+	"github.com/skevetter/log"
+)
+
+func TestKeepIDUserNamespace(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      *driver.RunOptions
+		parsedConfig *config.DevContainerConfig
+		rootless     bool
+		want         string
+	}{
+		{name: "rootful", options: &driver.RunOptions{User: "vscode"}, want: ""},
+		{name: "rootless user", options: &driver.RunOptions{User: "vscode"}, rootless: true, want: "keep-id"},
+		{name: "rootless root", options: &driver.RunOptions{User: "root"}, rootless: true, want: ""},
+		{name: "rootless uid 0", options: &driver.RunOptions{User: "0"}, rootless: true, want: ""},
+		{name: "rootless without user", options: &driver.RunOptions{}, rootless: true, want: ""},
+		{name: "remote user", options: &driver.RunOptions{User: "root"}, parsedConfig: newConfig("node", ""), rootless: true, want: "keep-id"},
+		{name: "container user", options: &driver.RunOptions{}, parsedConfig: newConfig("", "node"), rootless: true, want: "keep-id"},
+		{name: "remote user root", options: &driver.RunOptions{}, parsedConfig: newConfig("root", "node"), rootless: true, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keepIDUserNamespace(&driver.RunDockerDevContainerParams{Options: tt.options, ParsedConfig: tt.parsedConfig}, tt.rootless)
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHasUserNamespace(t *testing.T) {
+	withRunArgs := func(runArgs ...string) *config.DevContainerConfig {
+		parsedConfig := &config.DevContainerConfig{}
+		parsedConfig.RunArgs = runArgs
+		return parsedConfig
+	}
+
+	tests := []struct {
+		name         string
+		options      *driver.RunOptions
+		parsedConfig *config.DevContainerConfig
+		want         bool
+	}{
+		{name: "none", options: &driver.RunOptions{}, parsedConfig: withRunArgs("--init"), want: false},
+		{name: "userns option", options: &driver.RunOptions{Userns: "auto"}, want: true},
+		{name: "uid map option", options: &driver.RunOptions{UidMap: []string{"0:1:1000"}}, want: true},
+		{name: "userns run arg", options: &driver.RunOptions{}, parsedConfig: withRunArgs("--userns=host"), want: true},
+		{name: "gid map run arg", options: &driver.RunOptions{}, parsedConfig: withRunArgs("--gidmap", "0:1:1000"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hasUserNamespace(&driver.RunDockerDevContainerParams{Options: tt.options, ParsedConfig: tt.parsedConfig})
+			if got != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFindDevContainer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /libpod/_ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"Id":"old"},{"Id":"abc"}]`))
+	})
+	mux.HandleFunc("GET /containers/old/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Id":"old","State":{"Status":"Removing"}}`))
+	})
+	mux.HandleFunc("GET /containers/abc/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Id":"abc","State":{"Status":"running"},"Config":{"User":"vscode"}}`))
+	})
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	d := &podmanDriver{Command: "podman", Socket: socket, Log: log.Discard}
+	container, err := d.FindDevContainer(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	} else if container == nil || container.ID != "abc" {
+		t.Fatalf("unexpected container %+v", container)
+	} else if container.Config.Labels[config.UserLabel] != "vscode" {
+		t.Fatalf("expected the user of the container as user label, got %v", container.Config.Labels)
+	}
+}
+
+func newConfig(remoteUser, containerUser string) *config.DevContainerConfig {
+	parsedConfig := &config.DevContainerConfig{}
+	parsedConfig.RemoteUser = remoteUser
+	parsedConfig.ContainerUser = containerUser
+	return parsedConfig
+}
@@ -41,6 +41,56 @@ type ReprovisioningDriver interface {
 	CanReprovision() bool
 }
 
+// HostResourcesDriver is implemented by drivers that can report the resources of the machine
+// the devcontainer will run on
+type HostResourcesDriver interface {
+	Driver
+
+	// HostResources returns the cpus and memory available to devcontainers
+	HostResources(ctx context.Context) (*HostResources, error)
+}
+
+// SnapshotDriver is implemented by drivers that can checkpoint and restore a devcontainer
+type SnapshotDriver interface {
+	Driver
+
+	// CreateSnapshot commits the devcontainer into the given image and archives its named volumes
+	// into the given folder. Returns the names of the archived volumes.
+	CreateSnapshot(ctx context.Context, workspaceID, image, volumesFolder string) ([]string, error)
+
+	// RestoreSnapshot removes the devcontainer and restores the given volumes from the given folder
+	RestoreSnapshot(ctx context.Context, workspaceID, image, volumesFolder string, volumes []string) error
+
+	// DeleteSnapshot removes the snapshot image
+	DeleteSnapshot(ctx context.Context, image string) error
+}
+
+// KubeExportDriver is implemented by drivers that can export a devcontainer as kubernetes yaml
+type KubeExportDriver interface {
+	Driver
+
+	// GenerateKube returns the kubernetes yaml of the devcontainer
+	GenerateKube(ctx context.Context, workspaceID string) ([]byte, error)
+}
+
+// PortForwardDriver is implemented by drivers that can connect to ports of the devcontainer
+// without the agent running in the container
+type PortForwardDriver interface {
+	Driver
+
+	// PortForward connects the stream to the given port of the devcontainer until either side closes
+	PortForward(ctx context.Context, workspaceID string, port int, stream io.ReadWriter) error
+}
+
+// HostResources are the resources available on the machine running the devcontainer
+type HostResources struct {
+	// CPUs is the number of available cpus
+	CPUs int `json:"cpus,omitempty"`
+
+	// Memory is the available memory in bytes
+	Memory int64 `json:"memory,omitempty"`
+}
+
 // RunOptions are the options for running a container
 type RunOptions struct {
 	// UID is a unique identifier for this workspace
@@ -92,4 +142,7 @@ type RunOptions struct {
 
 	// GidMap are GID mappings for user namespace
 	GidMap []string `json:"gidMap,omitempty"`
+
+	// HostRequirements are the cpu, memory and storage requirements of the devcontainer
+	HostRequirements *config.HostRequirements `json:"hostRequirements,omitempty"`
 }
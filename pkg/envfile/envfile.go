@@ -16,6 +16,9 @@ type EnvFile struct {
 }
 
 func Apply(log log.Logger) {
+	// secrets take precedence over the environment of the envfile
+	defer applySecrets(log)
+
 	out, err := os.ReadFile(location)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -0,0 +1,87 @@
+package envfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	copypkg "github.com/skevetter/devpod/pkg/copy"
+	"github.com/skevetter/log"
+)
+
+// secretsLocation is on a tmpfs, so resolved secrets never touch the disk and are gone when the container stops
+var secretsLocation = "/dev/shm/devpod-secrets.json"
+
+// WriteSecrets replaces the secrets of the workspace. The file is only readable by the given user.
+func WriteSecrets(secrets map[string]string, userName string) error {
+	_, err := os.Stat(filepath.Dir(secretsLocation))
+	if err != nil {
+		return fmt.Errorf("%s is not available to hold secrets in memory %w", filepath.Dir(secretsLocation), err)
+	}
+
+	out, err := json.Marshal(&EnvFile{Env: secrets})
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so readers never see a partial file
+	tmpFile := secretsLocation + ".tmp"
+	err = os.WriteFile(tmpFile, out, 0600)
+	if err != nil {
+		return err
+	}
+	if userName != "" {
+		err = copypkg.Chown(tmpFile, userName)
+		if err != nil {
+			_ = os.Remove(tmpFile)
+			return err
+		}
+	}
+
+	return os.Rename(tmpFile, secretsLocation)
+}
+
+// Secrets returns the secrets of the workspace as KEY=VALUE pairs
+func Secrets() []string {
+	out, err := os.ReadFile(secretsLocation)
+	if err != nil {
+		return nil
+	}
+
+	envFile := &EnvFile{}
+	err = json.Unmarshal(out, envFile)
+	if err != nil {
+		return nil
+	}
+
+	env := []string{}
+	for k, v := range envFile.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+func applySecrets(log log.Logger) {
+	out, err := os.ReadFile(secretsLocation)
+	if err != nil {
+		if !os.IsNotExist(err) && !os.IsPermission(err) {
+			log.Debugf("Error reading secrets: %v", err)
+		}
+
+		return
+	}
+
+	envFile := &EnvFile{}
+	err = json.Unmarshal(out, envFile)
+	if err != nil {
+		log.Debugf("Error parsing secrets: %v", err)
+		return
+	}
+
+	for k, v := range envFile.Env {
+		_ = os.Setenv(k, v)
+	}
+}
@@ -0,0 +1,298 @@
+package filesync
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	copypkg "github.com/skevetter/devpod/pkg/copy"
+)
+
+// WriteTar writes the given paths of the folder into a tar stream. Directories are written without their
+// contents and paths that vanished in the meantime are skipped.
+func WriteTar(writer io.Writer, root string, paths []string) error {
+	tarWriter := tar.NewWriter(writer)
+	for _, relPath := range paths {
+		err := writeTarEntry(tarWriter, root, relPath)
+		if err != nil {
+			return fmt.Errorf("write %s %w", relPath, err)
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+func writeTarEntry(tarWriter *tar.Writer, root, relPath string) error {
+	absPath, err := ResolvePath(root, relPath, false)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return nil
+	}
+
+	linkName := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		linkName, err = os.Readlink(absPath)
+		if err != nil {
+			return nil
+		}
+	} else if !info.IsDir() && !info.Mode().IsRegular() {
+		return nil
+	}
+
+	hdr, err := tar.FileInfoHeader(info, linkName)
+	if err != nil {
+		return err
+	}
+	hdr.Name = relPath
+	hdr.Uid = 0
+	hdr.Gid = 0
+	hdr.Uname = ""
+	hdr.Gname = ""
+	if !info.Mode().IsRegular() {
+		return tarWriter.WriteHeader(hdr)
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	err = tarWriter.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	// the file might have been truncated since the stat, so pad it with zeros to keep the stream intact
+	copied, err := io.CopyN(tarWriter, f, hdr.Size)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if copied < hdr.Size {
+		_, err = io.CopyN(tarWriter, zeroReader{}, hdr.Size-copied)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExtractOptions change how ExtractTar writes the entries
+type ExtractOptions struct {
+	// User is the owner of the written entries, if empty the owner isn't changed
+	User string
+
+	// Expected are the entries the folder had when the transfer was planned. If set, entries that changed
+	// in the meantime are skipped instead of being overwritten.
+	Expected Index
+
+	// Excludes are paths the sender isn't allowed to write, entries that match fail the extraction
+	Excludes []string
+
+	// LocalLinks fails the extraction on symlinks that point outside of the folder
+	LocalLinks bool
+}
+
+// ExtractTar writes the entries of a tar stream written by WriteTar into the folder and returns the paths that
+// were written. Files are replaced atomically.
+func ExtractTar(reader io.Reader, root string, options ExtractOptions) ([]string, error) {
+	written := []string{}
+	scanner := NewScanner(root)
+	tarReader := tar.NewReader(reader)
+	for {
+		hdr, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		} else if err != nil {
+			return written, err
+		}
+
+		relPath := path.Clean(hdr.Name)
+		if !isLocalPath(relPath) {
+			return written, fmt.Errorf("invalid path %s in archive", hdr.Name)
+		} else if IsExcluded(options.Excludes, relPath, hdr.Typeflag == tar.TypeDir) {
+			return written, fmt.Errorf("path %s is excluded from the sync", relPath)
+		} else if options.LocalLinks && hdr.Typeflag == tar.TypeSymlink && !isLocalLink(relPath, hdr.Linkname) {
+			return written, fmt.Errorf("symlink %s points outside of the synced folder", relPath)
+		}
+
+		// don't overwrite changes that happened since the transfer was planned
+		if options.Expected != nil {
+			expected, wasExpected := options.Expected[relPath]
+			current, exists := scanner.Entry(relPath)
+			if exists != wasExpected || current != expected {
+				continue
+			}
+		}
+
+		err = extractEntry(tarReader, hdr, root, relPath, options.User)
+		if err != nil {
+			return written, fmt.Errorf("extract %s %w", relPath, err)
+		}
+
+		written = append(written, relPath)
+	}
+}
+
+func extractEntry(reader io.Reader, hdr *tar.Header, root, relPath, userName string) error {
+	absPath, err := ResolvePath(root, relPath, true)
+	if err != nil {
+		return err
+	}
+
+	// replace entries of a different type
+	info, err := os.Lstat(absPath)
+	if err == nil && (info.IsDir() != (hdr.Typeflag == tar.TypeDir) || info.Mode()&os.ModeSymlink != 0) {
+		err = os.Remove(absPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		err = os.MkdirAll(absPath, hdr.FileInfo().Mode().Perm())
+	case tar.TypeSymlink:
+		err = os.Symlink(hdr.Linkname, absPath)
+	case tar.TypeReg:
+		err = writeFile(reader, absPath, hdr.FileInfo().Mode().Perm())
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if userName != "" {
+		return copypkg.Chown(absPath, userName)
+	}
+
+	return nil
+}
+
+func writeFile(reader io.Reader, absPath string, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(absPath), "."+filepath.Base(absPath)+".devpod-sync-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = io.Copy(f, reader)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(f.Name(), mode)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), absPath)
+}
+
+// Delete deletes the given paths of the folder. Directories are only deleted if they are empty, so excluded
+// or conflicting files are never lost. Paths that couldn't be deleted are returned.
+func Delete(root string, paths []string, expected Index) []string {
+	failed := []string{}
+	scanner := NewScanner(root)
+	for _, relPath := range paths {
+		absPath, err := ResolvePath(root, relPath, false)
+		if err != nil {
+			failed = append(failed, relPath)
+			continue
+		}
+		if expected != nil {
+			current, exists := scanner.Entry(relPath)
+			if !exists {
+				continue
+			} else if current != expected[relPath] {
+				failed = append(failed, relPath)
+				continue
+			}
+		}
+
+		err = os.Remove(absPath)
+		if err != nil && !os.IsNotExist(err) {
+			failed = append(failed, relPath)
+		}
+	}
+
+	return failed
+}
+
+// ResolvePath returns the absolute path of the slash separated path within the root. The parents of the path
+// are resolved without following symlinks and symlinked parents are rejected, so a symlink within the folder
+// can't redirect reads or writes outside of it. If create is true, missing parents are created.
+func ResolvePath(root, relPath string, create bool) (string, error) {
+	relPath = path.Clean(relPath)
+	if !isLocalPath(relPath) {
+		return "", fmt.Errorf("path %s is outside of the synced folder", relPath)
+	}
+
+	current := root
+	parents := strings.Split(relPath, "/")
+	for _, name := range parents[:len(parents)-1] {
+		current = filepath.Join(current, name)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return "", err
+			} else if !create {
+				// nothing below a missing parent exists
+				return filepath.Join(root, filepath.FromSlash(relPath)), nil
+			}
+
+			err = os.Mkdir(current, 0755)
+			if err != nil && !os.IsExist(err) {
+				return "", err
+			}
+			info, err = os.Lstat(current)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("parent %s of path %s is a symlink", name, relPath)
+		} else if !info.IsDir() {
+			return "", fmt.Errorf("parent %s of path %s is not a directory", name, relPath)
+		}
+	}
+
+	return filepath.Join(root, filepath.FromSlash(relPath)), nil
+}
+
+func isLocalPath(relPath string) bool {
+	return relPath != "." && !path.IsAbs(relPath) && relPath != ".." && !strings.HasPrefix(relPath, "../")
+}
+
+// isLocalLink returns true if the target of the symlink at the path is the folder or within it
+func isLocalLink(relPath, target string) bool {
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		return false
+	}
+
+	resolved := path.Join(path.Dir(relPath), filepath.ToSlash(target))
+	return resolved == "." || isLocalPath(resolved)
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,296 @@
+package filesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/types"
+	"github.com/skevetter/log"
+)
+
+const (
+	// BaselineFile holds the index of the last sync in the container
+	BaselineFile = "/var/devpod/sync-baseline.json"
+
+	// debounceInterval collects bursts of changes, e.g. a git checkout, into a single sync
+	debounceInterval = 300 * time.Millisecond
+)
+
+// Remote gives the sync engine in the container access to the local folder
+type Remote interface {
+	// Watch blocks until the local folder changed since the given generation
+	Watch(ctx context.Context, generation string) (*WatchResponse, error)
+
+	// Index returns the current index of the local folder or the index of the last upload
+	Index(ctx context.Context, request *IndexRequest) (Index, error)
+
+	// Download returns a tar stream of the given local paths
+	Download(ctx context.Context, paths []string) (io.Reader, error)
+
+	// Upload writes the entries of the tar stream into the local folder
+	Upload(ctx context.Context, reader io.Reader) error
+
+	// Delete deletes the given local paths and returns the ones that couldn't be deleted
+	Delete(ctx context.Context, paths []string) ([]string, error)
+
+	// ReportStatus saves the status on the local machine
+	ReportStatus(ctx context.Context, status *Status) error
+}
+
+// Engine keeps a folder in the container and the local folder in sync
+type Engine struct {
+	root         string
+	user         string
+	baselineFile string
+	remote       Remote
+	log          log.Logger
+
+	scanner  *Scanner
+	watcher  *Watcher
+	baseline Index
+	status   Status
+}
+
+// NewEngine creates a sync engine for the folder in the container, written files are owned by the given user
+func NewEngine(root, user string, remote Remote, log log.Logger) *Engine {
+	return &Engine{
+		root:         root,
+		user:         user,
+		baselineFile: BaselineFile,
+		remote:       remote,
+		log:          log,
+		scanner:      NewScanner(root),
+		watcher:      NewWatcher(root, log),
+		status:       Status{ContainerFolder: root},
+	}
+}
+
+// Run syncs both folders whenever one of them changes until the context is done
+func (e *Engine) Run(ctx context.Context) error {
+	err := e.loadBaseline(ctx)
+	if err != nil {
+		return fmt.Errorf("load sync baseline %w", err)
+	}
+
+	go e.watcher.Run(ctx)
+	localChanges := e.watchLocal(ctx)
+
+	paused := false
+	containerGeneration := ""
+	for {
+		if !paused {
+			containerGeneration = e.watcher.Generation()
+			e.Sync(ctx)
+		} else {
+			e.report(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case response := <-localChanges:
+			paused = response.Paused
+		case <-e.watcher.Changed(containerGeneration):
+		}
+
+		// wait for a burst of changes to settle
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(debounceInterval):
+		}
+	}
+}
+
+// watchLocal sends a response whenever the local folder changed or the sync was paused or resumed
+func (e *Engine) watchLocal(ctx context.Context) <-chan *WatchResponse {
+	changes := make(chan *WatchResponse, 1)
+	go func() {
+		last := &WatchResponse{}
+		for ctx.Err() == nil {
+			response, err := e.remote.Watch(ctx, last.Generation)
+			if err != nil {
+				if ctx.Err() == nil {
+					e.log.Debugf("Error watching local folder: %v", err)
+					time.Sleep(pollInterval)
+				}
+				continue
+			} else if *response == *last {
+				continue
+			}
+
+			last = response
+			select {
+			case <-changes:
+			default:
+			}
+			changes <- response
+		}
+	}()
+
+	return changes
+}
+
+// Sync brings both folders in sync once
+func (e *Engine) Sync(ctx context.Context) {
+	e.status.State = StateSyncing
+	e.report(ctx)
+
+	defer e.report(ctx)
+
+	err := e.sync(ctx)
+	if err != nil {
+		e.log.Debugf("Error syncing: %v", err)
+		e.status.State = StateError
+		e.status.Error = err.Error()
+		return
+	}
+
+	now := types.Now()
+	e.status.State = StateWatching
+	e.status.Error = ""
+	e.status.LastSync = &now
+}
+
+func (e *Engine) sync(ctx context.Context) error {
+	local, err := e.remote.Index(ctx, &IndexRequest{})
+	if err != nil {
+		return fmt.Errorf("index local folder %w", err)
+	}
+	container, err := e.scanner.Scan()
+	if err != nil {
+		return fmt.Errorf("index container folder %w", err)
+	}
+
+	plan, baseline := Reconcile(e.baseline, local, container)
+	e.status.Conflicts = plan.Conflicts
+	if plan.Empty() {
+		e.status.Files = len(baseline)
+		return e.saveBaseline(baseline)
+	}
+
+	// until a change is applied, the last synced state of its path stays the same
+	for _, paths := range [][]string{plan.DeleteLocal, plan.DeleteContainer, plan.ToLocal, plan.ToContainer} {
+		for _, p := range paths {
+			if entry, ok := e.baseline[p]; ok {
+				baseline[p] = entry
+			}
+		}
+	}
+	defer func() {
+		e.status.Files = len(baseline)
+		saveErr := e.saveBaseline(baseline)
+		if saveErr != nil {
+			e.log.Debugf("Error saving sync baseline: %v", saveErr)
+		}
+	}()
+
+	// deletions
+	if len(plan.DeleteLocal) > 0 {
+		failed, err := e.remote.Delete(ctx, plan.DeleteLocal)
+		if err != nil {
+			return fmt.Errorf("delete local files %w", err)
+		}
+		applyDeletes(baseline, plan.DeleteLocal, failed)
+	}
+	if len(plan.DeleteContainer) > 0 {
+		failed := Delete(e.root, plan.DeleteContainer, container)
+		applyDeletes(baseline, plan.DeleteContainer, failed)
+	}
+
+	// container to local
+	if len(plan.ToLocal) > 0 {
+		reader, writer := io.Pipe()
+		go func() {
+			_ = writer.CloseWithError(WriteTar(writer, e.root, plan.ToLocal))
+		}()
+		err = e.remote.Upload(ctx, reader)
+		_ = reader.Close()
+		if err != nil {
+			return fmt.Errorf("upload files %w", err)
+		}
+
+		// entries that changed locally in the meantime were skipped and are conflicts with the next sync
+		uploaded, err := e.remote.Index(ctx, &IndexRequest{})
+		if err != nil {
+			return fmt.Errorf("index local folder %w", err)
+		}
+		for _, p := range plan.ToLocal {
+			if entry, ok := uploaded[p]; ok && entry == container[p] {
+				baseline[p] = container[p]
+			}
+		}
+	}
+
+	// local to container
+	if len(plan.ToContainer) > 0 {
+		reader, err := e.remote.Download(ctx, plan.ToContainer)
+		if err != nil {
+			return fmt.Errorf("download files %w", err)
+		}
+		written, err := ExtractTar(reader, e.root, ExtractOptions{User: e.user, Expected: container})
+		for _, p := range written {
+			baseline[p] = local[p]
+		}
+		if err != nil {
+			return fmt.Errorf("download files %w", err)
+		}
+	}
+
+	e.log.Debugf("Synced %d files to the container, %d files to the local folder and deleted %d files", len(plan.ToContainer), len(plan.ToLocal), len(plan.DeleteContainer)+len(plan.DeleteLocal))
+	return nil
+}
+
+func applyDeletes(baseline Index, deleted []string, failed []string) {
+	failedPaths := map[string]bool{}
+	for _, p := range failed {
+		failedPaths[p] = true
+	}
+	for _, p := range deleted {
+		if !failedPaths[p] {
+			delete(baseline, p)
+		}
+	}
+}
+
+func (e *Engine) report(ctx context.Context) {
+	err := e.remote.ReportStatus(ctx, &e.status)
+	if err != nil {
+		e.log.Debugf("Error reporting sync status: %v", err)
+	}
+}
+
+// loadBaseline reads the index of the last sync. If the container was never synced, the index of the last
+// upload is used, which is the content the container folder was created with.
+func (e *Engine) loadBaseline(ctx context.Context) error {
+	out, err := os.ReadFile(e.baselineFile)
+	if err == nil {
+		e.baseline = Index{}
+		return json.Unmarshal(out, &e.baseline)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	e.baseline, err = e.remote.Index(ctx, &IndexRequest{Uploaded: true})
+	return err
+}
+
+func (e *Engine) saveBaseline(baseline Index) error {
+	e.baseline = baseline
+	out, err := json.Marshal(baseline)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(e.baselineFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(e.baselineFile, out, 0600)
+}
@@ -0,0 +1,339 @@
+package filesync
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/skevetter/log"
+)
+
+func TestReconcile(t *testing.T) {
+	a := Entry{Type: TypeFile, Hash: "a"}
+	b := Entry{Type: TypeFile, Hash: "b"}
+	c := Entry{Type: TypeFile, Hash: "c"}
+	tests := []struct {
+		name      string
+		baseline  Index
+		local     Index
+		container Index
+		want      *Plan
+	}{
+		{
+			name:      "in sync",
+			baseline:  Index{"f": a},
+			local:     Index{"f": a},
+			container: Index{"f": a},
+			want:      &Plan{},
+		},
+		{
+			name:      "changed locally",
+			baseline:  Index{"f": a},
+			local:     Index{"f": b},
+			container: Index{"f": a},
+			want:      &Plan{ToContainer: []string{"f"}},
+		},
+		{
+			name:      "created in container",
+			baseline:  Index{},
+			local:     Index{},
+			container: Index{"f": a},
+			want:      &Plan{ToLocal: []string{"f"}},
+		},
+		{
+			name:      "deleted in container",
+			baseline:  Index{"d": {Type: TypeDir}, "d/f": a},
+			local:     Index{"d": {Type: TypeDir}, "d/f": a},
+			container: Index{},
+			want:      &Plan{DeleteLocal: []string{"d/f", "d"}},
+		},
+		{
+			name:      "modification wins over deletion",
+			baseline:  Index{"f": a},
+			local:     Index{},
+			container: Index{"f": b},
+			want:      &Plan{ToLocal: []string{"f"}},
+		},
+		{
+			name:      "conflict",
+			baseline:  Index{"f": a},
+			local:     Index{"f": b},
+			container: Index{"f": c},
+			want:      &Plan{Conflicts: []string{"f"}},
+		},
+		{
+			name:      "conflict without baseline",
+			baseline:  Index{},
+			local:     Index{"f": a},
+			container: Index{"f": b},
+			want:      &Plan{Conflicts: []string{"f"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Reconcile(tt.baseline, tt.local, tt.container)
+			if !reflect.DeepEqual(normalizePlan(got), normalizePlan(tt.want)) {
+				t.Errorf("Reconcile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func normalizePlan(plan *Plan) *Plan {
+	normalize := func(paths []string) []string {
+		if len(paths) == 0 {
+			return nil
+		}
+		return paths
+	}
+
+	return &Plan{
+		ToContainer:     normalize(plan.ToContainer),
+		ToLocal:         normalize(plan.ToLocal),
+		DeleteContainer: normalize(plan.DeleteContainer),
+		DeleteLocal:     normalize(plan.DeleteLocal),
+		Conflicts:       normalize(plan.Conflicts),
+	}
+}
+
+// localRemote connects the engine directly to a local folder server
+type localRemote struct {
+	local *Local
+
+	// beforeUpload is called after the engine planned an upload, before the entries are written
+	beforeUpload func()
+}
+
+func (l *localRemote) Watch(ctx context.Context, generation string) (*WatchResponse, error) {
+	return l.local.Watch(ctx, generation), nil
+}
+
+func (l *localRemote) Index(_ context.Context, request *IndexRequest) (Index, error) {
+	return l.local.Index(request)
+}
+
+func (l *localRemote) Download(_ context.Context, paths []string) (io.Reader, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(l.local.WriteTar(writer, paths))
+	}()
+	return reader, nil
+}
+
+func (l *localRemote) Upload(_ context.Context, reader io.Reader) error {
+	if l.beforeUpload != nil {
+		l.beforeUpload()
+	}
+
+	return l.local.ExtractTar(reader)
+}
+
+func (l *localRemote) Delete(_ context.Context, paths []string) ([]string, error) {
+	return l.local.Delete(paths)
+}
+
+func (l *localRemote) ReportStatus(_ context.Context, status *Status) error {
+	return l.local.SaveStatus(status)
+}
+
+func TestEngineSync(t *testing.T) {
+	ctx := context.Background()
+	localDir, containerDir, workspaceDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(localDir, "local.txt"), "local")
+	writeTestFile(t, filepath.Join(localDir, "shared.txt"), "shared")
+	writeTestFile(t, filepath.Join(containerDir, "shared.txt"), "shared")
+	writeTestFile(t, filepath.Join(containerDir, "build", "out.txt"), "out")
+
+	engine := NewEngine(containerDir, "", &localRemote{local: NewLocal(ctx, localDir, workspaceDir, log.Discard)}, log.Discard)
+	engine.baselineFile = filepath.Join(t.TempDir(), "baseline.json")
+	err := engine.loadBaseline(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// new files are copied to the other side
+	engine.Sync(ctx)
+	expectFile(t, filepath.Join(containerDir, "local.txt"), "local")
+	expectFile(t, filepath.Join(localDir, "build", "out.txt"), "out")
+
+	// changes and deletions are applied to the other side
+	writeTestFile(t, filepath.Join(containerDir, "shared.txt"), "changed")
+	err = os.Remove(filepath.Join(localDir, "local.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.Sync(ctx)
+	expectFile(t, filepath.Join(localDir, "shared.txt"), "changed")
+	if _, err := os.Stat(filepath.Join(containerDir, "local.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected local.txt to be deleted in the container, got %v", err)
+	}
+
+	// different changes on both sides are a conflict
+	writeTestFile(t, filepath.Join(containerDir, "shared.txt"), "container")
+	writeTestFile(t, filepath.Join(localDir, "shared.txt"), "local")
+	engine.Sync(ctx)
+	if !reflect.DeepEqual(engine.status.Conflicts, []string{"shared.txt"}) {
+		t.Errorf("expected conflict for shared.txt, got %v", engine.status.Conflicts)
+	}
+	expectFile(t, filepath.Join(containerDir, "shared.txt"), "container")
+	expectFile(t, filepath.Join(localDir, "shared.txt"), "local")
+
+	status, err := LoadStatus(workspaceDir)
+	if err != nil || status == nil || status.State != StateWatching {
+		t.Errorf("expected reported status, got %+v, %v", status, err)
+	}
+}
+
+func TestExtractTarSymlinkParent(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	err := os.Symlink(outside, filepath.Join(root, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+	err = tarWriter.WriteHeader(&tar.Header{Name: "link/escaped.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tarWriter.Write([]byte("evil"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tarWriter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ExtractTar(buffer, root, ExtractOptions{})
+	if err == nil {
+		t.Errorf("expected extracting below a symlinked parent to fail")
+	}
+	_, err = os.Stat(filepath.Join(outside, "escaped.txt"))
+	if !os.IsNotExist(err) {
+		t.Errorf("expected no file outside of the folder, got %v", err)
+	}
+
+	failed := Delete(root, []string{"link/other.txt"}, nil)
+	if !reflect.DeepEqual(failed, []string{"link/other.txt"}) {
+		t.Errorf("expected delete below a symlinked parent to fail, got %v", failed)
+	}
+	err = WriteTar(io.Discard, root, []string{"link/other.txt"})
+	if err == nil {
+		t.Errorf("expected reading below a symlinked parent to fail")
+	}
+}
+
+func TestEngineSyncUploadConflict(t *testing.T) {
+	ctx := context.Background()
+	localDir, containerDir, workspaceDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(localDir, "shared.txt"), "shared")
+	writeTestFile(t, filepath.Join(containerDir, "shared.txt"), "shared")
+
+	remote := &localRemote{local: NewLocal(ctx, localDir, workspaceDir, log.Discard)}
+	engine := NewEngine(containerDir, "", remote, log.Discard)
+	engine.baselineFile = filepath.Join(t.TempDir(), "baseline.json")
+	err := engine.loadBaseline(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.Sync(ctx)
+
+	// the local file is edited after the engine planned to upload the change of the container
+	writeTestFile(t, filepath.Join(containerDir, "shared.txt"), "container")
+	remote.beforeUpload = func() {
+		writeTestFile(t, filepath.Join(localDir, "shared.txt"), "local")
+	}
+	engine.Sync(ctx)
+	expectFile(t, filepath.Join(localDir, "shared.txt"), "local")
+
+	remote.beforeUpload = nil
+	engine.Sync(ctx)
+	if !reflect.DeepEqual(engine.status.Conflicts, []string{"shared.txt"}) {
+		t.Errorf("expected conflict for shared.txt, got %v", engine.status.Conflicts)
+	}
+	expectFile(t, filepath.Join(containerDir, "shared.txt"), "container")
+	expectFile(t, filepath.Join(localDir, "shared.txt"), "local")
+}
+
+func TestLocalExtractTarRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  *tar.Header
+	}{
+		{name: "excluded path", hdr: &tar.Header{Name: ".git/hooks/pre-commit", Typeflag: tar.TypeReg, Mode: 0755}},
+		{name: "devpodignore", hdr: &tar.Header{Name: ".devpodignore", Typeflag: tar.TypeReg, Mode: 0644}},
+		{name: "absolute symlink", hdr: &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+		{name: "relative symlink outside", hdr: &tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../../secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localDir := t.TempDir()
+			writeTestFile(t, filepath.Join(localDir, ".devpodignore"), ".git\n")
+			local := NewLocal(context.Background(), localDir, t.TempDir(), log.Discard)
+			_, err := local.Index(&IndexRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buffer := &bytes.Buffer{}
+			tarWriter := tar.NewWriter(buffer)
+			err = tarWriter.WriteHeader(tt.hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tarWriter.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = local.ExtractTar(buffer)
+			if err == nil {
+				t.Errorf("expected %s to be rejected", tt.hdr.Name)
+			}
+			expectFile(t, filepath.Join(localDir, ".devpodignore"), ".git\n")
+			if _, err := os.Lstat(filepath.Join(localDir, filepath.FromSlash(tt.hdr.Name))); tt.hdr.Name != ".devpodignore" && !os.IsNotExist(err) {
+				t.Errorf("expected %s not to be written, got %v", tt.hdr.Name, err)
+			}
+		})
+	}
+
+	// symlinks within the folder are fine
+	localDir := t.TempDir()
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+	_ = tarWriter.WriteHeader(&tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../file"})
+	_ = tarWriter.Close()
+	err := NewLocal(context.Background(), localDir, t.TempDir(), log.Discard).ExtractTar(buffer)
+	if err != nil {
+		t.Errorf("expected symlink within the folder to be written, got %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectFile(t *testing.T, path, content string) {
+	t.Helper()
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(out) != content {
+		t.Errorf("expected %s to contain %q, got %q", path, content, string(out))
+	}
+}
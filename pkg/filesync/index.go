@@ -0,0 +1,202 @@
+package filesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+const (
+	TypeFile    = "file"
+	TypeDir     = "dir"
+	TypeSymlink = "symlink"
+)
+
+// Entry is a file, directory or symlink of a synced folder
+type Entry struct {
+	Type string `json:"type"`
+
+	// Hash is the sha256 of a file or the target of a symlink
+	Hash string `json:"hash,omitempty"`
+}
+
+// Index holds the entries of a synced folder by their slash separated path relative to the folder
+type Index map[string]Entry
+
+// ReadExcludes reads the .devpodignore file of the folder
+func ReadExcludes(root string) ([]string, error) {
+	f, err := os.Open(filepath.Join(root, ".devpodignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return ignorefile.ReadAll(f)
+}
+
+// IsExcluded matches the path the same way the initial upload of the folder does
+func IsExcluded(excludes []string, relativePath string, isDir bool) bool {
+	relativePath = path.Clean(relativePath)
+	if isDir {
+		relativePath += "/"
+	}
+	for _, exclude := range excludes {
+		if strings.HasPrefix(relativePath, exclude) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type cachedHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// Scanner indexes a folder and only hashes files whose size or modification time changed since the last scan
+type Scanner struct {
+	root   string
+	hashes map[string]cachedHash
+}
+
+func NewScanner(root string) *Scanner {
+	return &Scanner{
+		root:   root,
+		hashes: map[string]cachedHash{},
+	}
+}
+
+// Scan indexes the folder, the .devpodignore file is read again on every scan
+func (s *Scanner) Scan() (Index, error) {
+	excludes, err := ReadExcludes(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	index := Index{}
+	hashes := map[string]cachedHash{}
+	err = filepath.WalkDir(s.root, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// files can vanish while we walk
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		relPath, err := filepath.Rel(s.root, absPath)
+		if err != nil {
+			return err
+		} else if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+		if IsExcluded(excludes, relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			index[relPath] = Entry{Type: TypeDir}
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(absPath)
+			if err != nil {
+				return nil
+			}
+
+			index[relPath] = Entry{Type: TypeSymlink, Hash: target}
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+
+			cached, ok := s.hashes[relPath]
+			if !ok || cached.size != info.Size() || !cached.modTime.Equal(info.ModTime()) {
+				hash, err := hashFile(absPath)
+				if err != nil {
+					return nil
+				}
+
+				cached = cachedHash{size: info.Size(), modTime: info.ModTime(), hash: hash}
+			}
+
+			hashes[relPath] = cached
+			index[relPath] = Entry{Type: TypeFile, Hash: cached.hash}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.hashes = hashes
+	return index, nil
+}
+
+// Entry returns the current entry of a single path, ok is false if the path doesn't exist
+func (s *Scanner) Entry(relPath string) (Entry, bool) {
+	absPath := filepath.Join(s.root, filepath.FromSlash(relPath))
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	switch {
+	case info.IsDir():
+		return Entry{Type: TypeDir}, true
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(absPath)
+		if err != nil {
+			return Entry{}, false
+		}
+
+		return Entry{Type: TypeSymlink, Hash: target}, true
+	case info.Mode().IsRegular():
+		hash, err := hashFile(absPath)
+		if err != nil {
+			return Entry{}, false
+		}
+
+		return Entry{Type: TypeFile, Hash: hash}, true
+	}
+
+	return Entry{}, false
+}
+
+func hashFile(absPath string) (string, error) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,224 @@
+package filesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/types"
+	"github.com/skevetter/log"
+)
+
+const (
+	// UploadedIndexFile holds the index of the local folder at the last upload in the workspace folder
+	UploadedIndexFile = "sync-uploaded.json"
+
+	// watchTimeout is the longest time a watch request of the container blocks
+	watchTimeout = 10 * time.Second
+)
+
+// IndexRequest is sent by the container to retrieve the index of the local folder
+type IndexRequest struct {
+	// Uploaded requests the index of the last upload instead of the current index
+	Uploaded bool `json:"uploaded,omitempty"`
+}
+
+// WatchResponse is returned to a watch request of the container
+type WatchResponse struct {
+	Generation string `json:"generation"`
+	Paused     bool   `json:"paused,omitempty"`
+}
+
+// Local serves the local folder of a workspace to the sync engine in the container
+type Local struct {
+	root         string
+	workspaceDir string
+	log          log.Logger
+
+	scanMutex sync.Mutex
+	scanner   *Scanner
+	// scanned is the index last sent to the container, which plans its uploads with it
+	scanned Index
+
+	watchOnce sync.Once
+	watcher   *Watcher
+	ctx       context.Context
+}
+
+// NewLocal creates a new local folder server, the folder is watched for changes until the context is done
+func NewLocal(ctx context.Context, root, workspaceDir string, log log.Logger) *Local {
+	return &Local{
+		root:         root,
+		workspaceDir: workspaceDir,
+		log:          log,
+		scanner:      NewScanner(root),
+		watcher:      NewWatcher(root, log),
+		ctx:          ctx,
+	}
+}
+
+// RecordUpload saves the current index of the local folder as the content of the last upload
+func RecordUpload(root, workspaceDir string) error {
+	index, err := NewScanner(root).Scan()
+	if err != nil {
+		return err
+	}
+
+	out, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(workspaceDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(workspaceDir, UploadedIndexFile), out, 0644)
+}
+
+// Watch blocks until the local folder changed since the given generation
+func (l *Local) Watch(ctx context.Context, generation string) *WatchResponse {
+	l.watchOnce.Do(func() {
+		go l.watcher.Run(l.ctx)
+	})
+
+	return &WatchResponse{
+		Generation: l.watcher.Wait(ctx, generation, watchTimeout),
+		Paused:     IsPaused(l.workspaceDir),
+	}
+}
+
+// Index returns the current index of the local folder or the index of the last upload
+func (l *Local) Index(request *IndexRequest) (Index, error) {
+	if request.Uploaded {
+		out, err := os.ReadFile(filepath.Join(l.workspaceDir, UploadedIndexFile))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return Index{}, nil
+			}
+
+			return nil, err
+		}
+
+		index := Index{}
+		err = json.Unmarshal(out, &index)
+		if err != nil {
+			return nil, err
+		}
+
+		return index, nil
+	}
+
+	l.scanMutex.Lock()
+	defer l.scanMutex.Unlock()
+
+	index, err := l.scanner.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	l.scanned = index
+	return index, nil
+}
+
+// WriteTar writes the given paths of the local folder into a tar stream
+func (l *Local) WriteTar(writer io.Writer, paths []string) error {
+	err := l.validatePaths(paths, false)
+	if err != nil {
+		return err
+	}
+
+	return WriteTar(writer, l.root, paths)
+}
+
+// ExtractTar writes the entries sent by the container into the local folder. Entries that changed locally
+// since the container retrieved the index are skipped, the container detects them as conflicts.
+func (l *Local) ExtractTar(reader io.Reader) error {
+	excludes, err := l.writeExcludes()
+	if err != nil {
+		return err
+	}
+
+	l.scanMutex.Lock()
+	expected := l.scanned
+	l.scanMutex.Unlock()
+	if expected == nil {
+		expected = Index{}
+	}
+
+	written, err := ExtractTar(reader, l.root, ExtractOptions{
+		Expected:   expected,
+		Excludes:   excludes,
+		LocalLinks: true,
+	})
+	if len(written) > 0 {
+		l.log.Debugf("Synced %d files from the container", len(written))
+	}
+
+	return err
+}
+
+// Delete deletes the given paths of the local folder and returns the paths that couldn't be deleted
+func (l *Local) Delete(paths []string) ([]string, error) {
+	err := l.validatePaths(paths, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return Delete(l.root, paths, nil), nil
+}
+
+// SaveStatus saves the status reported by the container
+func (l *Local) SaveStatus(status *Status) error {
+	status.LocalFolder = l.root
+	now := types.Now()
+	status.Updated = &now
+	if IsPaused(l.workspaceDir) {
+		status.State = StatePaused
+	}
+
+	return SaveStatus(l.workspaceDir, status)
+}
+
+// validatePaths makes sure the container only accesses synced paths within the local folder
+func (l *Local) validatePaths(paths []string, write bool) error {
+	excludes, err := ReadExcludes(l.root)
+	if write {
+		excludes, err = l.writeExcludes()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		_, err := ResolvePath(l.root, p, false)
+		if err != nil {
+			return err
+		}
+
+		cleaned := path.Clean(p)
+		if IsExcluded(excludes, cleaned, false) || IsExcluded(excludes, cleaned, true) {
+			return fmt.Errorf("path %s is excluded from the sync", p)
+		}
+	}
+
+	return nil
+}
+
+// writeExcludes returns the paths the container can't write, which are the excluded paths and the
+// .devpodignore file, so the container can't change what is synced
+func (l *Local) writeExcludes() ([]string, error) {
+	excludes, err := ReadExcludes(l.root)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(excludes, ".devpodignore"), nil
+}
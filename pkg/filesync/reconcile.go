@@ -0,0 +1,90 @@
+package filesync
+
+import (
+	"sort"
+)
+
+// Plan holds the changes needed to bring the local folder and the container folder in sync
+type Plan struct {
+	// ToContainer are the paths to copy from the local folder into the container
+	ToContainer []string
+
+	// ToLocal are the paths to copy from the container into the local folder
+	ToLocal []string
+
+	// DeleteContainer are the paths to delete in the container
+	DeleteContainer []string
+
+	// DeleteLocal are the paths to delete in the local folder
+	DeleteLocal []string
+
+	// Conflicts are the paths that were changed differently on both sides since the last sync
+	Conflicts []string
+}
+
+// Empty returns true if there is nothing to transfer
+func (p *Plan) Empty() bool {
+	return len(p.ToContainer) == 0 && len(p.ToLocal) == 0 && len(p.DeleteContainer) == 0 && len(p.DeleteLocal) == 0
+}
+
+// Reconcile compares both sides against the index of the last sync. A change on one side is applied to the
+// other side, a modification wins over a deletion and different modifications on both sides are a conflict.
+// Paths that are equal on both sides are added to the returned baseline, which becomes the new index of the
+// last sync once the plan is applied.
+func Reconcile(baseline, local, container Index) (*Plan, Index) {
+	paths := map[string]bool{}
+	for _, index := range []Index{baseline, local, container} {
+		for p := range index {
+			paths[p] = true
+		}
+	}
+
+	plan := &Plan{}
+	newBaseline := Index{}
+	for p := range paths {
+		b, inBaseline := baseline[p]
+		l, inLocal := local[p]
+		c, inContainer := container[p]
+
+		switch {
+		case inLocal == inContainer && l == c:
+			// in sync
+			if inLocal {
+				newBaseline[p] = l
+			}
+		case inLocal == inBaseline && l == b:
+			// only changed in the container
+			if inContainer {
+				plan.ToLocal = append(plan.ToLocal, p)
+			} else {
+				plan.DeleteLocal = append(plan.DeleteLocal, p)
+			}
+		case inContainer == inBaseline && c == b:
+			// only changed locally
+			if inLocal {
+				plan.ToContainer = append(plan.ToContainer, p)
+			} else {
+				plan.DeleteContainer = append(plan.DeleteContainer, p)
+			}
+		case !inLocal:
+			// deleted locally, but modified in the container
+			plan.ToLocal = append(plan.ToLocal, p)
+		case !inContainer:
+			// deleted in the container, but modified locally
+			plan.ToContainer = append(plan.ToContainer, p)
+		default:
+			plan.Conflicts = append(plan.Conflicts, p)
+			if inBaseline {
+				newBaseline[p] = b
+			}
+		}
+	}
+
+	// parents are created before and deleted after their children
+	sort.Strings(plan.ToContainer)
+	sort.Strings(plan.ToLocal)
+	sort.Sort(sort.Reverse(sort.StringSlice(plan.DeleteContainer)))
+	sort.Sort(sort.Reverse(sort.StringSlice(plan.DeleteLocal)))
+	sort.Strings(plan.Conflicts)
+	return plan, newBaseline
+}
@@ -0,0 +1,114 @@
+package filesync
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/skevetter/devpod/pkg/types"
+)
+
+const (
+	// StatusFile holds the last reported sync status in the workspace folder
+	StatusFile = "sync.json"
+
+	// PausedFile exists in the workspace folder while the sync is paused
+	PausedFile = "sync-paused"
+)
+
+type State string
+
+const (
+	StateWatching State = "Watching"
+	StateSyncing  State = "Syncing"
+	StatePaused   State = "Paused"
+	StateError    State = "Error"
+)
+
+// Status is reported by the container after every sync
+type Status struct {
+	State State `json:"state,omitempty"`
+
+	// LocalFolder is the synced local folder
+	LocalFolder string `json:"localFolder,omitempty"`
+
+	// ContainerFolder is the synced folder in the container
+	ContainerFolder string `json:"containerFolder,omitempty"`
+
+	// Files is the number of entries that are in sync
+	Files int `json:"files,omitempty"`
+
+	// Conflicts are the paths that were changed differently on both sides, they are not synced until both
+	// sides are equal again
+	Conflicts []string `json:"conflicts,omitempty"`
+
+	// Error is the error of the last sync
+	Error string `json:"error,omitempty"`
+
+	// LastSync is the time of the last successful sync
+	LastSync *types.Time `json:"lastSync,omitempty"`
+
+	// Updated is the time the status was reported
+	Updated *types.Time `json:"updated,omitempty"`
+}
+
+// LoadStatus reads the status from the workspace folder, it returns nil if the workspace was never synced
+func LoadStatus(workspaceDir string) (*Status, error) {
+	out, err := os.ReadFile(filepath.Join(workspaceDir, StatusFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	status := &Status{}
+	err = json.Unmarshal(out, status)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// SaveStatus writes the status into the workspace folder
+func SaveStatus(workspaceDir string, status *Status) error {
+	out, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(workspaceDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(workspaceDir, StatusFile), out, 0644)
+}
+
+// IsPaused returns true if the sync of the workspace is paused
+func IsPaused(workspaceDir string) bool {
+	_, err := os.Stat(filepath.Join(workspaceDir, PausedFile))
+	return err == nil
+}
+
+// SetPaused pauses or resumes the sync of the workspace
+func SetPaused(workspaceDir string, paused bool) error {
+	pausedFile := filepath.Join(workspaceDir, PausedFile)
+	if !paused {
+		err := os.Remove(pausedFile)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	err := os.MkdirAll(workspaceDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pausedFile, nil, 0644)
+}
@@ -0,0 +1,164 @@
+package filesync
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/skevetter/log"
+)
+
+// pollInterval is used if the folder can't be watched, e.g. because the limit of watches is exceeded
+const pollInterval = 2 * time.Second
+
+// Watcher counts the changes of a folder, so syncs only have to run if the generation changed
+type Watcher struct {
+	root string
+	log  log.Logger
+
+	m          sync.Mutex
+	generation int
+	changed    chan struct{}
+}
+
+// NewWatcher creates a watcher for the folder, Run has to be called to start watching
+func NewWatcher(root string, log log.Logger) *Watcher {
+	return &Watcher{
+		root:    root,
+		log:     log,
+		changed: make(chan struct{}),
+	}
+}
+
+// Run watches the folder recursively until the context is done. If the folder can't be watched, the watcher
+// falls back to increasing the generation periodically.
+func (w *Watcher) Run(ctx context.Context) {
+	err := w.watch(ctx)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+
+	w.log.Debugf("Watching %s failed, falling back to polling: %v", w.root, err)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Notify()
+		}
+	}
+}
+
+func (w *Watcher) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = watcher.Close() }()
+
+	err = w.addRecursive(watcher, w.root)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			return err
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			// watch new directories
+			if event.Has(fsnotify.Create) {
+				stat, err := os.Lstat(event.Name)
+				if err == nil && stat.IsDir() {
+					err = w.addRecursive(watcher, event.Name)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			w.Notify()
+		}
+	}
+}
+
+func (w *Watcher) addRecursive(watcher *fsnotify.Watcher, dir string) error {
+	excludes, err := ReadExcludes(w.root)
+	if err != nil {
+		excludes = nil
+	}
+
+	return filepath.WalkDir(dir, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(w.root, absPath)
+		if err == nil && relPath != "." && IsExcluded(excludes, filepath.ToSlash(relPath), true) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(absPath)
+	})
+}
+
+// Notify increases the generation and wakes up everyone waiting for a change
+func (w *Watcher) Notify() {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.generation++
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// Generation returns the current generation of the folder
+func (w *Watcher) Generation() string {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	return strconv.Itoa(w.generation)
+}
+
+// Changed returns a channel that is closed once the generation differs from the given one
+func (w *Watcher) Changed(generation string) <-chan struct{} {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if strconv.Itoa(w.generation) != generation {
+		changed := make(chan struct{})
+		close(changed)
+		return changed
+	}
+
+	return w.changed
+}
+
+// Wait blocks until the generation differs from the given one, the context is done or the timeout is reached
+// and returns the current generation
+func (w *Watcher) Wait(ctx context.Context, generation string, timeout time.Duration) string {
+	select {
+	case <-ctx.Done():
+	case <-w.Changed(generation):
+	case <-time.After(timeout):
+	}
+
+	return w.Generation()
+}
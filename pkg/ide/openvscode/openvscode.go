@@ -2,6 +2,7 @@ package openvscode
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 
 	"github.com/sirupsen/logrus"
+	"github.com/skevetter/devpod/pkg/cache"
 	"github.com/skevetter/devpod/pkg/command"
 	"github.com/skevetter/devpod/pkg/config"
 	copy2 "github.com/skevetter/devpod/pkg/copy"
@@ -126,13 +128,13 @@ func (o *OpenVSCodeServer) Install() error {
 	vscode.InstallAPKRequirements(o.log)
 
 	// download tar
-	resp, err := devpodhttp.GetHTTPClient().Get(url)
+	release, err := openRelease(url)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = resp.Body.Close() }()
+	defer func() { _ = release.Close() }()
 
-	err = extract.Extract(resp.Body, location, extract.StripLevels(1))
+	err = extract.Extract(release, location, extract.StripLevels(1))
 	if err != nil {
 		return fmt.Errorf("extract vscode %w", err)
 	}
@@ -155,16 +157,21 @@ func (o *OpenVSCodeServer) Install() error {
 }
 
 func (o *OpenVSCodeServer) getReleaseUrl() string {
+	return ReleaseURL(o.values, runtime.GOARCH)
+}
+
+// ReleaseURL returns the download url of the openvscode server for the architecture
+func ReleaseURL(values map[string]config.OptionValue, arch string) string {
 	var url string
-	version := Options.GetValue(o.values, VersionOption)
+	version := Options.GetValue(values, VersionOption)
 
-	if runtime.GOARCH == "arm64" {
-		url = Options.GetValue(o.values, DownloadArm64Option)
+	if arch == "arm64" {
+		url = Options.GetValue(values, DownloadArm64Option)
 		if url == "" {
 			url = fmt.Sprintf(DownloadArm64Template, version, version)
 		}
 	} else {
-		url = Options.GetValue(o.values, DownloadAmd64Option)
+		url = Options.GetValue(values, DownloadAmd64Option)
 		if url == "" {
 			url = fmt.Sprintf(DownloadAmd64Template, version, version)
 		}
@@ -173,6 +180,26 @@ func (o *OpenVSCodeServer) getReleaseUrl() string {
 	return url
 }
 
+// openRelease opens the release from the cache or downloads it
+func openRelease(url string) (io.ReadCloser, error) {
+	file, err := os.Open(cache.Path(cache.IDEFolder, url))
+	if err == nil {
+		return file, nil
+	} else if cache.Offline() {
+		return nil, cache.OfflineError(url)
+	}
+
+	resp, err := devpodhttp.GetHTTPClient().Get(url)
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode >= 400 {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("received status code %d when trying to download %s", resp.StatusCode, url)
+	}
+
+	return resp.Body, nil
+}
+
 func (o *OpenVSCodeServer) installExtensions() error {
 	if len(o.extensions) == 0 {
 		return nil
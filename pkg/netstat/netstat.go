@@ -72,3 +72,8 @@ func UDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 func UDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	return osUDP6Socks(accept)
 }
+
+// UnixSocks returns the paths of all listening unix stream sockets
+func UnixSocks() ([]string, error) {
+	return osUnixSocks()
+}
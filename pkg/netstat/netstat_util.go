@@ -21,9 +21,15 @@ const (
 	pathTCP6Tab = "/proc/net/tcp6"
 	pathUDPTab  = "/proc/net/udp"
 	pathUDP6Tab = "/proc/net/udp6"
+	pathUnixTab = "/proc/net/unix"
 
 	ipv4StrLen = 8
 	ipv6StrLen = 32
+
+	// unixAcceptCon is the __SO_ACCEPTCON flag of listening unix sockets
+	unixAcceptCon = 0x10000
+	// unixStream is the SOCK_STREAM type of unix sockets
+	unixStream = 0x0001
 )
 
 // Socket states
@@ -283,3 +289,46 @@ func osUDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	return doNetstat(pathUDP6Tab, accept)
 }
+
+// parseUnixSocktab returns the paths of all listening stream unix sockets in the given /proc/net/unix table.
+// Abstract and unnamed sockets are skipped.
+func parseUnixSocktab(r io.Reader) ([]string, error) {
+	br := bufio.NewScanner(r)
+	paths := []string{}
+
+	// Discard title
+	br.Scan()
+
+	for br.Scan() {
+		fields := strings.Fields(br.Text())
+		if len(fields) < 8 || !strings.HasPrefix(fields[7], "/") {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		sockType, err := strconv.ParseUint(fields[4], 16, 16)
+		if err != nil {
+			return nil, err
+		}
+		if flags&unixAcceptCon == 0 || sockType != unixStream {
+			continue
+		}
+
+		paths = append(paths, fields[7])
+	}
+	return paths, br.Err()
+}
+
+// UnixSocks returns the paths of listening unix sockets
+func osUnixSocks() ([]string, error) {
+	f, err := os.Open(pathUnixTab)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return parseUnixSocktab(f)
+}
@@ -3,30 +3,78 @@ package netstat
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"time"
 
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/log"
 )
 
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolUnix = "unix"
+)
+
+// Port is a listener that can be forwarded. For unix sockets Port holds the path of the socket.
+type Port struct {
+	Protocol string
+	Port     string
+}
+
+func (p Port) String() string {
+	if p.Protocol == ProtocolTCP {
+		return p.Port
+	}
+
+	return p.Port + "/" + p.Protocol
+}
+
 type Forwarder interface {
-	Forward(port string) error
-	StopForward(port string) error
+	Forward(port Port) error
+	StopForward(port Port) error
+}
+
+type Option func(w *Watcher)
+
+// WithPortsAttributes restricts the forwarded protocols of a port to the protocol of its attributes
+func WithPortsAttributes(portsAttributes map[string]config.PortAttribute, otherPortsAttributes *config.PortAttribute) Option {
+	return func(w *Watcher) {
+		w.portsAttributes = portsAttributes
+		w.otherPortsAttributes = otherPortsAttributes
+	}
 }
 
-func NewWatcher(forwarder Forwarder, log log.Logger) *Watcher {
-	return &Watcher{
+// WithUnixSockets forwards listening unix sockets matching one of the given glob patterns
+func WithUnixSockets(patterns []string) Option {
+	return func(w *Watcher) {
+		w.unixSockets = patterns
+	}
+}
+
+func NewWatcher(forwarder Forwarder, log log.Logger, options ...Option) *Watcher {
+	w := &Watcher{
 		forwarder:      forwarder,
-		forwardedPorts: map[string]bool{},
+		forwardedPorts: map[Port]bool{},
 		log:            log,
 	}
+	for _, o := range options {
+		o(w)
+	}
+
+	return w
 }
 
 type Watcher struct {
 	log log.Logger
 
+	portsAttributes      map[string]config.PortAttribute
+	otherPortsAttributes *config.PortAttribute
+	unixSockets          []string
+
 	forwarder      Forwarder
-	forwardedPorts map[string]bool
+	forwardedPorts map[Port]bool
 }
 
 func (w *Watcher) Run(ctx context.Context) error {
@@ -64,9 +112,10 @@ func (w *Watcher) runOnce() error {
 	for port := range newPorts {
 		if !w.forwardedPorts[port] {
 			w.log.Debugf("Found open port %s ready to forward", port)
+			// a port that can't be forwarded is not retried until it is closed and opened again
 			err = w.forwarder.Forward(port)
 			if err != nil {
-				return fmt.Errorf("error forwarding port %s %w", port, err)
+				w.log.Errorf("Error forwarding port %s: %v", port, err)
 			}
 		}
 	}
@@ -75,7 +124,7 @@ func (w *Watcher) runOnce() error {
 	return nil
 }
 
-func (w *Watcher) findPorts() (map[string]bool, error) {
+func (w *Watcher) findPorts() (map[Port]bool, error) {
 	tcpSocks, err := TCPSocks(func(s *SockTabEntry) bool {
 		return s.State == Listen
 	})
@@ -91,15 +140,84 @@ func (w *Watcher) findPorts() (map[string]bool, error) {
 	}
 	tcpSocks = append(tcpSocks, tcp6Socks...)
 
-	// we only return ports that are within range 1024-12000 that have a program assigned
-	retSocks := map[string]bool{}
-	for _, sock := range tcpSocks {
-		if sock.LocalAddr.Port < 1024 || sock.LocalAddr.Port > 12000 || sock.LocalAddr == nil {
-			continue
+	// unconnected udp sockets are the udp equivalent of a listener
+	isUDPListener := func(s *SockTabEntry) bool {
+		return s.State == Close && (s.RemoteAddr == nil || s.RemoteAddr.Port == 0)
+	}
+	udpSocks, err := UDPSocks(isUDPListener)
+	if err != nil {
+		return nil, err
+	}
+	udp6Socks, err := UDP6Socks(isUDPListener)
+	if err != nil {
+		return nil, err
+	}
+	udpSocks = append(udpSocks, udp6Socks...)
+
+	retSocks := map[Port]bool{}
+	w.addSocks(retSocks, ProtocolTCP, tcpSocks)
+	w.addSocks(retSocks, ProtocolUDP, udpSocks)
+
+	if len(w.unixSockets) > 0 {
+		unixSocks, err := UnixSocks()
+		if err != nil {
+			return nil, err
 		}
 
-		retSocks[strconv.Itoa(int(sock.LocalAddr.Port))] = true
+		for _, path := range unixSocks {
+			if matchesAny(w.unixSockets, path) {
+				retSocks[Port{Protocol: ProtocolUnix, Port: path}] = true
+			}
+		}
 	}
 
 	return retSocks, nil
 }
+
+func (w *Watcher) addSocks(retSocks map[Port]bool, protocol string, socks []SockTabEntry) {
+	for _, sock := range socks {
+		if sock.LocalAddr == nil {
+			continue
+		}
+
+		port := int(sock.LocalAddr.Port)
+		if !w.shouldForward(protocol, port) {
+			continue
+		}
+
+		retSocks[Port{Protocol: protocol, Port: strconv.Itoa(port)}] = true
+	}
+}
+
+// shouldForward only returns true for ports that are within range 1024-12000 or have explicit port attributes.
+// Ports with onAutoForward ignore are never forwarded. If the attributes of the port specify a protocol, only
+// listeners of that protocol are forwarded.
+func (w *Watcher) shouldForward(protocol string, port int) bool {
+	attributes, explicit := config.GetPortAttributes(w.portsAttributes, w.otherPortsAttributes, port)
+	if !explicit && (port < 1024 || port > 12000) {
+		return false
+	} else if attributes.OnAutoForward == config.OnAutoForwardIgnore {
+		return false
+	}
+
+	switch attributes.Protocol {
+	case "":
+		return true
+	case ProtocolUDP:
+		return protocol == ProtocolUDP
+	default:
+		// tcp, http and https
+		return protocol == ProtocolTCP
+	}
+}
+
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		matched, err := filepath.Match(pattern, path)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
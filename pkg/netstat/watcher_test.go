@@ -0,0 +1,56 @@
+package netstat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+)
+
+func TestShouldForward(t *testing.T) {
+	w := NewWatcher(nil, nil, WithPortsAttributes(map[string]config.PortAttribute{
+		"53":        {Protocol: "udp"},
+		"3000":      {Protocol: "https"},
+		"5000-5100": {OnAutoForward: config.OnAutoForwardIgnore},
+	}, nil))
+	tests := []struct {
+		name     string
+		protocol string
+		port     int
+		want     bool
+	}{
+		{name: "tcp in range", protocol: ProtocolTCP, port: 8080, want: true},
+		{name: "udp in range", protocol: ProtocolUDP, port: 8080, want: true},
+		{name: "out of range", protocol: ProtocolTCP, port: 22, want: false},
+		{name: "explicit udp port out of range", protocol: ProtocolUDP, port: 53, want: true},
+		{name: "explicit udp port ignores tcp", protocol: ProtocolTCP, port: 53, want: false},
+		{name: "https port ignores udp", protocol: ProtocolUDP, port: 3000, want: false},
+		{name: "https port forwards tcp", protocol: ProtocolTCP, port: 3000, want: true},
+		{name: "ignored port range", protocol: ProtocolTCP, port: 5050, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.shouldForward(tt.protocol, tt.port); got != tt.want {
+				t.Errorf("shouldForward(%s, %d) = %t, want %t", tt.protocol, tt.port, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnixSocktab(t *testing.T) {
+	table := `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 20401 /run/app/api.sock
+0000000000000000: 00000002 00000000 00010000 0001 01 20402 @abstract
+0000000000000000: 00000003 00000000 00000000 0001 03 20403 /run/app/api.sock
+0000000000000000: 00000002 00000000 00000000 0002 01 20404 /run/dgram.sock
+0000000000000000: 00000003 00000000 00000000 0001 03 20405
+`
+	paths, err := parseUnixSocktab(strings.NewReader(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(paths, []string{"/run/app/api.sock"}) {
+		t.Errorf("parseUnixSocktab() = %v", paths)
+	}
+}
@@ -232,6 +232,12 @@ func ResolveAgentConfig(devConfig *config.Config, provider *provider2.ProviderCo
 	agentConfig.Docker.Install = types.StrBool(resolver.ResolveDefaultValue(string(agentConfig.Docker.Install), options))
 	agentConfig.Docker.Env = resolver.ResolveDefaultValues(agentConfig.Docker.Env, options)
 
+	// podman driver
+	agentConfig.Podman.Path = resolver.ResolveDefaultValue(agentConfig.Podman.Path, options)
+	agentConfig.Podman.Socket = resolver.ResolveDefaultValue(agentConfig.Podman.Socket, options)
+	agentConfig.Podman.Pod = types.StrBool(resolver.ResolveDefaultValue(string(agentConfig.Podman.Pod), options))
+	agentConfig.Podman.Env = resolver.ResolveDefaultValues(agentConfig.Podman.Env, options)
+
 	// kubernetes driver
 	agentConfig.Kubernetes.KubernetesContext = resolver.ResolveDefaultValue(agentConfig.Kubernetes.KubernetesContext, options)
 	agentConfig.Kubernetes.KubernetesConfig = resolver.ResolveDefaultValue(agentConfig.Kubernetes.KubernetesConfig, options)
@@ -239,6 +245,7 @@ func ResolveAgentConfig(devConfig *config.Config, provider *provider2.ProviderCo
 	agentConfig.Kubernetes.Architecture = resolver.ResolveDefaultValue(agentConfig.Kubernetes.Architecture, options)
 	agentConfig.Kubernetes.InactivityTimeout = resolver.ResolveDefaultValue(agentConfig.Kubernetes.InactivityTimeout, options)
 	agentConfig.Kubernetes.StorageClass = resolver.ResolveDefaultValue(agentConfig.Kubernetes.StorageClass, options)
+	agentConfig.Kubernetes.VolumeSnapshotClass = resolver.ResolveDefaultValue(agentConfig.Kubernetes.VolumeSnapshotClass, options)
 	agentConfig.Kubernetes.PvcAccessMode = resolver.ResolveDefaultValue(agentConfig.Kubernetes.PvcAccessMode, options)
 	agentConfig.Kubernetes.PvcAnnotations = resolver.ResolveDefaultValue(agentConfig.Kubernetes.PvcAnnotations, options)
 	agentConfig.Kubernetes.NodeSelector = resolver.ResolveDefaultValue(agentConfig.Kubernetes.NodeSelector, options)
@@ -248,12 +255,23 @@ func ResolveAgentConfig(devConfig *config.Config, provider *provider2.ProviderCo
 	agentConfig.Kubernetes.Labels = resolver.ResolveDefaultValue(agentConfig.Kubernetes.Labels, options)
 	agentConfig.Kubernetes.StrictSecurity = resolver.ResolveDefaultValue(agentConfig.Kubernetes.StrictSecurity, options)
 	agentConfig.Kubernetes.CreateNamespace = resolver.ResolveDefaultValue(agentConfig.Kubernetes.CreateNamespace, options)
+	agentConfig.Kubernetes.WorkspaceNamespace = resolver.ResolveDefaultValue(agentConfig.Kubernetes.WorkspaceNamespace, options)
+	agentConfig.Kubernetes.NamespaceResourceQuota = resolver.ResolveDefaultValue(agentConfig.Kubernetes.NamespaceResourceQuota, options)
+	agentConfig.Kubernetes.NamespaceLimitRange = resolver.ResolveDefaultValue(agentConfig.Kubernetes.NamespaceLimitRange, options)
+	agentConfig.Kubernetes.NetworkPolicyEgress = resolver.ResolveDefaultValue(agentConfig.Kubernetes.NetworkPolicyEgress, options)
 	agentConfig.Kubernetes.ClusterRole = resolver.ResolveDefaultValue(agentConfig.Kubernetes.ClusterRole, options)
 	agentConfig.Kubernetes.ServiceAccount = resolver.ResolveDefaultValue(agentConfig.Kubernetes.ServiceAccount, options)
 	agentConfig.Kubernetes.PodTimeout = resolver.ResolveDefaultValue(agentConfig.Kubernetes.PodTimeout, options)
 	agentConfig.Kubernetes.KubernetesPullSecretsEnabled = resolver.ResolveDefaultValue(agentConfig.Kubernetes.KubernetesPullSecretsEnabled, options)
 	agentConfig.Kubernetes.DiskSize = resolver.ResolveDefaultValue(agentConfig.Kubernetes.DiskSize, options)
 
+	// ssh access policy
+	agentConfig.SSH.AllowForwards = resolver.ResolveDefaultValue(agentConfig.SSH.AllowForwards, options)
+	agentConfig.SSH.DenyForwards = resolver.ResolveDefaultValue(agentConfig.SSH.DenyForwards, options)
+	agentConfig.SSH.AllowSockets = resolver.ResolveDefaultValue(agentConfig.SSH.AllowSockets, options)
+	agentConfig.SSH.DenySockets = resolver.ResolveDefaultValue(agentConfig.SSH.DenySockets, options)
+	agentConfig.SSH.RecordSessions = types.StrBool(resolver.ResolveDefaultValue(string(agentConfig.SSH.RecordSessions), options))
+
 	agentConfig.DataPath = resolver.ResolveDefaultValue(agentConfig.DataPath, options)
 	agentConfig.Path = resolver.ResolveDefaultValue(agentConfig.Path, options)
 	if agentConfig.Path == "" && agentConfig.Local == "true" {
@@ -0,0 +1,427 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/log"
+)
+
+// serviceIdleTimeout is the time in seconds a podman api service started by DevPod keeps running without requests
+const serviceIdleTimeout = 300
+
+// Client talks to the REST API of podman over its unix socket
+type Client struct {
+	Socket string
+
+	http *http.Client
+}
+
+// APIError is an error response of the podman API
+type APIError struct {
+	StatusCode int
+	Message    string `json:"message"`
+	Cause      string `json:"cause"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("podman api returned status %d", e.StatusCode)
+}
+
+// IsNotFound returns true if the error is a not found response of the podman API
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// NewClient connects to the podman API. If socket is empty, the socket of CONTAINER_HOST, the
+// rootless socket of the current user and the rootful socket are tried. If none of them is
+// reachable, a temporary API service is started with the podman command.
+func NewClient(ctx context.Context, podmanCommand, socket string, env []string, log log.Logger) (*Client, error) {
+	candidates := []string{}
+	if socket != "" {
+		candidates = append(candidates, socket)
+	} else {
+		candidates = append(candidates, defaultSockets()...)
+	}
+
+	for _, candidate := range candidates {
+		client := newClient(candidate)
+		err := client.Ping(ctx)
+		if err == nil {
+			log.Debugf("Using podman api socket %s", client.Socket)
+			return client, nil
+		}
+
+		log.Debugf("Podman api socket %s not reachable: %v", client.Socket, err)
+	}
+	if socket != "" {
+		return nil, fmt.Errorf("podman api socket %s not reachable", socket)
+	}
+
+	return startService(ctx, podmanCommand, env, log)
+}
+
+func newClient(socket string) *Client {
+	socket = strings.TrimPrefix(socket, "unix://")
+	return &Client{
+		Socket: socket,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+func defaultSockets() []string {
+	sockets := []string{}
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		sockets = append(sockets, host)
+	}
+	if os.Getuid() != 0 {
+		runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+		if runtimeDir == "" {
+			runtimeDir = filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+		}
+		sockets = append(sockets, filepath.Join(runtimeDir, "podman", "podman.sock"))
+	} else {
+		sockets = append(sockets, "/run/podman/podman.sock")
+	}
+
+	// only use a running api service of DevPod if the socket belongs to the user
+	socket, err := serviceSocket()
+	if err == nil {
+		info, err := os.Lstat(socket)
+		if err == nil && info.Mode()&os.ModeSocket != 0 && checkOwner(info) == nil {
+			sockets = append(sockets, socket)
+		}
+	}
+
+	return sockets
+}
+
+// serviceSocket returns the socket of the api service DevPod starts if no other socket is reachable. The
+// socket is in a folder only the user can access, either in $XDG_RUNTIME_DIR or the temp dir.
+func serviceSocket() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("devpod-podman-%d", os.Getuid()))
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dir = filepath.Join(runtimeDir, "devpod-podman")
+	}
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	// the folder might have been created by someone else before
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder", dir)
+	}
+	err = checkOwner(info)
+	if err != nil {
+		return "", err
+	} else if info.Mode().Perm() != 0700 {
+		err = os.Chmod(dir, 0700)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return filepath.Join(dir, "podman.sock"), nil
+}
+
+func startService(ctx context.Context, podmanCommand string, env []string, log log.Logger) (*Client, error) {
+	socket, err := serviceSocket()
+	if err != nil {
+		return nil, fmt.Errorf("podman api service socket %w", err)
+	}
+	_ = os.Remove(socket)
+
+	log.Debugf("Starting podman api service on %s", socket)
+	cmd := exec.Command(podmanCommand, "system", "service", "--time", strconv.Itoa(serviceIdleTimeout), "unix://"+socket)
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("start podman api service %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+
+	client := newClient(socket)
+	for range 50 {
+		err = client.Ping(ctx)
+		if err == nil {
+			return client, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return nil, fmt.Errorf("podman api service not reachable %w", err)
+}
+
+// Ping checks that the API is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return c.do(ctx, http.MethodGet, "/libpod/_ping", nil)
+}
+
+// Info holds the parts of the podman info DevPod uses
+type Info struct {
+	Host struct {
+		CPUs     int   `json:"cpus"`
+		MemTotal int64 `json:"memTotal"`
+		Security struct {
+			Rootless bool `json:"rootless"`
+		} `json:"security"`
+	} `json:"host"`
+	Version struct {
+		Version string `json:"Version"`
+	} `json:"version"`
+}
+
+// Info returns information about the podman host
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	info := &Info{}
+	err := c.do(ctx, http.MethodGet, "/libpod/info", info)
+	if err != nil {
+		return nil, fmt.Errorf("podman info %w", err)
+	}
+
+	return info, nil
+}
+
+// FindContainers returns the ids of all containers with the given labels
+func (c *Client) FindContainers(ctx context.Context, labels []string) ([]string, error) {
+	filters, err := json.Marshal(map[string][]string{"label": labels})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []struct {
+		ID string `json:"Id"`
+	}{}
+	err = c.do(ctx, http.MethodGet, "/containers/json?all=true&filters="+url.QueryEscape(string(filters)), &containers)
+	if err != nil {
+		return nil, fmt.Errorf("list containers %w", err)
+	}
+
+	ids := []string{}
+	for _, container := range containers {
+		ids = append(ids, container.ID)
+	}
+
+	return ids, nil
+}
+
+// InspectContainer returns the docker compatible details of the container or nil if it doesn't exist
+func (c *Client) InspectContainer(ctx context.Context, id string) (*config.ContainerDetails, error) {
+	details := &config.ContainerDetails{}
+	err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", details)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("inspect container %w", err)
+	}
+
+	details.State.Status = strings.ToLower(details.State.Status)
+	return details, nil
+}
+
+// StartContainer starts the container, a running container is left untouched
+func (c *Client) StartContainer(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodPost, "/libpod/containers/"+url.PathEscape(id)+"/start", nil)
+	if err != nil {
+		return fmt.Errorf("start container %w", err)
+	}
+
+	return nil
+}
+
+// StopContainer stops the container
+func (c *Client) StopContainer(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodPost, "/libpod/containers/"+url.PathEscape(id)+"/stop", nil)
+	if err != nil {
+		return fmt.Errorf("stop container %w", err)
+	}
+
+	return nil
+}
+
+// RemoveContainer removes the container, anonymous volumes are kept like with docker rm
+func (c *Client) RemoveContainer(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, "/libpod/containers/"+url.PathEscape(id)+"?force=true", nil)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("remove container %w", err)
+	}
+
+	return nil
+}
+
+// ContainerLogs writes the logs of the container to stdout and stderr
+func (c *Client) ContainerLogs(ctx context.Context, id string, stdout io.Writer, stderr io.Writer) error {
+	container := struct {
+		Config struct {
+			Tty bool `json:"Tty"`
+		} `json:"Config"`
+	}{}
+	err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", &container)
+	if err != nil {
+		return fmt.Errorf("inspect container %w", err)
+	}
+
+	body, err := c.stream(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs?stdout=true&stderr=true")
+	if err != nil {
+		return fmt.Errorf("container logs %w", err)
+	}
+	defer func() { _ = body.Close() }()
+
+	// the output of containers with tty is not multiplexed
+	if container.Config.Tty {
+		_, err = io.Copy(stdout, body)
+		return err
+	}
+
+	return demultiplex(body, stdout, stderr)
+}
+
+// PodExists returns true if the pod exists
+func (c *Client) PodExists(ctx context.Context, name string) (bool, error) {
+	err := c.do(ctx, http.MethodGet, "/libpod/pods/"+url.PathEscape(name)+"/exists", nil)
+	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("find pod %w", err)
+	}
+
+	return true, nil
+}
+
+// GenerateKube returns the kubernetes yaml of the given containers or pods, like podman generate kube
+func (c *Client) GenerateKube(ctx context.Context, names []string) ([]byte, error) {
+	query := url.Values{}
+	for _, name := range names {
+		query.Add("names", name)
+	}
+
+	body, err := c.stream(ctx, http.MethodGet, "/libpod/generate/kube?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("generate kube %w", err)
+	}
+	defer func() { _ = body.Close() }()
+
+	return io.ReadAll(body)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, obj any) error {
+	response, err := c.request(ctx, method, path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if obj == nil {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return nil
+	}
+
+	err = json.NewDecoder(response.Body).Decode(obj)
+	if err != nil {
+		return fmt.Errorf("parse podman api response %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) stream(ctx context.Context, method, path string) (io.ReadCloser, error) {
+	response, err := c.request(ctx, method, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (c *Client) request(ctx context.Context, method, path string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, "http://d"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// 304 is returned if a container is already started or stopped
+	if response.StatusCode >= 400 {
+		defer func() { _ = response.Body.Close() }()
+
+		apiErr := &APIError{}
+		_ = json.NewDecoder(response.Body).Decode(apiErr)
+		apiErr.StatusCode = response.StatusCode
+		return nil, apiErr
+	}
+
+	return response, nil
+}
+
+// demultiplex splits the docker stream format of the logs of containers without tty into stdout and stderr
+func demultiplex(reader io.Reader, stdout io.Writer, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		writer := stdout
+		if header[0] == 2 {
+			writer = stderr
+		}
+
+		size := int64(header[4])<<24 | int64(header[5])<<16 | int64(header[6])<<8 | int64(header[7])
+		_, err = io.CopyN(writer, reader, size)
+		if err != nil {
+			return err
+		}
+	}
+}
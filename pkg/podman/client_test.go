@@ -0,0 +1,150 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/skevetter/log"
+)
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /libpod/_ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		filters := map[string][]string{}
+		_ = json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		if len(filters["label"]) != 1 || filters["label"][0] != "dev.containers.id=test" {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"Id":"abc"}]`))
+	})
+	mux.HandleFunc("GET /containers/abc/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Id":"abc","State":{"Status":"running"},"Config":{"Tty":false,"Labels":{"a":"b"}}}`))
+	})
+	mux.HandleFunc("GET /containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"cause":"no such container","message":"no container with name or ID \"missing\" found","response":404}`))
+	})
+	mux.HandleFunc("GET /containers/abc/logs", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 4})
+		_, _ = w.Write([]byte("out\n"))
+		_, _ = w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 4})
+		_, _ = w.Write([]byte("err\n"))
+	})
+	mux.HandleFunc("POST /libpod/containers/abc/start", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	})
+	mux.HandleFunc("GET /libpod/generate/kube", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("kind: Pod\nname: " + r.URL.Query().Get("names") + "\n"))
+	})
+
+	socket := filepath.Join(t.TempDir(), "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(mux)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, "podman", "unix://"+socket, nil, log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := client.FindContainers(ctx, []string{"dev.containers.id=test"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || ids[0] != "abc" {
+		t.Fatalf("unexpected containers %v", ids)
+	}
+
+	details, err := client.InspectContainer(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	} else if details.ID != "abc" || details.State.Status != "running" || details.Config.Labels["a"] != "b" {
+		t.Fatalf("unexpected details %+v", details)
+	}
+
+	details, err = client.InspectContainer(ctx, "missing")
+	if err != nil || details != nil {
+		t.Fatalf("expected missing container, got %+v %v", details, err)
+	}
+
+	err = client.StartContainer(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = client.ContainerLogs(ctx, "abc", stdout, stderr)
+	if err != nil {
+		t.Fatal(err)
+	} else if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Fatalf("unexpected logs %q %q", stdout.String(), stderr.String())
+	}
+
+	kube, err := client.GenerateKube(ctx, []string{"pod_test"})
+	if err != nil {
+		t.Fatal(err)
+	} else if string(kube) != "kind: Pod\nname: pod_test\n" {
+		t.Fatalf("unexpected kube yaml %q", kube)
+	}
+}
+
+func TestServiceSocket(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	// a folder with wrong permissions is made private
+	dir := filepath.Join(runtimeDir, "devpod-podman")
+	err := os.Mkdir(dir, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chmod(dir, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket, err := serviceSocket()
+	if err != nil {
+		t.Fatal(err)
+	} else if socket != filepath.Join(dir, "podman.sock") {
+		t.Fatalf("unexpected socket %s", socket)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+		t.Fatalf("expected a private folder, got %s", info.Mode().Perm())
+	}
+
+	// a file in place of the folder is rejected
+	err = os.RemoveAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(dir, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serviceSocket()
+	if err == nil {
+		t.Fatal("expected an error for a file in place of the socket folder")
+	}
+}
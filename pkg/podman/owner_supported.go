@@ -0,0 +1,21 @@
+//go:build !windows
+
+package podman
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner returns an error if the file isn't owned by the current user
+func checkOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unable to determine the owner of %s", info.Name())
+	} else if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d instead of the current user", info.Name(), stat.Uid)
+	}
+
+	return nil
+}
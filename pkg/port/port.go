@@ -41,3 +41,25 @@ func IsAvailable(addr string) (bool, error) {
 	_ = conn.Close()
 	return false, nil
 }
+
+// FindAvailableUDPPort returns the first udp port starting at start that can be bound on localhost
+func FindAvailableUDPPort(start int) (int, error) {
+	for i := start; i < start+1000; i++ {
+		if IsUDPAvailable("localhost:" + strconv.Itoa(i)) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("couldn't find an available port")
+}
+
+// IsUDPAvailable returns true if the given udp address can be bound
+func IsUDPAvailable(addr string) bool {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return false
+	}
+
+	_ = conn.Close()
+	return true
+}
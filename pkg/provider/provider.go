@@ -113,17 +113,42 @@ type ProviderAgentConfig struct {
 	Dockerless ProviderDockerlessOptions `json:"dockerless"`
 
 	// Driver is the driver to use for deploying the devcontainer. Currently supports
-	// docker (default) or kubernetes (experimental)
+	// docker (default), podman or kubernetes (experimental)
 	Driver string `json:"driver,omitempty"`
 
 	// Docker holds docker specific configuration
 	Docker ProviderDockerDriverConfig `json:"docker"`
 
+	// Podman holds podman specific configuration
+	Podman ProviderPodmanDriverConfig `json:"podman"`
+
 	// Custom holds custom driver specific configuration
 	Custom ProviderCustomDriverConfig `json:"custom"`
 
 	// Kubernetes holds kubernetes specific configuration
 	Kubernetes ProviderKubernetesDriverConfig `json:"kubernetes"`
+
+	// SSH holds the access policy of the ssh server in the workspace container
+	SSH ProviderSSHConfig `json:"ssh"`
+}
+
+type ProviderSSHConfig struct {
+	// AllowForwards is a comma separated list of host:port patterns that may be forwarded, if set
+	// everything else is denied
+	AllowForwards string `json:"allowForwards,omitempty"`
+
+	// DenyForwards is a comma separated list of host:port patterns that may not be forwarded
+	DenyForwards string `json:"denyForwards,omitempty"`
+
+	// AllowSockets is a comma separated list of unix socket globs that may be forwarded, if set
+	// everything else is denied
+	AllowSockets string `json:"allowSockets,omitempty"`
+
+	// DenySockets is a comma separated list of unix socket globs that may not be forwarded
+	DenySockets string `json:"denySockets,omitempty"`
+
+	// RecordSessions records the output of interactive ssh sessions in the container
+	RecordSessions types.StrBool `json:"recordSessions,omitempty"`
 }
 
 type ProviderDockerlessOptions struct {
@@ -149,6 +174,7 @@ func (a ProviderAgentConfig) IsDockerDriver() bool {
 
 const (
 	DockerDriver     = "docker"
+	PodmanDriver     = "podman"
 	KubernetesDriver = "kubernetes"
 	CustomDriver     = "custom"
 )
@@ -196,6 +222,21 @@ type ProviderDockerDriverConfig struct {
 	Env map[string]string `json:"env,omitempty"`
 }
 
+type ProviderPodmanDriverConfig struct {
+	// Path where to find the podman binary, defaults to 'podman'
+	Path string `json:"path,omitempty"`
+
+	// Socket is the podman API socket, by default the socket of CONTAINER_HOST, the rootless socket
+	// of the user or the rootful socket is used. If none is reachable, DevPod starts the API service.
+	Socket string `json:"socket,omitempty"`
+
+	// Pod groups the services of docker compose devcontainers into a podman pod
+	Pod types.StrBool `json:"pod,omitempty"`
+
+	// Environment variables to set when running podman commands
+	Env map[string]string `json:"env,omitempty"`
+}
+
 type ProviderKubernetesDriverConfig struct {
 	KubernetesContext   string `json:"kubernetesContext,omitempty"`
 	KubernetesConfig    string `json:"kubernetesConfig,omitempty"`
@@ -204,6 +245,13 @@ type ProviderKubernetesDriverConfig struct {
 
 	KubernetesPullSecretsEnabled string `json:"kubernetesPullSecretsEnabled,omitempty"`
 	CreateNamespace              string `json:"createNamespace,omitempty"`
+
+	// WorkspaceNamespace runs each workspace in a dedicated namespace
+	WorkspaceNamespace     string `json:"workspaceNamespace,omitempty"`
+	NamespaceResourceQuota string `json:"namespaceResourceQuota,omitempty"`
+	NamespaceLimitRange    string `json:"namespaceLimitRange,omitempty"`
+	NetworkPolicyEgress    string `json:"networkPolicyEgress,omitempty"`
+
 	ClusterRole                  string `json:"clusterRole,omitempty"`
 	ServiceAccount               string `json:"serviceAccount,omitempty"`
 
@@ -211,6 +259,9 @@ type ProviderKubernetesDriverConfig struct {
 	InactivityTimeout string `json:"inactivityTimeout,omitempty"`
 	StorageClass      string `json:"storageClass,omitempty"`
 
+	// VolumeSnapshotClass enables snapshots of the workspace volume when the workspace is stopped
+	VolumeSnapshotClass string `json:"volumeSnapshotClass,omitempty"`
+
 	DiskSize             string `json:"diskSize,omitempty"`
 	PvcAccessMode        string `json:"pvcAccessMode,omitempty"`
 	PvcAnnotations       string `json:"pvcAnnotations,omitempty"`
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/types"
+)
+
+const WorkspaceSnapshotsFile = "snapshots.json"
+
+var snapshotNameRegEx = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Snapshot is a checkpoint of a workspace that can be restored later on
+type Snapshot struct {
+	// Name is the unique name of the snapshot within the workspace
+	Name string `json:"name"`
+
+	// Image is the image the devcontainer was committed to
+	Image string `json:"image"`
+
+	// Volumes are the named volumes that were archived with the snapshot
+	Volumes []string `json:"volumes,omitempty"`
+
+	// Content is true if the workspace content folder was archived with the snapshot
+	Content bool `json:"content,omitempty"`
+
+	// CreationTimestamp is the time the snapshot was created
+	CreationTimestamp types.Time `json:"creationTimestamp"`
+}
+
+// ValidateSnapshotName checks that the name can be used as part of an image tag and a folder name
+func ValidateSnapshotName(name string) error {
+	if !snapshotNameRegEx.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name '%s', only lower case letters, digits, '_', '.' and '-' are allowed and it must start with a letter or digit", name)
+	}
+
+	return nil
+}
+
+// SnapshotImageName returns the image a workspace snapshot is committed to
+func SnapshotImageName(workspaceUID, name string) string {
+	return "devpod-snapshot-" + strings.ToLower(workspaceUID) + ":" + name
+}
+
+func LoadWorkspaceSnapshots(context, workspaceID string) ([]*Snapshot, error) {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotsBytes, err := os.ReadFile(filepath.Join(workspaceDir, WorkspaceSnapshotsFile))
+	if os.IsNotExist(err) {
+		return []*Snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*Snapshot{}
+	err = json.Unmarshal(snapshotsBytes, &snapshots)
+	if err != nil {
+		return nil, fmt.Errorf("parse snapshots %w", err)
+	}
+
+	return snapshots, nil
+}
+
+func SaveWorkspaceSnapshots(context, workspaceID string, snapshots []*Snapshot) error {
+	workspaceDir, err := GetWorkspaceDir(context, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(workspaceDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	snapshotsBytes, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(workspaceDir, WorkspaceSnapshotsFile), snapshotsBytes, 0644)
+}
@@ -0,0 +1,27 @@
+package provider
+
+import "testing"
+
+func TestValidateSnapshotName(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "simple", value: "before-upgrade"},
+		{name: "dots and underscores", value: "go1.22_rc"},
+		{name: "empty", value: "", wantErr: true},
+		{name: "upper case", value: "Before", wantErr: true},
+		{name: "leading dash", value: "-snapshot", wantErr: true},
+		{name: "path separator", value: "a/b", wantErr: true},
+		{name: "too long", value: "a1234567890123456789012345678901234567890123456789012345678901234", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSnapshotName(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSnapshotName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
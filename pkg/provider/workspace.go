@@ -73,6 +73,9 @@ type Workspace struct {
 
 	// Path to an alternate file where DevPod entries are written (for read-only SSH configs)
 	SSHConfigIncludePath string `json:"sshConfigIncludePath,omitempty"`
+
+	// Sync signals that the local folder is continuously synced with the workspace
+	Sync bool `json:"sync,omitempty"`
 }
 
 type ProMetadata struct {
@@ -231,6 +234,11 @@ type CLIOptions struct {
 	Userns                      string            `json:"userns,omitempty"`
 	UidMap                      []string          `json:"uidMap,omitempty"`
 	GidMap                      []string          `json:"gidMap,omitempty"`
+	StrictHostRequirements      bool              `json:"strictHostRequirements,omitempty"`
+	Sync                        bool              `json:"sync,omitempty"`
+
+	// Offline makes the agent use its cache only
+	Offline bool `json:"offline,omitempty"`
 
 	// build options
 	Repository string   `json:"repository,omitempty"`
@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/shell"
+)
+
+// envBackend reads secrets from the environment of the DevPod process
+type envBackend struct{}
+
+func (e *envBackend) Get(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+// fileBackend reads secrets from files in a directory, the file name is the secret name
+type fileBackend struct {
+	dir string
+}
+
+func (f *fileBackend) Get(_ context.Context, name string) (string, error) {
+	path := filepath.Join(f.dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, filepath.Clean(f.dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("secret name %s is outside of %s", name, f.dir)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// passBackend reads secrets from the standard unix password manager, only the first line of an entry is used
+type passBackend struct{}
+
+func (p *passBackend) Get(ctx context.Context, name string) (string, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "pass", "show", name)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pass show: %s %w", strings.TrimSpace(stderr.String()), err)
+	}
+
+	line, _, _ := strings.Cut(string(out), "\n")
+	return line, nil
+}
+
+// commandBackend runs a user defined command that prints the secret with the name DEVPOD_SECRET_NAME
+type commandBackend struct {
+	command string
+}
+
+func (c *commandBackend) Get(ctx context.Context, name string) (string, error) {
+	if c.command == "" {
+		return "", fmt.Errorf("no secrets command configured, please set it via devpod context set-options -o SECRETS_COMMAND=...")
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	env := append(os.Environ(), "DEVPOD_SECRET_NAME="+name)
+	err := shell.RunEmulatedShell(ctx, c.command, nil, stdout, stderr, env)
+	if err != nil {
+		return "", fmt.Errorf("exec command: %s %w", strings.TrimSpace(stderr.String()), err)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
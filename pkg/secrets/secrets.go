@@ -0,0 +1,241 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/skevetter/devpod/pkg/config"
+	devcontainerconfig "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+)
+
+// Scheme is the prefix of values that reference a secret, e.g. secret://github-token or secret://pass:work/db
+const Scheme = "secret://"
+
+const (
+	BackendEnv     = "env"
+	BackendFile    = "file"
+	BackendPass    = "pass"
+	BackendCommand = "command"
+)
+
+// Backend resolves secrets by name from a local secret store
+type Backend interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// IsReference returns true if the value is a secret reference
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// ParseReference returns the backend and the name of a secret reference. The backend is empty if the
+// reference doesn't name one, in which case the default backend is used.
+func ParseReference(reference string) (string, string, error) {
+	if !IsReference(reference) {
+		return "", "", fmt.Errorf("%s is not a secret reference", reference)
+	}
+
+	name := strings.TrimPrefix(reference, Scheme)
+	backend, rest, found := strings.Cut(name, ":")
+	if found && isBackend(backend) {
+		name = rest
+	} else {
+		backend = ""
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("secret reference %s has no name", reference)
+	}
+
+	return backend, name, nil
+}
+
+// References returns the variables of the given environments that reference a secret. Later environments
+// override earlier ones.
+func References(env []string, envMaps ...map[string]string) map[string]string {
+	references := map[string]string{}
+	for _, value := range env {
+		key, val, found := strings.Cut(value, "=")
+		if found && IsReference(val) {
+			references[key] = val
+		}
+	}
+	for _, envMap := range envMaps {
+		for key, val := range envMap {
+			if IsReference(val) {
+				references[key] = val
+			}
+		}
+	}
+
+	return references
+}
+
+// WorkspaceReferences returns the secret references of the workspace the client resolves. References of the
+// given workspace env are declared by the user. References of the devcontainer.json and of the result of the
+// last devpod up come from the repository or the container, so they are only resolved if the user allowed the
+// secret via the SECRETS_ALLOWED context option.
+func WorkspaceReferences(workspace *provider.Workspace, workspaceEnv []string, resolver *Resolver, log log.Logger) map[string]string {
+	envMaps := []map[string]string{}
+	result, err := provider.LoadWorkspaceResult(workspace.Context, workspace.ID)
+	if err == nil && result != nil && result.MergedConfig != nil {
+		envMaps = append(envMaps, result.MergedConfig.RemoteEnv)
+	}
+	if workspace.Source.LocalFolder != "" {
+		devContainerConfig, err := devcontainerconfig.ParseDevContainerJSON(workspace.Source.LocalFolder, workspace.DevContainerPath)
+		if err == nil && devContainerConfig != nil {
+			envMaps = append(envMaps, devContainerConfig.RemoteEnv)
+		}
+	}
+	if workspace.DevContainerConfig != nil {
+		envMaps = append(envMaps, workspace.DevContainerConfig.RemoteEnv)
+	}
+
+	references := References(nil, envMaps...)
+	for _, key := range slices.Sorted(maps.Keys(references)) {
+		if !resolver.Allowed(references[key]) {
+			log.Warnf("Not resolving %s=%s, allow the secret via devpod context set-options -o %s=...", key, references[key], config.ContextOptionSecretsAllowed)
+			delete(references, key)
+		}
+	}
+
+	maps.Copy(references, References(workspaceEnv))
+	return references
+}
+
+// WithoutReferences returns the environment without the variables that reference a secret
+func WithoutReferences(env map[string]string) map[string]string {
+	retEnv := maps.Clone(env)
+	maps.DeleteFunc(retEnv, func(_, value string) bool {
+		return IsReference(value)
+	})
+
+	return retEnv
+}
+
+// Resolver resolves secret references on the client with the configured backends
+type Resolver struct {
+	defaultBackend string
+	backends       map[string]Backend
+
+	// allowed are the secrets the devcontainer.json may reference as backend:name
+	allowed map[string]bool
+}
+
+// NewResolver creates a resolver with the backends configured in the context options
+func NewResolver(devPodConfig *config.Config) (*Resolver, error) {
+	secretsDir := devPodConfig.ContextOption(config.ContextOptionSecretsDir)
+	if secretsDir == "" {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return nil, err
+		}
+
+		secretsDir = filepath.Join(configDir, "secrets")
+	}
+
+	resolver := &Resolver{
+		defaultBackend: devPodConfig.ContextOption(config.ContextOptionSecretsBackend),
+		allowed:        map[string]bool{},
+		backends: map[string]Backend{
+			BackendEnv:     &envBackend{},
+			BackendFile:    &fileBackend{dir: secretsDir},
+			BackendPass:    &passBackend{},
+			BackendCommand: &commandBackend{command: devPodConfig.ContextOption(config.ContextOptionSecretsCommand)},
+		},
+	}
+	for secret := range strings.SplitSeq(devPodConfig.ContextOption(config.ContextOptionSecretsAllowed), ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+
+		key, err := resolver.key(Scheme + strings.TrimPrefix(secret, Scheme))
+		if err != nil {
+			return nil, fmt.Errorf("parse allowed secret %s %w", secret, err)
+		}
+		resolver.allowed[key] = true
+	}
+
+	return resolver, nil
+}
+
+// Allowed returns true if the user allowed the secret of the reference via the SECRETS_ALLOWED context option
+func (r *Resolver) Allowed(reference string) bool {
+	key, err := r.key(reference)
+	if err != nil {
+		return false
+	}
+
+	return r.allowed[key]
+}
+
+// key returns the backend and name of the reference, references without backend use the default backend
+func (r *Resolver) key(reference string) (string, error) {
+	backendName, name, err := ParseReference(reference)
+	if err != nil {
+		return "", err
+	} else if backendName == "" {
+		backendName = r.defaultBackend
+	}
+
+	return backendName + ":" + name, nil
+}
+
+// Resolve returns the value of a single secret reference
+func (r *Resolver) Resolve(ctx context.Context, reference string) (string, error) {
+	backendName, name, err := ParseReference(reference)
+	if err != nil {
+		return "", err
+	} else if backendName == "" {
+		backendName = r.defaultBackend
+	}
+
+	backend, ok := r.backends[backendName]
+	if !ok {
+		return "", fmt.Errorf("unknown secrets backend %s", backendName)
+	}
+
+	value, err := backend.Get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %s from %s %w", name, backendName, err)
+	}
+
+	return value, nil
+}
+
+// ResolveAll resolves the given variable to secret reference map into a variable to value map
+func (r *Resolver) ResolveAll(ctx context.Context, references map[string]string) (map[string]string, error) {
+	keys := make([]string, 0, len(references))
+	for key := range references {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := map[string]string{}
+	for _, key := range keys {
+		value, err := r.Resolve(ctx, references[key])
+		if err != nil {
+			return nil, err
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+func isBackend(name string) bool {
+	switch name {
+	case BackendEnv, BackendFile, BackendPass, BackendCommand:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/config"
+	devcontainerconfig "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/log"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		reference   string
+		wantBackend string
+		wantName    string
+		wantErr     bool
+	}{
+		{reference: "secret://github-token", wantName: "github-token"},
+		{reference: "secret://pass:work/db", wantBackend: "pass", wantName: "work/db"},
+		{reference: "secret://vault:token", wantName: "vault:token"},
+		{reference: "secret://env:", wantErr: true},
+		{reference: "plain", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			backend, name, err := ParseReference(tt.reference)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if backend != tt.wantBackend || name != tt.wantName {
+				t.Errorf("ParseReference() = %q, %q, want %q, %q", backend, name, tt.wantBackend, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	got := References([]string{"A=secret://a", "B=plain", "C=secret://c"}, map[string]string{"C": "secret://pass:c", "D": "plain"})
+	want := map[string]string{"A": "secret://a", "C": "secret://pass:c"}
+	if !maps.Equal(got, want) {
+		t.Errorf("References() = %v, want %v", got, want)
+	}
+}
+
+func TestWorkspaceReferences(t *testing.T) {
+	t.Setenv(config.DEVPOD_HOME, t.TempDir())
+	folder := t.TempDir()
+	err := os.MkdirAll(filepath.Join(folder, ".devcontainer"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(folder, ".devcontainer", "devcontainer.json"), []byte(`{"image": "alpine", "remoteEnv": {"TOKEN": "secret://token", "AWS": "secret://AWS_SECRET_ACCESS_KEY", "PLAIN": "value"}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the result is returned by the container
+	workspace := &provider.Workspace{ID: "test", Context: "default", Source: provider.WorkspaceSource{LocalFolder: folder}}
+	err = provider.SaveWorkspaceResult(workspace, &devcontainerconfig.Result{
+		MergedConfig: &devcontainerconfig.MergedDevContainerConfig{
+			DevContainerConfigBase: devcontainerconfig.DevContainerConfigBase{
+				RemoteEnv: map[string]string{"KEY": "secret://command:ssh-key"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := NewResolver(&config.Config{
+		DefaultContext: "default",
+		Contexts: map[string]*config.ContextConfig{"default": {Options: map[string]config.OptionValue{
+			config.ContextOptionSecretsAllowed: {Value: "token, env:other"},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := WorkspaceReferences(workspace, []string{"DB=secret://pass:db"}, resolver, log.Discard)
+	want := map[string]string{"TOKEN": "secret://token", "DB": "secret://pass:db"}
+	if !maps.Equal(got, want) {
+		t.Errorf("WorkspaceReferences() = %v, want %v", got, want)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	resolver := &Resolver{defaultBackend: BackendEnv, allowed: map[string]bool{"env:token": true, "pass:work/db": true}}
+	tests := []struct {
+		reference string
+		want      bool
+	}{
+		{reference: "secret://token", want: true},
+		{reference: "secret://env:token", want: true},
+		{reference: "secret://pass:work/db", want: true},
+		{reference: "secret://pass:token", want: false},
+		{reference: "secret://AWS_SECRET_ACCESS_KEY", want: false},
+		{reference: "secret://command:work/db", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			if got := resolver.Allowed(tt.reference); got != tt.want {
+				t.Errorf("Allowed() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "token"), []byte("s3cr3t\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &fileBackend{dir: dir}
+	value, err := backend.Get(context.Background(), "token")
+	if err != nil || value != "s3cr3t" {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+
+	_, err = backend.Get(context.Background(), "../token")
+	if err == nil {
+		t.Errorf("Get() expected error for name outside of the secrets dir")
+	}
+}
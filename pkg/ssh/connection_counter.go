@@ -8,8 +8,9 @@ import (
 	"github.com/skevetter/log"
 )
 
-func newConnectionCounter(ctx context.Context, timeout time.Duration, onTimeout func(), address string, log log.Logger) *connectionCounter {
-	return &connectionCounter{
+// NewConnectionCounter creates a counter that calls onTimeout once there were no connections for the given timeout
+func NewConnectionCounter(ctx context.Context, timeout time.Duration, onTimeout func(), address string, log log.Logger) *ConnectionCounter {
+	return &ConnectionCounter{
 		ctx:       ctx,
 		address:   address,
 		timeout:   timeout,
@@ -18,7 +19,7 @@ func newConnectionCounter(ctx context.Context, timeout time.Duration, onTimeout
 	}
 }
 
-type connectionCounter struct {
+type ConnectionCounter struct {
 	address string
 
 	ctx       context.Context
@@ -31,7 +32,8 @@ type connectionCounter struct {
 	generation  int
 }
 
-func (c *connectionCounter) Add() {
+// Add registers a new connection
+func (c *ConnectionCounter) Add() {
 	c.m.Lock()
 	defer c.m.Unlock()
 
@@ -39,7 +41,8 @@ func (c *connectionCounter) Add() {
 	c.log.Debugf("New connection on %s (Total: %d)", c.address, c.connections)
 }
 
-func (c *connectionCounter) Dec() {
+// Dec unregisters a closed connection
+func (c *ConnectionCounter) Dec() {
 	c.m.Lock()
 	defer c.m.Unlock()
 
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/skevetter/log"
+	"github.com/skevetter/ssh"
+)
+
+// accessControl applies the access policy to forwards and records sessions in the audit log
+type accessControl struct {
+	policy *AccessPolicy
+	audit  *AuditLog
+	log    log.Logger
+}
+
+func newAccessControl(log log.Logger) (*accessControl, error) {
+	policy, err := LoadAccessPolicy(AccessPolicyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	audit, err := OpenAuditLog(AuditLogFileOf(currentUserName()))
+	if err != nil {
+		log.Warnf("Audit log is disabled: %v", err)
+	}
+
+	if !policy.Empty() {
+		log.Debugf("Apply ssh access policy %+v", *policy)
+	}
+
+	return &accessControl{
+		policy: policy,
+		audit:  audit,
+		log:    log,
+	}, nil
+}
+
+// apply sets the forwarding callbacks of the ssh server
+func (a *accessControl) apply(sshServer *ssh.Server) {
+	sshServer.LocalPortForwardingCallback = a.localPortForward
+	sshServer.LocalUnixForwardingCallback = a.localUnixForward
+	sshServer.ReversePortForwardingCallback = a.reversePortForward
+	sshServer.ReverseUnixForwardingCallback = a.reverseUnixForward
+}
+
+// close closes the audit log once the ssh server stopped
+func (a *accessControl) close() error {
+	return a.audit.Close()
+}
+
+func (a *accessControl) localPortForward(ctx ssh.Context, dhost string, dport uint32) bool {
+	allowed := a.policy.AllowForward(dhost, dport)
+	a.audit.forward(ctx, "local", net.JoinHostPort(dhost, strconv.Itoa(int(dport))), allowed)
+	if !allowed {
+		a.log.Debugf("Denied forward: %s:%d", dhost, dport)
+		return false
+	}
+
+	a.log.Debugf("Accepted forward: %s:%d", dhost, dport)
+	return true
+}
+
+func (a *accessControl) localUnixForward(ctx ssh.Context, socketPath string) bool {
+	allowed := a.policy.AllowSocket(socketPath)
+	a.audit.forward(ctx, "local-unix", socketPath, allowed)
+	a.log.Debugf("attempt to forward socket %s - %s", socketPath, decision(allowed))
+	return allowed
+}
+
+func (a *accessControl) reversePortForward(ctx ssh.Context, host string, port uint32) bool {
+	allowed := a.policy.AllowForward(host, port)
+	a.audit.forward(ctx, "reverse", net.JoinHostPort(host, strconv.Itoa(int(port))), allowed)
+	a.log.Debugf("attempt to bind %s:%d - %s", host, port, decision(allowed))
+	return allowed
+}
+
+func (a *accessControl) reverseUnixForward(ctx ssh.Context, socketPath string) bool {
+	allowed := a.policy.AllowSocket(socketPath)
+	a.audit.forward(ctx, "reverse-unix", socketPath, allowed)
+	a.log.Debugf("attempt to bind socket %s - %s", socketPath, decision(allowed))
+	if !allowed {
+		return false
+	}
+
+	_, err := os.Stat(socketPath)
+	if err == nil {
+		a.log.Debugf("%s already exists, removing", socketPath)
+
+		_ = os.Remove(socketPath)
+	}
+
+	return true
+}
+
+// handler records the session in the audit log and exits the session with the error of the handler
+func (a *accessControl) handler(handler func(sess ssh.Session) error) ssh.Handler {
+	return func(sess ssh.Session) {
+		err := a.audit.session(sess, "", func() error {
+			return handler(sess)
+		})
+
+		exitWithError(sess, err, a.log)
+	}
+}
+
+// sftp records the session and the requested operations in the audit log
+func (a *accessControl) sftp(currentUser string) ssh.SubsystemHandler {
+	return func(sess ssh.Session) {
+		err := a.audit.session(sess, "sftp", func() error {
+			return sftpHandler(sess, newSFTPAuditor(sess, a.audit, sess.Context()), currentUser, a.log)
+		})
+		_ = sess.Exit(exitCode(err))
+	}
+}
+
+func decision(allowed bool) string {
+	if allowed {
+		return "granted"
+	}
+
+	return "denied"
+}
+
+func currentUserName() string {
+	currentUser, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return currentUser.Username
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	copypkg "github.com/skevetter/devpod/pkg/copy"
+	"github.com/skevetter/ssh"
+)
+
+// AuditLogFile is the append-only audit log of the ssh servers in the container that run as root, it is only
+// accessible by root
+const AuditLogFile = "/var/devpod/audit.log"
+
+// AuditLogFileOf returns the audit log the ssh server of the user appends to. Ssh servers of non-root users
+// can't write the audit log of root and use a log of their own. That log is owned by the user, so unlike the
+// log of root it can be modified by the user and isn't append-only.
+func AuditLogFileOf(userName string) string {
+	if userName == "" || userName == "root" {
+		return AuditLogFile
+	}
+
+	return filepath.Join(filepath.Dir(AuditLogFile), "audit-"+userName+".log")
+}
+
+const (
+	AuditSessionStart = "session.start"
+	AuditSessionEnd   = "session.end"
+	AuditForward      = "forward"
+	AuditSFTP         = "sftp"
+)
+
+// AuditEvent is a single line of the audit log
+type AuditEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Session    string    `json:"session,omitempty"`
+	User       string    `json:"user,omitempty"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+
+	// Command, Subsystem and Pty describe a session
+	Command   string `json:"command,omitempty"`
+	Subsystem string `json:"subsystem,omitempty"`
+	Pty       bool   `json:"pty,omitempty"`
+
+	// Duration and ExitCode are set when a session ends
+	Duration string `json:"duration,omitempty"`
+	ExitCode *int   `json:"exitCode,omitempty"`
+
+	// Forward is the kind of forward, either local, local-unix, reverse or reverse-unix, and Allowed the decision of the
+	// access policy
+	Forward string `json:"forward,omitempty"`
+	Allowed *bool  `json:"allowed,omitempty"`
+
+	// Operation, Path and Target describe a requested sftp operation
+	Operation string `json:"operation,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Target    string `json:"target,omitempty"`
+}
+
+// AuditLog appends events as json lines to a file. A nil AuditLog discards all events.
+type AuditLog struct {
+	m    sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog opens the audit log for appending. The folder of the file needs to exist.
+func OpenAuditLog(file string) (*AuditLog, error) {
+	_, err := os.Stat(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditLog{file: f}, nil
+}
+
+// Record writes the event for the given ssh connection
+func (a *AuditLog) Record(ctx ssh.Context, event *AuditEvent) {
+	if a == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	if ctx != nil {
+		event.Session = shortSessionID(ctx.SessionID())
+		event.User = ctx.User()
+		if ctx.RemoteAddr() != nil {
+			event.RemoteAddr = ctx.RemoteAddr().String()
+		}
+	}
+
+	out, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.file == nil {
+		return
+	}
+
+	_, _ = a.file.Write(append(out, '\n'))
+}
+
+// Close closes the file of the audit log, events recorded afterwards are discarded
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.file == nil {
+		return nil
+	}
+
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// session records the start and the end of a session around the handler, the error of the handler
+// determines the exit code
+func (a *AuditLog) session(sess ssh.Session, subsystem string, handler func() error) error {
+	_, _, isPty := sess.Pty()
+	a.Record(sess.Context(), &AuditEvent{
+		Type:      AuditSessionStart,
+		Command:   sess.RawCommand(),
+		Subsystem: subsystem,
+		Pty:       isPty,
+	})
+
+	start := time.Now()
+	err := handler()
+	code := exitCode(err)
+	a.Record(sess.Context(), &AuditEvent{
+		Type:      AuditSessionEnd,
+		Command:   sess.RawCommand(),
+		Subsystem: subsystem,
+		Pty:       isPty,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+		ExitCode:  &code,
+	})
+	return err
+}
+
+func (a *AuditLog) forward(ctx ssh.Context, kind string, target string, allowed bool) {
+	a.Record(ctx, &AuditEvent{
+		Type:    AuditForward,
+		Forward: kind,
+		Target:  target,
+		Allowed: &allowed,
+	})
+}
+
+func shortSessionID(id string) string {
+	if len(id) > 16 {
+		return id[:16]
+	}
+
+	return id
+}
+
+// sftp packet types that change files or reveal what is read, see draft-ietf-secsh-filexfer-02
+const (
+	sftpOpen     = 3
+	sftpSetstat  = 9
+	sftpRemove   = 13
+	sftpMkdir    = 14
+	sftpRmdir    = 15
+	sftpRename   = 18
+	sftpSymlink  = 20
+	sftpExtended = 200
+
+	sftpFlagWrite = 0x2
+
+	// maxAuditedPacket is the maximum size of a packet that is parsed, larger packets are skipped
+	maxAuditedPacket = 64 * 1024
+)
+
+// sftpAuditor parses the requests of the sftp client and records the operations
+type sftpAuditor struct {
+	io.ReadWriteCloser
+
+	record func(operation, path, target string)
+
+	buf  []byte
+	skip uint64
+}
+
+func newSFTPAuditor(channel io.ReadWriteCloser, audit *AuditLog, ctx ssh.Context) io.ReadWriteCloser {
+	if audit == nil {
+		return channel
+	}
+
+	return &sftpAuditor{
+		ReadWriteCloser: channel,
+		record: func(operation, path, target string) {
+			audit.Record(ctx, &AuditEvent{Type: AuditSFTP, Operation: operation, Path: path, Target: target})
+		},
+	}
+}
+
+func (s *sftpAuditor) Read(p []byte) (int, error) {
+	n, err := s.ReadWriteCloser.Read(p)
+	if n > 0 {
+		s.parse(p[:n])
+	}
+
+	return n, err
+}
+
+func (s *sftpAuditor) parse(p []byte) {
+	if s.skip > 0 {
+		n := min(uint64(len(p)), s.skip)
+		p = p[n:]
+		s.skip -= n
+	}
+
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= 5 {
+		length := 4 + uint64(binary.BigEndian.Uint32(s.buf))
+		if !auditedPacket(s.buf[4]) || length > maxAuditedPacket {
+			if uint64(len(s.buf)) >= length {
+				s.buf = s.buf[length:]
+				continue
+			}
+
+			s.skip = length - uint64(len(s.buf))
+			s.buf = nil
+			return
+		} else if uint64(len(s.buf)) < length {
+			return
+		}
+
+		s.parsePacket(s.buf[4:length])
+		s.buf = s.buf[length:]
+	}
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+}
+
+func auditedPacket(packetType byte) bool {
+	switch packetType {
+	case sftpOpen, sftpSetstat, sftpRemove, sftpMkdir, sftpRmdir, sftpRename, sftpSymlink, sftpExtended:
+		return true
+	}
+
+	return false
+}
+
+// parsePacket parses a packet without its length, the request id is skipped
+func (s *sftpAuditor) parsePacket(packet []byte) {
+	if len(packet) < 5 {
+		return
+	}
+
+	data := packet[5:]
+	first, data, ok := readSFTPString(data)
+	if !ok {
+		return
+	}
+
+	switch packet[0] {
+	case sftpOpen:
+		operation := "read"
+		if len(data) >= 4 && binary.BigEndian.Uint32(data)&sftpFlagWrite != 0 {
+			operation = "write"
+		}
+		s.record(operation, first, "")
+	case sftpSetstat:
+		s.record("setstat", first, "")
+	case sftpRemove:
+		s.record("remove", first, "")
+	case sftpMkdir:
+		s.record("mkdir", first, "")
+	case sftpRmdir:
+		s.record("rmdir", first, "")
+	case sftpRename, sftpSymlink:
+		second, _, ok := readSFTPString(data)
+		if !ok {
+			return
+		}
+
+		operation := "rename"
+		if packet[0] == sftpSymlink {
+			operation = "symlink"
+		}
+		s.record(operation, first, second)
+	case sftpExtended:
+		if first != "posix-rename@openssh.com" {
+			return
+		}
+
+		from, data, ok := readSFTPString(data)
+		if !ok {
+			return
+		}
+		to, _, ok := readSFTPString(data)
+		if !ok {
+			return
+		}
+		s.record("rename", from, to)
+	}
+}
+
+func readSFTPString(data []byte) (string, []byte, bool) {
+	if len(data) < 4 {
+		return "", nil, false
+	}
+
+	length := uint64(binary.BigEndian.Uint32(data))
+	if uint64(len(data)-4) < length {
+		return "", nil, false
+	}
+
+	return string(data[4 : 4+length]), data[4+length:], true
+}
+
+// PrepareAuditFiles creates the audit log of root and the one of the given user, which is owned by the user, so
+// the ssh server of the user can append to it
+func PrepareAuditFiles(userName string) error {
+	err := createAuditLog(AuditLogFile, "")
+	if err != nil {
+		return err
+	}
+
+	if AuditLogFileOf(userName) == AuditLogFile {
+		return nil
+	}
+
+	return createAuditLog(AuditLogFileOf(userName), userName)
+}
+
+func createAuditLog(file string, userName string) error {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+
+	err = os.Chmod(file, 0600)
+	if err != nil {
+		return err
+	}
+
+	return copypkg.Chown(file, userName)
+}
@@ -77,10 +77,16 @@ func execPTY(
 	ptyReq ssh.Pty,
 	winCh <-chan ssh.Window,
 	cmd *exec.Cmd,
+	record bool,
 	log log.Logger,
 ) (err error) {
 	log.Debugf("Execute SSH server PTY command: %s", strings.Join(cmd.Args, " "))
 
+	output, recorder := sessionOutput(sess, ptyReq, record, log)
+	if recorder != nil {
+		defer func() { _ = recorder.Close() }()
+	}
+
 	cmd.Env = append(cmd.Env, fmt.Sprintf("TERM=%s", ptyReq.Term))
 	f, err := startPTY(cmd)
 	if err != nil {
@@ -91,6 +97,9 @@ func execPTY(
 	go func() {
 		for win := range winCh {
 			setWinSize(f, win.Width, win.Height)
+			if recorder != nil {
+				_ = recorder.Resize(win.Width, win.Height)
+			}
 		}
 	}()
 
@@ -107,7 +116,7 @@ func execPTY(
 		defer close(stdoutDoneChan)
 
 		// copy stdout
-		_, _ = io.Copy(sess, f)
+		_, _ = io.Copy(output, f)
 	}()
 
 	err = cmd.Wait()
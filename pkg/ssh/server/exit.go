@@ -2,6 +2,7 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"os/exec"
 	"strings"
 
@@ -13,7 +14,8 @@ import (
 func exitWithError(sess ssh.Session, err error, log log.Logger) {
 	if err != nil {
 		var exitError *exec.ExitError
-		if !errors.As(perrors.Cause(err), &exitError) {
+		var status exitStatus
+		if !errors.As(perrors.Cause(err), &exitError) && !errors.As(perrors.Cause(err), &status) {
 			log.Errorf("Exit error: %v", err)
 			msg := strings.TrimPrefix(err.Error(), "exec: ")
 			if _, err := sess.Stderr().Write([]byte(msg)); err != nil {
@@ -35,6 +37,11 @@ func exitCode(err error) int {
 		return 0
 	}
 
+	var status exitStatus
+	if errors.As(err, &status) {
+		return int(status)
+	}
+
 	var exitErr *exec.ExitError
 	if !errors.As(err, &exitErr) {
 		return 1
@@ -42,3 +49,10 @@ func exitCode(err error) int {
 
 	return exitErr.ExitCode()
 }
+
+// exitStatus is the exit code of a command that isn't a child of the ssh server
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
@@ -0,0 +1,752 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/skevetter/log"
+	"github.com/skevetter/ssh"
+)
+
+// PersistentSessionEnv is set by the client to attach its pty session to the persistent session with the
+// given name. The persistent session is created if it doesn't exist yet.
+const PersistentSessionEnv = "DEVPOD_SESSION"
+
+// PersistentSessionActionEnv is set by the client to list or kill persistent sessions instead of attaching to one
+const PersistentSessionActionEnv = "DEVPOD_SESSION_ACTION"
+
+const (
+	PersistentSessionActionList = "list"
+	PersistentSessionActionKill = "kill"
+)
+
+const (
+	// scrollbackSize is the amount of output a persistent session keeps for viewers that attach later
+	scrollbackSize = 256 * 1024
+
+	// detachKey detaches a viewer from a persistent session (Ctrl-\)
+	detachKey = 0x1c
+
+	// viewerWriteTimeout is the time a viewer has to accept output before it is disconnected
+	viewerWriteTimeout = 10 * time.Second
+
+	// viewerQueueSize is the number of frames queued for a viewer, a viewer that falls further behind is
+	// disconnected
+	viewerQueueSize = 64
+
+	maxFrameSize = 1024 * 1024
+)
+
+// frame types of the protocol between the ssh server and the session host
+const (
+	frameAttach byte = iota + 1
+	frameInfo
+	frameKill
+	frameData
+	frameResize
+	frameExit
+)
+
+var sessionNameRegEx = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// ValidateSessionName checks that the name of a persistent session can be used as part of a file name
+func ValidateSessionName(name string) error {
+	if !sessionNameRegEx.MatchString(name) {
+		return fmt.Errorf("invalid session name %s, only letters, digits, '.', '_' and '-' are allowed", name)
+	}
+
+	return nil
+}
+
+// PersistentSession describes a running persistent session
+type PersistentSession struct {
+	Name    string    `json:"name"`
+	Command string    `json:"command,omitempty"`
+	Started time.Time `json:"started"`
+	Viewers int       `json:"viewers"`
+}
+
+// RunPersistentSessionHost runs the command in a pty and serves it on the unix socket until the command exits.
+// The ssh server starts the host in its own process, so the command survives disconnects. Once the socket is
+// listening, the host writes ready to the given writer and closes it, errors before are written to it instead.
+func RunPersistentSessionHost(socketPath string, args []string, ready io.WriteCloser) error {
+	err := runPersistentSessionHost(socketPath, args, ready)
+	if err != nil {
+		_, _ = fmt.Fprintln(ready, err.Error())
+		_ = ready.Close()
+	}
+
+	return err
+}
+
+func runPersistentSessionHost(socketPath string, args []string, ready io.WriteCloser) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+
+	err := ensurePrivateDir(filepath.Dir(socketPath))
+	if err != nil {
+		return err
+	}
+
+	// another host could have been started in the meantime
+	conn, err := net.Dial("unix", socketPath)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("session %s is already running", socketPath)
+	}
+	_ = os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("listen on %s %w", socketPath, err)
+	}
+	defer func() { _ = os.Remove(socketPath) }()
+	defer func() { _ = listener.Close() }()
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = os.Environ()
+	f, err := startPTY(cmd)
+	if err != nil {
+		return fmt.Errorf("start pty %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	host := &sessionHost{
+		pty:     f,
+		cmd:     cmd,
+		viewers: map[net.Conn]*viewer{},
+		done:    make(chan struct{}),
+		info: PersistentSession{
+			Command: strings.Join(args, " "),
+			Started: time.Now(),
+		},
+	}
+	_, _ = fmt.Fprintln(ready, "ready")
+	_ = ready.Close()
+
+	go host.accept(listener)
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		host.copyOutput()
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-outputDone:
+	case <-time.After(time.Second):
+	}
+
+	// remove the socket first, so the session isn't listed anymore once viewers see the exit
+	_ = listener.Close()
+	_ = os.Remove(socketPath)
+	host.exit(exitCode(err))
+	return nil
+}
+
+// sessionHost owns the pty of a persistent session and broadcasts its output to all attached viewers
+type sessionHost struct {
+	m sync.Mutex
+
+	pty  *os.File
+	cmd  *exec.Cmd
+	info PersistentSession
+
+	scrollback []byte
+	viewers    map[net.Conn]*viewer
+	writers    sync.WaitGroup
+	exited     bool
+	done       chan struct{}
+}
+
+// viewer is a connection attached to a session host. Output is queued per viewer and written by its own
+// goroutine, so a slow viewer doesn't block the session or the other viewers.
+type viewer struct {
+	conn   net.Conn
+	frames chan viewerFrame
+}
+
+type viewerFrame struct {
+	frameType byte
+	payload   []byte
+}
+
+func (h *sessionHost) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go h.serve(conn)
+	}
+}
+
+func (h *sessionHost) serve(conn net.Conn) {
+	frameType, payload, err := readFrame(conn)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	switch frameType {
+	case frameAttach:
+		h.attach(conn, payload)
+	case frameInfo:
+		defer func() { _ = conn.Close() }()
+
+		h.m.Lock()
+		info := h.info
+		info.Viewers = len(h.viewers)
+		h.m.Unlock()
+
+		out, err := json.Marshal(info)
+		if err != nil {
+			return
+		}
+		_ = writeFrame(conn, frameInfo, out)
+	case frameKill:
+		defer func() { _ = conn.Close() }()
+
+		// hang up the command like a closed terminal, so a shell can stop its jobs
+		_ = h.cmd.Process.Signal(syscall.SIGHUP)
+		select {
+		case <-h.done:
+		case <-time.After(5 * time.Second):
+			_ = h.cmd.Process.Kill()
+			<-h.done
+		}
+	default:
+		_ = conn.Close()
+	}
+}
+
+// attach sends the scrollback to the viewer and forwards its input and window changes to the pty until it
+// disconnects
+func (h *sessionHost) attach(conn net.Conn, size []byte) {
+	defer func() { _ = conn.Close() }()
+
+	h.m.Lock()
+	if h.exited {
+		h.m.Unlock()
+		return
+	}
+	h.resize(size)
+	v := &viewer{conn: conn, frames: make(chan viewerFrame, viewerQueueSize)}
+	v.frames <- viewerFrame{frameType: frameData, payload: append([]byte(nil), h.scrollback...)}
+	h.viewers[conn] = v
+	h.writers.Add(1)
+	go h.writeViewer(v)
+	h.m.Unlock()
+
+	defer func() {
+		h.m.Lock()
+		if h.viewers[conn] == v {
+			h.removeViewer(v)
+		}
+		h.m.Unlock()
+	}()
+
+	for {
+		frameType, payload, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+
+		switch frameType {
+		case frameData:
+			_, _ = h.pty.Write(payload)
+		case frameResize:
+			h.resize(payload)
+		}
+	}
+}
+
+// resize sets the size of the pty to the window of the viewer that attached or resized last
+func (h *sessionHost) resize(size []byte) {
+	if len(size) != 8 {
+		return
+	}
+
+	setWinSize(h.pty, int(binary.BigEndian.Uint32(size)), int(binary.BigEndian.Uint32(size[4:])))
+}
+
+func (h *sessionHost) copyOutput() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := h.pty.Read(buf)
+		if n > 0 {
+			h.broadcast(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (h *sessionHost) broadcast(data []byte) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.scrollback = append(h.scrollback, data...)
+	if len(h.scrollback) > scrollbackSize {
+		h.scrollback = append([]byte(nil), h.scrollback[len(h.scrollback)-scrollbackSize:]...)
+	}
+
+	// the read buffer is reused, the copy is shared by all viewers
+	frame := viewerFrame{frameType: frameData, payload: append([]byte(nil), data...)}
+	for _, v := range h.viewers {
+		h.queue(v, frame)
+	}
+}
+
+func (h *sessionHost) exit(code int) {
+	h.m.Lock()
+	h.exited = true
+	close(h.done)
+	frame := viewerFrame{frameType: frameExit, payload: binary.BigEndian.AppendUint32(nil, uint32(code))}
+	for _, v := range h.viewers {
+		h.queue(v, frame)
+		if h.viewers[v.conn] == v {
+			h.removeViewer(v)
+		}
+	}
+	h.m.Unlock()
+
+	// give the viewers the chance to receive the exit code before the host exits
+	written := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(viewerWriteTimeout):
+	}
+}
+
+// queue queues the frame for the viewer and disconnects the viewer if its queue is full, h.m must be held
+func (h *sessionHost) queue(v *viewer, frame viewerFrame) {
+	select {
+	case v.frames <- frame:
+	default:
+		_ = v.conn.Close()
+		h.removeViewer(v)
+	}
+}
+
+// removeViewer removes the viewer, its writer closes the connection once the queued frames are written.
+// h.m must be held.
+func (h *sessionHost) removeViewer(v *viewer) {
+	delete(h.viewers, v.conn)
+	close(v.frames)
+}
+
+// writeViewer writes the queued frames to the viewer until it is removed or a write fails
+func (h *sessionHost) writeViewer(v *viewer) {
+	defer h.writers.Done()
+	defer func() { _ = v.conn.Close() }()
+
+	for frame := range v.frames {
+		_ = v.conn.SetWriteDeadline(time.Now().Add(viewerWriteTimeout))
+		err := writeFrame(v.conn, frame.frameType, frame.payload)
+		if err != nil {
+			h.m.Lock()
+			if h.viewers[v.conn] == v {
+				h.removeViewer(v)
+			}
+			h.m.Unlock()
+			return
+		}
+	}
+}
+
+// persistentSessionEnv returns the persistent session and the action the client requested
+func persistentSessionEnv(sess ssh.Session) (string, string) {
+	name, action := "", ""
+	for _, env := range sess.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		switch key {
+		case PersistentSessionEnv:
+			name = value
+		case PersistentSessionActionEnv:
+			action = value
+		}
+	}
+
+	return name, action
+}
+
+// execPersistent attaches the session to a persistent session, lists or kills persistent sessions
+func execPersistent(
+	sess ssh.Session,
+	ptyReq ssh.Pty,
+	winCh <-chan ssh.Window,
+	isPty bool,
+	cmd *exec.Cmd,
+	name string,
+	action string,
+	record bool,
+	log log.Logger,
+) error {
+	if strings.ContainsAny(sess.User(), `/\`) {
+		return fmt.Errorf("persistent sessions are not supported for user %s", sess.User())
+	}
+
+	newline := "\n"
+	if isPty {
+		newline = "\r\n"
+	}
+
+	dir := persistentSessionsDir(processUID(cmd))
+	switch action {
+	case PersistentSessionActionList:
+		return printPersistentSessions(sess, dir, newline)
+	case PersistentSessionActionKill:
+		err := ValidateSessionName(name)
+		if err != nil {
+			return err
+		}
+
+		err = killPersistentSession(persistentSocket(dir, sess.User(), name))
+		if err != nil {
+			return fmt.Errorf("kill session %s %w", name, err)
+		}
+
+		_, _ = fmt.Fprintf(sess, "Killed session %s%s", name, newline)
+		return nil
+	case "":
+	default:
+		return fmt.Errorf("unknown session action %s", action)
+	}
+
+	err := ValidateSessionName(name)
+	if err != nil {
+		return err
+	} else if !isPty {
+		return fmt.Errorf("persistent sessions require a terminal")
+	}
+
+	return attachPersistentSession(sess, ptyReq, winCh, cmd, name, persistentSocket(dir, sess.User(), name), record, log)
+}
+
+func attachPersistentSession(
+	sess ssh.Session,
+	ptyReq ssh.Pty,
+	winCh <-chan ssh.Window,
+	cmd *exec.Cmd,
+	name string,
+	socketPath string,
+	record bool,
+	log log.Logger,
+) error {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		log.Debugf("Start persistent session %s", name)
+		// the agent socket of this connection is gone once it disconnects, but the session outlives it
+		cmd.Env = slices.DeleteFunc(cmd.Env, func(env string) bool {
+			return strings.HasPrefix(env, "SSH_AUTH_SOCK=")
+		})
+		cmd.Env = append(cmd.Env, fmt.Sprintf("TERM=%s", ptyReq.Term))
+		err = startSessionHost(cmd, socketPath)
+		if err != nil {
+			return fmt.Errorf("start session %s %w", name, err)
+		}
+
+		conn, err = net.Dial("unix", socketPath)
+		if err != nil {
+			return fmt.Errorf("attach to session %s %w", name, err)
+		}
+	} else {
+		log.Debugf("Attach to persistent session %s", name)
+	}
+	defer func() { _ = conn.Close() }()
+
+	output, recorder := sessionOutput(sess, ptyReq, record, log)
+	if recorder != nil {
+		defer func() { _ = recorder.Close() }()
+	}
+
+	// frames to the host are written from the input and the window change goroutines
+	writeM := sync.Mutex{}
+	send := func(frameType byte, payload []byte) error {
+		writeM.Lock()
+		defer writeM.Unlock()
+		return writeFrame(conn, frameType, payload)
+	}
+
+	err = send(frameAttach, windowSize(ptyReq.Window.Width, ptyReq.Window.Height))
+	if err != nil {
+		return fmt.Errorf("attach to session %s %w", name, err)
+	}
+
+	go func() {
+		for win := range winCh {
+			_ = send(frameResize, windowSize(win.Width, win.Height))
+			if recorder != nil {
+				_ = recorder.Resize(win.Width, win.Height)
+			}
+		}
+	}()
+
+	detached := make(chan struct{})
+	go func() {
+		defer func() { _ = conn.Close() }()
+
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := sess.Read(buf)
+			if n > 0 {
+				data := buf[:n]
+				i := bytes.IndexByte(data, detachKey)
+				if i >= 0 {
+					data = data[:i]
+				}
+				if len(data) > 0 && send(frameData, data) != nil {
+					return
+				}
+				if i >= 0 {
+					close(detached)
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		frameType, payload, err := readFrame(conn)
+		if err != nil {
+			select {
+			case <-detached:
+				_, _ = fmt.Fprintf(sess, "\r\n[detached from session %s]\r\n", name)
+				return nil
+			case <-sess.Context().Done():
+				return nil
+			default:
+				return fmt.Errorf("session %s closed unexpectedly", name)
+			}
+		}
+
+		switch frameType {
+		case frameData:
+			_, _ = output.Write(payload)
+		case frameExit:
+			if len(payload) == 4 && binary.BigEndian.Uint32(payload) != 0 {
+				return exitStatus(binary.BigEndian.Uint32(payload))
+			}
+
+			return nil
+		}
+	}
+}
+
+// startSessionHost starts a new session host for the command and waits until it is ready
+func startSessionHost(cmd *exec.Cmd, socketPath string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"helper", "ssh-session-host", "--socket", socketPath, "--", cmd.Path}, cmd.Args[1:]...)
+	hostCmd := exec.Command(executable, args...)
+	hostCmd.Env = cmd.Env
+	hostCmd.Dir = cmd.Dir
+	if cmd.SysProcAttr != nil {
+		attr := *cmd.SysProcAttr
+		hostCmd.SysProcAttr = &attr
+	}
+	detachProcess(hostCmd)
+
+	stdout, err := hostCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = hostCmd.Start()
+	if err != nil {
+		return err
+	}
+
+	result := make(chan string, 1)
+	go func() {
+		out, _ := io.ReadAll(stdout)
+		result <- strings.TrimSpace(string(out))
+	}()
+
+	select {
+	case out := <-result:
+		go func() { _ = hostCmd.Wait() }()
+		if out != "ready" {
+			if out == "" {
+				out = "session host exited"
+			}
+
+			return fmt.Errorf("%s", out)
+		}
+
+		return nil
+	case <-time.After(10 * time.Second):
+		_ = hostCmd.Process.Kill()
+		go func() { _ = hostCmd.Wait() }()
+		return fmt.Errorf("timed out waiting for session host")
+	}
+}
+
+func killPersistentSession(socketPath string) error {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("session doesn't exist")
+	}
+	defer func() { _ = conn.Close() }()
+
+	err = writeFrame(conn, frameKill, nil)
+	if err != nil {
+		return err
+	}
+
+	// the host closes the connection once the command exited
+	_, _ = io.Copy(io.Discard, conn)
+	return nil
+}
+
+func printPersistentSessions(sess ssh.Session, dir string, newline string) error {
+	sessions, err := listPersistentSessions(dir, sess.User())
+	if err != nil {
+		return fmt.Errorf("list sessions %w", err)
+	} else if len(sessions) == 0 {
+		_, _ = fmt.Fprintf(sess, "No sessions found%s", newline)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(sess, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "NAME\tVIEWERS\tAGE\tCOMMAND%s", newline)
+	for _, session := range sessions {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s%s", session.Name, session.Viewers, time.Since(session.Started).Round(time.Second), session.Command, newline)
+	}
+
+	return w.Flush()
+}
+
+// listPersistentSessions returns the running persistent sessions of the user, the oldest first. Sockets of
+// sessions that don't respond anymore are removed.
+func listPersistentSessions(dir string, user string) ([]*PersistentSession, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	sessions := []*PersistentSession{}
+	for _, entry := range entries {
+		name, sessionUser, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sock"), "@")
+		if !ok || sessionUser != user || !strings.HasSuffix(entry.Name(), ".sock") {
+			continue
+		}
+
+		session, err := persistentSessionInfo(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+
+		session.Name = name
+		sessions = append(sessions, session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Started.Before(sessions[j].Started)
+	})
+
+	return sessions, nil
+}
+
+func persistentSessionInfo(socketPath string) (*PersistentSession, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	err = writeFrame(conn, frameInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, payload, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &PersistentSession{}
+	err = json.Unmarshal(payload, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// persistentSessionsDir is the folder of the session sockets of the given user id
+func persistentSessionsDir(uid int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("devpod-sessions-%d", uid))
+}
+
+// persistentSocket returns the socket of a session, session names can't contain @ so the user is
+// separated unambiguously
+func persistentSocket(dir string, user string, name string) string {
+	return filepath.Join(dir, name+"@"+user+".sock")
+}
+
+func windowSize(width, height int) []byte {
+	return binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(width)), uint32(height))
+}
+
+func writeFrame(w io.Writer, frameType byte, payload []byte) error {
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = frameType
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame of %d bytes exceeds the maximum size", length)
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPersistentSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pty is not supported on windows")
+	}
+
+	dir := filepath.Join(t.TempDir(), "sessions")
+	socketPath := persistentSocket(dir, "vscode", "build")
+	readyReader, readyWriter := io.Pipe()
+	hostErr := make(chan error, 1)
+	go func() {
+		hostErr <- RunPersistentSessionHost(socketPath, []string{"sh", "-c", "echo started; read line; echo got $line; exit 3"}, readyWriter)
+	}()
+
+	ready, _ := io.ReadAll(readyReader)
+	if strings.TrimSpace(string(ready)) != "ready" {
+		t.Fatalf("session host not ready: %s", ready)
+	}
+
+	first := attachViewer(t, socketPath)
+	readOutput(t, first, "started")
+
+	sessions, err := listPersistentSessions(dir, "vscode")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(sessions) != 1 || sessions[0].Name != "build" || sessions[0].Viewers != 1 {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+
+	// a second viewer receives the scrollback and the output caused by the first one
+	second := attachViewer(t, socketPath)
+	readOutput(t, second, "started")
+	err = writeFrame(first, frameData, []byte("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readOutput(t, second, "got hello")
+
+	for {
+		frameType, payload, err := readFrame(second)
+		if err != nil {
+			t.Fatal(err)
+		} else if frameType == frameExit {
+			if code := binary.BigEndian.Uint32(payload); code != 3 {
+				t.Fatalf("unexpected exit code %d", code)
+			}
+			break
+		}
+	}
+
+	err = <-hostErr
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPersistentSessionSlowViewer(t *testing.T) {
+	host := &sessionHost{viewers: map[net.Conn]*viewer{}, done: make(chan struct{})}
+
+	// the other end of the pipe never reads, so every write blocks
+	conn, _ := net.Pipe()
+	v := &viewer{conn: conn, frames: make(chan viewerFrame, viewerQueueSize)}
+	host.viewers[conn] = v
+	host.writers.Add(1)
+	go host.writeViewer(v)
+
+	broadcasted := make(chan struct{})
+	go func() {
+		defer close(broadcasted)
+		for range viewerQueueSize + 2 {
+			host.broadcast([]byte("output"))
+		}
+	}()
+
+	select {
+	case <-broadcasted:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked on a slow viewer")
+	}
+
+	host.m.Lock()
+	defer host.m.Unlock()
+	if len(host.viewers) != 0 {
+		t.Fatal("slow viewer wasn't disconnected")
+	}
+}
+
+func attachViewer(t *testing.T, socketPath string) net.Conn {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	err = writeFrame(conn, frameAttach, windowSize(80, 24))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+func readOutput(t *testing.T, conn net.Conn, want string) {
+	output := ""
+	for !strings.Contains(output, want) {
+		frameType, payload, err := readFrame(conn)
+		if err != nil {
+			t.Fatalf("waiting for %q in %q: %v", want, output, err)
+		} else if frameType == frameData {
+			output += string(payload)
+		}
+	}
+}
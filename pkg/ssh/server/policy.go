@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AccessPolicyFile holds the access policy of the ssh servers in the container
+const AccessPolicyFile = "/var/devpod/ssh-policy.json"
+
+// AccessPolicy restricts what can be forwarded through the ssh server and if sessions are recorded. Deny rules take precedence and if an
+// allow list is not empty, everything that doesn't match it is denied.
+type AccessPolicy struct {
+	// AllowForwards and DenyForwards are host:port patterns, the host is a glob and the port either a
+	// number, a range like 8000-8999 or *. A pattern without port matches every port of the host.
+	AllowForwards []string `json:"allowForwards,omitempty"`
+	DenyForwards  []string `json:"denyForwards,omitempty"`
+
+	// AllowSockets and DenySockets are glob patterns of unix socket paths
+	AllowSockets []string `json:"allowSockets,omitempty"`
+	DenySockets  []string `json:"denySockets,omitempty"`
+
+	// RecordSessions records the output of interactive sessions in RecordingsDir
+	RecordSessions bool `json:"recordSessions,omitempty"`
+}
+
+// Empty returns true if the policy doesn't restrict or record anything
+func (p *AccessPolicy) Empty() bool {
+	return p == nil || (len(p.AllowForwards) == 0 && len(p.DenyForwards) == 0 && len(p.AllowSockets) == 0 && len(p.DenySockets) == 0 && !p.RecordSessions)
+}
+
+// AllowForward returns true if forwarding the given host and port is allowed
+func (p *AccessPolicy) AllowForward(host string, port uint32) bool {
+	if p == nil {
+		return true
+	}
+
+	return allowed(p.AllowForwards, p.DenyForwards, func(pattern string) bool {
+		return matchForward(pattern, host, port)
+	})
+}
+
+// AllowSocket returns true if forwarding the given unix socket is allowed
+func (p *AccessPolicy) AllowSocket(socketPath string) bool {
+	if p == nil {
+		return true
+	}
+
+	socketPath = path.Clean(filepath.ToSlash(socketPath))
+	return allowed(p.AllowSockets, p.DenySockets, func(pattern string) bool {
+		matched, err := path.Match(pattern, socketPath)
+		return err == nil && matched
+	})
+}
+
+// LoadAccessPolicy reads the policy from the given file. If the file doesn't exist, nothing is restricted.
+func LoadAccessPolicy(file string) (*AccessPolicy, error) {
+	out, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &AccessPolicy{}, nil
+		}
+
+		return nil, err
+	}
+
+	policy := &AccessPolicy{}
+	err = json.Unmarshal(out, policy)
+	if err != nil {
+		return nil, fmt.Errorf("parse ssh access policy %w", err)
+	}
+
+	return policy, nil
+}
+
+// WriteAccessPolicy writes the policy to the given file or removes the file if the policy is empty
+func WriteAccessPolicy(file string, policy *AccessPolicy) error {
+	if policy.Empty() {
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	out, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, out, 0644)
+}
+
+func allowed(allow, deny []string, match func(pattern string) bool) bool {
+	for _, pattern := range deny {
+		if match(pattern) {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, pattern := range allow {
+		if match(pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchForward(pattern string, host string, port uint32) bool {
+	hostPattern, portPattern := pattern, "*"
+	if h, p, err := net.SplitHostPort(pattern); err == nil {
+		hostPattern, portPattern = h, p
+	}
+
+	matched, err := path.Match(strings.ToLower(hostPattern), strings.ToLower(strings.Trim(host, "[]")))
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchPort(portPattern, port)
+}
+
+func matchPort(pattern string, port uint32) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	from, to, isRange := strings.Cut(pattern, "-")
+	if !isRange {
+		to = from
+	}
+	fromPort, err := strconv.ParseUint(from, 10, 32)
+	if err != nil {
+		return false
+	}
+	toPort, err := strconv.ParseUint(to, 10, 32)
+	if err != nil {
+		return false
+	}
+
+	return uint64(port) >= fromPort && uint64(port) <= toPort
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/skevetter/log"
+	"github.com/skevetter/ssh"
+)
+
+func TestAccessPolicyAllowForward(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *AccessPolicy
+		host   string
+		port   uint32
+		want   bool
+	}{
+		{name: "no policy", policy: nil, host: "localhost", port: 22, want: true},
+		{name: "empty policy", policy: &AccessPolicy{}, host: "10.0.0.1", port: 443, want: true},
+		{name: "denied port", policy: &AccessPolicy{DenyForwards: []string{"*:22"}}, host: "localhost", port: 22, want: false},
+		{name: "other port", policy: &AccessPolicy{DenyForwards: []string{"*:22"}}, host: "localhost", port: 8080, want: true},
+		{name: "denied host without port", policy: &AccessPolicy{DenyForwards: []string{"169.254.169.254"}}, host: "169.254.169.254", port: 80, want: false},
+		{name: "allowed range", policy: &AccessPolicy{AllowForwards: []string{"localhost:3000-3999"}}, host: "localhost", port: 3001, want: true},
+		{name: "outside allowed range", policy: &AccessPolicy{AllowForwards: []string{"localhost:3000-3999"}}, host: "localhost", port: 4000, want: false},
+		{name: "host glob", policy: &AccessPolicy{AllowForwards: []string{"127.0.0.*:*"}}, host: "127.0.0.1", port: 5432, want: true},
+		{name: "ipv6", policy: &AccessPolicy{AllowForwards: []string{"[::1]:8080"}}, host: "::1", port: 8080, want: true},
+		{name: "deny wins", policy: &AccessPolicy{AllowForwards: []string{"localhost"}, DenyForwards: []string{"localhost:2375"}}, host: "localhost", port: 2375, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.AllowForward(tt.host, tt.port); got != tt.want {
+				t.Errorf("AllowForward(%s, %d) = %v, want %v", tt.host, tt.port, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessPolicyAllowSocket(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     *AccessPolicy
+		socketPath string
+		want       bool
+	}{
+		{name: "empty policy", policy: &AccessPolicy{}, socketPath: "/var/run/docker.sock", want: true},
+		{name: "denied socket", policy: &AccessPolicy{DenySockets: []string{"/var/run/docker.sock"}}, socketPath: "/var/run/docker.sock", want: false},
+		{name: "allowed glob", policy: &AccessPolicy{AllowSockets: []string{"/tmp/*.sock"}}, socketPath: "/tmp/agent.sock", want: true},
+		{name: "not allowed", policy: &AccessPolicy{AllowSockets: []string{"/tmp/*.sock"}}, socketPath: "/run/agent.sock", want: false},
+		{name: "cleaned path", policy: &AccessPolicy{DenySockets: []string{"/var/run/docker.sock"}}, socketPath: "/var/run/../run/docker.sock", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.AllowSocket(tt.socketPath); got != tt.want {
+				t.Errorf("AllowSocket(%s) = %v, want %v", tt.socketPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessControlUnixForward(t *testing.T) {
+	audit, err := OpenAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access := &accessControl{policy: &AccessPolicy{DenySockets: []string{"/var/run/docker.sock"}}, audit: audit, log: log.Discard}
+	sshServer := &ssh.Server{}
+	access.apply(sshServer)
+	if sshServer.LocalUnixForwardingCallback == nil || sshServer.LocalUnixForwardingCallback(nil, "/var/run/docker.sock") {
+		t.Fatal("local unix forward of a denied socket was allowed")
+	} else if sshServer.ReverseUnixForwardingCallback(nil, "/var/run/docker.sock") {
+		t.Fatal("reverse unix forward of a denied socket was allowed")
+	} else if !sshServer.LocalUnixForwardingCallback(nil, "/run/app/api.sock") {
+		t.Fatal("local unix forward of an allowed socket was denied")
+	}
+
+	err = access.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// events after the close are discarded
+	access.localUnixForward(nil, "/run/app/api.sock")
+	if err := access.close(); err != nil {
+		t.Fatal(err)
+	}
+}
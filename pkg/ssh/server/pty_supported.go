@@ -3,6 +3,7 @@
 package server
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"syscall"
@@ -19,3 +20,40 @@ func setWinSize(f *os.File, w, h int) {
 	_, _, _ = syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCSWINSZ),
 		uintptr(unsafe.Pointer(&struct{ h, w, x, y uint16 }{uint16(h), uint16(w), 0, 0})))
 }
+
+// detachProcess starts the process in a new session, so it isn't hung up when the ssh server exits
+func detachProcess(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+}
+
+// processUID returns the user id the command will run as
+func processUID(cmd *exec.Cmd) int {
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		return int(cmd.SysProcAttr.Credential.Uid)
+	}
+
+	return os.Getuid()
+}
+
+// ensurePrivateDir creates the folder and checks that only the current user can access it
+func ensurePrivateDir(dir string) error {
+	err := os.Mkdir(dir, 0700)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	stat, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	sys, ok := stat.Sys().(*syscall.Stat_t)
+	if !stat.IsDir() || !ok || int(sys.Uid) != os.Getuid() || stat.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is not a private folder of the current user", dir)
+	}
+
+	return nil
+}
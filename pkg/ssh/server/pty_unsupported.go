@@ -15,3 +15,15 @@ func startPTY(cmd *exec.Cmd) (*os.File, error) {
 func setWinSize(f *os.File, w, h int) {
 
 }
+
+func detachProcess(cmd *exec.Cmd) {
+
+}
+
+func processUID(cmd *exec.Cmd) int {
+	return os.Getuid()
+}
+
+func ensurePrivateDir(dir string) error {
+	return os.MkdirAll(dir, 0700)
+}
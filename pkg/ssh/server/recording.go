@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/asciicast"
+	"github.com/skevetter/log"
+	"github.com/skevetter/ssh"
+)
+
+// RecordingsDir holds the recorded sessions in the container
+const RecordingsDir = "/var/devpod/sessions"
+
+// sessionOutput returns the writer for the output of a pty session, which also writes to the recording if the
+// session is recorded
+func sessionOutput(sess ssh.Session, ptyReq ssh.Pty, record bool, log log.Logger) (io.Writer, *asciicast.Writer) {
+	if !record {
+		return sess, nil
+	}
+
+	recorder := newRecorder(sess, ptyReq, log)
+	if recorder == nil {
+		return sess, nil
+	}
+
+	return io.MultiWriter(sess, &recordingWriter{recorder: recorder, log: log}), recorder
+}
+
+// recordingWriter writes the output to the recording, the recording stops at the first error without failing
+// the session
+type recordingWriter struct {
+	recorder io.Writer
+	log      log.Logger
+	failed   bool
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.failed {
+		return len(p), nil
+	}
+
+	_, err := w.recorder.Write(p)
+	if err != nil {
+		w.failed = true
+		w.log.Errorf("Error writing session recording, stop recording: %v", err)
+	}
+
+	return len(p), nil
+}
+
+// newRecorder creates a new asciicast recording of the session. If the recording can't be created, the
+// session continues without it.
+func newRecorder(sess ssh.Session, ptyReq ssh.Pty, log log.Logger) *asciicast.Writer {
+	err := PrepareRecordingsDir()
+	if err != nil {
+		log.Errorf("Error creating session recordings folder: %v", err)
+		return nil
+	}
+
+	prefix := fmt.Sprintf("%s-%s-", time.Now().UTC().Format("20060102-150405"), shortSessionID(sess.Context().SessionID()))
+	f, err := os.CreateTemp(RecordingsDir, prefix+"*"+asciicast.Extension)
+	if err != nil {
+		log.Errorf("Error creating session recording: %v", err)
+		return nil
+	}
+
+	recorder, err := asciicast.NewWriter(f, &asciicast.Header{
+		Width:   ptyReq.Window.Width,
+		Height:  ptyReq.Window.Height,
+		Command: sess.RawCommand(),
+		Title:   sess.User(),
+		Env:     map[string]string{"TERM": ptyReq.Term},
+	})
+	if err != nil {
+		_ = f.Close()
+		log.Errorf("Error writing session recording: %v", err)
+		return nil
+	}
+
+	log.Debugf("Recording session to %s", f.Name())
+	return recorder
+}
+
+// PrepareRecordingsDir creates the recordings folder, so ssh servers of non-root users can add recordings
+// without being able to list or read the ones of others
+func PrepareRecordingsDir() error {
+	info, err := os.Stat(RecordingsDir)
+	if err == nil && info.Mode()&os.ModeSticky != 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(RecordingsDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(RecordingsDir, 0733|os.ModeSticky)
+}
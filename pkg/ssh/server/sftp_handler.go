@@ -2,6 +2,7 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/pkg/sftp"
@@ -11,7 +12,7 @@ import (
 	"github.com/skevetter/ssh"
 )
 
-func sftpHandler(sess ssh.Session, currentUser string, log log.Logger) {
+func sftpHandler(sess ssh.Session, channel io.ReadWriteCloser, currentUser string, log log.Logger) error {
 	writer := log.Writer(logrus.DebugLevel, false)
 	defer func() { _ = writer.Close() }()
 
@@ -26,24 +27,23 @@ func sftpHandler(sess ssh.Session, currentUser string, log log.Logger) {
 		sftp.WithServerWorkingDirectory(workingDir),
 	}
 	server, err := sftp.NewServer(
-		sess,
+		channel,
 		serverOptions...,
 	)
 	if err != nil {
 		log.Debugf("sftp server init error: %s\n", err)
-		return
+		return fmt.Errorf("sftp server init %w", err)
 	}
 	defer func() { _ = server.Close() }()
 
 	// serve
 	err = server.Serve()
 	if errors.Is(err, io.EOF) {
-		_ = sess.Exit(0)
-		return
+		return nil
 	}
 
 	if err != nil {
 		log.Debugf("sftp server completed with error: %v", err)
 	}
-	_ = sess.Exit(1)
+	return err
 }
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"os/user"
 
+	"github.com/skevetter/devpod/pkg/envfile"
 	"github.com/skevetter/devpod/pkg/shell"
 	"github.com/skevetter/log"
 	"github.com/skevetter/ssh"
@@ -20,6 +21,9 @@ const (
 type Server interface {
 	Serve(listener net.Listener) error
 	ListenAndServe() error
+
+	// Close stops the server and closes its audit log
+	Close() error
 }
 
 type server struct {
@@ -28,7 +32,11 @@ type server struct {
 	workdir     string
 	reuseSock   string
 	sshServer   ssh.Server
+	access      *accessControl
 	log         log.Logger
+
+	// recordSessions records interactive sessions in RecordingsDir
+	recordSessions bool
 }
 
 func NewServer(addr string, hostKey []byte, keys []ssh.PublicKey, workdir string, reuseSock string, log log.Logger) (Server, error) {
@@ -42,36 +50,23 @@ func NewServer(addr string, hostKey []byte, keys []ssh.PublicKey, workdir string
 		return nil, err
 	}
 
+	access, err := newAccessControl(log)
+	if err != nil {
+		return nil, err
+	}
+
 	forwardHandler := &ssh.ForwardedTCPHandler{}
 	forwardedUnixHandler := &ssh.ForwardedUnixHandler{}
 	server := &server{
-		shell:       sh,
-		workdir:     workdir,
-		reuseSock:   reuseSock,
-		log:         log,
-		currentUser: currentUser.Username,
+		shell:          sh,
+		workdir:        workdir,
+		reuseSock:      reuseSock,
+		log:            log,
+		currentUser:    currentUser.Username,
+		access:         access,
+		recordSessions: access.policy.RecordSessions,
 		sshServer: ssh.Server{
 			Addr: addr,
-			LocalPortForwardingCallback: func(ctx ssh.Context, dhost string, dport uint32) bool {
-				log.Debugf("Accepted forward: %s:%d", dhost, dport)
-				return true
-			},
-			ReversePortForwardingCallback: func(ctx ssh.Context, host string, port uint32) bool {
-				log.Debugf("attempt to bind %s:%d - %s", host, port, "granted")
-				return true
-			},
-			ReverseUnixForwardingCallback: func(ctx ssh.Context, socketPath string) bool {
-				log.Debugf("attempt to bind socket %s", socketPath)
-
-				_, err := os.Stat(socketPath)
-				if err == nil {
-					log.Debugf("%s already exists, removing", socketPath)
-
-					_ = os.Remove(socketPath)
-				}
-
-				return true
-			},
 			ChannelHandlers: map[string]ssh.ChannelHandler{
 				"direct-tcpip":                   ssh.DirectTCPIPHandler,
 				"direct-streamlocal@openssh.com": ssh.DirectStreamLocalHandler,
@@ -84,9 +79,7 @@ func NewServer(addr string, hostKey []byte, keys []ssh.PublicKey, workdir string
 				"cancel-tcpip-forward":                   forwardHandler.HandleSSHRequest,
 			},
 			SubsystemHandlers: map[string]ssh.SubsystemHandler{
-				"sftp": func(s ssh.Session) {
-					sftpHandler(s, currentUser.Username, log)
-				},
+				"sftp": access.sftp(currentUser.Username),
 			},
 		},
 	}
@@ -111,20 +104,19 @@ func NewServer(addr string, hostKey []byte, keys []ssh.PublicKey, workdir string
 		}
 	}
 
-	server.sshServer.Handler = server.handler
+	access.apply(&server.sshServer)
+	server.sshServer.Handler = access.handler(server.handler)
 	return server, nil
 }
 
-func (s *server) handler(sess ssh.Session) {
-	var err error
+func (s *server) handler(sess ssh.Session) error {
 	ptyReq, winCh, isPty := sess.Pty()
 	cmd := s.getCommand(sess, isPty)
 
 	if ssh.AgentRequested(sess) {
 		l, tmpDir, err := setupAgentListener(sess, s.reuseSock)
 		if err != nil {
-			exitWithError(sess, err, s.log)
-			return
+			return err
 		}
 		defer func() { _ = l.Close() }()
 		defer func() { _ = os.RemoveAll(tmpDir) }()
@@ -134,15 +126,17 @@ func (s *server) handler(sess ssh.Session) {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", "SSH_AUTH_SOCK", l.Addr().String()))
 	}
 
+	// attach to a persistent session
+	if name, action := persistentSessionEnv(sess); name != "" || action != "" {
+		return execPersistent(sess, ptyReq, winCh, isPty, cmd, name, action, s.recordSessions, s.log)
+	}
+
 	// start shell session
 	if isPty {
-		err = execPTY(sess, ptyReq, winCh, cmd, s.log)
-	} else {
-		err = execNonPTY(sess, cmd, s.log)
+		return execPTY(sess, ptyReq, winCh, cmd, s.recordSessions, s.log)
 	}
 
-	// exit session
-	exitWithError(sess, err, s.log)
+	return execNonPTY(sess, cmd, s.log)
 }
 
 func (s *server) getCommand(sess ssh.Session, isPty bool) *exec.Cmd {
@@ -187,15 +181,23 @@ func (s *server) getCommand(sess ssh.Session, isPty bool) *exec.Cmd {
 
 	cmd.Dir = findWorkdir(s.workdir, user)
 	cmd.Env = append(cmd.Env, os.Environ()...)
+	cmd.Env = append(cmd.Env, envfile.Secrets()...)
 	cmd.Env = append(cmd.Env, sess.Environ()...)
 	return cmd
 }
 
 func (s *server) Serve(listener net.Listener) error {
+	defer func() { _ = s.access.close() }()
 	return s.sshServer.Serve(listener)
 }
 
 func (s *server) ListenAndServe() error {
+	defer func() { _ = s.access.close() }()
 	s.log.Debugf("Start ssh server on %s", s.sshServer.Addr)
 	return s.sshServer.ListenAndServe()
 }
+
+func (s *server) Close() error {
+	defer func() { _ = s.access.close() }()
+	return s.sshServer.Close()
+}
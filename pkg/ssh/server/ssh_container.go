@@ -9,39 +9,27 @@ import (
 
 	copypkg "github.com/skevetter/devpod/pkg/copy"
 	"github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/envfile"
 	shellpkg "github.com/skevetter/devpod/pkg/shell"
 	"github.com/skevetter/log"
 	"github.com/skevetter/ssh"
 )
 
 func NewContainerServer(addr string, workdir string, log log.Logger) (Server, error) {
+	access, err := newAccessControl(log)
+	if err != nil {
+		return nil, err
+	}
+
 	forwardHandler := &ssh.ForwardedTCPHandler{}
 	forwardedUnixHandler := &ssh.ForwardedUnixHandler{}
 	server := &containerServer{
-		workdir: workdir,
-		log:     log,
+		workdir:        workdir,
+		access:         access,
+		log:            log,
+		recordSessions: access.policy.RecordSessions,
 		sshServer: ssh.Server{
 			Addr: addr,
-			LocalPortForwardingCallback: func(ctx ssh.Context, dhost string, dport uint32) bool {
-				log.Debugf("Accepted forward: %s:%d", dhost, dport)
-				return true
-			},
-			ReversePortForwardingCallback: func(ctx ssh.Context, host string, port uint32) bool {
-				log.Debugf("attempt to bind %s:%d - %s", host, port, "granted")
-				return true
-			},
-			ReverseUnixForwardingCallback: func(ctx ssh.Context, socketPath string) bool {
-				log.Debugf("attempt to bind socket %s", socketPath)
-
-				_, err := os.Stat(socketPath)
-				if err == nil {
-					log.Debugf("%s already exists, removing", socketPath)
-
-					_ = os.Remove(socketPath)
-				}
-
-				return true
-			},
 			ChannelHandlers: map[string]ssh.ChannelHandler{
 				"direct-tcpip":                   ssh.DirectTCPIPHandler,
 				"direct-streamlocal@openssh.com": ssh.DirectStreamLocalHandler,
@@ -54,54 +42,60 @@ func NewContainerServer(addr string, workdir string, log log.Logger) (Server, er
 				"cancel-tcpip-forward":                   forwardHandler.HandleSSHRequest,
 			},
 			SubsystemHandlers: map[string]ssh.SubsystemHandler{
-				"sftp": func(s ssh.Session) {
-					sftpHandler(s, "", log)
-				},
+				"sftp": access.sftp(""),
 			},
 		},
 	}
 
-	server.sshServer.Handler = server.handler
+	access.apply(&server.sshServer)
+	server.sshServer.Handler = access.handler(server.handler)
 	return server, nil
 }
 
 type containerServer struct {
 	sshServer ssh.Server
+	access    *accessControl
 	log       log.Logger
 	workdir   string
+
+	// recordSessions records interactive sessions in RecordingsDir
+	recordSessions bool
 }
 
 func (s *containerServer) Serve(listener net.Listener) error {
+	defer func() { _ = s.access.close() }()
 	return s.sshServer.Serve(listener)
 }
 
 func (s *containerServer) ListenAndServe() error {
+	defer func() { _ = s.access.close() }()
 	s.log.Debugf("Start ssh server on %s", s.sshServer.Addr)
 	return s.sshServer.ListenAndServe()
 }
 
-func (s *containerServer) handler(sess ssh.Session) {
-	var err error
+func (s *containerServer) Close() error {
+	defer func() { _ = s.access.close() }()
+	return s.sshServer.Close()
+}
+
+func (s *containerServer) handler(sess ssh.Session) error {
 	ptyReq, winCh, isPty := sess.Pty()
 	cmd, err := s.getCommand(sess, isPty)
 	if err != nil {
-		exitWithError(sess, fmt.Errorf("get command %w", err), s.log)
-		return
+		return fmt.Errorf("get command %w", err)
 	}
 
 	if ssh.AgentRequested(sess) {
 		l, tmpDir, err := setupAgentListener(sess, "")
 		if err != nil {
-			exitWithError(sess, err, s.log)
-			return
+			return err
 		}
 		defer func() { _ = l.Close() }()
 		defer func() { _ = os.RemoveAll(tmpDir) }()
 
 		err = chownListener(l.Addr().String(), sess.User())
 		if err != nil {
-			exitWithError(sess, fmt.Errorf("chown listener %w", err), s.log)
-			return
+			return fmt.Errorf("chown listener %w", err)
 		}
 
 		go ssh.ForwardAgentConnections(l, sess)
@@ -109,13 +103,16 @@ func (s *containerServer) handler(sess ssh.Session) {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", "SSH_AUTH_SOCK", l.Addr().String()))
 	}
 
+	// attach to a persistent session
+	if name, action := persistentSessionEnv(sess); name != "" || action != "" {
+		return execPersistent(sess, ptyReq, winCh, isPty, cmd, name, action, s.recordSessions, s.log)
+	}
+
 	if isPty {
-		err = execPTY(sess, ptyReq, winCh, cmd, s.log)
-	} else {
-		err = execNonPTY(sess, cmd, s.log)
+		return execPTY(sess, ptyReq, winCh, cmd, s.recordSessions, s.log)
 	}
 
-	exitWithError(sess, err, s.log)
+	return execNonPTY(sess, cmd, s.log)
 }
 
 func (s *containerServer) getCommand(sess ssh.Session, isPty bool) (*exec.Cmd, error) {
@@ -146,6 +143,7 @@ func (s *containerServer) getCommand(sess ssh.Session, isPty bool) (*exec.Cmd, e
 		return cmd, fmt.Errorf("prepare cmd env %w", err)
 	}
 	cmd.Dir = findWorkdir(s.workdir, user)
+	cmd.Env = append(cmd.Env, envfile.Secrets()...)
 	cmd.Env = append(cmd.Env, sess.Environ()...)
 	return cmd, nil
 }
@@ -0,0 +1,247 @@
+package ssh
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/skevetter/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxDatagramSize is the maximum payload of a single udp datagram
+const maxDatagramSize = 65535
+
+// WriteDatagram writes a length prefixed datagram to the writer
+func WriteDatagram(w io.Writer, datagram []byte) error {
+	if len(datagram) > maxDatagramSize {
+		return fmt.Errorf("datagram of %d bytes exceeds maximum size", len(datagram))
+	}
+
+	buf := make([]byte, 2+len(datagram))
+	binary.BigEndian.PutUint16(buf, uint16(len(datagram)))
+	copy(buf[2:], datagram)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadDatagram reads a length prefixed datagram from the reader into buf, which needs to be able to hold
+// a datagram of maximum size
+func ReadDatagram(r io.Reader, buf []byte) ([]byte, error) {
+	header := [2]byte{}
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(binary.BigEndian.Uint16(header[:]))
+	_, err = io.ReadFull(r, buf[:size])
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:size], nil
+}
+
+// UDPPortForward listens on the local udp address and forwards all datagrams through the ssh client. SSH has
+// no udp channels, so every local peer gets its own session running the given relay command, which exchanges
+// length prefixed datagrams with the remote udp address over stdio.
+func UDPPortForward(
+	ctx context.Context,
+	client *ssh.Client,
+	localAddr string,
+	relayCommand string,
+	log log.Logger,
+) error {
+	conn, err := net.ListenPacket("udp", localAddr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	peers := map[string]io.WriteCloser{}
+	peersMutex := sync.Mutex{}
+	defer func() {
+		peersMutex.Lock()
+		defer peersMutex.Unlock()
+
+		for _, peer := range peers {
+			_ = peer.Close()
+		}
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			_ = conn.Close()
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		peersMutex.Lock()
+		peer := peers[addr.String()]
+		if peer == nil {
+			peer, err = startUDPRelay(client, relayCommand, conn, addr, func() {
+				peersMutex.Lock()
+				defer peersMutex.Unlock()
+
+				delete(peers, addr.String())
+			}, log)
+			if err != nil {
+				peersMutex.Unlock()
+				log.Debugf("error starting udp relay: %v", err)
+				continue
+			}
+
+			peers[addr.String()] = peer
+		}
+		peersMutex.Unlock()
+
+		err = WriteDatagram(peer, buf[:n])
+		if err != nil {
+			log.Debugf("error writing datagram to remote: %v", err)
+			_ = peer.Close()
+		}
+	}
+}
+
+type udpRelaySession struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+}
+
+func (u *udpRelaySession) Write(p []byte) (int, error) {
+	return u.stdin.Write(p)
+}
+
+func (u *udpRelaySession) Close() error {
+	_ = u.stdin.Close()
+	return u.session.Close()
+}
+
+func startUDPRelay(client *ssh.Client, relayCommand string, conn net.PacketConn, addr net.Addr, onExit func(), log log.Logger) (io.WriteCloser, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+
+	err = session.Start(relayCommand)
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+
+	relay := &udpRelaySession{session: session, stdin: stdin}
+	go func() {
+		defer onExit()
+		defer func() { _ = relay.Close() }()
+
+		buf := make([]byte, maxDatagramSize)
+		for {
+			datagram, err := ReadDatagram(stdout, buf)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					log.Debugf("error reading datagram from remote: %v", err)
+				}
+				return
+			}
+
+			_, err = conn.WriteTo(datagram, addr)
+			if err != nil {
+				log.Debugf("error writing datagram to local: %v", err)
+				return
+			}
+		}
+	}()
+
+	return relay, nil
+}
+
+// UDPRelay relays length prefixed datagrams between stdio and the given udp address until stdin is closed
+// or no datagram was exchanged for the idle timeout
+func UDPRelay(stdin io.Reader, stdout io.Writer, address string, idleTimeout time.Duration) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	extendDeadline := func() {
+		_ = conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	}
+	extendDeadline()
+
+	errChan := make(chan error, 2)
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			datagram, err := ReadDatagram(stdin, buf)
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				errChan <- err
+				return
+			}
+
+			extendDeadline()
+			_, err = conn.Write(datagram)
+			if err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					err = nil
+				}
+				errChan <- err
+				return
+			}
+
+			extendDeadline()
+			err = WriteDatagram(stdout, buf[:n])
+			if err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	return <-errChan
+}
@@ -2,24 +2,42 @@ package tunnel
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/skevetter/devpod/pkg/agent"
+	"github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/netstat"
+	"github.com/skevetter/devpod/pkg/open"
+	portpkg "github.com/skevetter/devpod/pkg/port"
 	devssh "github.com/skevetter/devpod/pkg/ssh"
 	"github.com/skevetter/log"
 	"golang.org/x/crypto/ssh"
 )
 
 // newForwarder returns a new forwarder using an SSH client and list of ports to forward,
-// for each port a new go routine is used to manage the SSH channel
-func newForwarder(sshClient *ssh.Client, forwardedPorts []string, log log.Logger) netstat.Forwarder {
-	return &forwarder{
+// for each port a new go routine is used to manage the SSH channel. The ports attributes
+// of the devcontainer decide how a discovered port is forwarded.
+func newForwarder(sshClient *ssh.Client, forwardedPorts []string, mergedConfig *config.MergedDevContainerConfig, log log.Logger) netstat.Forwarder {
+	f := &forwarder{
 		sshClient:      sshClient,
 		forwardedPorts: forwardedPorts,
-		portMap:        map[string]context.CancelFunc{},
+		portMap:        map[netstat.Port]context.CancelFunc{},
+		openedPorts:    map[netstat.Port]bool{},
 		log:            log,
 	}
+	if mergedConfig != nil {
+		f.portsAttributes = mergedConfig.PortsAttributes
+		f.otherPortsAttributes = mergedConfig.OtherPortsAttributes
+	}
+
+	return f
 }
 
 // forwarder multiplexes a SSH client to forward ports to the remote container
@@ -29,12 +47,17 @@ type forwarder struct {
 	sshClient      *ssh.Client
 	forwardedPorts []string
 
-	portMap map[string]context.CancelFunc
-	log     log.Logger
+	portsAttributes      map[string]config.PortAttribute
+	otherPortsAttributes *config.PortAttribute
+
+	portMap     map[netstat.Port]context.CancelFunc
+	openedPorts map[netstat.Port]bool
+	log         log.Logger
 }
 
-// Forward opens an SSH channel in the existing connection with channel type "direct-tcpip" to forward the local port
-func (f *forwarder) Forward(port string) error {
+// Forward opens an SSH channel in the existing connection to forward the local port. TCP ports and unix sockets
+// use the "direct-tcpip" and "direct-streamlocal" channel types, udp ports are relayed through a helper session.
+func (f *forwarder) Forward(port netstat.Port) error {
 	f.Lock()
 	defer f.Unlock()
 
@@ -42,23 +65,130 @@ func (f *forwarder) Forward(port string) error {
 		return nil
 	}
 
+	// unix sockets have no port attributes
+	if port.Protocol == netstat.ProtocolUnix {
+		localPath := localSocketPath(port.Port)
+		err := os.MkdirAll(filepath.Dir(localPath), 0700)
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(localPath)
+
+		cancelCtx, cancel := context.WithCancel(context.Background())
+		f.portMap[port] = cancel
+		f.log.Infof("Start forwarding unix socket %s to %s", port.Port, localPath)
+		go f.forward(cancelCtx, port, func() error {
+			return devssh.PortForward(cancelCtx, f.sshClient, "unix", localPath, "unix", port.Port, 0, f.log)
+		})
+		return nil
+	}
+
+	portNumber, err := strconv.Atoi(port.Port)
+	if err != nil {
+		return fmt.Errorf("parse port %s %w", port.Port, err)
+	}
+	attributes, _ := config.GetPortAttributes(f.portsAttributes, f.otherPortsAttributes, portNumber)
+	if attributes.OnAutoForward == config.OnAutoForwardIgnore {
+		return nil
+	}
+
+	localPort, err := f.findLocalPort(port, portNumber, attributes)
+	if err != nil {
+		return err
+	}
+
 	cancelCtx, cancel := context.WithCancel(context.Background())
 	f.portMap[port] = cancel
-	f.log.Infof("Start port-forwarding on port %s", port)
-
-	go func(port string) {
-		// do the forward
-		err := devssh.PortForward(cancelCtx, f.sshClient, "tcp", "localhost:"+port, "tcp", "localhost:"+port, 0, f.log)
-		if err != nil {
-			f.log.Errorf("Error port forwarding %s: %v", port, err)
+	f.notify(cancelCtx, port, localPort, attributes)
+	go f.forward(cancelCtx, port, func() error {
+		localAddr := "localhost:" + strconv.Itoa(localPort)
+		if port.Protocol == netstat.ProtocolUDP {
+			relayCommand := fmt.Sprintf("'%s' helper udp-relay --address 'localhost:%s'", agent.ContainerDevPodHelperLocation, port.Port)
+			return devssh.UDPPortForward(cancelCtx, f.sshClient, localAddr, relayCommand, f.log)
 		}
-	}(port)
+
+		return devssh.PortForward(cancelCtx, f.sshClient, "tcp", localAddr, "tcp", "localhost:"+port.Port, 0, f.log)
+	})
 
 	return nil
 }
 
+func (f *forwarder) forward(ctx context.Context, port netstat.Port, fn func() error) {
+	err := fn()
+	if err != nil && ctx.Err() == nil {
+		f.log.Errorf("Error port forwarding %s: %v", port, err)
+	}
+}
+
+// findLocalPort returns the local port to forward the given port to. If the same port is not available locally
+// the next free port is used, unless the port attributes require the same local port.
+func (f *forwarder) findLocalPort(port netstat.Port, portNumber int, attributes *config.PortAttribute) (int, error) {
+	available := isLocalPortAvailable(port.Protocol, portNumber)
+	if available && portNumber < 1024 && runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		// privileged ports can only be bound by root
+		if attributes.ElevateIfNeeded {
+			f.log.Warnf("Port %s requires elevated permissions to be forwarded locally, please run DevPod as root to forward it on the same port", port)
+		}
+		available = false
+	}
+	if available {
+		return portNumber, nil
+	} else if attributes.RequireLocalPort {
+		return 0, fmt.Errorf("port %s is required locally by portsAttributes.requireLocalPort, but it is not available", port)
+	}
+
+	var localPort int
+	var err error
+	if port.Protocol == netstat.ProtocolUDP {
+		localPort, err = portpkg.FindAvailableUDPPort(max(portNumber, 1024))
+	} else {
+		localPort, err = portpkg.FindAvailablePort(max(portNumber, 1024))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find local port for %s %w", port, err)
+	}
+
+	return localPort, nil
+}
+
+// notify tells the user about a forwarded port as configured by portsAttributes.onAutoForward
+func (f *forwarder) notify(ctx context.Context, port netstat.Port, localPort int, attributes *config.PortAttribute) {
+	name := port.String()
+	if attributes.Label != "" {
+		name = fmt.Sprintf("%s (%s)", port, attributes.Label)
+	}
+	message := fmt.Sprintf("Start port-forwarding on port %s", name)
+	if strconv.Itoa(localPort) != port.Port {
+		message = fmt.Sprintf("Start port-forwarding port %s to local port %d", name, localPort)
+	}
+
+	switch attributes.OnAutoForward {
+	case config.OnAutoForwardSilent:
+		f.log.Debug(message)
+	case config.OnAutoForwardOpenBrowser, config.OnAutoForwardOpenBrowserOnce, config.OnAutoForwardOpenPreview:
+		f.log.Info(message)
+		if port.Protocol != netstat.ProtocolTCP || (attributes.OnAutoForward == config.OnAutoForwardOpenBrowserOnce && f.openedPorts[port]) {
+			return
+		}
+
+		scheme := "http"
+		if attributes.Protocol == "https" {
+			scheme = "https"
+		}
+		f.openedPorts[port] = true
+		go func() {
+			err := open.Open(ctx, fmt.Sprintf("%s://localhost:%d", scheme, localPort), f.log)
+			if err != nil {
+				f.log.Debugf("Error opening browser for port %s: %v", port, err)
+			}
+		}()
+	default:
+		f.log.Info(message)
+	}
+}
+
 // StopForward stops the port forwarding for the given port
-func (f *forwarder) StopForward(port string) error {
+func (f *forwarder) StopForward(port netstat.Port) error {
 	f.Lock()
 	defer f.Unlock()
 
@@ -73,6 +203,22 @@ func (f *forwarder) StopForward(port string) error {
 	return nil
 }
 
-func (f *forwarder) isExcluded(port string) bool {
-	return slices.Contains(f.forwardedPorts, port)
+func (f *forwarder) isExcluded(port netstat.Port) bool {
+	return port.Protocol == netstat.ProtocolTCP && slices.Contains(f.forwardedPorts, port.Port)
+}
+
+func isLocalPortAvailable(protocol string, port int) bool {
+	addr := "localhost:" + strconv.Itoa(port)
+	if protocol == netstat.ProtocolUDP {
+		return portpkg.IsUDPAvailable(addr)
+	}
+
+	available, _ := portpkg.IsAvailable(addr)
+	return available
+}
+
+// localSocketPath returns the path a remote unix socket is forwarded to, e.g. /run/app/api.sock is forwarded to
+// $TMPDIR/devpod-sockets/run_app_api.sock
+func localSocketPath(remotePath string) string {
+	return filepath.Join(os.TempDir(), "devpod-sockets", strings.ReplaceAll(strings.TrimPrefix(remotePath, "/"), "/", "_"))
 }
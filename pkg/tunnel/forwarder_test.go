@@ -0,0 +1,99 @@
+package tunnel
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/skevetter/devpod/pkg/netstat"
+	"github.com/skevetter/devpod/pkg/ssh/server"
+	"github.com/skevetter/log"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestForwardUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not forwarded on windows")
+	}
+
+	// unix socket paths are limited to about 100 characters
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	remoteSocket := filepath.Join(tempDir, "app.sock")
+	appListener, err := net.Listen("unix", remoteSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = appListener.Close() }()
+	go func() {
+		for {
+			conn, err := appListener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("hello"))
+			_ = conn.Close()
+		}
+	}()
+
+	sshClient := startContainerServer(t)
+	f := newForwarder(sshClient, nil, nil, log.Discard)
+	port := netstat.Port{Protocol: netstat.ProtocolUnix, Port: remoteSocket}
+	err = f.Forward(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.StopForward(port) }()
+
+	var conn net.Conn
+	for range 50 {
+		conn, err = net.Dial("unix", localSocketPath(remoteSocket))
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	out, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(out) != "hello" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+// startContainerServer starts the ssh server of the container and returns a client connected to it
+func startContainerServer(t *testing.T) *ssh.Client {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	sshServer, err := server.NewContainerServer(listener.Addr().String(), "", log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = sshServer.Serve(listener) }()
+
+	sshClient, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            "root",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sshClient.Close() })
+
+	return sshClient
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -20,10 +22,13 @@ import (
 	"github.com/skevetter/devpod/pkg/config"
 	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
 	"github.com/skevetter/devpod/pkg/devcontainer/setup"
+	"github.com/skevetter/devpod/pkg/filesync"
 	"github.com/skevetter/devpod/pkg/gitsshsigning"
 	"github.com/skevetter/devpod/pkg/ide/openvscode"
 	"github.com/skevetter/devpod/pkg/netstat"
+	"github.com/skevetter/devpod/pkg/port"
 	"github.com/skevetter/devpod/pkg/provider"
+	"github.com/skevetter/devpod/pkg/secrets"
 	devssh "github.com/skevetter/devpod/pkg/ssh"
 	"github.com/skevetter/log"
 	"golang.org/x/crypto/ssh"
@@ -50,12 +55,36 @@ func RunServices(
 		exitAfterTimeout = 0
 	}
 
-	// forward ports
-	forwardedPorts, err := forwardDevContainerPorts(ctx, containerClient, extraPorts, exitAfterTimeout, log)
+	// read the devcontainer result
+	result, err := readContainerResult(ctx, containerClient, log)
 	if err != nil {
 		return fmt.Errorf("forward ports %w", err)
 	}
 
+	// forward ports
+	forwardedPorts := forwardDevContainerPorts(ctx, containerClient, result, extraPorts, exitAfterTimeout, log)
+
+	// secrets are resolved on request of the container and never stored
+	secretsResolver, err := secrets.NewResolver(devPodConfig)
+	if err != nil {
+		return fmt.Errorf("create secrets resolver %w", err)
+	}
+	var secretReferences map[string]string
+	if workspace != nil {
+		secretReferences = secrets.WorkspaceReferences(workspace, nil, secretsResolver, log)
+	}
+
+	// serve the local folder to the sync engine in the container
+	var syncLocal *filesync.Local
+	if workspace != nil && workspace.Sync && workspace.Source.LocalFolder != "" {
+		workspaceDir, err := provider.GetWorkspaceDir(workspace.Context, workspace.ID)
+		if err != nil {
+			return fmt.Errorf("get workspace dir %w", err)
+		}
+
+		syncLocal = filesync.NewLocal(ctx, workspace.Source.LocalFolder, workspaceDir, log)
+	}
+
 	return retry.OnError(wait.Backoff{
 		Steps:    math.MaxInt,
 		Duration: 500 * time.Millisecond,
@@ -83,7 +112,7 @@ func RunServices(
 		// create a port forwarder
 		var forwarder netstat.Forwarder
 		if forwardPorts {
-			forwarder = newForwarder(containerClient, append(forwardedPorts, fmt.Sprintf("%d", openvscode.DefaultVSCodePort)), log)
+			forwarder = newForwarder(containerClient, append(forwardedPorts, fmt.Sprintf("%d", openvscode.DefaultVSCodePort)), result.MergedConfig, log)
 		}
 
 		errChan := make(chan error, 1)
@@ -101,6 +130,8 @@ func RunServices(
 				workspace,
 				log,
 				tunnelserver.WithPlatformOptions(platformOptions),
+				tunnelserver.WithSecretsResolver(secretsResolver, secretReferences),
+				tunnelserver.WithSync(syncLocal),
 			)
 			if err != nil {
 				errChan <- fmt.Errorf("run tunnel server %w", err)
@@ -129,6 +160,9 @@ func RunServices(
 		if forwardPorts {
 			command += " --forward-ports"
 		}
+		if syncLocal != nil {
+			command += " --sync"
+		}
 		if log.GetLevel() == logrus.DebugLevel {
 			command += " --debug"
 		}
@@ -146,8 +180,8 @@ func RunServices(
 	})
 }
 
-// forwardDevContainerPorts forwards all the ports defined in the devcontainer.json
-func forwardDevContainerPorts(ctx context.Context, containerClient *ssh.Client, extraPorts []string, exitAfterTimeout time.Duration, log log.Logger) ([]string, error) {
+// readContainerResult reads the devcontainer setup result from the container
+func readContainerResult(ctx context.Context, containerClient *ssh.Client, log log.Logger) (*config2.Result, error) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 	err := devssh.Run(ctx, containerClient, "cat "+setup.ResultLocation, nil, stdout, stderr, nil)
@@ -164,7 +198,15 @@ func forwardDevContainerPorts(ctx context.Context, containerClient *ssh.Client,
 	log.WithFields(logrus.Fields{
 		"location": setup.ResultLocation,
 	}).Debug("parsed container result")
+	if result.MergedConfig == nil {
+		result.MergedConfig = &config2.MergedDevContainerConfig{}
+	}
 
+	return result, nil
+}
+
+// forwardDevContainerPorts forwards all the ports defined in the devcontainer.json
+func forwardDevContainerPorts(ctx context.Context, containerClient *ssh.Client, result *config2.Result, extraPorts []string, exitAfterTimeout time.Duration, log log.Logger) []string {
 	// return forwarded ports
 	forwardedPorts := []string{}
 
@@ -207,7 +249,7 @@ func forwardDevContainerPorts(ctx context.Context, containerClient *ssh.Client,
 		forwardedPorts = append(forwardedPorts, port)
 	}
 
-	return forwardedPorts, nil
+	return forwardedPorts
 }
 
 func forwardPort(ctx context.Context, containerClient *ssh.Client, port string, exitAfterTimeout time.Duration, log log.Logger) []string {
@@ -262,3 +304,123 @@ func parseForwardPort(port string) (string, int64, error) {
 
 	return "", 0, fmt.Errorf("invalid forwardPorts port")
 }
+
+// DriverDialer connects the stream to the port of the devcontainer through the driver of the workspace,
+// which works without the agent running in the container
+type DriverDialer func(ctx context.Context, port int, stream io.ReadWriter) error
+
+// ForwardPortsWithDriver forwards the given port mappings through the driver until the context is done.
+// If the result is given, the forwardPorts and appPort of the devcontainer are forwarded as well.
+func ForwardPortsWithDriver(ctx context.Context, dial DriverDialer, mappings []string, result *config2.Result, log log.Logger) error {
+	if result != nil && result.MergedConfig != nil {
+		mappings = append(mappings, getDevContainerPortMappings(result.MergedConfig, log)...)
+	}
+	if len(mappings) == 0 {
+		return fmt.Errorf("no ports to forward")
+	}
+
+	errChan := make(chan error, len(mappings))
+	for _, rawMapping := range mappings {
+		mapping, err := port.ParsePortSpec(rawMapping)
+		if err != nil {
+			return fmt.Errorf("parse port mapping %w", err)
+		}
+		containerPort, err := getDriverPort(mapping.Container)
+		if err != nil {
+			return fmt.Errorf("port mapping %s %w", rawMapping, err)
+		}
+
+		listener, err := net.Listen(mapping.Host.Protocol, mapping.Host.Address)
+		if err != nil {
+			return fmt.Errorf("listen on %s %w", mapping.Host.Address, err)
+		}
+
+		log.Infof("Forwarding local %s/%s to port %d of the workspace container", mapping.Host.Protocol, mapping.Host.Address, containerPort)
+		go func() {
+			errChan <- serveDriverPort(ctx, listener, dial, containerPort, log)
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errChan:
+		return err
+	}
+}
+
+func serveDriverPort(ctx context.Context, listener net.Listener, dial DriverDialer, containerPort int, log log.Logger) error {
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("accept connection %w", err)
+		}
+
+		go func() {
+			defer func() { _ = conn.Close() }()
+
+			err := dial(ctx, containerPort, conn)
+			if err != nil {
+				log.Errorf("Error forwarding port %d: %v", containerPort, err)
+			}
+		}()
+	}
+}
+
+// getDriverPort returns the port of a container address, drivers can only forward tcp ports of the container itself
+func getDriverPort(address port.Address) (int, error) {
+	if address.Protocol != "tcp" {
+		return 0, fmt.Errorf("only tcp ports can be forwarded through the driver")
+	}
+
+	host, rawPort, err := net.SplitHostPort(address.Address)
+	if err != nil {
+		return 0, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return 0, fmt.Errorf("only ports on localhost can be forwarded through the driver")
+	}
+
+	return strconv.Atoi(rawPort)
+}
+
+// getDevContainerPortMappings returns the forwardPorts and appPort of the devcontainer as port mappings
+func getDevContainerPortMappings(mergedConfig *config2.MergedDevContainerConfig, log log.Logger) []string {
+	mappings := []string{}
+	seen := map[string]bool{}
+	add := func(mapping string) {
+		if !seen[mapping] {
+			seen[mapping] = true
+			mappings = append(mappings, mapping)
+		}
+	}
+
+	for _, appPort := range mergedConfig.AppPort {
+		add(appPort)
+	}
+	for _, forwardPort := range mergedConfig.ForwardPorts {
+		host, portNumber, err := parseForwardPort(forwardPort)
+		if err != nil {
+			log.Debugf("Error parsing forwardPort %s: %v", forwardPort, err)
+			continue
+		}
+
+		// the driver connects to the port of the container, services on other hosts can't be reached
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			log.Warnf("Skip forwarding %s, only ports of the workspace container can be forwarded through the driver", forwardPort)
+			continue
+		}
+		add(strconv.FormatInt(portNumber, 10))
+	}
+
+	return mappings
+}
@@ -0,0 +1,140 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	config2 "github.com/skevetter/devpod/pkg/devcontainer/config"
+	"github.com/skevetter/devpod/pkg/port"
+	"github.com/skevetter/log"
+)
+
+func TestGetDriverPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		address port.Address
+		want    int
+		wantErr bool
+	}{
+		{name: "localhost", address: port.Address{Protocol: "tcp", Address: "localhost:80"}, want: 80},
+		{name: "loopback", address: port.Address{Protocol: "tcp", Address: "127.0.0.1:3000"}, want: 3000},
+		{name: "udp", address: port.Address{Protocol: "udp", Address: "localhost:53"}, wantErr: true},
+		{name: "other host", address: port.Address{Protocol: "tcp", Address: "10.0.0.1:80"}, wantErr: true},
+		{name: "unix socket", address: port.Address{Protocol: "unix", Address: "/run/app.sock"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getDriverPort(tt.address)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got port %d", got)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Fatalf("expected port %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetDevContainerPortMappings(t *testing.T) {
+	mergedConfig := &config2.MergedDevContainerConfig{}
+	mergedConfig.AppPort = []string{"8080:80", "3000"}
+	mergedConfig.ForwardPorts = []string{"3000", "db:5432", "127.0.0.1:4000", "invalid:port"}
+
+	got := getDevContainerPortMappings(mergedConfig, log.Discard)
+	want := []string{"8080:80", "3000", "4000"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestForwardPortsWithDriver(t *testing.T) {
+	localPort, err := port.FindAvailablePort(20000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake driver answers with the port of the container that was dialed
+	dial := func(ctx context.Context, port int, stream io.ReadWriter) error {
+		_, err := fmt.Fprintf(stream, "%d", port)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- ForwardPortsWithDriver(ctx, dial, []string{fmt.Sprintf("%d:80", localPort)}, nil, log.Discard)
+	}()
+
+	for range 2 {
+		out := readForwardedPort(t, "localhost:"+strconv.Itoa(localPort))
+		if out != "80" {
+			t.Fatalf("expected the connection to be forwarded to port 80, got %q", out)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("forwarding didn't stop")
+	}
+}
+
+func TestForwardPortsWithDriverInvalid(t *testing.T) {
+	dial := func(ctx context.Context, port int, stream io.ReadWriter) error {
+		return nil
+	}
+
+	tests := map[string][]string{
+		"no ports":   nil,
+		"udp port":   {"5353:53/udp"},
+		"other host": {"8080:10.0.0.1:80"},
+	}
+	for name, mappings := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ForwardPortsWithDriver(context.Background(), dial, mappings, nil, log.Discard)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func readForwardedPort(t *testing.T, address string) string {
+	var conn net.Conn
+	var err error
+	for range 50 {
+		conn, err = net.Dial("tcp", address)
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	out, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
@@ -0,0 +1,216 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/skevetter/devpod/pkg/filesync"
+)
+
+const (
+	// ChunkSize is the size of the content addressed chunks files are split into
+	ChunkSize = 4 * 1024 * 1024
+
+	// ManifestCacheFile caches the manifest of the local folder in the workspace folder
+	ManifestCacheFile = "upload-manifest.json"
+)
+
+type Type string
+
+const (
+	TypeFile    Type = "file"
+	TypeDir     Type = "dir"
+	TypeSymlink Type = "symlink"
+)
+
+// File is an entry of a manifest
+type File struct {
+	Type Type
+	Mode uint32
+
+	// Size and Chunks are the size and the sha256 hashes of the chunks of a file
+	Size   int64
+	Chunks []string
+
+	// Target is the target of a symlink
+	Target string
+}
+
+// Equal returns true if both entries have the same type, mode and content
+func (f *File) Equal(other *File) bool {
+	if other == nil || f.Type != other.Type || f.Mode != other.Mode || f.Size != other.Size || f.Target != other.Target || len(f.Chunks) != len(other.Chunks) {
+		return false
+	}
+	for i := range f.Chunks {
+		if f.Chunks[i] != other.Chunks[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// chunkLength returns the length of the chunk with the given index
+func (f *File) chunkLength(index int) int64 {
+	return min(ChunkSize, f.Size-int64(index)*ChunkSize)
+}
+
+// Manifest maps the slash separated relative paths of a folder to their entries
+type Manifest map[string]*File
+
+// cachedFile remembers the chunks of a file, so unchanged files don't need to be hashed again. Directories
+// and symlinks are cached with their type only, so deleted entries can be detected.
+type cachedFile struct {
+	Type    Type     `json:"type,omitempty"`
+	Size    int64    `json:"size"`
+	ModTime int64    `json:"modTime"`
+	Chunks  []string `json:"chunks"`
+}
+
+// Scan creates the manifest of the given folder. Paths matching the excludes are skipped. If cacheFile is
+// not empty, the chunks of files are cached in it and reused as long as size and modification time match.
+func Scan(root string, excludes []string, cacheFile string) (Manifest, error) {
+	manifest, _, saveCache, err := scan(root, excludes, cacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	saveCache()
+	return manifest, nil
+}
+
+// scan creates the manifest of the folder and returns the entries of the previous scan as well. The new
+// entries are only written to the cache file once saveCache is called.
+func scan(root string, excludes []string, cacheFile string) (Manifest, map[string]cachedFile, func(), error) {
+	cache := map[string]cachedFile{}
+	if cacheFile != "" {
+		out, err := os.ReadFile(cacheFile)
+		if err == nil {
+			_ = json.Unmarshal(out, &cache)
+		}
+	}
+
+	manifest := Manifest{}
+	newCache := map[string]cachedFile{}
+	err := filepath.WalkDir(root, func(absPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, absPath)
+		if err != nil {
+			return err
+		} else if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+		if filesync.IsExcluded(excludes, relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		switch {
+		case info.IsDir():
+			newCache[relPath] = cachedFile{Type: TypeDir}
+			manifest[relPath] = &File{Type: TypeDir, Mode: fileMode(info.Mode())}
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(absPath)
+			if err != nil {
+				return nil
+			}
+
+			newCache[relPath] = cachedFile{Type: TypeSymlink}
+			manifest[relPath] = &File{Type: TypeSymlink, Target: target}
+		case info.Mode().IsRegular():
+			cached, ok := cache[relPath]
+			if !ok || cached.Type != TypeFile || cached.Size != info.Size() || cached.ModTime != info.ModTime().UnixNano() {
+				chunks, err := hashChunks(absPath)
+				if err != nil {
+					// files we can't read are skipped like in the tar upload
+					return nil
+				}
+
+				cached = cachedFile{Type: TypeFile, Size: info.Size(), ModTime: info.ModTime().UnixNano(), Chunks: chunks}
+			}
+
+			newCache[relPath] = cached
+			manifest[relPath] = &File{Type: TypeFile, Mode: fileMode(info.Mode()), Size: cached.Size, Chunks: cached.Chunks}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	saveCache := func() {
+		if cacheFile == "" {
+			return
+		}
+
+		out, err := json.Marshal(newCache)
+		if err == nil {
+			_ = os.MkdirAll(filepath.Dir(cacheFile), 0755)
+			_ = os.WriteFile(cacheFile, out, 0600)
+		}
+	}
+
+	return manifest, cache, saveCache, nil
+}
+
+func hashChunks(absPath string) ([]string, error) {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	chunks := []string{}
+	buf := make([]byte, ChunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			chunks = append(chunks, hashChunk(buf[:n]))
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return chunks, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func hashChunk(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// fileMode returns the permissions that are applied on the other side, files from windows are executable
+// like in the tar upload
+func fileMode(mode os.FileMode) uint32 {
+	perm := mode.Perm()
+	if runtime.GOOS == "windows" {
+		perm = (perm | 0111) & 0755
+	}
+
+	return uint32(perm)
+}
@@ -0,0 +1,461 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/skevetter/devpod/pkg/agent/tunnel"
+	"github.com/skevetter/devpod/pkg/filesync"
+	"github.com/skevetter/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxAttempts is the number of times an interrupted upload is resumed before giving up
+const maxAttempts = 5
+
+// ErrUnsupported is returned if the local machine doesn't support incremental uploads
+var ErrUnsupported = errors.New("incremental upload is not supported")
+
+// Stream is a bidirectional stream to the local machine
+type Stream interface {
+	Send(*tunnel.Chunk) error
+	Recv() (*tunnel.Chunk, error)
+	CloseSend() error
+}
+
+// Open opens a new upload stream
+type Open func(ctx context.Context) (Stream, error)
+
+// Options configure an upload
+type Options struct {
+	// Mount is the mount to upload, if empty the workspace folder is uploaded
+	Mount string
+
+	// Target is the folder the upload is written to
+	Target string
+
+	// CacheDir keeps received chunks and the manifest of the target between uploads. If empty, a temporary
+	// folder is used and an upload can only be resumed while the agent is running.
+	CacheDir string
+}
+
+// Receive uploads the changed files of the local folder into the target folder. If the connection drops, the
+// upload is resumed and already received chunks are not sent again.
+func Receive(ctx context.Context, open Open, options Options, log log.Logger) error {
+	if options.CacheDir == "" {
+		tempDir, err := os.MkdirTemp("", "devpod-upload-")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+
+		options.CacheDir = tempDir
+	}
+
+	r := &receiver{
+		options:   options,
+		chunksDir: filepath.Join(options.CacheDir, "chunks"),
+		log:       log,
+	}
+	for attempt := 1; ; attempt++ {
+		err := r.receive(ctx, open)
+		if err == nil {
+			_ = os.RemoveAll(r.chunksDir)
+			return nil
+		} else if errors.Is(err, ErrUnsupported) || ctx.Err() != nil || attempt >= maxAttempts {
+			return err
+		}
+
+		log.Warnf("Upload interrupted, resuming: %v", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+type receiver struct {
+	options   Options
+	chunksDir string
+	log       log.Logger
+
+	// locations of the chunks that are already in the target folder
+	locations map[string]location
+
+	total       int64
+	received    int64
+	lastMessage time.Time
+}
+
+type location struct {
+	path  string
+	index int
+}
+
+func (r *receiver) receive(ctx context.Context, open Open) error {
+	err := os.MkdirAll(r.chunksDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	request, err := r.request()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := open(ctx)
+	if err != nil {
+		return err
+	}
+
+	// send what we already have
+	buf := bufio.NewWriterSize(&streamWriter{stream: stream}, 64*1024)
+	err = WriteRequest(buf, request)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	if err != nil {
+		return fmt.Errorf("send upload request %w", err)
+	}
+
+	// receive the changes
+	reader := &streamReader{stream: stream}
+	err = r.receiveFrames(reader)
+	if err != nil {
+		if status.Code(reader.err) == codes.Unimplemented {
+			return ErrUnsupported
+		} else if reader.err != nil && !errors.Is(reader.err, io.EOF) {
+			return reader.err
+		}
+
+		return err
+	}
+
+	// wait until the local machine finished the upload
+	_, _ = io.Copy(io.Discard, reader)
+	return nil
+}
+
+// request creates the manifest of the target folder and lists the chunks of an interrupted upload
+func (r *receiver) request() (*Request, error) {
+	excludes, err := filesync.ReadExcludes(r.options.Target)
+	if err != nil {
+		excludes = nil
+	}
+
+	files, err := Scan(r.options.Target, excludes, filepath.Join(r.options.CacheDir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("scan %s %w", r.options.Target, err)
+	}
+
+	r.locations = map[string]location{}
+	for relPath, file := range files {
+		for i, hash := range file.Chunks {
+			r.locations[hash] = location{path: relPath, index: i}
+		}
+	}
+
+	cached := []string{}
+	entries, err := os.ReadDir(r.chunksDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && len(entry.Name()) == 64 {
+			cached = append(cached, entry.Name())
+		}
+	}
+	if len(cached) > 0 {
+		r.log.Debugf("Resuming upload with %d received chunks", len(cached))
+	}
+
+	return &Request{Mount: r.options.Mount, Files: files, Cached: cached}, nil
+}
+
+func (r *receiver) receiveFrames(reader io.Reader) error {
+	decoder, err := zstd.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer decoder.Close()
+
+	gobDecoder := gob.NewDecoder(decoder)
+	files := 0
+	for {
+		f := &frame{}
+		err := gobDecoder.Decode(f)
+		if err != nil {
+			return fmt.Errorf("receive upload %w", err)
+		}
+
+		switch {
+		case f.Header != nil:
+			r.total = f.Header.Bytes
+			r.received = 0
+			r.lastMessage = time.Now()
+			if f.Header.Files > 0 {
+				r.log.Infof("Uploading %d changed files (%.2f MB)", f.Header.Files, megabytes(f.Header.Bytes))
+			}
+		case f.Chunk != nil:
+			err = r.writeChunk(f.Chunk)
+			if err != nil {
+				return err
+			}
+		case f.File != nil:
+			err = r.writeFile(f.File.Path, f.File.File)
+			if err != nil {
+				return fmt.Errorf("write %s %w", f.File.Path, err)
+			}
+			files++
+		case f.Deleted != nil:
+			r.deleteFiles(f.Deleted)
+		case f.Done:
+			if files > 0 {
+				r.log.Donef("Uploaded %d changed files", files)
+			} else {
+				r.log.Infof("Workspace folder is up to date")
+			}
+			return nil
+		}
+	}
+}
+
+func (r *receiver) writeChunk(c *chunk) error {
+	if hashChunk(c.Data) != c.Hash {
+		return fmt.Errorf("received corrupted chunk %s", c.Hash)
+	}
+
+	err := writeAtomic(filepath.Join(r.chunksDir, c.Hash), c.Data)
+	if err != nil {
+		return err
+	}
+
+	r.received += int64(len(c.Data))
+	if time.Since(r.lastMessage) > time.Second*2 {
+		r.log.Infof("Uploaded %.2f of %.2f MB", megabytes(r.received), megabytes(r.total))
+		r.lastMessage = time.Now()
+	}
+
+	return nil
+}
+
+func (r *receiver) writeFile(relPath string, file *File) error {
+	// symlinked parents are rejected, so the upload can't write outside of the target folder
+	absPath, err := filesync.ResolvePath(r.options.Target, relPath, true)
+	if err != nil {
+		return err
+	}
+
+	switch file.Type {
+	case TypeDir:
+		stat, err := os.Lstat(absPath)
+		if err == nil && !stat.IsDir() {
+			err = os.Remove(absPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = os.MkdirAll(absPath, 0755)
+		if err != nil {
+			return err
+		}
+
+		return os.Chmod(absPath, os.FileMode(file.Mode))
+	case TypeSymlink:
+		err = os.RemoveAll(absPath)
+		if err != nil {
+			return err
+		}
+
+		return os.Symlink(file.Target, absPath)
+	case TypeFile:
+		return r.assembleFile(absPath, file)
+	}
+
+	return fmt.Errorf("unknown type %s", file.Type)
+}
+
+// deleteFiles deletes the files that were deleted locally, directories are only deleted if they are empty
+func (r *receiver) deleteFiles(paths []string) {
+	for _, relPath := range paths {
+		absPath, err := filesync.ResolvePath(r.options.Target, relPath, false)
+		if err != nil {
+			r.log.Debugf("Skip deleting %s: %v", relPath, err)
+			continue
+		}
+
+		err = os.Remove(absPath)
+		if err != nil && !os.IsNotExist(err) {
+			r.log.Debugf("Error deleting %s: %v", relPath, err)
+		}
+	}
+	if len(paths) > 0 {
+		r.log.Infof("Deleted %d locally deleted files", len(paths))
+	}
+}
+
+// assembleFile writes the file from received chunks and chunks of files that already exist
+func (r *receiver) assembleFile(absPath string, file *File) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(absPath), ".devpod-upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tempFile.Name()) }()
+
+	for i, hash := range file.Chunks {
+		data, err := r.readChunk(hash, file.chunkLength(i))
+		if err != nil {
+			_ = tempFile.Close()
+			return err
+		}
+
+		_, err = tempFile.Write(data)
+		if err != nil {
+			_ = tempFile.Close()
+			return err
+		}
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tempFile.Name(), os.FileMode(file.Mode))
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Lstat(absPath)
+	if err == nil && stat.IsDir() {
+		err = os.RemoveAll(absPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tempFile.Name(), absPath)
+}
+
+func (r *receiver) readChunk(hash string, length int64) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(r.chunksDir, hash))
+	if err == nil {
+		return data, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// copy the chunk from a file in the target folder
+	loc, ok := r.locations[hash]
+	if !ok {
+		return nil, fmt.Errorf("chunk %s is missing", hash)
+	}
+
+	absPath, err := filesync.ResolvePath(r.options.Target, loc.path, false)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, err
+	} else if !stat.Mode().IsRegular() {
+		return nil, fmt.Errorf("chunk %s changed in %s", hash, loc.path)
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	data = make([]byte, length)
+	_, err = f.ReadAt(data, int64(loc.index)*ChunkSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	} else if hashChunk(data) != hash {
+		return nil, fmt.Errorf("chunk %s changed in %s", hash, loc.path)
+	}
+
+	return data, nil
+}
+
+func writeAtomic(absPath string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(absPath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tempFile.Name()) }()
+
+	_, err = tempFile.Write(data)
+	if err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), absPath)
+}
+
+func megabytes(bytes int64) float64 {
+	return float64(bytes) / 1024 / 1024
+}
+
+// streamReader reads the chunks of a stream and remembers the error that ended the stream
+type streamReader struct {
+	stream Stream
+	buf    []byte
+	err    error
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+
+		c, err := s.stream.Recv()
+		if err != nil {
+			s.err = err
+			return 0, err
+		}
+		s.buf = c.Content
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+type streamWriter struct {
+	stream Stream
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	err := s.stream.Send(&tunnel.Chunk{Content: p})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,221 @@
+package upload
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Request is sent by the agent to start an upload
+type Request struct {
+	// Mount is the mount to upload, if empty the workspace folder is uploaded
+	Mount string
+
+	// Files is the manifest of the folder the agent already has
+	Files Manifest
+
+	// Cached are the chunks the agent received during an interrupted upload
+	Cached []string
+}
+
+// frame is a single message of the upload stream, exactly one of the fields is set
+type frame struct {
+	Header  *header
+	Chunk   *chunk
+	File    *fileFrame
+	Deleted []string
+	Done    bool
+}
+
+// header announces the amount of data that is sent
+type header struct {
+	Files int
+	Bytes int64
+}
+
+type chunk struct {
+	Hash string
+	Data []byte
+}
+
+// fileFrame is sent after all missing chunks of the file were sent
+type fileFrame struct {
+	Path string
+	File *File
+}
+
+// ReadRequest reads the compressed request of the agent
+func ReadRequest(reader io.Reader) (*Request, error) {
+	decoder, err := zstd.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	request := &Request{}
+	err = gob.NewDecoder(decoder).Decode(request)
+	if err != nil {
+		return nil, fmt.Errorf("decode upload request %w", err)
+	}
+
+	return request, nil
+}
+
+// WriteRequest writes the compressed request of the agent
+func WriteRequest(writer io.Writer, request *Request) error {
+	encoder, err := zstd.NewWriter(writer)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(encoder).Encode(request)
+	if err != nil {
+		_ = encoder.Close()
+		return fmt.Errorf("encode upload request %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// Send compares the folder with the manifest of the agent and writes the changed files as compressed stream.
+// Only chunks the agent has neither in its folder nor in its cache are sent.
+func Send(writer io.Writer, request *Request, root string, excludes []string, cacheFile string) error {
+	local, previous, saveCache, err := scan(root, excludes, cacheFile)
+	if err != nil {
+		return fmt.Errorf("scan %s %w", root, err)
+	}
+
+	available := map[string]bool{}
+	for _, file := range request.Files {
+		for _, hash := range file.Chunks {
+			available[hash] = true
+		}
+	}
+	for _, hash := range request.Cached {
+		available[hash] = true
+	}
+
+	// parents are sorted before their children
+	changed := []string{}
+	for relPath, file := range local {
+		if !file.Equal(request.Files[relPath]) {
+			changed = append(changed, relPath)
+		}
+	}
+	sort.Strings(changed)
+
+	// calculate what needs to be sent
+	missing := map[string]bool{}
+	size := int64(0)
+	for _, relPath := range changed {
+		file := local[relPath]
+		for i, hash := range file.Chunks {
+			if !available[hash] && !missing[hash] {
+				missing[hash] = true
+				size += file.chunkLength(i)
+			}
+		}
+	}
+
+	encoder, err := zstd.NewWriter(writer)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = encoder.Close() }()
+
+	gobEncoder := gob.NewEncoder(encoder)
+	err = gobEncoder.Encode(&frame{Header: &header{Files: len(changed), Bytes: size}})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, ChunkSize)
+	for _, relPath := range changed {
+		file := local[relPath]
+		err = sendChunks(gobEncoder, filepath.Join(root, filepath.FromSlash(relPath)), file, available, buf)
+		if err != nil {
+			return fmt.Errorf("send %s %w", relPath, err)
+		}
+
+		err = gobEncoder.Encode(&frame{File: &fileFrame{Path: relPath, File: file}})
+		if err != nil {
+			return err
+		}
+	}
+
+	deleted := deletedPaths(previous, local, request.Files)
+	if len(deleted) > 0 {
+		err = gobEncoder.Encode(&frame{Deleted: deleted})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = gobEncoder.Encode(&frame{Done: true})
+	if err != nil {
+		return err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return err
+	}
+
+	// deletions of an interrupted upload are sent again
+	saveCache()
+	return nil
+}
+
+// deletedPaths returns the paths that were deleted locally since the last upload and are unchanged on the
+// other side, so files created or changed in the workspace are never deleted. Children are sorted before
+// their parents.
+func deletedPaths(previous map[string]cachedFile, local, remote Manifest) []string {
+	deleted := []string{}
+	for relPath, cached := range previous {
+		remoteFile := remote[relPath]
+		if local[relPath] != nil || remoteFile == nil || remoteFile.Type != cached.Type {
+			continue
+		} else if cached.Type == TypeFile && !remoteFile.Equal(&File{Type: TypeFile, Mode: remoteFile.Mode, Size: cached.Size, Chunks: cached.Chunks}) {
+			continue
+		}
+
+		deleted = append(deleted, relPath)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(deleted)))
+
+	return deleted
+}
+
+func sendChunks(gobEncoder *gob.Encoder, absPath string, file *File, available map[string]bool, buf []byte) error {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	for i, hash := range file.Chunks {
+		if available[hash] {
+			continue
+		}
+
+		data := buf[:file.chunkLength(i)]
+		_, err := f.ReadAt(data, int64(i)*ChunkSize)
+		if err != nil && err != io.EOF {
+			return err
+		} else if hashChunk(data) != hash {
+			return fmt.Errorf("file changed during upload")
+		}
+
+		err = gobEncoder.Encode(&frame{Chunk: &chunk{Hash: hash, Data: data}})
+		if err != nil {
+			return err
+		}
+		available[hash] = true
+	}
+
+	return nil
+}
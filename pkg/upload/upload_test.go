@@ -0,0 +1,219 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/skevetter/devpod/pkg/agent/tunnel"
+	"github.com/skevetter/log"
+)
+
+// pipeStream connects the receiver directly to Send
+type pipeStream struct {
+	requestWriter  *io.PipeWriter
+	responseReader *io.PipeReader
+}
+
+func (p *pipeStream) Send(c *tunnel.Chunk) error {
+	_, err := p.requestWriter.Write(c.Content)
+	return err
+}
+
+func (p *pipeStream) Recv() (*tunnel.Chunk, error) {
+	buf := make([]byte, 32*1024)
+	n, err := p.responseReader.Read(buf)
+	if n > 0 {
+		return &tunnel.Chunk{Content: buf[:n]}, nil
+	}
+
+	return nil, err
+}
+
+func (p *pipeStream) CloseSend() error {
+	return p.requestWriter.Close()
+}
+
+// limitWriter fails after limit bytes to simulate a dropped connection
+type limitWriter struct {
+	writer  io.Writer
+	limit   int64
+	written int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.limit > 0 && l.written+int64(len(p)) > l.limit {
+		return 0, errors.New("connection dropped")
+	}
+
+	l.written += int64(len(p))
+	return l.writer.Write(p)
+}
+
+type testServer struct {
+	root      string
+	cacheFile string
+
+	// limits are applied to the responses in order
+	limits []int64
+
+	m        sync.Mutex
+	requests []*Request
+	sent     []int64
+}
+
+func (s *testServer) open(ctx context.Context) (Stream, error) {
+	requestReader, requestWriter := io.Pipe()
+	responseReader, responseWriter := io.Pipe()
+	limit := int64(0)
+	if len(s.limits) > 0 {
+		limit, s.limits = s.limits[0], s.limits[1:]
+	}
+
+	go func() {
+		request, err := ReadRequest(requestReader)
+		if err != nil {
+			_ = responseWriter.CloseWithError(err)
+			return
+		}
+		writer := &limitWriter{writer: responseWriter, limit: limit}
+		err = Send(writer, request, s.root, nil, s.cacheFile)
+
+		s.m.Lock()
+		s.requests = append(s.requests, request)
+		s.sent = append(s.sent, writer.written)
+		s.m.Unlock()
+		_ = responseWriter.CloseWithError(err)
+	}()
+
+	return &pipeStream{requestWriter: requestWriter, responseReader: responseReader}, nil
+}
+
+func TestReceive(t *testing.T) {
+	ctx := context.Background()
+	localDir, targetDir, cacheDir := t.TempDir(), t.TempDir(), t.TempDir()
+	big := make([]byte, 3*ChunkSize)
+	_, _ = rand.Read(big)
+	writeTestFile(t, filepath.Join(localDir, "big.bin"), big)
+	writeTestFile(t, filepath.Join(localDir, "src", "main.go"), []byte("package main"))
+	err := os.Symlink("src/main.go", filepath.Join(localDir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first connection drops after the first chunks were received
+	server := &testServer{root: localDir, limits: []int64{ChunkSize + ChunkSize/2}}
+	err = Receive(ctx, server.open, Options{Target: targetDir, CacheDir: cacheDir}, log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.requests) != 2 || len(server.requests[1].Cached) == 0 {
+		t.Fatalf("expected the upload to resume with cached chunks, got %d requests", len(server.requests))
+	} else if server.sent[1] >= int64(len(big)) {
+		t.Errorf("expected the resumed upload to skip received chunks, sent %d bytes", server.sent[1])
+	}
+	expectFile(t, filepath.Join(targetDir, "big.bin"), big)
+	expectFile(t, filepath.Join(targetDir, "src", "main.go"), []byte("package main"))
+	if target, err := os.Readlink(filepath.Join(targetDir, "link")); err != nil || target != "src/main.go" {
+		t.Errorf("expected symlink to src/main.go, got %q, %v", target, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(cacheDir, "chunks")); len(entries) > 0 {
+		t.Errorf("expected received chunks to be removed, got %d", len(entries))
+	}
+
+	// only changed content is sent again
+	writeTestFile(t, filepath.Join(localDir, "src", "main.go"), []byte("package main\n\nfunc main() {}"))
+	server = &testServer{root: localDir}
+	err = Receive(ctx, server.open, Options{Target: targetDir, CacheDir: cacheDir}, log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.sent[0] >= ChunkSize {
+		t.Errorf("expected only the changed file to be sent, sent %d bytes", server.sent[0])
+	}
+	expectFile(t, filepath.Join(targetDir, "src", "main.go"), []byte("package main\n\nfunc main() {}"))
+}
+
+func TestReceiveDeleted(t *testing.T) {
+	ctx := context.Background()
+	localDir, targetDir, cacheDir := t.TempDir(), t.TempDir(), t.TempDir()
+	cacheFile := filepath.Join(t.TempDir(), ManifestCacheFile)
+	writeTestFile(t, filepath.Join(localDir, "keep.txt"), []byte("keep"))
+	writeTestFile(t, filepath.Join(localDir, "old", "deleted.txt"), []byte("deleted"))
+	writeTestFile(t, filepath.Join(localDir, "changed.txt"), []byte("changed"))
+
+	server := &testServer{root: localDir, cacheFile: cacheFile}
+	err := Receive(ctx, server.open, Options{Target: targetDir, CacheDir: cacheDir}, log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files deleted locally are deleted in the target, unless they were changed or created there
+	err = os.RemoveAll(filepath.Join(localDir, "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(filepath.Join(localDir, "changed.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(targetDir, "changed.txt"), []byte("changed in the workspace"))
+	writeTestFile(t, filepath.Join(targetDir, "created.txt"), []byte("created"))
+
+	server = &testServer{root: localDir, cacheFile: cacheFile}
+	err = Receive(ctx, server.open, Options{Target: targetDir, CacheDir: cacheDir}, log.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(targetDir, "old")); !os.IsNotExist(err) {
+		t.Errorf("expected the deleted folder to be deleted, got %v", err)
+	}
+	expectFile(t, filepath.Join(targetDir, "keep.txt"), []byte("keep"))
+	expectFile(t, filepath.Join(targetDir, "changed.txt"), []byte("changed in the workspace"))
+	expectFile(t, filepath.Join(targetDir, "created.txt"), []byte("created"))
+}
+
+func TestWriteFileSymlinkParent(t *testing.T) {
+	targetDir, outsideDir := t.TempDir(), t.TempDir()
+	err := os.Symlink(outsideDir, filepath.Join(targetDir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &receiver{options: Options{Target: targetDir}, log: log.Discard}
+	err = r.writeFile("link/escaped", &File{Type: TypeSymlink, Target: "/etc/passwd"})
+	if err == nil {
+		t.Errorf("expected writing below a symlinked parent to fail")
+	}
+	if _, err := os.Lstat(filepath.Join(outsideDir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("expected no file outside of the target folder, got %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, content, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(out, content) {
+		t.Errorf("unexpected content of %s", path)
+	}
+}
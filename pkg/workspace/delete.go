@@ -86,7 +86,7 @@ func Delete(ctx context.Context, devPodConfig *config.Config, args []string, ign
 		instanceStatus, err := client.Status(ctx, client2.StatusOptions{})
 		if err != nil {
 			return "", err
-		} else if instanceStatus == client2.StatusNotFound {
+		} else if instanceStatus.State == client2.StatusNotFound {
 			return "", fmt.Errorf("cannot delete workspace because it couldn't be found. Run with --force to ignore this error")
 		}
 	}